@@ -32,9 +32,7 @@ func AllReduceRing(
 				uint64(eachGPUDataSize),
 				uint64(dataOffset),
 			)
-			for i := 0; i < numGPU; i++ {
-				d.DrainCommandQueue(cmdQs[i])
-			}
+			syncRing(d, cmdQs)
 
 			allReduceReduce(
 				d, comms, cmdQs, data, bufs, step, numGPU, numThread,
@@ -42,9 +40,7 @@ func AllReduceRing(
 				uint64(eachGPUDataSize),
 				uint64(dataOffset),
 			)
-			for i := 0; i < numGPU; i++ {
-				d.DrainCommandQueue(cmdQs[i])
-			}
+			syncRing(d, cmdQs)
 		}
 
 		//Step 2: k-1 steps push only. push to next gpu directly
@@ -55,11 +51,11 @@ func AllReduceRing(
 				uint64(eachGPUDataSize),
 				uint64(dataOffset),
 			)
-			for i := 0; i < numGPU; i++ {
-				d.DrainCommandQueue(cmdQs[i])
-			}
+			syncRing(d, cmdQs)
 		}
 	}
+
+	drainAll(d, cmdQs)
 }
 
 func createCommandQueues(
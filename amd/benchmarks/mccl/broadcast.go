@@ -62,8 +62,8 @@ func BroadcastRing(
 			)
 		}
 
-		for i := 0; i < numGPU; i++ {
-			d.DrainCommandQueue(cmdQs[i])
-		}
+		syncRing(d, cmdQs)
 	}
+
+	drainAll(d, cmdQs)
 }
@@ -5,6 +5,7 @@ import (
 	// For embedded HsaCo files.
 	_ "embed"
 
+	"github.com/sarchlab/mgpusim/v4/amd/driver"
 	"github.com/sarchlab/mgpusim/v4/amd/insts"
 	"github.com/sarchlab/mgpusim/v4/amd/kernels"
 )
@@ -47,6 +48,31 @@ func computeGPUDist(comms []*Communicator, root int) []int {
 	return distance
 }
 
+// syncRing makes the commands enqueued next in each queue wait for the commands
+// already enqueued in the neighbors in the ring. The previous GPU writes the
+// data that a GPU reads next, and the next GPU reads the data that a GPU
+// overwrites next. The host does not wait.
+func syncRing(d *driver.Driver, cmdQs []*driver.CommandQueue) {
+	numGPU := len(cmdQs)
+
+	events := make([]*driver.Event, numGPU)
+	for i := 0; i < numGPU; i++ {
+		events[i] = d.CreateEvent()
+		d.RecordEvent(cmdQs[i], events[i])
+	}
+
+	for i := 0; i < numGPU; i++ {
+		d.QueueWaitEvent(cmdQs[i], events[(i+numGPU-1)%numGPU])
+		d.QueueWaitEvent(cmdQs[i], events[(i+1)%numGPU])
+	}
+}
+
+func drainAll(d *driver.Driver, cmdQs []*driver.CommandQueue) {
+	for i := 0; i < len(cmdQs); i++ {
+		d.DrainCommandQueue(cmdQs[i])
+	}
+}
+
 func minUint64(a, b uint64) uint64 {
 	if a < b {
 		return a
@@ -63,7 +63,7 @@ var _ = Describe("MCCL", func() {
 			gpuDriver.SelectGPU(context, i+1)
 			gpuDriver.MemCopyD2H(context, tmp, datas[i])
 			for j := 0; j < dataSize; j++ {
-				Expect(tmp[i]).To(Equal(float32(-0.0000123)))
+				Expect(tmp[j]).To(Equal(float32(-0.0000123)))
 			}
 		}
 	})
@@ -97,7 +97,7 @@ var _ = Describe("MCCL", func() {
 			gpuDriver.SelectGPU(context, i+1)
 			gpuDriver.MemCopyD2H(context, tmp, datas[i])
 			for j := 0; j < int(dataSize); j++ {
-				Expect(tmp[i]).To(Equal(float32(2.5)))
+				Expect(tmp[j]).To(Equal(float32(2.5)))
 			}
 			log.Printf("Passed")
 		}
@@ -132,7 +132,7 @@ var _ = Describe("MCCL", func() {
 			gpuDriver.SelectGPU(context, i+1)
 			gpuDriver.MemCopyD2H(context, tmp, datas[i])
 			for j := 0; j < int(dataSize); j++ {
-				Expect(tmp[i]).To(Equal(float32(2.5)))
+				Expect(tmp[j]).To(Equal(float32(2.5)))
 			}
 			log.Printf("Passed")
 		}
@@ -0,0 +1,260 @@
+package driver
+
+import (
+	"github.com/sarchlab/akita/v4/mem/vm"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/protocol"
+)
+
+// AccessCounterConfig configures the migration of the pages of unified memory
+// that the GPUs access remotely, like the access counters of NVIDIA Volta.
+// The RDMA engine of each GPU counts the accesses to each page of remote
+// memory and notifies the driver when a page reaches a threshold. The driver
+// then moves the page to that GPU, without waiting for a fault.
+type AccessCounterConfig struct {
+	// MinInterval is the minimum time between two migrations of a page. A
+	// page that becomes hot again sooner stays where it is, so that the GPUs
+	// that share it do not move it back and forth.
+	MinInterval sim.VTimeInSec
+
+	// MaxMigrations is the number of migrations after which a page is no
+	// longer moved by the access counters. 0 means no limit.
+	MaxMigrations int
+}
+
+// PageMigrationStats counts the pages that the driver has migrated.
+type PageMigrationStats struct {
+	// FaultMigrations is the number of pages migrated because a GPU touched
+	// a page that was not on it.
+	FaultMigrations uint64
+
+	// CounterMigrations is the number of hot pages migrated by the access
+	// counters.
+	CounterMigrations uint64
+
+	// Notifications is the number of access counter notifications that the
+	// GPUs sent, and Throttled the number of them that the thrashing
+	// protection ignored.
+	Notifications uint64
+	Throttled     uint64
+
+	// BytesMigrated is the number of bytes copied between GPUs.
+	BytesMigrated uint64
+
+	// Evictions is the number of pages evicted from the GPUs to the memory
+	// of the host, EvictedBytes their size, and EvictionStallTime the time
+	// that the migrations waited for the evictions.
+	Evictions         uint64
+	EvictedBytes      uint64
+	EvictionStallTime sim.VTimeInSec
+
+	// HostPageIns is the number of pages copied back from the memory of the
+	// host to a GPU.
+	HostPageIns uint64
+
+	// PagesAllocatedOnHost is the number of pages of unified memory that did
+	// not fit on their GPU when allocated.
+	PagesAllocatedOnHost uint64
+}
+
+type hotPage struct {
+	pAddr uint64
+	gpuID uint64
+}
+
+type pageMigrationHistory struct {
+	numMigrations int
+	lastMigration sim.VTimeInSec
+}
+
+// accessCounterMigrator migrates the pages that the access counters find
+// hot. It uses the same steps as the migrations requested by the MMU, one
+// page at a time, when the driver is not migrating other pages.
+type accessCounterMigrator struct {
+	driver *Driver
+	config AccessCounterConfig
+
+	hotPages []hotPage
+	queued   map[hotPage]bool
+	history  map[pageKey]*pageMigrationHistory
+
+	// migrating is the page being migrated, if any.
+	migrating *vm.Page
+}
+
+// EnableAccessCounterMigration makes the driver migrate the pages that the
+// access counters of the GPUs find hot. The GPUs must be built with an
+// access counter threshold. See AccessCounterConfig.
+func (d *Driver) EnableAccessCounterMigration(config AccessCounterConfig) {
+	d.accessCounterMigrator = &accessCounterMigrator{
+		driver:  d,
+		config:  config,
+		queued:  make(map[hotPage]bool),
+		history: make(map[pageKey]*pageMigrationHistory),
+	}
+	d.addPreDispatchMiddleware(preDispatchAccessCounters,
+		d.accessCounterMigrator)
+}
+
+// PageMigrationStats returns the number of pages that the driver has
+// migrated.
+func (d *Driver) PageMigrationStats() PageMigrationStats {
+	return d.migrationStats
+}
+
+func (d *Driver) processAccessCounterNotification(
+	msg *protocol.AccessCounterNotificationToDriver,
+) bool {
+	d.migrationStats.Notifications++
+
+	if d.accessCounterMigrator == nil {
+		return true
+	}
+
+	for i, gpu := range d.GPUs {
+		if gpu.AsRemote() == msg.Src {
+			d.accessCounterMigrator.enqueue(
+				hotPage{pAddr: msg.PAddr, gpuID: uint64(i + 1)})
+
+			return true
+		}
+	}
+
+	panic("notification from unknown GPU")
+}
+
+// enqueue queues a hot page, unless the same GPU already notified it. The
+// GPUs clear their counters after each migration, so a page that waits may be
+// notified again.
+func (m *accessCounterMigrator) enqueue(hot hotPage) {
+	if m.queued[hot] {
+		return
+	}
+
+	m.queued[hot] = true
+	m.hotPages = append(m.hotPages, hot)
+}
+
+// ProcessCommand leaves every command to the driver. The migrations start
+// from the access counter notifications, not from the commands.
+func (m *accessCounterMigrator) ProcessCommand(
+	cmd Command,
+	queue *CommandQueue,
+) bool {
+	return false
+}
+
+// Tick starts the migration of the next hot page.
+func (m *accessCounterMigrator) Tick() bool {
+	if m.driver.isCurrentlyHandlingMigrationReq || len(m.hotPages) == 0 ||
+		m.driver.evictor.delaysMigration() {
+		return false
+	}
+
+	hot := m.hotPages[0]
+	m.hotPages = m.hotPages[1:]
+	delete(m.queued, hot)
+
+	page, ok := m.pageToMigrate(hot)
+	if !ok {
+		return true
+	}
+
+	m.startMigration(page, hot.gpuID)
+
+	return true
+}
+
+// pageToMigrate returns the page that a notification refers to, if it still
+// needs to move. The page may have moved or have been freed since the GPU
+// counted the accesses.
+func (m *accessCounterMigrator) pageToMigrate(hot hotPage) (vm.Page, bool) {
+	d := m.driver
+
+	page, found := d.pageTable.ReverseLookup(hot.pAddr)
+	if !found || !page.Unified || page.IsMigrating ||
+		page.DeviceID == hot.gpuID {
+		return page, false
+	}
+
+	// The preferred location of a page and the static placements win over
+	// the access counters.
+	if preferredGPU, _ := d.placement.lookup(page.PID, page.VAddr); preferredGPU > 0 ||
+		d.placement.isStatic(page.PID, page.VAddr) {
+		return page, false
+	}
+
+	if m.isThrashing(page) {
+		d.migrationStats.Throttled++
+		return page, false
+	}
+
+	return page, true
+}
+
+func (m *accessCounterMigrator) isThrashing(page vm.Page) bool {
+	h, found := m.history[pageKey{page.PID, page.VAddr}]
+	if !found {
+		return false
+	}
+
+	if m.config.MaxMigrations > 0 && h.numMigrations >= m.config.MaxMigrations {
+		return true
+	}
+
+	now := m.driver.Engine.CurrentTime()
+
+	return now-h.lastMigration < m.config.MinInterval
+}
+
+// startMigration migrates the page as if the GPU had touched it. All the
+// GPUs may access the page remotely, so all of them are shot down.
+func (m *accessCounterMigrator) startMigration(page vm.Page, gpuID uint64) {
+	d := m.driver
+
+	req := vm.NewPageMigrationReqToDriver("", d.mmuPort.AsRemote())
+	req.PID = page.PID
+	req.PageSize = page.PageSize
+	req.CurrPageHostGPU = page.DeviceID
+	req.CurrAccessingGPUs = d.allGPUIDs()
+	req.MigrationInfo = &vm.PageMigrationInfo{
+		GPUReqToVAddrMap: map[uint64][]uint64{gpuID: {page.VAddr}},
+	}
+
+	m.migrating = &page
+	d.currentPageMigrationReq = req
+	d.isCurrentlyHandlingMigrationReq = true
+	d.initiateRDMADrain()
+}
+
+// completeMigration does what the MMU does with the pages that it asks to
+// migrate: the page is no longer migrating and stays where it is until the
+// access counters move it again.
+func (m *accessCounterMigrator) completeMigration() {
+	d := m.driver
+
+	page, found := d.pageTable.Find(m.migrating.PID, m.migrating.VAddr)
+	if !found {
+		panic("page not found")
+	}
+
+	page.IsMigrating = false
+	page.IsPinned = true
+	d.pageTable.Update(page)
+
+	key := pageKey{page.PID, page.VAddr}
+	h, found := m.history[key]
+	if !found {
+		h = &pageMigrationHistory{}
+		m.history[key] = h
+	}
+	h.numMigrations++
+	h.lastMigration = d.Engine.CurrentTime()
+
+	m.migrating = nil
+}
+
+// isMigrating returns true if the current migration is one of a hot page.
+func (m *accessCounterMigrator) isMigrating() bool {
+	return m != nil && m.migrating != nil
+}
@@ -0,0 +1,178 @@
+package driver
+
+import (
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/mem/vm"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/protocol"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = ginkgo.Describe("Access Counter Migration", func() {
+	var (
+		mockCtrl  *gomock.Controller
+		engine    *MockEngine
+		pageTable *MockPageTable
+		driver    *Driver
+	)
+
+	ginkgo.BeforeEach(func() {
+		f := newDriverFixture(nil)
+		mockCtrl = f.mockCtrl
+		engine = f.engine
+		pageTable = f.pageTable
+		driver = f.driver
+
+		driver.EnableAccessCounterMigration(AccessCounterConfig{
+			MinInterval:   10,
+			MaxMigrations: 2,
+		})
+	})
+
+	ginkgo.AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	hotPageOnGPU1 := func() vm.Page {
+		return vm.Page{
+			PID:      1,
+			VAddr:    0x1000,
+			PAddr:    0x1_0000_1000,
+			PageSize: 4096,
+			Valid:    true,
+			DeviceID: 1,
+			Unified:  true,
+			IsPinned: true,
+		}
+	}
+
+	notify := func(gpuID int) {
+		msg := protocol.NewAccessCounterNotificationToDriver(
+			driver.GPUs[gpuID-1], driver.gpuPort, 0x1_0000_1000, 8)
+		driver.processAccessCounterNotification(msg)
+	}
+
+	ginkgo.It("should queue the page that a GPU notifies once", func() {
+		notify(2)
+		notify(2)
+
+		Expect(driver.accessCounterMigrator.hotPages).To(Equal([]hotPage{
+			{pAddr: 0x1_0000_1000, gpuID: 2},
+		}))
+		Expect(driver.PageMigrationStats().Notifications).
+			To(Equal(uint64(2)))
+	})
+
+	ginkgo.It("should only count the notifications if not enabled", func() {
+		driver.accessCounterMigrator = nil
+
+		notify(2)
+
+		Expect(driver.PageMigrationStats().Notifications).
+			To(Equal(uint64(1)))
+	})
+
+	ginkgo.It("should start the migration of a hot page", func() {
+		notify(2)
+		pageTable.EXPECT().
+			ReverseLookup(uint64(0x1_0000_1000)).
+			Return(hotPageOnGPU1(), true)
+
+		madeProgress := driver.accessCounterMigrator.Tick()
+
+		Expect(madeProgress).To(BeTrue())
+		Expect(driver.isCurrentlyHandlingMigrationReq).To(BeTrue())
+		Expect(driver.accessCounterMigrator.isMigrating()).To(BeTrue())
+		req := driver.currentPageMigrationReq
+		Expect(req.CurrPageHostGPU).To(Equal(uint64(1)))
+		Expect(req.CurrAccessingGPUs).To(Equal([]uint64{1, 2}))
+		Expect(req.MigrationInfo.GPUReqToVAddrMap).
+			To(Equal(map[uint64][]uint64{2: {0x1000}}))
+		Expect(driver.numRDMADrainACK).To(Equal(uint64(2)))
+	})
+
+	ginkgo.It("should not migrate a page already on the GPU", func() {
+		notify(1)
+		pageTable.EXPECT().
+			ReverseLookup(uint64(0x1_0000_1000)).
+			Return(hotPageOnGPU1(), true)
+
+		driver.accessCounterMigrator.Tick()
+
+		Expect(driver.isCurrentlyHandlingMigrationReq).To(BeFalse())
+	})
+
+	ginkgo.It("should not migrate a page of a static placement", func() {
+		notify(2)
+		driver.placement.place(1, 0x1000, true)
+		pageTable.EXPECT().
+			ReverseLookup(uint64(0x1_0000_1000)).
+			Return(hotPageOnGPU1(), true)
+
+		driver.accessCounterMigrator.Tick()
+
+		Expect(driver.isCurrentlyHandlingMigrationReq).To(BeFalse())
+	})
+
+	ginkgo.It("should not migrate while migrating other pages", func() {
+		notify(2)
+		driver.isCurrentlyHandlingMigrationReq = true
+
+		madeProgress := driver.accessCounterMigrator.Tick()
+
+		Expect(madeProgress).To(BeFalse())
+		Expect(driver.accessCounterMigrator.hotPages).To(HaveLen(1))
+	})
+
+	ginkgo.It("should not move a page back too soon", func() {
+		driver.accessCounterMigrator.history[pageKey{1, 0x1000}] =
+			&pageMigrationHistory{numMigrations: 1, lastMigration: 5}
+		notify(2)
+		pageTable.EXPECT().
+			ReverseLookup(uint64(0x1_0000_1000)).
+			Return(hotPageOnGPU1(), true)
+		engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(12))
+
+		driver.accessCounterMigrator.Tick()
+
+		Expect(driver.isCurrentlyHandlingMigrationReq).To(BeFalse())
+		Expect(driver.PageMigrationStats().Throttled).To(Equal(uint64(1)))
+	})
+
+	ginkgo.It("should stop moving a page that migrated too often", func() {
+		driver.accessCounterMigrator.history[pageKey{1, 0x1000}] =
+			&pageMigrationHistory{numMigrations: 2, lastMigration: 5}
+		notify(2)
+		pageTable.EXPECT().
+			ReverseLookup(uint64(0x1_0000_1000)).
+			Return(hotPageOnGPU1(), true)
+
+		driver.accessCounterMigrator.Tick()
+
+		Expect(driver.isCurrentlyHandlingMigrationReq).To(BeFalse())
+		Expect(driver.PageMigrationStats().Throttled).To(Equal(uint64(1)))
+	})
+
+	ginkgo.It("should pin the page when the migration completes", func() {
+		page := hotPageOnGPU1()
+		page.DeviceID = 2
+		page.IsPinned = false
+		page.IsMigrating = true
+		driver.accessCounterMigrator.migrating = &page
+		pageTable.EXPECT().Find(vm.PID(1), uint64(0x1000)).Return(page, true)
+		page.IsMigrating = false
+		page.IsPinned = true
+		pageTable.EXPECT().Update(page)
+		engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(20))
+
+		driver.accessCounterMigrator.completeMigration()
+
+		Expect(driver.accessCounterMigrator.isMigrating()).To(BeFalse())
+		Expect(driver.accessCounterMigrator.history[pageKey{1, 0x1000}]).
+			To(Equal(&pageMigrationHistory{
+				numMigrations: 1,
+				lastMigration: 20,
+			}))
+	})
+})
@@ -147,12 +147,23 @@ func (d *Driver) AllocateMemory(
 	return Ptr(ptr)
 }
 
-// AllocateUnifiedMemory allocates a unified memory. Allocation is done on CPU
+// AllocateUnifiedMemory allocates a unified memory. Allocation is done on CPU,
+// unless a placement is set with SetUnifiedMemoryPlacement.
 func (d *Driver) AllocateUnifiedMemory(
 	ctx *Context,
 	byteSize uint64,
 ) Ptr {
-	ptr := Ptr(d.memAllocator.AllocateUnified(ctx.pid, byteSize))
+	if d.unifiedMemoryPlacement != nil {
+		return d.AllocateUnifiedMemoryWithPlacement(ctx, byteSize,
+			d.unifiedMemoryGPUIDs, d.unifiedMemoryPlacement)
+	}
+
+	var ptr Ptr
+	if d.evictor != nil {
+		ptr = d.allocateUnifiedMemoryOnGPU1(ctx, byteSize)
+	} else {
+		ptr = Ptr(d.memAllocator.AllocateUnified(ctx.pid, byteSize))
+	}
 
 	ctx.buffers = append(ctx.buffers, &buffer{
 		vAddr:   ptr,
@@ -164,6 +175,25 @@ func (d *Driver) AllocateUnifiedMemory(
 	return ptr
 }
 
+// allocateUnifiedMemoryOnGPU1 allocates unified memory on the first GPU, as
+// AllocateUnified does, except for the pages that do not fit there.
+func (d *Driver) allocateUnifiedMemoryOnGPU1(ctx *Context, byteSize uint64) Ptr {
+	pageSize := uint64(1) << d.Log2PageSize
+	numPages := (byteSize-1)/pageSize + 1
+
+	deviceIDs := make([]int, numPages)
+	for i := range deviceIDs {
+		deviceIDs[i] = 1
+	}
+
+	deviceIDs = d.evictor.placeOnDevices(deviceIDs)
+	ptr := Ptr(d.memAllocator.AllocateUnifiedOnDevices(
+		ctx.pid, byteSize, deviceIDs))
+	d.evictor.trackAllocation(ctx.pid, ptr, deviceIDs)
+
+	return ptr
+}
+
 // Remap keeps the virtual address unchanged and moves the physical address to
 // another GPU
 func (d *Driver) Remap(ctx *Context, addr, size uint64, deviceID int) {
@@ -179,6 +209,11 @@ func (d *Driver) Distribute(
 	byteSize uint64,
 	gpuIDs []int,
 ) []uint64 {
+	if d.distributePlacement != nil {
+		return d.DistributeWithPlacement(
+			ctx, addr, byteSize, gpuIDs, d.distributePlacement)
+	}
+
 	if len(gpuIDs) == 1 {
 		return []uint64{byteSize}
 	}
@@ -210,6 +245,8 @@ func (d *Driver) FreeMemory(ctx *Context, ptr Ptr) error {
 	for i, buffer := range ctx.buffers {
 		if buffer.vAddr == ptr {
 			ctx.buffers[i].freed = true
+			d.removePlacement(ctx, ptr, buffer.size)
+			d.evictor.forgetFreedPages(ctx.pid, ptr, buffer.size)
 		}
 	}
 
@@ -270,6 +307,28 @@ func (d *Driver) EnqueueMemCopyD2D(
 	d.EnqueueLaunchKernel(queue, co, gridSize, wgSize, &kernelArgs)
 }
 
+// EnqueueMemCopyPeer registers a MemCopyPeerCommand in the queue. Unlike
+// EnqueueMemCopyD2D, the copy does not run a kernel. The DMA engine of the GPU
+// that holds the source copies the data over the inter-GPU network.
+func (d *Driver) EnqueueMemCopyPeer(
+	queue *CommandQueue,
+	dst Ptr,
+	src Ptr,
+	byteSize uint64,
+) {
+	if byteSize == 0 {
+		return
+	}
+
+	cmd := &MemCopyPeerCommand{
+		ID:       sim.GetIDGenerator().Generate(),
+		Dst:      dst,
+		Src:      src,
+		ByteSize: byteSize,
+	}
+	d.Enqueue(queue, cmd)
+}
+
 // MemCopyH2D copies a memory from the host to a GPU device.
 func (d *Driver) MemCopyH2D(ctx *Context, dst Ptr, src interface{}) {
 	queue := d.CreateCommandQueue(ctx)
@@ -291,3 +350,11 @@ func (d *Driver) MemCopyD2D(ctx *Context, dst Ptr, src Ptr, num int) {
 	d.EnqueueMemCopyD2D(queue, dst, src, num)
 	d.DrainCommandQueue(queue)
 }
+
+// MemCopyPeer copies byteSize bytes from a GPU device to another GPU device
+// with the DMA engine of the source GPU.
+func (d *Driver) MemCopyPeer(ctx *Context, dst Ptr, src Ptr, byteSize uint64) {
+	queue := d.CreateCommandQueue(ctx)
+	d.EnqueueMemCopyPeer(queue, dst, src, byteSize)
+	d.DrainCommandQueue(queue)
+}
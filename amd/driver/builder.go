@@ -12,6 +12,7 @@ type Builder struct {
 	engine              sim.Engine
 	freq                sim.Freq
 	log2PageSize        uint64
+	cpuMemSize          uint64
 	pageTable           vm.PageTable
 	globalStorage       *mem.Storage
 	useMagicMemoryCopy  bool
@@ -23,7 +24,8 @@ type Builder struct {
 // parameters.
 func MakeBuilder() Builder {
 	return Builder{
-		freq: 1 * sim.GHz,
+		freq:       1 * sim.GHz,
+		cpuMemSize: 4 * mem.GB,
 	}
 }
 
@@ -52,6 +54,13 @@ func (b Builder) WithLog2PageSize(log2PageSize uint64) Builder {
 	return b
 }
 
+// WithCPUMemSize sets the size of the host memory, which takes the physical
+// addresses from 0 to size. The GPUs registered with RegisterGPU follow it.
+func (b Builder) WithCPUMemSize(size uint64) Builder {
+	b.cpuMemSize = size
+	return b
+}
+
 // WithGlobalStorage sets the global storage that the driver uses.
 func (b Builder) WithGlobalStorage(storage *mem.Storage) Builder {
 	b.globalStorage = storage
@@ -89,6 +98,8 @@ func (b Builder) Build(name string) *Driver {
 	distributorImpl.pageSizeAsPowerOf2 = b.log2PageSize
 	driver.distributor = distributorImpl
 
+	driver.placement = newPlacementTable()
+
 	driver.pageTable = b.pageTable
 	driver.globalStorage = b.globalStorage
 
@@ -125,7 +136,9 @@ func (b *Builder) createCPU(d *Driver) {
 		Type:     internal.DeviceTypeCPU,
 		MemState: internal.NewDeviceMemoryState(d.Log2PageSize),
 	}
-	cpu.SetTotalMemSize(4 * mem.GB)
+	// The allocator never gives out the first page (address 0), so the CPU
+	// has one page less and the first GPU starts at exactly cpuMemSize.
+	cpu.SetTotalMemSize(b.cpuMemSize - 1<<b.log2PageSize)
 
 	d.memAllocator.RegisterDevice(cpu)
 	d.devices = append(d.devices, cpu)
@@ -0,0 +1,525 @@
+package driver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+
+	"github.com/sarchlab/akita/v4/mem/vm"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/protocol"
+)
+
+// checkpointVersion changes when the checkpoint file format changes.
+const checkpointVersion = 2
+
+// checkpointFile is what a checkpoint file stores. A checkpoint is taken at a
+// kernel boundary, after every command queue is idle and the GPU caches are
+// flushed. At that point the compute units, the caches and the interconnect
+// hold no state that the rest of the simulation depends on, so the state of
+// the simulation is the content of the memory plus the state of the host
+// program.
+//
+// The host program (the benchmark) cannot be serialized. Instead, a restored
+// run executes the benchmark again from the beginning and the driver replays
+// the commands before the checkpoint without simulating them: the memory
+// copies to the host return the data recorded in the checkpoint, the memory
+// copies to the device write the recorded data back to the host buffers, in
+// case the benchmark generated different inputs, and the rest of the commands
+// complete immediately. The allocations are repeated by the benchmark, so the
+// virtual memory layout is the same. At the first command after the
+// checkpoint, the driver loads the memory and the simulation continues.
+//
+// The memory is stored by virtual address and loaded through the page table
+// of the restored run. Pages that moved before the checkpoint, because of the
+// unified memory placement or the page migrations, are loaded where the
+// allocation placed them. Evicted pages are not in the global storage, so
+// checkpoints do not support oversubscription.
+type checkpointFile struct {
+	Version    int
+	NumGPUs    int
+	NumKernels int
+	Time       sim.VTimeInSec
+
+	// The type of every command before the checkpoint, to detect a restore
+	// with a different benchmark or configuration.
+	Commands []string
+
+	// The data of every MemCopyH2DCommand and MemCopyD2HCommand before the
+	// checkpoint.
+	H2D [][]byte
+	D2H [][]byte
+
+	// The content of every buffer that was not freed.
+	Memory []checkpointRange
+}
+
+type checkpointRange struct {
+	PID   vm.PID
+	VAddr uint64
+	Data  []byte
+}
+
+type checkpointState int
+
+const (
+	checkpointIdle checkpointState = iota
+	checkpointDraining
+	checkpointFlushing
+)
+
+// checkpointer saves and restores checkpoints. It is not a regular
+// Middleware because it sees every command before the driver processes it,
+// including the kernel launches.
+type checkpointer struct {
+	driver *Driver
+
+	saveAfter int
+	savePath  string
+	saved     bool
+
+	restored   *checkpointFile
+	replaying  bool
+	timeOffset sim.VTimeInSec
+
+	commands   []string
+	numKernels int
+	h2dData    [][]byte
+	d2hCmds    []*MemCopyD2HCommand
+
+	state     checkpointState
+	queue     *CommandQueue
+	flushReqs []sim.Msg
+}
+
+// SaveCheckpointAfter makes the driver write a checkpoint to path once the
+// given number of kernels have completed. The simulation continues after the
+// checkpoint is written.
+func (d *Driver) SaveCheckpointAfter(numKernels int, path string) {
+	if numKernels <= 0 {
+		log.Panicf("cannot checkpoint after %d kernels", numKernels)
+	}
+
+	if d.evictor != nil {
+		log.Panic("checkpoints do not support oversubscription")
+	}
+
+	c := d.getCheckpointer()
+	c.saveAfter = numKernels
+	c.savePath = path
+}
+
+// RestoreCheckpoint makes the driver resume the simulation from the
+// checkpoint in path. It must be called before the benchmark starts, and the
+// benchmark and the platform must be the same as in the run that wrote the
+// checkpoint.
+func (d *Driver) RestoreCheckpoint(path string) error {
+	if d.evictor != nil {
+		return fmt.Errorf("checkpoints do not support oversubscription")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ckpt := &checkpointFile{}
+	if err := gob.NewDecoder(f).Decode(ckpt); err != nil {
+		return fmt.Errorf("cannot read checkpoint %s: %w", path, err)
+	}
+
+	if ckpt.Version != checkpointVersion {
+		return fmt.Errorf("checkpoint %s has version %d, expected %d",
+			path, ckpt.Version, checkpointVersion)
+	}
+
+	if ckpt.NumGPUs != len(d.GPUs) {
+		return fmt.Errorf("checkpoint %s was taken with %d GPUs, not %d",
+			path, ckpt.NumGPUs, len(d.GPUs))
+	}
+
+	c := d.getCheckpointer()
+	c.restored = ckpt
+	c.replaying = true
+
+	return nil
+}
+
+// RestoredTime returns the simulated time at which the restored checkpoint
+// was taken, or 0 if the simulation did not start from a checkpoint. The time
+// of the restored simulation starts from 0 at the checkpoint.
+func (d *Driver) RestoredTime() sim.VTimeInSec {
+	if d.checkpointer == nil || d.checkpointer.restored == nil {
+		return 0
+	}
+
+	return d.checkpointer.restored.Time
+}
+
+func (d *Driver) getCheckpointer() *checkpointer {
+	if d.checkpointer == nil {
+		d.checkpointer = &checkpointer{driver: d}
+		d.addPreDispatchMiddleware(preDispatchCheckpoint, d.checkpointer)
+	}
+
+	return d.checkpointer
+}
+
+// ProcessCommand is called with every command before the driver processes
+// it. It returns true if the command must not be processed now.
+func (c *checkpointer) ProcessCommand(
+	cmd Command,
+	queue *CommandQueue,
+) (processed bool) {
+	if c.replaying {
+		return c.replay(cmd, queue)
+	}
+
+	if c.state != checkpointIdle {
+		return true
+	}
+
+	if !c.saved && c.saveAfter > 0 && c.numKernels == c.saveAfter {
+		c.state = checkpointDraining
+		c.queue = queue
+		queue.IsRunning = true
+
+		return true
+	}
+
+	// Events only order the queues, and a wait is processed again on every
+	// tick until its event completes, so they are not recorded.
+	switch cmd.(type) {
+	case *RecordEventCommand, *WaitEventCommand:
+		return false
+	}
+
+	c.record(cmd)
+
+	return false
+}
+
+func (c *checkpointer) record(cmd Command) {
+	c.commands = append(c.commands, reflect.TypeOf(cmd).String())
+
+	switch cmd := cmd.(type) {
+	case *LaunchKernelCommand, *LaunchUnifiedMultiGPUKernelCommand:
+		c.numKernels++
+	case *MemCopyH2DCommand:
+		buf := bytes.NewBuffer(nil)
+		err := binary.Write(buf, binary.LittleEndian, cmd.Src)
+		if err != nil {
+			panic(err)
+		}
+
+		c.h2dData = append(c.h2dData, buf.Bytes())
+	case *MemCopyD2HCommand:
+		c.d2hCmds = append(c.d2hCmds, cmd)
+	}
+}
+
+func (c *checkpointer) replay(cmd Command, queue *CommandQueue) bool {
+	switch cmd := cmd.(type) {
+	case *RecordEventCommand:
+		cmd.Event.complete(cmd.record, c.driver.Engine.CurrentTime())
+		queue.Dequeue()
+
+		return true
+	case *WaitEventCommand:
+		queue.Dequeue()
+
+		return true
+	}
+
+	i := len(c.commands)
+	if i == len(c.restored.Commands) {
+		c.loadMemory()
+		c.replaying = false
+		c.timeOffset = c.restored.Time - c.driver.Engine.CurrentTime()
+
+		log.Printf("Restored the checkpoint taken after %d kernels at %.9fs",
+			c.restored.NumKernels, c.restored.Time)
+
+		return c.ProcessCommand(cmd, queue)
+	}
+
+	what := reflect.TypeOf(cmd).String()
+	if what != c.restored.Commands[i] {
+		log.Panicf("command %d is a %s, but it is a %s in the checkpoint; "+
+			"restore with the same benchmark and arguments",
+			i, what, c.restored.Commands[i])
+	}
+
+	switch cmd := cmd.(type) {
+	case *MemCopyH2DCommand:
+		c.replayH2D(cmd, c.restored.H2D[len(c.h2dData)])
+	case *MemCopyD2HCommand:
+		c.replayD2H(cmd, c.restored.D2H[len(c.d2hCmds)])
+	}
+
+	c.record(cmd)
+
+	queue.Dequeue()
+
+	return true
+}
+
+// replayH2D overwrites the source of the copy with the recorded data. Only
+// slices of numbers are overwritten, which is how the benchmarks pass their
+// inputs. The rest, like the kernel arguments, are generated by the driver.
+func (c *checkpointer) replayH2D(cmd *MemCopyH2DCommand, data []byte) {
+	t := reflect.TypeOf(cmd.Src)
+	if t.Kind() != reflect.Slice || !isNumberKind(t.Elem().Kind()) {
+		return
+	}
+
+	err := binary.Read(bytes.NewReader(data), binary.LittleEndian, cmd.Src)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+func (c *checkpointer) replayD2H(cmd *MemCopyD2HCommand, data []byte) {
+	cmd.RawData = data
+
+	err := binary.Read(bytes.NewReader(data), binary.LittleEndian, cmd.Dst)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Tick drains the GPUs and writes the checkpoint.
+func (c *checkpointer) Tick() (madeProgress bool) {
+	switch c.state {
+	case checkpointDraining:
+		return c.flushWhenIdle()
+	case checkpointFlushing:
+		return c.processFlushRsp()
+	}
+
+	return false
+}
+
+// flushWhenIdle waits for the other queues, which may still run kernels, and
+// then flushes the caches of every GPU.
+func (c *checkpointer) flushWhenIdle() bool {
+	d := c.driver
+
+	if d.isCurrentlyHandlingMigrationReq || len(d.requestsToSend) > 0 {
+		return false
+	}
+
+	if c.anyOtherQueueRunning() {
+		return false
+	}
+
+	for _, gpu := range d.GPUs {
+		req := protocol.NewFlushReq(d.gpuPort, gpu)
+		d.requestsToSend = append(d.requestsToSend, req)
+		c.flushReqs = append(c.flushReqs, req)
+	}
+
+	c.state = checkpointFlushing
+
+	return true
+}
+
+func (c *checkpointer) anyOtherQueueRunning() bool {
+	d := c.driver
+
+	d.contextMutex.Lock()
+	defer d.contextMutex.Unlock()
+
+	for _, ctx := range d.contexts {
+		ctx.queueMutex.Lock()
+		for _, q := range ctx.queues {
+			if q != c.queue && q.IsRunning {
+				ctx.queueMutex.Unlock()
+				return true
+			}
+		}
+		ctx.queueMutex.Unlock()
+	}
+
+	return false
+}
+
+func (c *checkpointer) processFlushRsp() bool {
+	rsp, ok := c.driver.gpuPort.PeekIncoming().(*sim.GeneralRsp)
+	if !ok {
+		return false
+	}
+
+	flushReq := -1
+	for i, req := range c.flushReqs {
+		if req == rsp.OriginalReq {
+			flushReq = i
+		}
+	}
+
+	if flushReq < 0 {
+		return false
+	}
+
+	c.driver.gpuPort.RetrieveIncoming()
+	c.flushReqs = append(c.flushReqs[:flushReq], c.flushReqs[flushReq+1:]...)
+
+	if len(c.flushReqs) == 0 {
+		c.save()
+
+		c.saved = true
+		c.state = checkpointIdle
+		c.queue.IsRunning = false
+		c.queue = nil
+	}
+
+	return true
+}
+
+func (c *checkpointer) save() {
+	d := c.driver
+
+	ckpt := &checkpointFile{
+		Version:    checkpointVersion,
+		NumGPUs:    len(d.GPUs),
+		NumKernels: c.numKernels,
+		Time:       d.Engine.CurrentTime() + c.timeOffset,
+		Commands:   c.commands,
+		H2D:        c.h2dData,
+		Memory:     c.snapshotMemory(),
+	}
+
+	for _, cmd := range c.d2hCmds {
+		ckpt.D2H = append(ckpt.D2H, cmd.RawData)
+	}
+
+	// Write to a temporary file first, so that a crash never leaves a
+	// half-written checkpoint behind.
+	tmpPath := c.savePath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	if err := gob.NewEncoder(f).Encode(ckpt); err != nil {
+		log.Panic(err)
+	}
+
+	if err := f.Close(); err != nil {
+		log.Panic(err)
+	}
+
+	if err := os.Rename(tmpPath, c.savePath); err != nil {
+		log.Panic(err)
+	}
+
+	log.Printf("Checkpoint after %d kernels at %.9fs written to %s",
+		ckpt.NumKernels, ckpt.Time, c.savePath)
+}
+
+func (c *checkpointer) snapshotMemory() []checkpointRange {
+	d := c.driver
+
+	if d.globalStorage == nil {
+		log.Panic("checkpoints require the driver to have the global storage")
+	}
+
+	var ranges []checkpointRange
+
+	d.contextMutex.Lock()
+	defer d.contextMutex.Unlock()
+
+	for _, ctx := range d.contexts {
+		for _, buf := range ctx.buffers {
+			if buf.freed {
+				continue
+			}
+
+			r := checkpointRange{PID: ctx.pid, VAddr: uint64(buf.vAddr)}
+			c.forEachPage(ctx.pid, r.VAddr, buf.size,
+				func(pAddr, size uint64) {
+					data, err := d.globalStorage.Read(pAddr, size)
+					if err != nil {
+						log.Panic(err)
+					}
+
+					r.Data = append(r.Data, data...)
+				})
+
+			ranges = append(ranges, r)
+		}
+	}
+
+	return ranges
+}
+
+// forEachPage splits a virtual address range into the physical ranges of
+// each page it touches.
+func (c *checkpointer) forEachPage(
+	pid vm.PID,
+	addr, size uint64,
+	f func(pAddr, size uint64),
+) {
+	for size > 0 {
+		page, found := c.driver.pageTable.Find(pid, addr)
+		if !found {
+			panic("page not found")
+		}
+
+		pAddr := page.PAddr + (addr - page.VAddr)
+		sizeToCopy := page.PageSize - (addr - page.VAddr)
+		if size < sizeToCopy {
+			sizeToCopy = size
+		}
+
+		f(pAddr, sizeToCopy)
+
+		size -= sizeToCopy
+		addr += sizeToCopy
+	}
+}
+
+func (c *checkpointer) loadMemory() {
+	for _, r := range c.restored.Memory {
+		data := r.Data
+		c.forEachPage(r.PID, r.VAddr, uint64(len(r.Data)),
+			func(pAddr, size uint64) {
+				err := c.driver.globalStorage.Write(pAddr, data[:size])
+				if err != nil {
+					log.Panic(err)
+				}
+
+				data = data[size:]
+			})
+	}
+}
+
+// warnIfNotSaved tells that the run ended before the checkpoint. The
+// checkpoint is taken at the first command after the kernel, so a benchmark
+// that launches fewer kernels, or none after the last one, never writes it.
+func (c *checkpointer) warnIfNotSaved() {
+	if c.saveAfter == 0 || c.saved {
+		return
+	}
+
+	log.Printf("warning: no checkpoint was written to %s: it is taken at "+
+		"the first command after kernel %d, and the benchmark launched %d",
+		c.savePath, c.saveAfter, c.numKernels)
+}
@@ -0,0 +1,170 @@
+package driver
+
+import (
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/mem/mem"
+	"github.com/sarchlab/akita/v4/mem/vm"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/protocol"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = ginkgo.Describe("Checkpointer", func() {
+	var (
+		mockCtrl  *gomock.Controller
+		engine    *MockEngine
+		pageTable *MockPageTable
+		storage   *mem.Storage
+		driver    *Driver
+		context   *Context
+		cmdQueue  *CommandQueue
+	)
+
+	ginkgo.BeforeEach(func() {
+		storage = mem.NewStorage(16 * mem.GB)
+		f := newDriverFixture(storage)
+		mockCtrl = f.mockCtrl
+		engine = f.engine
+		pageTable = f.pageTable
+		driver = f.driver
+
+		context = driver.Init()
+		context.pid = 1
+		cmdQueue = driver.CreateCommandQueue(context)
+	})
+
+	ginkgo.AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	ginkgo.Context("when restoring", func() {
+		ginkgo.BeforeEach(func() {
+			c := driver.getCheckpointer()
+			c.restored = &checkpointFile{
+				Version:    checkpointVersion,
+				NumGPUs:    2,
+				NumKernels: 1,
+				Time:       1,
+				Commands: []string{
+					"*driver.MemCopyH2DCommand",
+					"*driver.LaunchKernelCommand",
+					"*driver.MemCopyD2HCommand",
+				},
+				H2D: [][]byte{{1, 2, 3, 4}},
+				D2H: [][]byte{{5, 6, 7, 8}},
+				Memory: []checkpointRange{
+					{PID: 1, VAddr: 0x1ffe, Data: []byte{9, 8, 7, 6}}},
+			}
+			c.replaying = true
+		})
+
+		ginkgo.It("should replay the commands before the checkpoint", func() {
+			src := make([]byte, 4)
+			dst := make([]byte, 4)
+			cmdQueue.Enqueue(&MemCopyH2DCommand{Src: src})
+			cmdQueue.Enqueue(&LaunchKernelCommand{})
+			cmdQueue.Enqueue(&MemCopyD2HCommand{Dst: dst})
+
+			for i := 0; i < 3; i++ {
+				Expect(driver.processOneCommand(cmdQueue)).To(BeTrue())
+			}
+
+			Expect(cmdQueue.NumCommand()).To(Equal(0))
+			Expect(src).To(Equal([]byte{1, 2, 3, 4}))
+			Expect(dst).To(Equal([]byte{5, 6, 7, 8}))
+			Expect(driver.checkpointer.numKernels).To(Equal(1))
+		})
+
+		ginkgo.It("should load the memory at the checkpoint", func() {
+			c := driver.checkpointer
+			c.commands = c.restored.Commands
+			engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(0.5))
+			pageTable.EXPECT().Find(vm.PID(1), uint64(0x1ffe)).
+				Return(vm.Page{VAddr: 0x1000, PAddr: 0x100005000,
+					PageSize: 0x1000}, true)
+			pageTable.EXPECT().Find(vm.PID(1), uint64(0x2000)).
+				Return(vm.Page{VAddr: 0x2000, PAddr: 0x200000000,
+					PageSize: 0x1000}, true)
+
+			processed := c.ProcessCommand(&NoopCommand{}, cmdQueue)
+
+			Expect(processed).To(BeFalse())
+			Expect(c.replaying).To(BeFalse())
+			Expect(c.timeOffset).To(Equal(sim.VTimeInSec(0.5)))
+			data, _ := storage.Read(0x100005ffe, 2)
+			Expect(data).To(Equal([]byte{9, 8}))
+			data, _ = storage.Read(0x200000000, 2)
+			Expect(data).To(Equal([]byte{7, 6}))
+		})
+
+		ginkgo.It("should panic if the commands are different", func() {
+			cmdQueue.Enqueue(&LaunchKernelCommand{})
+
+			Expect(func() {
+				driver.processOneCommand(cmdQueue)
+			}).To(Panic())
+		})
+	})
+
+	ginkgo.Context("when saving", func() {
+		ginkgo.BeforeEach(func() {
+			driver.SaveCheckpointAfter(1, "checkpoint.gob")
+		})
+
+		ginkgo.It("should stop the queue after the kernel", func() {
+			c := driver.checkpointer
+
+			Expect(c.ProcessCommand(&LaunchKernelCommand{}, cmdQueue)).
+				To(BeFalse())
+			Expect(c.ProcessCommand(&NoopCommand{}, cmdQueue)).To(BeTrue())
+
+			Expect(c.state).To(Equal(checkpointDraining))
+			Expect(cmdQueue.IsRunning).To(BeTrue())
+		})
+
+		ginkgo.It("should save the buffers by virtual address", func() {
+			context.buffers = append(context.buffers,
+				&buffer{vAddr: 0x1ffe, size: 4},
+				&buffer{vAddr: 0x3000, size: 1, freed: true})
+			Expect(storage.Write(0x100005ffe, []byte{9, 8})).To(Succeed())
+			Expect(storage.Write(0x200000000, []byte{7, 6})).To(Succeed())
+			pageTable.EXPECT().Find(vm.PID(1), uint64(0x1ffe)).
+				Return(vm.Page{VAddr: 0x1000, PAddr: 0x100005000,
+					PageSize: 0x1000}, true)
+			pageTable.EXPECT().Find(vm.PID(1), uint64(0x2000)).
+				Return(vm.Page{VAddr: 0x2000, PAddr: 0x200000000,
+					PageSize: 0x1000}, true)
+
+			ranges := driver.checkpointer.snapshotMemory()
+
+			Expect(ranges).To(Equal([]checkpointRange{
+				{PID: 1, VAddr: 0x1ffe, Data: []byte{9, 8, 7, 6}}}))
+		})
+
+		ginkgo.It("should refuse oversubscription", func() {
+			Expect(func() {
+				driver.EnableOversubscription(OversubscriptionConfig{})
+			}).To(Panic())
+		})
+
+		ginkgo.It("should flush the GPUs when the other queues are idle", func() {
+			c := driver.checkpointer
+			c.state = checkpointDraining
+			c.queue = cmdQueue
+
+			otherQueue := driver.CreateCommandQueue(context)
+			otherQueue.IsRunning = true
+
+			Expect(c.Tick()).To(BeFalse())
+
+			otherQueue.IsRunning = false
+
+			Expect(c.Tick()).To(BeTrue())
+			Expect(c.state).To(Equal(checkpointFlushing))
+			Expect(driver.requestsToSend).To(HaveLen(2))
+			Expect(driver.requestsToSend[0]).
+				To(BeAssignableToTypeOf(&protocol.FlushReq{}))
+		})
+	})
+})
@@ -75,6 +75,38 @@ func (c *MemCopyD2HCommand) RemoveReq(req sim.Msg) {
 	c.Reqs = removeMsgFromMsgList(req, c.Reqs)
 }
 
+// A MemCopyPeerCommand is a command that copies memory from one GPU to
+// another without going through the host. The DMA engine of the GPU that holds
+// the source pushes the data to the destination.
+type MemCopyPeerCommand struct {
+	ID       string
+	Dst      Ptr
+	Src      Ptr
+	ByteSize uint64
+	Reqs     []sim.Msg
+}
+
+// GetID returns the ID of the command
+func (c *MemCopyPeerCommand) GetID() string {
+	return c.ID
+}
+
+// GetReqs returns the request associated with the command
+func (c *MemCopyPeerCommand) GetReqs() []sim.Msg {
+	return c.Reqs
+}
+
+// AddReq adds a request to the request list associated with the command
+func (c *MemCopyPeerCommand) AddReq(req sim.Msg) {
+	c.Reqs = append(c.Reqs, req)
+}
+
+// RemoveReq removes a request from the request list associated with the
+// command.
+func (c *MemCopyPeerCommand) RemoveReq(req sim.Msg) {
+	c.Reqs = removeMsgFromMsgList(req, c.Reqs)
+}
+
 // A LaunchKernelCommand is a command will execute a kernel when it is
 // processed.
 type LaunchKernelCommand struct {
@@ -206,3 +238,62 @@ func (c *LaunchUnifiedMultiGPUKernelCommand) AddReq(req sim.Msg) {
 func (c *LaunchUnifiedMultiGPUKernelCommand) RemoveReq(req sim.Msg) {
 	c.Reqs = removeMsgFromMsgList(req, c.Reqs)
 }
+
+// A RecordEventCommand is a command that completes an event when all the
+// commands before it in the queue have completed.
+type RecordEventCommand struct {
+	ID     string
+	Event  *Event
+	record int
+}
+
+// GetID returns the ID of the command
+func (c *RecordEventCommand) GetID() string {
+	return c.ID
+}
+
+// GetReqs returns the request associated with the command
+func (c *RecordEventCommand) GetReqs() []sim.Msg {
+	return nil
+}
+
+// AddReq adds a request to the request list associated with the command
+func (c *RecordEventCommand) AddReq(req sim.Msg) {
+	// No action
+}
+
+// RemoveReq removes a request from the request list associated with the
+// command.
+func (c *RecordEventCommand) RemoveReq(req sim.Msg) {
+	// No action
+}
+
+// A WaitEventCommand is a command that holds the commands after it in the
+// queue until an event completes.
+type WaitEventCommand struct {
+	ID      string
+	Event   *Event
+	record  int
+	waiting bool
+}
+
+// GetID returns the ID of the command
+func (c *WaitEventCommand) GetID() string {
+	return c.ID
+}
+
+// GetReqs returns the request associated with the command
+func (c *WaitEventCommand) GetReqs() []sim.Msg {
+	return nil
+}
+
+// AddReq adds a request to the request list associated with the command
+func (c *WaitEventCommand) AddReq(req sim.Msg) {
+	// No action
+}
+
+// RemoveReq removes a request from the request list associated with the
+// command.
+func (c *WaitEventCommand) RemoveReq(req sim.Msg) {
+	// No action
+}
@@ -1,6 +1,8 @@
 package driver
 
 import (
+	"log"
+
 	"github.com/sarchlab/mgpusim/v4/amd/driver/internal"
 )
 
@@ -11,6 +13,13 @@ type distributor interface {
 		addr, byteSize uint64,
 		gpuIDs []int,
 	) (byteAllocatedOnEachGPU []uint64)
+
+	DistributeWithPlacement(
+		ctx *Context,
+		addr, byteSize uint64,
+		gpuIDs []int,
+		policy PlacementPolicy,
+	) (byteAllocatedOnEachGPU []uint64)
 }
 
 type distributorImpl struct {
@@ -28,6 +37,16 @@ func (d *distributorImpl) Distribute(
 	ctx *Context,
 	addr, byteSize uint64,
 	gpuIDs []int,
+) (byteAllocatedOnEachGPU []uint64) {
+	return d.DistributeWithPlacement(
+		ctx, addr, byteSize, gpuIDs, ChunkedPlacement{})
+}
+
+func (d *distributorImpl) DistributeWithPlacement(
+	ctx *Context,
+	addr, byteSize uint64,
+	gpuIDs []int,
+	policy PlacementPolicy,
 ) (byteAllocatedOnEachGPU []uint64) {
 	pageSize := uint64(1 << d.pageSizeAsPowerOf2)
 	if addr%pageSize != 0 {
@@ -36,38 +55,25 @@ func (d *distributorImpl) Distribute(
 
 	byteAllocatedOnEachGPU = make([]uint64, len(gpuIDs))
 	numPages := (byteSize-1)/pageSize + 1
-	numGPUs := uint64(len(gpuIDs))
-	numPagesPerGPU := numPages / numGPUs
-	numGPUsToUse := uint64(0)
-	if numPagesPerGPU > 0 {
-		numGPUsToUse = numPages / numPagesPerGPU
-	}
-	if numGPUsToUse > numGPUs {
-		numGPUsToUse = numGPUs
-	}
-	remainingPages := numPages % numGPUs
 
-	var i uint64
-	var lastAllocatedGPU uint64
-	for i = 0; i < numGPUsToUse; i++ {
-		d.memAllocator.Remap(
-			ctx.pid,
-			addr+i*numPagesPerGPU*pageSize,
-			numPagesPerGPU*pageSize,
-			gpuIDs[i],
-		)
-		byteAllocatedOnEachGPU[i] += numPagesPerGPU * pageSize
-		lastAllocatedGPU = i
-	}
+	for _, r := range policy.Place(numPages, gpuIDs) {
+		if r.GPUID == 0 {
+			log.Panic("distributed memory must be placed on a GPU")
+		}
 
-	for i := uint64(0); i < remainingPages; i++ {
 		d.memAllocator.Remap(
 			ctx.pid,
-			addr+(numPagesPerGPU*numGPUsToUse+i)*pageSize,
-			pageSize,
-			gpuIDs[lastAllocatedGPU],
+			addr+r.FirstPage*pageSize,
+			r.NumPages*pageSize,
+			r.GPUID,
 		)
-		byteAllocatedOnEachGPU[lastAllocatedGPU] += pageSize
+
+		for i, gpuID := range gpuIDs {
+			if gpuID == r.GPUID {
+				byteAllocatedOnEachGPU[i] += r.NumPages * pageSize
+				break
+			}
+		}
 	}
 
 	return byteAllocatedOnEachGPU
@@ -51,4 +51,18 @@ var _ = ginkgo.Describe("Distributor", func() {
 
 		Expect(bytes).To(Equal([]uint64{4096, 4096, 12288}))
 	})
+
+	ginkgo.It("should distribute pages with a placement policy", func() {
+		memAllocator.EXPECT().
+			Remap(vm.PID(1), uint64(0x100000000), uint64(0x2000), 1)
+		memAllocator.EXPECT().
+			Remap(vm.PID(1), uint64(0x100002000), uint64(0x2000), 2)
+		memAllocator.EXPECT().
+			Remap(vm.PID(1), uint64(0x100004000), uint64(0x1000), 1)
+
+		bytes := dist.DistributeWithPlacement(ctx, 0x100000000, 0x4020,
+			[]int{1, 2}, InterleavedPlacement{Granularity: 2})
+
+		Expect(bytes).To(Equal([]uint64{12288, 8192}))
+	})
 })
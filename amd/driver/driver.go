@@ -29,6 +29,19 @@ type Driver struct {
 	devices     []*internal.Device
 	pageTable   vm.PageTable
 	middlewares []Middleware
+	preDispatch []preDispatchMiddleware
+
+	checkpointer  *checkpointer
+	kernelSampler *kernelSampler
+
+	distributePlacement    PlacementPolicy
+	unifiedMemoryPlacement PlacementPolicy
+	unifiedMemoryGPUIDs    []int
+	placement              *placementTable
+
+	accessCounterMigrator *accessCounterMigrator
+	migrationStats        PageMigrationStats
+	evictor               *evictor
 
 	requestsToSend []sim.Msg
 
@@ -70,6 +83,11 @@ func (d *Driver) Run() {
 // Terminate stops the driver thread execution.
 func (d *Driver) Terminate() {
 	d.driverStopped <- true
+
+	if d.checkpointer != nil {
+		d.checkpointer.warnIfNotSaved()
+	}
+
 	d.logSimulationTerminate()
 }
 
@@ -136,6 +154,11 @@ func (d *Driver) runEngine() {
 type DeviceProperties struct {
 	CUCount  int
 	DRAMSize uint64
+
+	// Freq is the frequency of the CUs. Unified multi-GPU kernels give each
+	// GPU a share of the work-groups proportional to CUCount * Freq, or to
+	// CUCount alone if the frequency of some of the GPUs is unknown (0).
+	Freq sim.Freq
 }
 
 // RegisterGPU tells the driver about the existence of a GPU
@@ -152,6 +175,7 @@ func (d *Driver) RegisterGPU(
 		Properties: internal.DeviceProperties{
 			CUCount:  properties.CUCount,
 			DRAMSize: properties.DRAMSize,
+			Freq:     properties.Freq,
 		},
 	}
 	gpuDevice.SetTotalMemSize(properties.DRAMSize)
@@ -168,6 +192,10 @@ func (d *Driver) Tick() bool {
 	madeProgress = d.sendToMMU() || madeProgress
 	madeProgress = d.sendMigrationReqToCP() || madeProgress
 
+	for _, mw := range d.preDispatch {
+		madeProgress = mw.Tick() || madeProgress
+	}
+
 	for _, mw := range d.middlewares {
 		madeProgress = mw.Tick() || madeProgress
 	}
@@ -220,6 +248,9 @@ func (d *Driver) processReturnReq() bool {
 	case *protocol.GPURestartRsp:
 		d.gpuPort.RetrieveIncoming()
 		return d.handleGPURestartRsp(req)
+	case *protocol.AccessCounterNotificationToDriver:
+		d.gpuPort.RetrieveIncoming()
+		return d.processAccessCounterNotification(req)
 	}
 
 	return false
@@ -269,6 +300,12 @@ func (d *Driver) processOneCommand(
 ) bool {
 	cmd := cmdQueue.Peek()
 
+	for _, mw := range d.preDispatch {
+		if mw.ProcessCommand(cmd, cmdQueue) {
+			return true
+		}
+	}
+
 	switch cmd := cmd.(type) {
 	case *LaunchKernelCommand:
 		d.logCmdStart(cmd)
@@ -279,6 +316,10 @@ func (d *Driver) processOneCommand(
 	case *LaunchUnifiedMultiGPUKernelCommand:
 		d.logCmdStart(cmd)
 		return d.processUnifiedMultiGPULaunchKernelCommand(cmd, cmdQueue)
+	case *RecordEventCommand:
+		return d.processRecordEventCommand(cmd, cmdQueue)
+	case *WaitEventCommand:
+		return d.processWaitEventCommand(cmd, cmdQueue)
 	default:
 		return d.processCommandWithMiddleware(cmd, cmdQueue)
 	}
@@ -414,40 +455,84 @@ func (d *Driver) processUnifiedMultiGPULaunchKernelCommand(
 	return true
 }
 
+// distributeWGToGPUs splits the work-groups of a unified multi-GPU kernel into
+// consecutive ranges, one per GPU, sized by the compute capability of each GPU.
+// GPU i runs the work-groups in [wgDist[i], wgDist[i+1]).
 func (d *Driver) distributeWGToGPUs(
 	queue *CommandQueue,
 	cmd *LaunchUnifiedMultiGPUKernelCommand,
 ) []int {
 	dev := d.devices[queue.GPUID]
 	actualGPUs := dev.UnifiedGPUIDs
-	wgAllocated := 0
-	wgDist := make([]int, len(actualGPUs)+1)
-
-	totalCUCount := 0
-	for _, devID := range actualGPUs {
-		totalCUCount += d.devices[devID].Properties.CUCount
-	}
 
 	numWGX := (cmd.PacketArray[0].GridSizeX-1)/uint32(cmd.PacketArray[0].WorkgroupSizeX) + 1
 	numWGY := (cmd.PacketArray[0].GridSizeY-1)/uint32(cmd.PacketArray[0].WorkgroupSizeY) + 1
 	numWGZ := (cmd.PacketArray[0].GridSizeZ-1)/uint32(cmd.PacketArray[0].WorkgroupSizeZ) + 1
 	totalWGCount := int(numWGX * numWGY * numWGZ)
-	wgPerCU := (totalWGCount-1)/totalCUCount + 1
 
+	useFreq := true
+	for _, devID := range actualGPUs {
+		if d.devices[devID].Properties.Freq == 0 {
+			useFreq = false
+		}
+	}
+
+	weights := make([]float64, len(actualGPUs))
 	for i, devID := range actualGPUs {
-		cuCount := d.devices[devID].Properties.CUCount
-		wgToAllocate := cuCount * wgPerCU
-		wgDist[i+1] = wgAllocated + wgToAllocate
-		wgAllocated += wgToAllocate
+		weights[i] = float64(d.devices[devID].Properties.CUCount)
+		if useFreq {
+			weights[i] *= float64(d.devices[devID].Properties.Freq)
+		}
 	}
 
-	if wgAllocated < totalWGCount {
-		panic("not all wg allocated")
+	wgCounts := splitProportionally(totalWGCount, weights)
+
+	wgDist := make([]int, len(actualGPUs)+1)
+	for i, count := range wgCounts {
+		wgDist[i+1] = wgDist[i] + count
 	}
 
 	return wgDist
 }
 
+// splitProportionally divides total into len(weights) integers proportional
+// to the weights. The units left after rounding down go to the largest
+// remainders, so the result always adds up to total.
+func splitProportionally(total int, weights []float64) []int {
+	sumWeights := 0.0
+	for _, w := range weights {
+		sumWeights += w
+	}
+
+	if sumWeights <= 0 {
+		panic("no compute capability to distribute work-groups")
+	}
+
+	counts := make([]int, len(weights))
+	remainders := make([]float64, len(weights))
+	allocated := 0
+	for i, w := range weights {
+		share := float64(total) * w / sumWeights
+		counts[i] = int(share)
+		remainders[i] = share - float64(counts[i])
+		allocated += counts[i]
+	}
+
+	for ; allocated < total; allocated++ {
+		largest := 0
+		for i := range remainders {
+			if remainders[i] > remainders[largest] {
+				largest = i
+			}
+		}
+
+		counts[largest]++
+		remainders[largest] = -1
+	}
+
+	return counts
+}
+
 func (d *Driver) processLaunchKernelReturn(
 	rsp *protocol.LaunchKernelRsp,
 ) bool {
@@ -460,6 +545,12 @@ func (d *Driver) processLaunchKernelReturn(
 		cmdQueue.IsRunning = false
 		cmdQueue.Dequeue()
 
+		for _, mw := range d.preDispatch {
+			if l, ok := mw.Middleware.(kernelCompletionListener); ok {
+				l.kernelCompleted(cmd)
+			}
+		}
+
 		d.logCmdComplete(cmd)
 	}
 
@@ -525,7 +616,7 @@ func (d *Driver) findCommandByReqID(reqID string) (
 }
 
 func (d *Driver) parseFromMMU() bool {
-	if d.isCurrentlyHandlingMigrationReq {
+	if d.isCurrentlyHandlingMigrationReq || d.evictor.delaysMigration() {
 		return false
 	}
 
@@ -586,7 +677,16 @@ func (d *Driver) sendShootDownReqs() bool {
 		}
 	}
 
-	accessingGPUs := d.currentPageMigrationReq.CurrAccessingGPUs
+	accessingGPUs := d.gpusAffectedByMigration()
+
+	// With oversubscription, the frames that the pages leave are reused, and
+	// any GPU may cache them.
+	if d.evictor != nil {
+		vAddr = append(vAddr, d.evictor.planEvictions()...)
+		accessingGPUs = d.allGPUIDs()
+	}
+
+	d.currentPageMigrationReq.CurrAccessingGPUs = accessingGPUs
 	pid := d.currentPageMigrationReq.PID
 	d.numShootDownACK = uint64(len(accessingGPUs))
 
@@ -606,43 +706,93 @@ func (d *Driver) processShootdownCompleteRsp(
 ) bool {
 	d.numShootDownACK--
 
-	if d.numShootDownACK == 0 {
-		toRequestFromGPU := d.currentPageMigrationReq.CurrPageHostGPU
-		toRequestFromPMCPort := d.RemotePMCPorts[toRequestFromGPU-1]
+	// The evictions make room for the pages first.
+	if d.numShootDownACK == 0 && !d.evictor.startEvictions() {
+		d.migratePages()
+	}
 
-		migrationInfo := d.currentPageMigrationReq.MigrationInfo
+	return true
+}
 
-		requestingGPUs := d.findRequestingGPUs(migrationInfo)
-		context := d.findContext(d.currentPageMigrationReq.PID)
+func (d *Driver) migratePages() {
+	migrationInfo := d.currentPageMigrationReq.MigrationInfo
 
-		pageVaddrs := make(map[uint64][]uint64)
+	requestingGPUs := d.findRequestingGPUs(migrationInfo)
+	context := d.findContext(d.currentPageMigrationReq.PID)
 
-		for i := 0; i < len(requestingGPUs); i++ {
-			pageVaddrs[requestingGPUs[i]] =
-				migrationInfo.GPUReqToVAddrMap[requestingGPUs[i]+1]
-		}
+	pageVaddrs := make(map[uint64][]uint64)
 
-		for gpuID, vAddrs := range pageVaddrs {
-			for i := 0; i < len(vAddrs); i++ {
-				vAddr := vAddrs[i]
-				page, oldPAddr :=
-					d.preparePageForMigration(vAddr, context, gpuID)
-
-				req := protocol.NewPageMigrationReqToCP(d.gpuPort,
-					d.GPUs[gpuID])
-				req.DestinationPMCPort = toRequestFromPMCPort
-				req.ToReadFromPhysicalAddress = oldPAddr
-				req.ToWriteToPhysicalAddress = page.PAddr
-				req.PageSize = d.currentPageMigrationReq.PageSize
-
-				d.migrationReqToSendToCP = append(d.migrationReqToSendToCP, req)
-				d.numPagesMigratingACK++
-			}
+	for i := 0; i < len(requestingGPUs); i++ {
+		pageVaddrs[requestingGPUs[i]] =
+			migrationInfo.GPUReqToVAddrMap[requestingGPUs[i]+1]
+	}
+
+	for gpuID, vAddrs := range pageVaddrs {
+		for i := 0; i < len(vAddrs); i++ {
+			d.migratePage(vAddrs[i], context, gpuID)
 		}
-		return true
 	}
 
-	return false
+	// The pages may already be on the GPUs that they move to.
+	if d.numPagesMigratingACK == 0 {
+		d.completePageMigration()
+	}
+}
+
+// migratePage moves a page to the GPU that it is migrating to and copies its
+// content from the GPU that holds it.
+func (d *Driver) migratePage(
+	vAddr uint64,
+	context *Context,
+	requestingGPU uint64,
+) {
+	page, found := d.pageTable.Find(context.pid, vAddr)
+	if !found {
+		panic("page not found")
+	}
+
+	gpuID := d.migrationDestination(context.pid, page.VAddr, requestingGPU)
+
+	if d.accessCounterMigrator.isMigrating() {
+		d.migrationStats.CounterMigrations++
+	} else {
+		d.migrationStats.FaultMigrations++
+	}
+
+	// A page that is not placed yet is on no GPU, but its frame is.
+	srcGPU := page.DeviceID
+	if srcGPU == 0 {
+		srcGPU = uint64(d.memAllocator.GetDeviceIDByPAddr(page.PAddr))
+	}
+
+	d.evictor.touch(context.pid, page.VAddr, gpuID+1)
+
+	if srcGPU == gpuID+1 {
+		page.DeviceID = gpuID + 1
+		page.IsMigrating = true
+		d.pageTable.Update(page)
+
+		return
+	}
+
+	// With oversubscription, the frame may also be on the host.
+	if srcGPU == 0 {
+		d.evictor.pageIn(page, context, gpuID)
+		return
+	}
+
+	newPage, oldPAddr := d.preparePageForMigration(page, vAddr, context, gpuID)
+
+	req := protocol.NewPageMigrationReqToCP(d.gpuPort, d.GPUs[gpuID])
+	req.DestinationPMCPort = d.RemotePMCPorts[srcGPU-1]
+	req.ToReadFromPhysicalAddress = oldPAddr
+	req.ToWriteToPhysicalAddress = newPage.PAddr
+	req.PageSize = d.currentPageMigrationReq.PageSize
+
+	d.migrationReqToSendToCP = append(d.migrationReqToSendToCP, req)
+	d.numPagesMigratingACK++
+	d.migrationStats.BytesMigrated += req.PageSize
+	d.evictor.leaveFrame(oldPAddr)
 }
 
 func (d *Driver) findRequestingGPUs(
@@ -673,14 +823,11 @@ func (d *Driver) findContext(pid vm.PID) *Context {
 }
 
 func (d *Driver) preparePageForMigration(
+	page vm.Page,
 	vAddr uint64,
 	context *Context,
 	gpuID uint64,
 ) (*vm.Page, uint64) {
-	page, found := d.pageTable.Find(context.pid, vAddr)
-	if !found {
-		panic("page not founds")
-	}
 	oldPAddr := page.PAddr
 
 	newPage := d.memAllocator.AllocatePageWithGivenVAddr(
@@ -721,13 +868,23 @@ func (d *Driver) processPageMigrationRspFromCP(
 	d.isCurrentlyMigratingOnePage = false
 
 	if d.numPagesMigratingACK == 0 {
-		d.prepareGPURestartReqs()
-		d.preparePageMigrationRspToMMU()
+		d.completePageMigration()
 	}
 
 	return true
 }
 
+func (d *Driver) completePageMigration() {
+	d.prepareGPURestartReqs()
+
+	if d.accessCounterMigrator.isMigrating() {
+		d.accessCounterMigrator.completeMigration()
+		return
+	}
+
+	d.preparePageMigrationRspToMMU()
+}
+
 func (d *Driver) prepareGPURestartReqs() {
 	accessingGPUs := d.currentPageMigrationReq.CurrAccessingGPUs
 
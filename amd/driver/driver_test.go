@@ -6,6 +6,7 @@ import (
 	"github.com/sarchlab/akita/v4/mem/mem"
 	"github.com/sarchlab/akita/v4/mem/vm"
 	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/kernels"
 	"github.com/sarchlab/mgpusim/v4/amd/protocol"
 	"go.uber.org/mock/gomock"
 )
@@ -234,6 +235,95 @@ var _ = ginkgo.Describe("Driver", func() {
 
 	})
 
+	ginkgo.Context("process MemCopyPeer command", func() {
+		ginkgo.It("should send a request to the GPU of each source page", func() {
+			cmd := &MemCopyPeerCommand{
+				Dst:      Ptr(0x300000400),
+				Src:      Ptr(0x200000700),
+				ByteSize: 0x200,
+			}
+			cmdQueue.Enqueue(cmd)
+
+			pageTable.EXPECT().
+				Find(vm.PID(1), uint64(0x200000700)).
+				Return(vm.Page{
+					VAddr:    0x200000000,
+					PAddr:    0x100000000,
+					PageSize: 0x800,
+				}, true)
+			pageTable.EXPECT().
+				Find(vm.PID(1), uint64(0x300000400)).
+				Return(vm.Page{
+					VAddr:    0x300000000,
+					PAddr:    0x200000000,
+					PageSize: 0x800,
+				}, true)
+			pageTable.EXPECT().
+				Find(vm.PID(1), uint64(0x200000800)).
+				Return(vm.Page{
+					VAddr:    0x200000800,
+					PAddr:    0x100001000,
+					PageSize: 0x800,
+				}, true)
+			pageTable.EXPECT().
+				Find(vm.PID(1), uint64(0x300000500)).
+				Return(vm.Page{
+					VAddr:    0x300000000,
+					PAddr:    0x200000000,
+					PageSize: 0x800,
+				}, true)
+			memAllocator.EXPECT().
+				GetDeviceIDByPAddr(uint64(0x1_0000_0700)).
+				Return(1)
+			memAllocator.EXPECT().
+				GetDeviceIDByPAddr(uint64(0x1_0000_1000)).
+				Return(2)
+
+			Expect(driver.processOneCommand(cmdQueue)).To(BeTrue())
+
+			Expect(cmdQueue.IsRunning).To(BeTrue())
+			Expect(cmd.Reqs).To(HaveLen(2))
+			Expect(driver.requestsToSend).To(Equal(cmd.Reqs))
+
+			req := cmd.Reqs[0].(*protocol.MemCopyPeerReq)
+			Expect(req.SrcAddress).To(Equal(uint64(0x100000700)))
+			Expect(req.DstAddress).To(Equal(uint64(0x200000400)))
+			Expect(req.ByteSize).To(Equal(uint64(0x100)))
+			Expect(req.Dst).To(Equal(driver.GPUs[0].AsRemote()))
+
+			req = cmd.Reqs[1].(*protocol.MemCopyPeerReq)
+			Expect(req.SrcAddress).To(Equal(uint64(0x100001000)))
+			Expect(req.DstAddress).To(Equal(uint64(0x200000500)))
+			Expect(req.ByteSize).To(Equal(uint64(0x100)))
+			Expect(req.Dst).To(Equal(driver.GPUs[1].AsRemote()))
+		})
+
+		ginkgo.It("should complete when all the requests return", func() {
+			nilPort := NewMockPort(mockCtrl)
+			nilPort.EXPECT().AsRemote().AnyTimes()
+
+			req := protocol.NewMemCopyPeerReq(toGPUs, nilPort,
+				0x100000000, 0x200000000, 0x100)
+			cmd := &MemCopyPeerCommand{
+				Dst:      Ptr(0x300000000),
+				Src:      Ptr(0x200000000),
+				ByteSize: 0x100,
+				Reqs:     []sim.Msg{req},
+			}
+			cmdQueue.Enqueue(cmd)
+			cmdQueue.IsRunning = true
+
+			rsp := sim.GeneralRspBuilder{}.WithOriginalReq(req).Build()
+			toGPUs.EXPECT().PeekIncoming().Return(rsp)
+			toGPUs.EXPECT().RetrieveIncoming().Return(rsp)
+
+			driver.middlewares[0].Tick()
+
+			Expect(cmdQueue.IsRunning).To(BeFalse())
+			Expect(cmdQueue.NumCommand()).To(Equal(0))
+		})
+	})
+
 	ginkgo.Context("process MemCopyD2HCommand", func() {
 		ginkgo.It("should send request", func() {
 			data := uint32(1)
@@ -391,6 +481,63 @@ var _ = ginkgo.Describe("Driver", func() {
 		})
 	})
 
+	ginkgo.Context("distribute work-groups to unified GPUs", func() {
+		var (
+			cmd *LaunchUnifiedMultiGPUKernelCommand
+		)
+
+		ginkgo.BeforeEach(func() {
+			cmd = &LaunchUnifiedMultiGPUKernelCommand{
+				PacketArray: []*kernels.HsaKernelDispatchPacket{{
+					GridSizeX:      100 * 64,
+					GridSizeY:      1,
+					GridSizeZ:      1,
+					WorkgroupSizeX: 64,
+					WorkgroupSizeY: 1,
+					WorkgroupSizeZ: 1,
+				}},
+			}
+		})
+
+		ginkgo.It("should split by CU count", func() {
+			gpu := NewMockPort(mockCtrl)
+			driver.RegisterGPU(gpu, DeviceProperties{
+				CUCount:  8,
+				DRAMSize: 4 * mem.GB,
+			})
+			cmdQueue.GPUID = driver.CreateUnifiedGPU(context, []int{1, 2, 3})
+
+			wgDist := driver.distributeWGToGPUs(cmdQueue, cmd)
+
+			Expect(wgDist).To(Equal([]int{0, 25, 50, 100}))
+		})
+
+		ginkgo.It("should split by CU count and frequency", func() {
+			for i := 0; i < 2; i++ {
+				gpu := NewMockPort(mockCtrl)
+				driver.RegisterGPU(gpu, DeviceProperties{
+					CUCount:  4,
+					DRAMSize: 4 * mem.GB,
+					Freq:     sim.Freq(i+1) * sim.GHz,
+				})
+			}
+			cmdQueue.GPUID = driver.CreateUnifiedGPU(context, []int{3, 4})
+
+			wgDist := driver.distributeWGToGPUs(cmdQueue, cmd)
+
+			Expect(wgDist).To(Equal([]int{0, 33, 100}))
+		})
+
+		ginkgo.It("should allocate every work-group", func() {
+			cmd.PacketArray[0].GridSizeX = 7 * 64
+			cmdQueue.GPUID = driver.CreateUnifiedGPU(context, []int{1, 2})
+
+			wgDist := driver.distributeWGToGPUs(cmdQueue, cmd)
+
+			Expect(wgDist).To(Equal([]int{0, 4, 7}))
+		})
+	})
+
 	ginkgo.It("should process LaunchKernel return", func() {
 		nilPort := NewMockPort(mockCtrl)
 		nilPort.EXPECT().AsRemote().AnyTimes()
@@ -470,6 +617,20 @@ var _ = ginkgo.Describe("Driver", func() {
 
 	})
 
+	ginkgo.It("should wait for all the shootdown complete rsps", func() {
+		nilPort := NewMockPort(mockCtrl)
+		nilPort.EXPECT().AsRemote().AnyTimes()
+
+		req := protocol.NewShootdownCompleteRsp(nilPort, driver.gpuPort)
+		driver.numShootDownACK = 2
+
+		madeProgress := driver.processShootdownCompleteRsp(req)
+
+		Expect(madeProgress).To(BeTrue())
+		Expect(driver.numShootDownACK).To(Equal(uint64(1)))
+		Expect(driver.migrationReqToSendToCP).To(BeEmpty())
+	})
+
 	ginkgo.It("should handle shootdown complete rsp", func() {
 		nilPort := NewMockPort(mockCtrl)
 		nilPort.EXPECT().AsRemote().AnyTimes()
@@ -0,0 +1,174 @@
+package driver
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+// An Event marks a point in a command queue, like a CUDA or HIP event. Other
+// queues, also on other GPUs, can wait for it without involving the host.
+//
+// Every time an event is recorded, it refers to the new position. Waiting for
+// an event waits for the last record made before the wait. Waiting for an
+// event that was never recorded does not wait.
+type Event struct {
+	ID string
+
+	mutex        sync.Mutex
+	cond         *sync.Cond
+	numRecorded  int
+	numCompleted int
+	time         sim.VTimeInSec
+}
+
+// CreateEvent creates an event that is not recorded.
+func (d *Driver) CreateEvent() *Event {
+	e := &Event{ID: sim.GetIDGenerator().Generate()}
+	e.cond = sync.NewCond(&e.mutex)
+
+	return e
+}
+
+// RecordEvent enqueues a command that completes the event once the commands
+// enqueued before it have completed.
+func (d *Driver) RecordEvent(queue *CommandQueue, event *Event) {
+	event.mutex.Lock()
+	event.numRecorded++
+	record := event.numRecorded
+	event.mutex.Unlock()
+
+	d.Enqueue(queue, &RecordEventCommand{
+		ID:     sim.GetIDGenerator().Generate(),
+		Event:  event,
+		record: record,
+	})
+}
+
+// QueueWaitEvent enqueues a command that holds the commands enqueued after it
+// until the last record of the event completes.
+func (d *Driver) QueueWaitEvent(queue *CommandQueue, event *Event) {
+	event.mutex.Lock()
+	record := event.numRecorded
+	event.mutex.Unlock()
+
+	d.Enqueue(queue, &WaitEventCommand{
+		ID:     sim.GetIDGenerator().Generate(),
+		Event:  event,
+		record: record,
+	})
+}
+
+// EventSynchronize returns when the last record of the event completes.
+func (d *Driver) EventSynchronize(event *Event) {
+	event.mutex.Lock()
+	record := event.numRecorded
+	event.mutex.Unlock()
+
+	d.enqueueSignal <- true
+
+	event.mutex.Lock()
+	for event.numCompleted < record {
+		event.cond.Wait()
+	}
+	event.mutex.Unlock()
+}
+
+// EventQuery returns true if the last record of the event has completed.
+func (d *Driver) EventQuery(event *Event) bool {
+	event.mutex.Lock()
+	defer event.mutex.Unlock()
+
+	return event.numCompleted >= event.numRecorded
+}
+
+// EventElapsedTime returns the simulated time between the completion of two
+// events. An error is returned if either of them is not recorded or has not
+// completed.
+func (d *Driver) EventElapsedTime(start, end *Event) (sim.VTimeInSec, error) {
+	startTime, err := start.completionTime()
+	if err != nil {
+		return 0, err
+	}
+
+	endTime, err := end.completionTime()
+	if err != nil {
+		return 0, err
+	}
+
+	return endTime - startTime, nil
+}
+
+func (e *Event) completionTime() (sim.VTimeInSec, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.numRecorded == 0 {
+		return 0, fmt.Errorf("event %s is not recorded", e.ID)
+	}
+
+	if e.numCompleted < e.numRecorded {
+		return 0, fmt.Errorf("event %s has not completed", e.ID)
+	}
+
+	return e.time, nil
+}
+
+// complete marks a record as completed. A record that completes after a later
+// one does not change the time of the event.
+func (e *Event) complete(record int, now sim.VTimeInSec) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if record <= e.numCompleted {
+		return
+	}
+
+	e.numCompleted = record
+	e.time = now
+	e.cond.Broadcast()
+}
+
+func (e *Event) isCompleted(record int) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.numCompleted >= record
+}
+
+// Commands before the record have completed, as a queue only processes a
+// command after the previous one leaves it.
+func (d *Driver) processRecordEventCommand(
+	cmd *RecordEventCommand,
+	queue *CommandQueue,
+) bool {
+	d.logCmdStart(cmd)
+	cmd.Event.complete(cmd.record, d.Engine.CurrentTime())
+	queue.Dequeue()
+	d.logCmdComplete(cmd)
+
+	return true
+}
+
+// The wait stays at the front of the queue until the event completes. The
+// driver keeps ticking while other queues make progress, so it retries after
+// every command that may complete the event.
+func (d *Driver) processWaitEventCommand(
+	cmd *WaitEventCommand,
+	queue *CommandQueue,
+) bool {
+	if !cmd.waiting {
+		cmd.waiting = true
+		d.logCmdStart(cmd)
+	}
+
+	if !cmd.Event.isCompleted(cmd.record) {
+		return false
+	}
+
+	queue.Dequeue()
+	d.logCmdComplete(cmd)
+
+	return true
+}
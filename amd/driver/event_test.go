@@ -0,0 +1,96 @@
+package driver
+
+import (
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/sim"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = ginkgo.Describe("Event", func() {
+	var (
+		mockCtrl *gomock.Controller
+		engine   *MockEngine
+		driver   *Driver
+		context  *Context
+		queue1   *CommandQueue
+		queue2   *CommandQueue
+		event    *Event
+	)
+
+	ginkgo.BeforeEach(func() {
+		f := newDriverFixture(nil)
+		mockCtrl = f.mockCtrl
+		engine = f.engine
+		driver = f.driver
+
+		context = driver.Init()
+		queue1 = driver.CreateCommandQueue(context)
+		queue2 = driver.CreateCommandQueue(context)
+		event = driver.CreateEvent()
+	})
+
+	ginkgo.AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	ginkgo.It("should complete when the record is processed", func() {
+		engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(2))
+		driver.RecordEvent(queue1, event)
+
+		Expect(driver.EventQuery(event)).To(BeFalse())
+		Expect(driver.processOneCommand(queue1)).To(BeTrue())
+		Expect(driver.EventQuery(event)).To(BeTrue())
+		Expect(queue1.NumCommand()).To(Equal(0))
+	})
+
+	ginkgo.It("should hold a queue until the event completes", func() {
+		engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(2))
+		driver.RecordEvent(queue1, event)
+		driver.QueueWaitEvent(queue2, event)
+
+		Expect(driver.processOneCommand(queue2)).To(BeFalse())
+		Expect(queue2.NumCommand()).To(Equal(1))
+
+		driver.processOneCommand(queue1)
+
+		Expect(driver.processOneCommand(queue2)).To(BeTrue())
+		Expect(queue2.NumCommand()).To(Equal(0))
+	})
+
+	ginkgo.It("should not wait for an event that is not recorded", func() {
+		driver.QueueWaitEvent(queue2, event)
+
+		Expect(driver.processOneCommand(queue2)).To(BeTrue())
+	})
+
+	ginkgo.It("should wait for the record made before the wait", func() {
+		engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(2))
+		driver.RecordEvent(queue1, event)
+		driver.processOneCommand(queue1)
+
+		driver.QueueWaitEvent(queue2, event)
+		driver.RecordEvent(queue1, event)
+
+		Expect(driver.processOneCommand(queue2)).To(BeTrue())
+		Expect(driver.EventQuery(event)).To(BeFalse())
+	})
+
+	ginkgo.It("should return the time between two events", func() {
+		end := driver.CreateEvent()
+		driver.RecordEvent(queue1, event)
+		driver.RecordEvent(queue1, end)
+
+		_, err := driver.EventElapsedTime(event, end)
+		Expect(err).To(HaveOccurred())
+
+		engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(2))
+		driver.processOneCommand(queue1)
+		engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(5))
+		driver.processOneCommand(queue1)
+
+		t, err := driver.EventElapsedTime(event, end)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(t).To(Equal(sim.VTimeInSec(3)))
+	})
+})
@@ -0,0 +1,59 @@
+package driver
+
+import (
+	"fmt"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/sarchlab/akita/v4/mem/mem"
+	"github.com/sarchlab/akita/v4/sim"
+	"go.uber.org/mock/gomock"
+)
+
+// driverFixture is a driver with two GPUs of 4 GB, with 4 CUs each, and 4 KB
+// pages. The GPUs, the memory allocator, the page table and the engine are
+// mocks.
+type driverFixture struct {
+	mockCtrl     *gomock.Controller
+	engine       *MockEngine
+	pageTable    *MockPageTable
+	memAllocator *MockMemoryAllocator
+	toGPUs       *MockPort
+	driver       *Driver
+}
+
+// newDriverFixture builds a driverFixture. The storage is the global storage
+// of the driver, and may be nil. The remote ports of the GPUs are GPU1 and
+// GPU2.
+func newDriverFixture(storage *mem.Storage) driverFixture {
+	f := driverFixture{}
+
+	f.mockCtrl = gomock.NewController(ginkgo.GinkgoT())
+	f.engine = NewMockEngine(f.mockCtrl)
+	f.pageTable = NewMockPageTable(f.mockCtrl)
+	f.memAllocator = NewMockMemoryAllocator(f.mockCtrl)
+	f.memAllocator.EXPECT().RegisterDevice(gomock.Any()).AnyTimes()
+	f.toGPUs = NewMockPort(f.mockCtrl)
+	f.toGPUs.EXPECT().AsRemote().AnyTimes()
+
+	f.driver = MakeBuilder().
+		WithEngine(f.engine).
+		WithLog2PageSize(12).
+		WithPageTable(f.pageTable).
+		WithGlobalStorage(storage).
+		Build("Driver")
+	f.driver.gpuPort = f.toGPUs
+	f.driver.memAllocator = f.memAllocator
+
+	for i := 0; i < 2; i++ {
+		gpu := NewMockPort(f.mockCtrl)
+		gpu.EXPECT().AsRemote().
+			Return(sim.RemotePort(fmt.Sprintf("GPU%d", i+1))).
+			AnyTimes()
+		f.driver.RegisterGPU(gpu, DeviceProperties{
+			CUCount:  4,
+			DRAMSize: 4 * mem.GB,
+		})
+	}
+
+	return f
+}
@@ -1,5 +1,7 @@
 package internal
 
+import "github.com/sarchlab/akita/v4/sim"
+
 // DeviceType marks the type of a device.
 type DeviceType int
 
@@ -27,6 +29,7 @@ var MemoryAllocatorType = AllocatorTypeDefault
 type DeviceProperties struct {
 	CUCount  int
 	DRAMSize uint64
+	Freq     sim.Freq
 }
 
 // Device is a CPU or GPU managed by the driver.
@@ -84,6 +84,15 @@ func (bms *deviceBuddyMemoryState) noAvailablePAddrs() bool {
 	return true
 }
 
+func (bms *deviceBuddyMemoryState) numAvailablePages() int {
+	numPages := 0
+	for level := range bms.freeList {
+		pagesInBlock := int(bms.sizeOfLevel(level) >> bms.log2PageSize)
+		numPages += bms.freeList[level].Len() * pagesInBlock
+	}
+	return numPages
+}
+
 func (bms *deviceBuddyMemoryState) allocateMultiplePages(
 	numPages int,
 ) (pAddrs []uint64) {
@@ -304,6 +304,18 @@ var _ = Describe("Implementation of buddy allocation DeviceMemoryState", func()
 		}
 	})
 
+	It("should count the available pages", func() {
+		Expect(buddyDMS.numAvailablePages()).To(Equal(1 << 20))
+
+		addr := buddyDMS.popNextAvailablePAddrs()
+
+		Expect(buddyDMS.numAvailablePages()).To(Equal(1<<20 - 1))
+
+		buddyDMS.addSinglePAddr(addr)
+
+		Expect(buddyDMS.numAvailablePages()).To(Equal(1 << 20))
+	})
+
 	It("should have no available PAddrs", func() {
 		bDMS := buddyDMS.(*deviceBuddyMemoryState)
 		bDMS.freeList[0].Init()
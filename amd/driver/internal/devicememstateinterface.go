@@ -9,6 +9,7 @@ type DeviceMemoryState interface {
 	addSinglePAddr(addr uint64)
 	popNextAvailablePAddrs() uint64
 	noAvailablePAddrs() bool
+	numAvailablePages() int
 	allocateMultiplePages(numPages int) []uint64
 }
 
@@ -74,6 +75,10 @@ func (dms *deviceMemoryStateImpl) noAvailablePAddrs() bool {
 	return len(dms.availablePAddrs) == 0
 }
 
+func (dms *deviceMemoryStateImpl) numAvailablePages() int {
+	return len(dms.availablePAddrs)
+}
+
 func (dms *deviceMemoryStateImpl) allocateMultiplePages(
 	numPages int,
 ) (pAddrs []uint64) {
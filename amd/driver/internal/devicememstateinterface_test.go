@@ -60,6 +60,17 @@ var _ = Describe("Implementation of regular DeviceMemoryState", func() {
 		Expect(rDMS.availablePAddrs).To(HaveLen(1))
 	})
 
+	It("should count the available pages", func() {
+		regularDMS.addSinglePAddr(0x0_0000_1000)
+		regularDMS.addSinglePAddr(0x0_0000_2000)
+
+		Expect(regularDMS.numAvailablePages()).To(Equal(2))
+
+		regularDMS.popNextAvailablePAddrs()
+
+		Expect(regularDMS.numAvailablePages()).To(Equal(1))
+	})
+
 	It("should have no available PAddrs", func() {
 		ok := regularDMS.noAvailablePAddrs()
 		Expect(ok).To(BeTrue())
@@ -2,6 +2,7 @@
 package internal
 
 import (
+	"log"
 	"sync"
 
 	"github.com/sarchlab/akita/v4/mem/vm"
@@ -13,6 +14,11 @@ type MemoryAllocator interface {
 	GetDeviceIDByPAddr(pAddr uint64) int
 	Allocate(pid vm.PID, byteSize uint64, deviceID int) uint64
 	AllocateUnified(pid vm.PID, byteSize uint64) uint64
+	AllocateUnifiedOnDevices(
+		pid vm.PID,
+		byteSize uint64,
+		deviceIDs []int,
+	) uint64
 	Free(vAddr uint64)
 	Remap(pid vm.PID, pageVAddr, byteSize uint64, deviceID int)
 	RemovePage(vAddr uint64)
@@ -22,6 +28,8 @@ type MemoryAllocator interface {
 		vAddr uint64,
 		unified bool,
 	) vm.Page
+	NumFreePages(deviceID int) int
+	FreeFrame(pAddr uint64)
 }
 
 // NewMemoryAllocator creates a new memory allocator.
@@ -128,11 +136,47 @@ func (a *memoryAllocatorImpl) AllocateUnified(
 	return a.allocatePages(int(numPages), pid, 1, true)
 }
 
+// AllocateUnifiedOnDevices allocates unified memory with the i-th page on
+// the device deviceIDs[i].
+func (a *memoryAllocatorImpl) AllocateUnifiedOnDevices(
+	pid vm.PID,
+	byteSize uint64,
+	deviceIDs []int,
+) uint64 {
+	if byteSize == 0 {
+		panic("Allocating 0 bytes.")
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	pageSize := uint64(1 << a.log2PageSize)
+	numPages := (byteSize-1)/pageSize + 1
+	if uint64(len(deviceIDs)) != numPages {
+		log.Panicf("%d pages placed on %d devices", numPages, len(deviceIDs))
+	}
+
+	return a.allocatePagesOnDevices(pid, deviceIDs, true)
+}
+
 func (a *memoryAllocatorImpl) allocatePages(
 	numPages int,
 	pid vm.PID,
 	deviceID int,
 	unified bool,
+) (firstPageVAddr uint64) {
+	deviceIDs := make([]int, numPages)
+	for i := range deviceIDs {
+		deviceIDs[i] = deviceID
+	}
+
+	return a.allocatePagesOnDevices(pid, deviceIDs, unified)
+}
+
+func (a *memoryAllocatorImpl) allocatePagesOnDevices(
+	pid vm.PID,
+	deviceIDs []int,
+	unified bool,
 ) (firstPageVAddr uint64) {
 	pState, found := a.processMemoryStates[pid]
 	if !found {
@@ -142,13 +186,12 @@ func (a *memoryAllocatorImpl) allocatePages(
 		}
 		pState = a.processMemoryStates[pid]
 	}
-	device := a.devices[deviceID]
 
 	pageSize := uint64(1 << a.log2PageSize)
 	nextVAddr := pState.nextVAddr
 
-	for i := 0; i < numPages; i++ {
-		pAddr := device.allocatePage()
+	for i, deviceID := range deviceIDs {
+		pAddr := a.devices[deviceID].allocatePage()
 		vAddr := nextVAddr + uint64(i)*pageSize
 
 		page := vm.Page{
@@ -167,7 +210,7 @@ func (a *memoryAllocatorImpl) allocatePages(
 		a.vAddrToPageMapping[page.VAddr] = page
 	}
 
-	pState.nextVAddr += pageSize * uint64(numPages)
+	pState.nextVAddr += pageSize * uint64(len(deviceIDs))
 
 	return nextVAddr
 }
@@ -279,6 +322,25 @@ func (a *memoryAllocatorImpl) allocateMultiplePagesWithGivenVAddrs(
 	return pages
 }
 
+// NumFreePages returns the number of pages that can still be allocated on a
+// device.
+func (a *memoryAllocatorImpl) NumFreePages(deviceID int) int {
+	a.Lock()
+	defer a.Unlock()
+
+	return a.devices[deviceID].MemState.numAvailablePages()
+}
+
+// FreeFrame returns a physical page to its device, without touching the page
+// table. The driver uses it for the frames that a page leaves when it moves.
+func (a *memoryAllocatorImpl) FreeFrame(pAddr uint64) {
+	a.Lock()
+	defer a.Unlock()
+
+	deviceID := a.deviceIDByPAddr(pAddr)
+	a.devices[deviceID].MemState.addSinglePAddr(pAddr)
+}
+
 func (a *memoryAllocatorImpl) Free(ptr uint64) {
 	a.Lock()
 	defer a.Unlock()
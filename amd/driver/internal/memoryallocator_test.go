@@ -59,6 +59,32 @@ var _ = Describe("MemoryAllocatorImpl", func() {
 		Expect(ptr).To(Equal(uint64(4096)))
 	})
 
+	It("should allocate unified memory on given devices", func() {
+		pageTable.EXPECT().Insert(
+			vm.Page{
+				PID:      1,
+				PAddr:    0x2_0000_1000,
+				VAddr:    4096,
+				PageSize: 4096,
+				DeviceID: 2,
+				Valid:    true,
+				Unified:  true,
+			})
+		pageTable.EXPECT().Insert(
+			vm.Page{
+				PID:      1,
+				PAddr:    0x1_0000_1000,
+				VAddr:    8192,
+				PageSize: 4096,
+				DeviceID: 1,
+				Valid:    true,
+				Unified:  true,
+			})
+
+		ptr := allocator.AllocateUnifiedOnDevices(1, 8000, []int{2, 1})
+		Expect(ptr).To(Equal(uint64(4096)))
+	})
+
 	It("should allocate memory larger than a page", func() {
 		for i := uint64(0); i < 3; i++ {
 			pageTable.EXPECT().Insert(
@@ -94,6 +120,18 @@ var _ = Describe("MemoryAllocatorImpl", func() {
 		pageTable.EXPECT().Update(updatedPage)
 		allocator.Remap(1, ptr, 4000, 2)
 	})
+
+	It("should count and free the frames of a device", func() {
+		pageTable.EXPECT().Insert(gomock.Any())
+		allocator.Allocate(1, 4096, 1)
+
+		Expect(allocator.NumFreePages(1)).To(Equal(1<<20 - 1))
+		Expect(allocator.NumFreePages(2)).To(Equal(1 << 20))
+
+		allocator.FreeFrame(0x1_0000_1000)
+
+		Expect(allocator.NumFreePages(1)).To(Equal(1 << 20))
+	})
 })
 
 func configAFourGPUSystem(allocator *memoryAllocatorImpl) {
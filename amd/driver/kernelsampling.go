@@ -0,0 +1,380 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/sarchlab/akita/v4/mem/vm"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/emu"
+	"github.com/sarchlab/mgpusim/v4/amd/insts"
+	"github.com/sarchlab/mgpusim/v4/amd/kernels"
+	"github.com/sarchlab/mgpusim/v4/amd/protocol"
+)
+
+// KernelSamplingConfig selects the kernel launches that a timing simulation
+// runs in detail. The rest are fast-forwarded: they run in the functional
+// emulator, so that the memory stays correct, and take the average time of
+// the detailed launches of the same kernel. A kernel is a code object
+// launched with a grid size and a work-group size.
+type KernelSamplingConfig struct {
+	// WarmUp is the number of kernel launches from the start of the
+	// simulation that always run in detail.
+	WarmUp int
+
+	// Samples is the number of launches of each kernel that run in detail to
+	// estimate the time of the rest. A kernel always runs in detail until
+	// one of its launches completes.
+	Samples int
+
+	// Period makes one out of every Period launches of each kernel run in
+	// detail after the samples, to follow changes in behavior. 0 disables
+	// it.
+	Period int
+}
+
+// KernelSamplingStats summarizes the launches of a kernel in a simulation
+// with kernel sampling.
+type KernelSamplingStats struct {
+	Kernel        string
+	Detailed      int
+	FastForwarded int
+
+	// DetailedTime is the total time of the detailed launches, and MeanTime
+	// and StdDevTime their mean and standard deviation.
+	DetailedTime sim.VTimeInSec
+	MeanTime     sim.VTimeInSec
+	StdDevTime   sim.VTimeInSec
+
+	// ExtrapolatedTime is the total time given to the fast-forwarded
+	// launches.
+	ExtrapolatedTime sim.VTimeInSec
+
+	// Error is the half-width of the 95% confidence interval of the total
+	// time of the fast-forwarded launches, from the variance of the
+	// detailed ones. It is NaN if there are fast-forwarded launches but less
+	// than 2 detailed ones.
+	Error sim.VTimeInSec
+}
+
+// kernelEmulator runs a kernel functionally, without simulating time.
+type kernelEmulator interface {
+	Run(
+		pid vm.PID,
+		co *insts.HsaCo,
+		packet *kernels.HsaKernelDispatchPacket,
+		packetAddr uint64,
+	)
+}
+
+type sampledKernel struct {
+	name string
+
+	launches      int
+	detailed      int
+	measured      int // Detailed launches that completed.
+	fastForwarded int
+
+	sum, sumSquares float64
+	extrapolated    float64
+}
+
+func (k *sampledKernel) mean() float64 {
+	return k.sum / float64(k.measured)
+}
+
+func (k *sampledKernel) stdDev() float64 {
+	if k.measured < 2 {
+		return math.NaN()
+	}
+
+	n := float64(k.measured)
+	variance := (k.sumSquares - k.sum*k.sum/n) / (n - 1)
+
+	return math.Sqrt(math.Max(0, variance))
+}
+
+type detailedLaunch struct {
+	kernel *sampledKernel
+	start  sim.VTimeInSec
+}
+
+type fastForwardedLaunch struct {
+	cmd        Command
+	queue      *CommandQueue
+	kernel     *sampledKernel
+	flushReqs  []sim.Msg
+	emulated   bool
+	finishTime sim.VTimeInSec
+}
+
+// kernelSampler decides which kernels run in detail and fast-forwards the
+// rest. Like the checkpointer, it sees the kernel launches before the driver
+// processes them.
+type kernelSampler struct {
+	driver   *Driver
+	config   KernelSamplingConfig
+	emulator kernelEmulator
+
+	numLaunches int
+	kernels     map[string]*sampledKernel
+	kernelOrder []*sampledKernel
+
+	detailed       map[Command]detailedLaunch
+	fastForwarding []*fastForwardedLaunch
+}
+
+// EnableKernelSampling makes the driver run only some of the kernel launches
+// in detail, and fast-forward the rest. See KernelSamplingConfig.
+func (d *Driver) EnableKernelSampling(config KernelSamplingConfig) {
+	if d.globalStorage == nil {
+		log.Panic("kernel sampling requires the driver to have the " +
+			"global storage")
+	}
+
+	d.kernelSampler = &kernelSampler{
+		driver:   d,
+		config:   config,
+		emulator: emu.NewKernelRunner(d.globalStorage, d.pageTable, d.Log2PageSize),
+		kernels:  make(map[string]*sampledKernel),
+		detailed: make(map[Command]detailedLaunch),
+	}
+	d.addPreDispatchMiddleware(preDispatchKernelSampling, d.kernelSampler)
+}
+
+// KernelSamplingStats returns the statistics of every kernel, in the order
+// of their first launch. It returns nil if kernel sampling is not enabled.
+func (d *Driver) KernelSamplingStats() []KernelSamplingStats {
+	if d.kernelSampler == nil {
+		return nil
+	}
+
+	stats := make([]KernelSamplingStats, 0, len(d.kernelSampler.kernelOrder))
+	for _, k := range d.kernelSampler.kernelOrder {
+		s := KernelSamplingStats{
+			Kernel:           k.name,
+			Detailed:         k.detailed,
+			FastForwarded:    k.fastForwarded,
+			DetailedTime:     sim.VTimeInSec(k.sum),
+			ExtrapolatedTime: sim.VTimeInSec(k.extrapolated),
+		}
+
+		if k.measured > 0 {
+			s.MeanTime = sim.VTimeInSec(k.mean())
+		}
+
+		// The sum of n future launches differs from n times the mean by the
+		// variance of each launch (n*sd^2) and by the error of the mean
+		// (n^2*sd^2/k).
+		sd := k.stdDev()
+		s.StdDevTime = sim.VTimeInSec(sd)
+		if k.fastForwarded > 0 {
+			n := float64(k.fastForwarded)
+			variance := sd * sd * (n*n/float64(k.measured) + n)
+			s.Error = sim.VTimeInSec(1.96 * math.Sqrt(variance))
+		}
+
+		stats = append(stats, s)
+	}
+
+	return stats
+}
+
+func kernelName(
+	co *insts.HsaCo,
+	packet *kernels.HsaKernelDispatchPacket,
+) string {
+	name := "kernel"
+	if co.Symbol != nil {
+		name = co.Symbol.Name
+	}
+
+	return fmt.Sprintf("%s[%dx%dx%d/%dx%dx%d]", name,
+		packet.GridSizeX, packet.GridSizeY, packet.GridSizeZ,
+		packet.WorkgroupSizeX, packet.WorkgroupSizeY, packet.WorkgroupSizeZ)
+}
+
+func (s *kernelSampler) kernel(name string) *sampledKernel {
+	k := s.kernels[name]
+	if k == nil {
+		k = &sampledKernel{name: name}
+		s.kernels[name] = k
+		s.kernelOrder = append(s.kernelOrder, k)
+	}
+
+	return k
+}
+
+// ProcessCommand is called with every command before the driver processes
+// it. It returns true if it takes care of the command.
+func (s *kernelSampler) ProcessCommand(
+	cmd Command,
+	queue *CommandQueue,
+) (processed bool) {
+	var name string
+	switch cmd := cmd.(type) {
+	case *LaunchKernelCommand:
+		name = kernelName(cmd.CodeObject, cmd.Packet)
+	case *LaunchUnifiedMultiGPUKernelCommand:
+		name = kernelName(cmd.CodeObject, cmd.PacketArray[0])
+	default:
+		return false
+	}
+
+	k := s.kernel(name)
+	s.numLaunches++
+	k.launches++
+
+	if s.runInDetail(k) {
+		k.detailed++
+		s.detailed[cmd] = detailedLaunch{
+			kernel: k,
+			start:  s.driver.Engine.CurrentTime(),
+		}
+
+		return false
+	}
+
+	s.startFastForward(cmd, queue, k)
+
+	return true
+}
+
+func (s *kernelSampler) runInDetail(k *sampledKernel) bool {
+	switch {
+	case s.numLaunches <= s.config.WarmUp:
+		return true
+	case k.measured == 0 || k.detailed < s.config.Samples:
+		return true
+	case s.config.Period > 0 && k.launches%s.config.Period == 0:
+		return true
+	}
+
+	return false
+}
+
+// kernelCompleted records the time of a kernel that ran in detail.
+func (s *kernelSampler) kernelCompleted(cmd Command) {
+	launch, ok := s.detailed[cmd]
+	if !ok {
+		return
+	}
+	delete(s.detailed, cmd)
+
+	t := float64(s.driver.Engine.CurrentTime() - launch.start)
+	launch.kernel.measured++
+	launch.kernel.sum += t
+	launch.kernel.sumSquares += t * t
+}
+
+// startFastForward flushes and invalidates the caches before the kernel runs
+// on the storage, as the emulator changes the memory behind their back.
+func (s *kernelSampler) startFastForward(
+	cmd Command,
+	queue *CommandQueue,
+	k *sampledKernel,
+) {
+	d := s.driver
+
+	d.logCmdStart(cmd)
+	queue.IsRunning = true
+
+	launch := &fastForwardedLaunch{cmd: cmd, queue: queue, kernel: k}
+	for _, gpu := range d.GPUs {
+		req := protocol.NewFlushReq(d.gpuPort, gpu)
+		req.InvalidateCaches = true
+		d.requestsToSend = append(d.requestsToSend, req)
+		launch.flushReqs = append(launch.flushReqs, req)
+
+		d.logTaskToGPUInitiate(cmd, req)
+	}
+
+	s.fastForwarding = append(s.fastForwarding, launch)
+}
+
+// Tick advances the fast-forwarded kernels.
+func (s *kernelSampler) Tick() (madeProgress bool) {
+	madeProgress = s.processFlushRsp()
+
+	now := s.driver.Engine.CurrentTime()
+	remaining := s.fastForwarding[:0]
+	for _, launch := range s.fastForwarding {
+		switch {
+		case len(launch.flushReqs) > 0:
+		case !launch.emulated:
+			s.emulate(launch, now)
+			madeProgress = true
+		case now >= launch.finishTime:
+			s.completeFastForward(launch)
+			madeProgress = true
+
+			continue
+		}
+
+		remaining = append(remaining, launch)
+	}
+	s.fastForwarding = remaining
+
+	return madeProgress
+}
+
+func (s *kernelSampler) processFlushRsp() bool {
+	rsp, ok := s.driver.gpuPort.PeekIncoming().(*sim.GeneralRsp)
+	if !ok {
+		return false
+	}
+
+	for _, launch := range s.fastForwarding {
+		for i, req := range launch.flushReqs {
+			if req != rsp.OriginalReq {
+				continue
+			}
+
+			s.driver.gpuPort.RetrieveIncoming()
+			s.driver.logTaskToGPUClear(req)
+			launch.flushReqs = append(launch.flushReqs[:i],
+				launch.flushReqs[i+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// emulate runs the kernel and schedules its completion after the mean time
+// of the detailed launches of the same kernel.
+func (s *kernelSampler) emulate(
+	launch *fastForwardedLaunch,
+	now sim.VTimeInSec,
+) {
+	pid := launch.queue.Context.pid
+
+	switch cmd := launch.cmd.(type) {
+	case *LaunchKernelCommand:
+		s.emulator.Run(pid, cmd.CodeObject, cmd.Packet, uint64(cmd.DPacket))
+	case *LaunchUnifiedMultiGPUKernelCommand:
+		// Every packet describes the same grid. Running the whole grid once
+		// does not need the work-group filter of each GPU.
+		s.emulator.Run(pid, cmd.CodeObject, cmd.PacketArray[0],
+			uint64(cmd.DPacketArray[0]))
+	}
+
+	k := launch.kernel
+	t := k.mean()
+	k.fastForwarded++
+	k.extrapolated += t
+
+	launch.emulated = true
+	launch.finishTime = now + sim.VTimeInSec(t)
+
+	s.driver.Engine.Schedule(sim.MakeTickEvent(s.driver, launch.finishTime))
+}
+
+func (s *kernelSampler) completeFastForward(launch *fastForwardedLaunch) {
+	launch.queue.IsRunning = false
+	launch.queue.Dequeue()
+
+	s.driver.logCmdComplete(launch.cmd)
+}
@@ -0,0 +1,212 @@
+package driver
+
+import (
+	"math"
+
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/mem/mem"
+	"github.com/sarchlab/akita/v4/mem/vm"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/insts"
+	"github.com/sarchlab/mgpusim/v4/amd/kernels"
+	"github.com/sarchlab/mgpusim/v4/amd/protocol"
+	"go.uber.org/mock/gomock"
+)
+
+type fakeKernelEmulator struct {
+	numRuns int
+}
+
+func (e *fakeKernelEmulator) Run(
+	pid vm.PID,
+	co *insts.HsaCo,
+	packet *kernels.HsaKernelDispatchPacket,
+	packetAddr uint64,
+) {
+	e.numRuns++
+}
+
+var _ = ginkgo.Describe("Kernel Sampler", func() {
+	var (
+		mockCtrl *gomock.Controller
+		engine   *MockEngine
+		toGPUs   *MockPort
+		driver   *Driver
+		context  *Context
+		cmdQueue *CommandQueue
+		emulator *fakeKernelEmulator
+		sampler  *kernelSampler
+	)
+
+	launch := func() *LaunchKernelCommand {
+		return &LaunchKernelCommand{
+			CodeObject: &insts.HsaCo{},
+			Packet: &kernels.HsaKernelDispatchPacket{
+				GridSizeX:      64,
+				GridSizeY:      1,
+				GridSizeZ:      1,
+				WorkgroupSizeX: 64,
+				WorkgroupSizeY: 1,
+				WorkgroupSizeZ: 1,
+			},
+		}
+	}
+
+	ginkgo.BeforeEach(func() {
+		f := newDriverFixture(mem.NewStorage(4 * mem.GB))
+		mockCtrl = f.mockCtrl
+		engine = f.engine
+		toGPUs = f.toGPUs
+		driver = f.driver
+
+		context = driver.Init()
+		cmdQueue = driver.CreateCommandQueue(context)
+
+		driver.EnableKernelSampling(KernelSamplingConfig{
+			WarmUp:  1,
+			Samples: 2,
+			Period:  5,
+		})
+		emulator = &fakeKernelEmulator{}
+		sampler = driver.kernelSampler
+		sampler.emulator = emulator
+	})
+
+	ginkgo.AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	ginkgo.It("should run the warm-up and the samples in detail", func() {
+		engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(0)).AnyTimes()
+
+		Expect(sampler.ProcessCommand(launch(), cmdQueue)).To(BeFalse())
+		Expect(sampler.ProcessCommand(launch(), cmdQueue)).To(BeFalse())
+
+		k := sampler.kernels["kernel[64x1x1/64x1x1]"]
+		Expect(k.detailed).To(Equal(2))
+	})
+
+	ginkgo.It("should not fast-forward a kernel that has no time yet", func() {
+		engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(0)).AnyTimes()
+
+		for i := 0; i < 3; i++ {
+			Expect(sampler.ProcessCommand(launch(), cmdQueue)).To(BeFalse())
+		}
+	})
+
+	ginkgo.It("should record the time of the detailed launches", func() {
+		cmd := launch()
+		engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(1))
+		sampler.ProcessCommand(cmd, cmdQueue)
+
+		engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(3))
+		sampler.kernelCompleted(cmd)
+
+		stats := driver.KernelSamplingStats()
+		Expect(stats).To(HaveLen(1))
+		Expect(stats[0].Detailed).To(Equal(1))
+		Expect(stats[0].MeanTime).To(Equal(sim.VTimeInSec(2)))
+		Expect(stats[0].DetailedTime).To(Equal(sim.VTimeInSec(2)))
+	})
+
+	ginkgo.Context("when fast-forwarding", func() {
+		var k *sampledKernel
+
+		ginkgo.BeforeEach(func() {
+			sampler.numLaunches = 2
+			k = sampler.kernel("kernel[64x1x1/64x1x1]")
+			k.launches = 2
+			k.detailed = 2
+			k.measured = 2
+			k.sum = 4
+			k.sumSquares = 10
+		})
+
+		ginkgo.It("should flush the GPUs", func() {
+			engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(10)).AnyTimes()
+			cmd := launch()
+
+			Expect(sampler.ProcessCommand(cmd, cmdQueue)).To(BeTrue())
+
+			Expect(cmdQueue.IsRunning).To(BeTrue())
+			Expect(driver.requestsToSend).To(HaveLen(2))
+			req := driver.requestsToSend[0].(*protocol.FlushReq)
+			Expect(req.InvalidateCaches).To(BeTrue())
+			Expect(sampler.fastForwarding).To(HaveLen(1))
+		})
+
+		ginkgo.It("should emulate the kernel after the flush", func() {
+			engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(10)).AnyTimes()
+			cmd := launch()
+			cmdQueue.Enqueue(cmd)
+			sampler.ProcessCommand(cmd, cmdQueue)
+			driver.requestsToSend = nil
+
+			engine.EXPECT().Schedule(gomock.Any()).Do(func(e sim.Event) {
+				Expect(e.Time()).To(Equal(sim.VTimeInSec(12)))
+			})
+
+			flushReqs := append([]sim.Msg{},
+				sampler.fastForwarding[0].flushReqs...)
+			for _, req := range flushReqs {
+				rsp := sim.GeneralRspBuilder{}.
+					WithSrc(req.Meta().Dst).
+					WithDst(req.Meta().Src).
+					WithOriginalReq(req).
+					Build()
+				toGPUs.EXPECT().PeekIncoming().Return(rsp)
+				toGPUs.EXPECT().RetrieveIncoming().Return(rsp)
+				Expect(emulator.numRuns).To(Equal(0))
+				Expect(sampler.Tick()).To(BeTrue())
+			}
+
+			Expect(emulator.numRuns).To(Equal(1))
+			Expect(k.fastForwarded).To(Equal(1))
+			Expect(cmdQueue.NumCommand()).To(Equal(1))
+		})
+
+		ginkgo.It("should complete the kernel after the mean time", func() {
+			engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(12)).AnyTimes()
+			cmd := launch()
+			cmdQueue.Enqueue(cmd)
+			cmdQueue.IsRunning = true
+			sampler.fastForwarding = []*fastForwardedLaunch{{
+				cmd:        cmd,
+				queue:      cmdQueue,
+				kernel:     k,
+				emulated:   true,
+				finishTime: 12,
+			}}
+
+			toGPUs.EXPECT().PeekIncoming().Return(nil)
+
+			Expect(sampler.Tick()).To(BeTrue())
+			Expect(sampler.fastForwarding).To(BeEmpty())
+			Expect(cmdQueue.IsRunning).To(BeFalse())
+			Expect(cmdQueue.NumCommand()).To(Equal(0))
+		})
+
+		ginkgo.It("should run one out of every period launches in detail", func() {
+			engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(10)).AnyTimes()
+			k.launches = 4
+
+			Expect(sampler.ProcessCommand(launch(), cmdQueue)).To(BeFalse())
+			Expect(k.detailed).To(Equal(3))
+		})
+
+		ginkgo.It("should estimate the error of the extrapolation", func() {
+			k.fastForwarded = 4
+			k.extrapolated = 8
+
+			stats := driver.KernelSamplingStats()
+
+			// The samples are 1 and 3: the standard deviation is sqrt(2).
+			Expect(float64(stats[0].StdDevTime)).
+				To(BeNumerically("~", math.Sqrt(2), 1e-9))
+			Expect(float64(stats[0].Error)).
+				To(BeNumerically("~", 1.96*math.Sqrt(2*(16.0/2+4)), 1e-9))
+			Expect(stats[0].ExtrapolatedTime).To(Equal(sim.VTimeInSec(8)))
+		})
+	})
+})
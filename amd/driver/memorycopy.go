@@ -3,7 +3,9 @@ package driver
 import (
 	"bytes"
 	"encoding/binary"
+	"log"
 
+	"github.com/sarchlab/akita/v4/mem/vm"
 	"github.com/sarchlab/akita/v4/sim"
 	"github.com/sarchlab/mgpusim/v4/amd/protocol"
 )
@@ -18,6 +20,15 @@ type defaultMemoryCopyMiddleware struct {
 	cyclesLeft   int
 
 	awaitingReqs []sim.Msg
+
+	// hostCopies are the commands that only copied pages on the host, which
+	// complete in the next tick.
+	hostCopies []hostCopy
+}
+
+type hostCopy struct {
+	cmd   Command
+	queue *CommandQueue
 }
 
 func (m *defaultMemoryCopyMiddleware) ProcessCommand(
@@ -29,6 +40,8 @@ func (m *defaultMemoryCopyMiddleware) ProcessCommand(
 		return m.processMemCopyH2DCommand(cmd, queue)
 	case *MemCopyD2HCommand:
 		return m.processMemCopyD2HCommand(cmd, queue)
+	case *MemCopyPeerCommand:
+		return m.processMemCopyPeerCommand(cmd, queue)
 	}
 
 	return false
@@ -38,10 +51,6 @@ func (m *defaultMemoryCopyMiddleware) processMemCopyH2DCommand(
 	cmd *MemCopyH2DCommand,
 	queue *CommandQueue,
 ) bool {
-	if m.needFlushing(queue.Context, cmd.Dst, uint64(binary.Size(cmd.Src))) {
-		m.sendFlushRequest(cmd)
-	}
-
 	buffer := bytes.NewBuffer(nil)
 	err := binary.Write(buffer, binary.LittleEndian, cmd.Src)
 	if err != nil {
@@ -66,24 +75,39 @@ func (m *defaultMemoryCopyMiddleware) processMemCopyH2DCommand(
 		}
 
 		gpuID := m.driver.memAllocator.GetDeviceIDByPAddr(pAddr)
-		req := protocol.NewMemCopyH2DReq(
-			m.driver.gpuPort, m.driver.GPUs[gpuID-1],
-			rawBytes[offset:offset+sizeToCopy],
-			pAddr)
-		cmd.Reqs = append(cmd.Reqs, req)
-		m.awaitingReqs = append(m.awaitingReqs, req)
-		// m.driver.requestsToSend = append(m.driver.requestsToSend, req)
+		if gpuID == 0 {
+			m.copyToHost(pAddr, rawBytes[offset:offset+sizeToCopy])
+		} else {
+			m.driver.evictor.touch(page.PID, page.VAddr, uint64(gpuID))
+
+			req := protocol.NewMemCopyH2DReq(
+				m.driver.gpuPort, m.driver.GPUs[gpuID-1],
+				rawBytes[offset:offset+sizeToCopy],
+				pAddr)
+			cmd.Reqs = append(cmd.Reqs, req)
+			m.awaitingReqs = append(m.awaitingReqs, req)
+			// m.driver.requestsToSend = append(m.driver.requestsToSend, req)
+
+			m.driver.logTaskToGPUInitiate(cmd, req)
+		}
 
 		sizeLeft -= sizeToCopy
 		addr += sizeToCopy
 		offset += sizeToCopy
+	}
 
-		m.driver.logTaskToGPUInitiate(cmd, req)
+	queue.IsRunning = true
+
+	if len(cmd.Reqs) == 0 {
+		m.hostCopies = append(m.hostCopies, hostCopy{cmd: cmd, queue: queue})
+		return true
 	}
 
-	m.cyclesLeft = m.cyclesPerH2D
+	if m.needFlushing(queue.Context, cmd.Dst, uint64(binary.Size(cmd.Src))) {
+		m.sendFlushRequest(cmd)
+	}
 
-	queue.IsRunning = true
+	m.cyclesLeft = m.cyclesPerH2D
 
 	return true
 }
@@ -92,11 +116,6 @@ func (m *defaultMemoryCopyMiddleware) processMemCopyD2HCommand(
 	cmd *MemCopyD2HCommand,
 	queue *CommandQueue,
 ) bool {
-	if m.needFlushing(queue.Context, cmd.Src, uint64(binary.Size(cmd.Dst))) {
-		m.sendFlushRequest(cmd)
-		queue.Context.removeFreedBuffers()
-	}
-
 	cmd.RawData = make([]byte, binary.Size(cmd.Dst))
 
 	offset := uint64(0)
@@ -116,26 +135,155 @@ func (m *defaultMemoryCopyMiddleware) processMemCopyD2HCommand(
 		}
 
 		gpuID := m.driver.memAllocator.GetDeviceIDByPAddr(pAddr)
-		req := protocol.NewMemCopyD2HReq(
-			m.driver.gpuPort, m.driver.GPUs[gpuID-1],
-			pAddr, cmd.RawData[offset:offset+sizeToCopy])
-		cmd.Reqs = append(cmd.Reqs, req)
-		m.awaitingReqs = append(m.awaitingReqs, req)
-		// m.driver.requestsToSend = append(m.driver.requestsToSend, req)
+		if gpuID == 0 {
+			m.copyFromHost(pAddr, cmd.RawData[offset:offset+sizeToCopy])
+		} else {
+			m.driver.evictor.touch(page.PID, page.VAddr, uint64(gpuID))
+
+			req := protocol.NewMemCopyD2HReq(
+				m.driver.gpuPort, m.driver.GPUs[gpuID-1],
+				pAddr, cmd.RawData[offset:offset+sizeToCopy])
+			cmd.Reqs = append(cmd.Reqs, req)
+			m.awaitingReqs = append(m.awaitingReqs, req)
+			// m.driver.requestsToSend = append(m.driver.requestsToSend, req)
+
+			m.driver.logTaskToGPUInitiate(cmd, req)
+		}
 
 		sizeLeft -= sizeToCopy
 		addr += sizeToCopy
 		offset += sizeToCopy
+	}
 
-		m.driver.logTaskToGPUInitiate(cmd, req)
+	queue.IsRunning = true
+
+	if len(cmd.Reqs) == 0 {
+		m.hostCopies = append(m.hostCopies, hostCopy{cmd: cmd, queue: queue})
+		return true
+	}
+
+	if m.needFlushing(queue.Context, cmd.Src, uint64(binary.Size(cmd.Dst))) {
+		m.sendFlushRequest(cmd)
+		queue.Context.removeFreedBuffers()
 	}
 
 	m.cyclesLeft = m.cyclesPerD2H
 
+	return true
+}
+
+// With oversubscription, some pages of unified memory are on the host. The
+// GPUs do not cache them, so the driver copies them directly.
+func (m *defaultMemoryCopyMiddleware) copyToHost(pAddr uint64, data []byte) {
+	err := m.driver.globalStorage.Write(pAddr, data)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (m *defaultMemoryCopyMiddleware) copyFromHost(pAddr uint64, buf []byte) {
+	data, err := m.driver.globalStorage.Read(pAddr, uint64(len(buf)))
+	if err != nil {
+		panic(err)
+	}
+
+	copy(buf, data)
+}
+
+func (m *defaultMemoryCopyMiddleware) completeHostCopies() bool {
+	if len(m.hostCopies) == 0 {
+		return false
+	}
+
+	for _, c := range m.hostCopies {
+		if cmd, ok := c.cmd.(*MemCopyD2HCommand); ok {
+			buf := bytes.NewReader(cmd.RawData)
+			err := binary.Read(buf, binary.LittleEndian, cmd.Dst)
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		c.queue.IsRunning = false
+		c.queue.Dequeue()
+
+		m.driver.logCmdComplete(c.cmd)
+	}
+
+	m.hostCopies = nil
+
+	return true
+}
+
+// The copies go through the L2 caches of the GPUs, so there is nothing to
+// flush before. After, the destination may be dirty in the L2 cache of its GPU.
+func (m *defaultMemoryCopyMiddleware) processMemCopyPeerCommand(
+	cmd *MemCopyPeerCommand,
+	queue *CommandQueue,
+) bool {
+	for _, c := range splitPeerCopy(m.driver, queue.Context.pid, cmd) {
+		gpuID := m.driver.memAllocator.GetDeviceIDByPAddr(c.src)
+		if gpuID == 0 {
+			log.Panicf("the source of a peer copy must be on a GPU, "+
+				"but 0x%x is on the host", c.src)
+		}
+
+		req := protocol.NewMemCopyPeerReq(
+			m.driver.gpuPort, m.driver.GPUs[gpuID-1],
+			c.src, c.dst, c.size)
+		cmd.Reqs = append(cmd.Reqs, req)
+		m.driver.requestsToSend = append(m.driver.requestsToSend, req)
+
+		m.driver.logTaskToGPUInitiate(cmd, req)
+	}
+
+	queue.Context.l2Dirty = true
+	queue.Context.markAllBuffersDirty()
+
 	queue.IsRunning = true
+
 	return true
 }
 
+// A peerCopyChunk is a part of a peer copy that does not cross a page, neither
+// in the source nor in the destination.
+type peerCopyChunk struct {
+	src, dst, size uint64
+}
+
+func splitPeerCopy(
+	d *Driver,
+	pid vm.PID,
+	cmd *MemCopyPeerCommand,
+) []peerCopyChunk {
+	var chunks []peerCopyChunk
+
+	offset := uint64(0)
+	for offset < cmd.ByteSize {
+		src, srcLeftInPage := translate(d, pid, uint64(cmd.Src)+offset)
+		dst, dstLeftInPage := translate(d, pid, uint64(cmd.Dst)+offset)
+		size := min(cmd.ByteSize-offset, srcLeftInPage, dstLeftInPage)
+
+		chunks = append(chunks, peerCopyChunk{src: src, dst: dst, size: size})
+
+		offset += size
+	}
+
+	return chunks
+}
+
+func translate(d *Driver, pid vm.PID, addr uint64) (pAddr, leftInPage uint64) {
+	page, found := d.pageTable.Find(pid, addr)
+	if !found {
+		panic("page not found")
+	}
+
+	pAddr = page.PAddr + (addr - page.VAddr)
+	leftInPage = page.PageSize - (addr - page.VAddr)
+
+	return pAddr, leftInPage
+}
+
 func (m *defaultMemoryCopyMiddleware) needFlushing(
 	ctx *Context,
 	vAddr Ptr,
@@ -183,7 +331,7 @@ func (m *defaultMemoryCopyMiddleware) sendFlushRequest(
 }
 
 func (m *defaultMemoryCopyMiddleware) Tick() (madeProgress bool) {
-	madeProgress = false
+	madeProgress = m.completeHostCopies()
 
 	if m.cyclesLeft > 0 {
 		m.cyclesLeft--
@@ -221,6 +369,8 @@ func (m *defaultMemoryCopyMiddleware) processGeneralRsp(
 		madeProgress = m.processMemCopyH2DReturn(originalReq)
 	case *protocol.MemCopyD2HReq:
 		madeProgress = m.processMemCopyD2HReturn(originalReq)
+	case *protocol.MemCopyPeerReq:
+		madeProgress = m.processMemCopyPeerReturn(originalReq)
 	}
 
 	return madeProgress
@@ -282,6 +432,28 @@ func (m *defaultMemoryCopyMiddleware) processMemCopyD2HReturn(
 	return true
 }
 
+func (m *defaultMemoryCopyMiddleware) processMemCopyPeerReturn(
+	req *protocol.MemCopyPeerReq,
+) bool {
+	m.driver.gpuPort.RetrieveIncoming()
+
+	m.driver.logTaskToGPUClear(req)
+
+	cmd, cmdQueue := m.driver.findCommandByReq(req)
+
+	copyCmd := cmd.(*MemCopyPeerCommand)
+	copyCmd.RemoveReq(req)
+
+	if len(copyCmd.Reqs) == 0 {
+		cmdQueue.IsRunning = false
+		cmdQueue.Dequeue()
+
+		m.driver.logCmdComplete(copyCmd)
+	}
+
+	return true
+}
+
 func (m *defaultMemoryCopyMiddleware) processFlushReturn(
 	req *protocol.FlushReq,
 ) bool {
@@ -20,6 +20,8 @@ func (m *globalStorageMemoryCopyMiddleware) ProcessCommand(
 		return m.processMemCopyH2DCommand(cmd, queue)
 	case *MemCopyD2HCommand:
 		return m.processMemCopyD2HCommand(cmd, queue)
+	case *MemCopyPeerCommand:
+		return m.processMemCopyPeerCommand(cmd, queue)
 	}
 
 	return false
@@ -106,6 +108,27 @@ func (m *globalStorageMemoryCopyMiddleware) processMemCopyD2HCommand(
 	return true
 }
 
+func (m *globalStorageMemoryCopyMiddleware) processMemCopyPeerCommand(
+	cmd *MemCopyPeerCommand,
+	queue *CommandQueue,
+) bool {
+	for _, c := range splitPeerCopy(m.driver, queue.Context.pid, cmd) {
+		data, err := m.driver.globalStorage.Read(c.src, c.size)
+		if err != nil {
+			panic(err)
+		}
+
+		err = m.driver.globalStorage.Write(c.dst, data)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	queue.IsRunning = false
+	queue.Dequeue()
+	return true
+}
+
 func (m *globalStorageMemoryCopyMiddleware) Tick() (madeProgress bool) {
 	return false
 }
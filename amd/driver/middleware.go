@@ -1,5 +1,7 @@
 package driver
 
+import "sort"
+
 // A Middleware is a pluggable element of the driver that can take care of the
 // handling of certain types of commands and parts of the driver-GPU
 // communication.
@@ -10,3 +12,45 @@ type Middleware interface {
 	) (processed bool)
 	Tick() (madeProgress bool)
 }
+
+// The stages of the pre-dispatch middlewares. The pre-dispatch middlewares
+// see every command before the driver dispatches it by type, and tick before
+// the regular middlewares. They run in the order of their stages, whatever
+// the order in which they are enabled.
+type preDispatchStage int
+
+const (
+	// The evictor holds the memory copies during a migration, so no other
+	// middleware sees them twice. It also takes the responses to its own
+	// copies before the memory copy middleware.
+	preDispatchEviction preDispatchStage = iota
+	// The checkpointer counts every kernel launch, including the ones that
+	// the kernel sampler fast-forwards.
+	preDispatchCheckpoint
+	preDispatchKernelSampling
+	preDispatchAccessCounters
+)
+
+type preDispatchMiddleware struct {
+	Middleware
+	stage preDispatchStage
+}
+
+// A kernelCompletionListener is a pre-dispatch middleware that is told when
+// a kernel launch completes on the GPUs.
+type kernelCompletionListener interface {
+	kernelCompleted(cmd Command)
+}
+
+func (d *Driver) addPreDispatchMiddleware(
+	stage preDispatchStage,
+	m Middleware,
+) {
+	i := sort.Search(len(d.preDispatch), func(i int) bool {
+		return d.preDispatch[i].stage > stage
+	})
+
+	d.preDispatch = append(d.preDispatch, preDispatchMiddleware{})
+	copy(d.preDispatch[i+1:], d.preDispatch[i:])
+	d.preDispatch[i] = preDispatchMiddleware{Middleware: m, stage: stage}
+}
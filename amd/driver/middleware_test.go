@@ -0,0 +1,66 @@
+package driver
+
+import (
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type recordingMiddleware struct {
+	name  string
+	calls *[]string
+	hold  bool
+}
+
+func (m *recordingMiddleware) ProcessCommand(
+	cmd Command,
+	queue *CommandQueue,
+) bool {
+	*m.calls = append(*m.calls, m.name)
+	return m.hold
+}
+
+func (m *recordingMiddleware) Tick() bool {
+	*m.calls = append(*m.calls, m.name)
+	return false
+}
+
+var _ = ginkgo.Describe("Pre-dispatch middlewares", func() {
+	var (
+		driver *Driver
+		calls  []string
+	)
+
+	ginkgo.BeforeEach(func() {
+		driver = &Driver{}
+		calls = nil
+	})
+
+	ginkgo.It("should run in the order of their stages", func() {
+		driver.addPreDispatchMiddleware(preDispatchAccessCounters,
+			&recordingMiddleware{name: "counters", calls: &calls})
+		driver.addPreDispatchMiddleware(preDispatchCheckpoint,
+			&recordingMiddleware{name: "checkpoint", calls: &calls})
+		driver.addPreDispatchMiddleware(preDispatchEviction,
+			&recordingMiddleware{name: "eviction", calls: &calls})
+
+		for _, mw := range driver.preDispatch {
+			mw.Tick()
+		}
+
+		Expect(calls).To(Equal([]string{"eviction", "checkpoint", "counters"}))
+	})
+
+	ginkgo.It("should stop the dispatch of a held command", func() {
+		driver.addPreDispatchMiddleware(preDispatchEviction,
+			&recordingMiddleware{name: "eviction", calls: &calls, hold: true})
+		driver.addPreDispatchMiddleware(preDispatchCheckpoint,
+			&recordingMiddleware{name: "checkpoint", calls: &calls})
+
+		queue := &CommandQueue{}
+		queue.Enqueue(&NoopCommand{})
+
+		Expect(driver.processOneCommand(queue)).To(BeTrue())
+		Expect(calls).To(Equal([]string{"eviction"}))
+		Expect(queue.NumCommand()).To(Equal(1))
+	})
+})
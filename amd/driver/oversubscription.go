@@ -0,0 +1,615 @@
+package driver
+
+import (
+	"container/list"
+	"log"
+
+	"github.com/sarchlab/akita/v4/mem/vm"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/protocol"
+)
+
+// EvictionPolicy selects the pages that the driver evicts from a GPU to make
+// room for others.
+type EvictionPolicy int
+
+const (
+	// EvictionPolicyLRU evicts the page that was used the longest ago.
+	EvictionPolicyLRU EvictionPolicy = iota
+
+	// EvictionPolicyClock sweeps the pages of a GPU in a circle and evicts the
+	// first one that was not used since the last sweep.
+	EvictionPolicyClock
+)
+
+// OversubscriptionConfig configures unified memory larger than the memory of
+// the GPUs. The pages that do not fit on a GPU stay in the memory of the host.
+// When a page moves to a full GPU, the driver evicts other pages of the GPU
+// back to the host, in the same steps as the migrations between GPUs.
+//
+// The driver does not see the accesses that hit in the memory of a GPU, so,
+// like the UVM driver of NVIDIA, it counts as uses of a page its allocation,
+// its migration to the GPU and the memory copies.
+type OversubscriptionConfig struct {
+	Policy EvictionPolicy
+
+	// ReservedPages is the number of pages of each GPU that unified memory
+	// leaves free, for the memory that the driver allocates when it launches
+	// kernels and for the pages that migrate while the frames that others
+	// left are not free yet.
+	ReservedPages int
+}
+
+// residentPages keeps the order in which the pages on a GPU are evicted.
+type residentPages interface {
+	touch(key pageKey)
+	remove(key pageKey)
+
+	// victim removes and returns the next page to evict, among the pages
+	// that canEvict accepts.
+	victim(canEvict func(pageKey) bool) (pageKey, bool)
+}
+
+type lruPages struct {
+	pages    *list.List
+	elements map[pageKey]*list.Element
+}
+
+func newLRUPages() *lruPages {
+	return &lruPages{
+		pages:    list.New(),
+		elements: make(map[pageKey]*list.Element),
+	}
+}
+
+func (p *lruPages) touch(key pageKey) {
+	if e, found := p.elements[key]; found {
+		p.pages.MoveToBack(e)
+		return
+	}
+
+	p.elements[key] = p.pages.PushBack(key)
+}
+
+func (p *lruPages) remove(key pageKey) {
+	if e, found := p.elements[key]; found {
+		p.pages.Remove(e)
+		delete(p.elements, key)
+	}
+}
+
+func (p *lruPages) victim(canEvict func(pageKey) bool) (pageKey, bool) {
+	for e := p.pages.Front(); e != nil; e = e.Next() {
+		key := e.Value.(pageKey)
+		if canEvict(key) {
+			p.remove(key)
+			return key, true
+		}
+	}
+
+	return pageKey{}, false
+}
+
+type clockPage struct {
+	key        pageKey
+	referenced bool
+}
+
+type clockPages struct {
+	pages    *list.List
+	elements map[pageKey]*list.Element
+	hand     *list.Element
+}
+
+func newClockPages() *clockPages {
+	return &clockPages{
+		pages:    list.New(),
+		elements: make(map[pageKey]*list.Element),
+	}
+}
+
+// touch marks a page as referenced. A new page goes right behind the hand, so
+// that the hand reaches it last.
+func (p *clockPages) touch(key pageKey) {
+	if e, found := p.elements[key]; found {
+		e.Value.(*clockPage).referenced = true
+		return
+	}
+
+	page := &clockPage{key: key, referenced: true}
+	if p.hand == nil {
+		p.elements[key] = p.pages.PushBack(page)
+		return
+	}
+
+	p.elements[key] = p.pages.InsertBefore(page, p.hand)
+}
+
+func (p *clockPages) remove(key pageKey) {
+	e, found := p.elements[key]
+	if !found {
+		return
+	}
+
+	if p.hand == e {
+		p.hand = p.next(e)
+		if p.hand == e {
+			p.hand = nil
+		}
+	}
+
+	p.pages.Remove(e)
+	delete(p.elements, key)
+}
+
+func (p *clockPages) next(e *list.Element) *list.Element {
+	if e.Next() != nil {
+		return e.Next()
+	}
+
+	return p.pages.Front()
+}
+
+// victim advances the hand, clearing the referenced bits, until it finds a
+// page that is not referenced. Two rounds clear all the bits.
+func (p *clockPages) victim(canEvict func(pageKey) bool) (pageKey, bool) {
+	if p.hand == nil {
+		p.hand = p.pages.Front()
+	}
+
+	for i := 0; i < 2*p.pages.Len(); i++ {
+		page := p.hand.Value.(*clockPage)
+
+		if !canEvict(page.key) {
+			p.hand = p.next(p.hand)
+			continue
+		}
+
+		if page.referenced {
+			page.referenced = false
+			p.hand = p.next(p.hand)
+			continue
+		}
+
+		p.remove(page.key)
+
+		return page.key, true
+	}
+
+	return pageKey{}, false
+}
+
+type evictionVictim struct {
+	key   pageKey
+	gpuID uint64
+}
+
+type eviction struct {
+	req       *protocol.MemCopyD2HReq
+	gpuPAddr  uint64
+	hostPAddr uint64
+}
+
+// evictor keeps track of the pages of unified memory on each GPU and evicts
+// them to the memory of the host when a migration needs their frames.
+type evictor struct {
+	driver *Driver
+	config OversubscriptionConfig
+
+	resident map[uint64]residentPages
+	location map[pageKey]uint64
+
+	// victims are the pages to evict before the current migration, and
+	// evictions the copies of them to the host.
+	victims       []evictionVictim
+	evictions     []*eviction
+	evictionStart sim.VTimeInSec
+
+	// pageIns are the copies of the pages from the host to the GPUs.
+	pageIns []*protocol.MemCopyH2DReq
+
+	// leftFrames are the frames that the pages left. The RDMA engines and the
+	// caches may still write them with the requests that they held during the
+	// last shootdown, so they are freed when the GPUs drain again.
+	leftFrames []uint64
+}
+
+// EnableOversubscription lets unified memory exceed the memory of the GPUs.
+// See OversubscriptionConfig. The driver copies the pages that are on the host
+// through the global storage, so the platform must provide one.
+func (d *Driver) EnableOversubscription(config OversubscriptionConfig) {
+	if d.globalStorage == nil {
+		log.Panic("oversubscription requires a global storage")
+	}
+
+	if d.checkpointer != nil {
+		log.Panic("checkpoints do not support oversubscription")
+	}
+
+	d.evictor = &evictor{
+		driver:   d,
+		config:   config,
+		resident: make(map[uint64]residentPages),
+		location: make(map[pageKey]uint64),
+	}
+	d.addPreDispatchMiddleware(preDispatchEviction, d.evictor)
+}
+
+func (e *evictor) residentOn(gpuID uint64) residentPages {
+	pages, found := e.resident[gpuID]
+	if found {
+		return pages
+	}
+
+	switch e.config.Policy {
+	case EvictionPolicyLRU:
+		pages = newLRUPages()
+	case EvictionPolicyClock:
+		pages = newClockPages()
+	default:
+		log.Panicf("unknown eviction policy %d", e.config.Policy)
+	}
+
+	e.resident[gpuID] = pages
+
+	return pages
+}
+
+// delaysMigration returns true if a migration must wait for the memory copies
+// that are running.
+func (e *evictor) delaysMigration() bool {
+	if e == nil {
+		return false
+	}
+
+	d := e.driver
+
+	d.contextMutex.Lock()
+	defer d.contextMutex.Unlock()
+
+	for _, ctx := range d.contexts {
+		ctx.queueMutex.Lock()
+		for _, q := range ctx.queues {
+			if q.IsRunning && isMemoryCopy(q.Peek()) {
+				ctx.queueMutex.Unlock()
+				return true
+			}
+		}
+		ctx.queueMutex.Unlock()
+	}
+
+	return false
+}
+
+func isMemoryCopy(cmd Command) bool {
+	switch cmd.(type) {
+	case *MemCopyH2DCommand, *MemCopyD2HCommand, *MemCopyPeerCommand:
+		return true
+	}
+
+	return false
+}
+
+// placeOnDevices returns the devices of the pages to allocate. The pages that
+// do not fit on their GPU go to the host, which is device 0.
+func (e *evictor) placeOnDevices(deviceIDs []int) []int {
+	if e == nil {
+		return deviceIDs
+	}
+
+	d := e.driver
+	freePages := make(map[int]int)
+	placed := make([]int, len(deviceIDs))
+
+	for i, deviceID := range deviceIDs {
+		if _, found := freePages[deviceID]; !found {
+			freePages[deviceID] = d.memAllocator.NumFreePages(deviceID) -
+				e.config.ReservedPages
+		}
+
+		if freePages[deviceID] > 0 {
+			freePages[deviceID]--
+			placed[i] = deviceID
+
+			continue
+		}
+
+		d.migrationStats.PagesAllocatedOnHost++
+	}
+
+	return placed
+}
+
+// trackAllocation records the pages just allocated on the devices.
+func (e *evictor) trackAllocation(pid vm.PID, ptr Ptr, deviceIDs []int) {
+	if e == nil {
+		return
+	}
+
+	pageSize := uint64(1) << e.driver.Log2PageSize
+	for i, deviceID := range deviceIDs {
+		if deviceID != 0 {
+			e.touch(pid, uint64(ptr)+uint64(i)*pageSize, uint64(deviceID))
+		}
+	}
+}
+
+// touch records a use of a page on a GPU.
+func (e *evictor) touch(pid vm.PID, vAddr uint64, gpuID uint64) {
+	if e == nil {
+		return
+	}
+
+	key := pageKey{pid, vAddr}
+	if oldGPUID, found := e.location[key]; found && oldGPUID != gpuID {
+		e.resident[oldGPUID].remove(key)
+	}
+
+	e.location[key] = gpuID
+	e.residentOn(gpuID).touch(key)
+}
+
+func (e *evictor) forget(key pageKey) {
+	gpuID, found := e.location[key]
+	if !found {
+		return
+	}
+
+	e.resident[gpuID].remove(key)
+	delete(e.location, key)
+}
+
+// forgetFreedPages stops tracking the pages of a buffer that are no longer
+// in the page table.
+func (e *evictor) forgetFreedPages(pid vm.PID, ptr Ptr, byteSize uint64) {
+	if e == nil {
+		return
+	}
+
+	pageSize := uint64(1) << e.driver.Log2PageSize
+	for vAddr := uint64(ptr); vAddr < uint64(ptr)+byteSize; vAddr += pageSize {
+		if _, found := e.driver.pageTable.Find(pid, vAddr); !found {
+			e.forget(pageKey{pid, vAddr})
+		}
+	}
+}
+
+// planEvictions selects the pages to evict so that the GPUs keep frames for
+// the pages of the next migrations, and returns their addresses, which the
+// GPUs must shoot down too. The GPUs are drained, so the frames that the pages
+// left in the previous migrations are free now.
+func (e *evictor) planEvictions() []uint64 {
+	d := e.driver
+	req := d.currentPageMigrationReq
+
+	e.freeLeftFrames()
+
+	migrating := make(map[pageKey]bool)
+	framesNeeded := make(map[uint64]int)
+	for gpuID := uint64(1); gpuID <= uint64(len(d.GPUs)); gpuID++ {
+		for _, vAddr := range req.MigrationInfo.GPUReqToVAddrMap[gpuID] {
+			page, found := d.pageTable.Find(req.PID, vAddr)
+			if !found {
+				panic("page not found")
+			}
+
+			migrating[pageKey{page.PID, page.VAddr}] = true
+
+			dst := d.migrationDestination(req.PID, page.VAddr, gpuID-1) + 1
+			if uint64(d.memAllocator.GetDeviceIDByPAddr(page.PAddr)) != dst {
+				framesNeeded[dst]++
+			}
+		}
+	}
+
+	canEvict := func(key pageKey) bool {
+		if key.pid != req.PID || migrating[key] {
+			return false
+		}
+
+		page, found := d.pageTable.Find(key.pid, key.vAddr)
+
+		return found && !page.IsMigrating
+	}
+
+	vAddrs := make([]uint64, 0)
+	for gpuID := uint64(1); gpuID <= uint64(len(d.GPUs)); gpuID++ {
+		if framesNeeded[gpuID] == 0 {
+			continue
+		}
+
+		freePages := d.memAllocator.NumFreePages(int(gpuID))
+		if freePages < framesNeeded[gpuID] {
+			log.Panicf("GPU %d is out of memory, reserve more pages", gpuID)
+		}
+
+		for freePages < framesNeeded[gpuID]+e.config.ReservedPages {
+			key, ok := e.residentOn(gpuID).victim(canEvict)
+			if !ok {
+				break
+			}
+
+			e.markEvicting(key)
+			delete(e.location, key)
+			e.victims = append(e.victims, evictionVictim{key, gpuID})
+			vAddrs = append(vAddrs, key.vAddr)
+			freePages++
+		}
+	}
+
+	return vAddrs
+}
+
+// markEvicting marks a victim as migrating before the shootdown, as the MMU
+// does with the pages that it migrates. Otherwise, a page walk that ends
+// after the TLB flush would give the GPU the frame that the page is leaving.
+// The GPUs that touch the page later wait in the MMU and fault it back in.
+func (e *evictor) markEvicting(key pageKey) {
+	d := e.driver
+
+	page, found := d.pageTable.Find(key.pid, key.vAddr)
+	if !found {
+		panic("page not found")
+	}
+
+	page.IsMigrating = true
+	d.pageTable.Update(page)
+}
+
+// startEvictions moves the victims to the host and copies them there. It
+// returns false if there is nothing to evict.
+func (e *evictor) startEvictions() bool {
+	if e == nil || len(e.victims) == 0 {
+		return false
+	}
+
+	d := e.driver
+	for _, v := range e.victims {
+		page, found := d.pageTable.Find(v.key.pid, v.key.vAddr)
+		if !found {
+			panic("page not found")
+		}
+
+		hostPage := d.memAllocator.AllocatePageWithGivenVAddr(
+			page.PID, 0, page.VAddr, true)
+
+		req := protocol.NewMemCopyD2HReq(d.gpuPort, d.GPUs[v.gpuID-1],
+			page.PAddr, make([]byte, page.PageSize))
+		d.requestsToSend = append(d.requestsToSend, req)
+
+		e.evictions = append(e.evictions, &eviction{
+			req:       req,
+			gpuPAddr:  page.PAddr,
+			hostPAddr: hostPage.PAddr,
+		})
+
+		d.migrationStats.Evictions++
+		d.migrationStats.EvictedBytes += page.PageSize
+	}
+
+	e.victims = nil
+	e.evictionStart = d.Engine.CurrentTime()
+
+	return true
+}
+
+// pageIn copies a page from the host to the GPU gpuID, counted from 0.
+func (e *evictor) pageIn(page vm.Page, context *Context, gpuID uint64) {
+	d := e.driver
+
+	data, err := d.globalStorage.Read(page.PAddr, page.PageSize)
+	if err != nil {
+		panic(err)
+	}
+
+	newPage, oldPAddr := d.preparePageForMigration(
+		page, page.VAddr, context, gpuID)
+
+	req := protocol.NewMemCopyH2DReq(d.gpuPort, d.GPUs[gpuID], data,
+		newPage.PAddr)
+	d.requestsToSend = append(d.requestsToSend, req)
+
+	e.pageIns = append(e.pageIns, req)
+	e.leftFrames = append(e.leftFrames, oldPAddr)
+	d.numPagesMigratingACK++
+	d.migrationStats.HostPageIns++
+}
+
+// leaveFrame records a frame that a page left.
+func (e *evictor) leaveFrame(pAddr uint64) {
+	if e == nil {
+		return
+	}
+
+	e.leftFrames = append(e.leftFrames, pAddr)
+}
+
+func (e *evictor) freeLeftFrames() {
+	for _, pAddr := range e.leftFrames {
+		e.driver.memAllocator.FreeFrame(pAddr)
+	}
+
+	e.leftFrames = nil
+}
+
+// ProcessCommand holds the memory copies while a migration runs. The
+// migrations move the pages that the memory copies access and, with
+// oversubscription, reuse their frames. It returns true if the command must
+// not be processed now.
+func (e *evictor) ProcessCommand(cmd Command, queue *CommandQueue) bool {
+	if !e.driver.isCurrentlyHandlingMigrationReq {
+		return false
+	}
+
+	return isMemoryCopy(cmd)
+}
+
+// Tick processes the responses to the copies of the evictor, before the
+// memory copy middleware takes them for its own.
+func (e *evictor) Tick() bool {
+	rsp, ok := e.driver.gpuPort.PeekIncoming().(*sim.GeneralRsp)
+	if !ok {
+		return false
+	}
+
+	switch req := rsp.OriginalReq.(type) {
+	case *protocol.MemCopyD2HReq:
+		return e.processEvictionRsp(req)
+	case *protocol.MemCopyH2DReq:
+		return e.processPageInRsp(req)
+	}
+
+	return false
+}
+
+func (e *evictor) processEvictionRsp(req *protocol.MemCopyD2HReq) bool {
+	d := e.driver
+
+	for i, ev := range e.evictions {
+		if ev.req != req {
+			continue
+		}
+
+		d.gpuPort.RetrieveIncoming()
+
+		err := d.globalStorage.Write(ev.hostPAddr, req.DstBuffer)
+		if err != nil {
+			panic(err)
+		}
+
+		e.leftFrames = append(e.leftFrames, ev.gpuPAddr)
+		e.evictions = append(e.evictions[:i], e.evictions[i+1:]...)
+
+		if len(e.evictions) == 0 {
+			d.migrationStats.EvictionStallTime +=
+				d.Engine.CurrentTime() - e.evictionStart
+			d.migratePages()
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (e *evictor) processPageInRsp(req *protocol.MemCopyH2DReq) bool {
+	d := e.driver
+
+	for i, r := range e.pageIns {
+		if r != req {
+			continue
+		}
+
+		d.gpuPort.RetrieveIncoming()
+		e.pageIns = append(e.pageIns[:i], e.pageIns[i+1:]...)
+
+		d.numPagesMigratingACK--
+		if d.numPagesMigratingACK == 0 {
+			d.completePageMigration()
+		}
+
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,235 @@
+package driver
+
+import (
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/mem/mem"
+	"github.com/sarchlab/akita/v4/mem/vm"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/protocol"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = ginkgo.Describe("Eviction Policies", func() {
+	all := func(pageKey) bool { return true }
+
+	ginkgo.It("should evict the least recently used page", func() {
+		pages := newLRUPages()
+		pages.touch(pageKey{1, 0x1000})
+		pages.touch(pageKey{1, 0x2000})
+		pages.touch(pageKey{1, 0x3000})
+		pages.touch(pageKey{1, 0x1000})
+
+		key, ok := pages.victim(all)
+
+		Expect(ok).To(BeTrue())
+		Expect(key).To(Equal(pageKey{1, 0x2000}))
+	})
+
+	ginkgo.It("should skip the pages that cannot be evicted", func() {
+		pages := newLRUPages()
+		pages.touch(pageKey{1, 0x1000})
+		pages.touch(pageKey{1, 0x2000})
+
+		key, ok := pages.victim(func(key pageKey) bool {
+			return key.vAddr != 0x1000
+		})
+
+		Expect(ok).To(BeTrue())
+		Expect(key).To(Equal(pageKey{1, 0x2000}))
+
+		_, ok = pages.victim(func(key pageKey) bool {
+			return key.vAddr != 0x1000
+		})
+
+		Expect(ok).To(BeFalse())
+	})
+
+	ginkgo.It("should give a second chance to the referenced pages", func() {
+		pages := newClockPages()
+		pages.touch(pageKey{1, 0x1000})
+		pages.touch(pageKey{1, 0x2000})
+		pages.touch(pageKey{1, 0x3000})
+
+		key, _ := pages.victim(all)
+		Expect(key).To(Equal(pageKey{1, 0x1000}))
+
+		pages.touch(pageKey{1, 0x2000})
+
+		key, _ = pages.victim(all)
+		Expect(key).To(Equal(pageKey{1, 0x3000}))
+	})
+
+	ginkgo.It("should find no victim on a GPU without pages", func() {
+		_, ok := newClockPages().victim(all)
+
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = ginkgo.Describe("Driver Oversubscription", func() {
+	var (
+		mockCtrl     *gomock.Controller
+		engine       *MockEngine
+		pageTable    *MockPageTable
+		memAllocator *MockMemoryAllocator
+		toGPUs       *MockPort
+		storage      *mem.Storage
+		driver       *Driver
+		context      *Context
+	)
+
+	ginkgo.BeforeEach(func() {
+		storage = mem.NewStorage(16 * mem.GB)
+		f := newDriverFixture(storage)
+		mockCtrl = f.mockCtrl
+		engine = f.engine
+		pageTable = f.pageTable
+		memAllocator = f.memAllocator
+		toGPUs = f.toGPUs
+		driver = f.driver
+
+		context = driver.Init()
+		context.pid = 1
+
+		driver.EnableOversubscription(OversubscriptionConfig{
+			Policy:        EvictionPolicyLRU,
+			ReservedPages: 2,
+		})
+	})
+
+	ginkgo.AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	unifiedPage := func(vAddr, pAddr, deviceID uint64) vm.Page {
+		return vm.Page{
+			PID:      1,
+			VAddr:    vAddr,
+			PAddr:    pAddr,
+			PageSize: 4096,
+			Valid:    true,
+			DeviceID: deviceID,
+			Unified:  true,
+		}
+	}
+
+	ginkgo.It("should place the pages that do not fit on the host", func() {
+		memAllocator.EXPECT().NumFreePages(1).Return(4)
+
+		deviceIDs := driver.evictor.placeOnDevices([]int{1, 1, 1})
+
+		Expect(deviceIDs).To(Equal([]int{1, 1, 0}))
+		Expect(driver.PageMigrationStats().PagesAllocatedOnHost).
+			To(Equal(uint64(1)))
+	})
+
+	ginkgo.It("should plan to evict the least recently used pages", func() {
+		driver.evictor.trackAllocation(1, 0x1000, []int{1, 1, 0})
+		driver.evictor.leaveFrame(0x1_0000_9000)
+		driver.currentPageMigrationReq = vm.NewPageMigrationReqToDriver(
+			"", toGPUs.AsRemote())
+		driver.currentPageMigrationReq.PID = 1
+		driver.currentPageMigrationReq.MigrationInfo = &vm.PageMigrationInfo{
+			GPUReqToVAddrMap: map[uint64][]uint64{1: {0x3000}},
+		}
+
+		memAllocator.EXPECT().FreeFrame(uint64(0x1_0000_9000))
+		pageTable.EXPECT().
+			Find(vm.PID(1), uint64(0x3000)).
+			Return(unifiedPage(0x3000, 0x5000, 0), true)
+		memAllocator.EXPECT().GetDeviceIDByPAddr(uint64(0x5000)).Return(0)
+		memAllocator.EXPECT().NumFreePages(1).Return(2)
+
+		victim := unifiedPage(0x1000, 0x1_0000_1000, 1)
+		pageTable.EXPECT().
+			Find(vm.PID(1), uint64(0x1000)).
+			Return(victim, true).
+			Times(2)
+		victim.IsMigrating = true
+		pageTable.EXPECT().Update(victim)
+
+		vAddrs := driver.evictor.planEvictions()
+
+		Expect(vAddrs).To(Equal([]uint64{0x1000}))
+		Expect(driver.evictor.victims).To(Equal([]evictionVictim{
+			{key: pageKey{1, 0x1000}, gpuID: 1},
+		}))
+		Expect(driver.evictor.leftFrames).To(BeEmpty())
+	})
+
+	ginkgo.It("should copy the victims to the host", func() {
+		driver.evictor.victims = []evictionVictim{
+			{key: pageKey{1, 0x1000}, gpuID: 2},
+		}
+
+		pageTable.EXPECT().
+			Find(vm.PID(1), uint64(0x1000)).
+			Return(unifiedPage(0x1000, 0x2_0000_1000, 2), true)
+		memAllocator.EXPECT().
+			AllocatePageWithGivenVAddr(vm.PID(1), 0, uint64(0x1000), true).
+			Return(unifiedPage(0x1000, 0x5000, 0))
+		engine.EXPECT().CurrentTime().Return(sim.VTimeInSec(1))
+
+		Expect(driver.evictor.startEvictions()).To(BeTrue())
+
+		Expect(driver.requestsToSend).To(HaveLen(1))
+		req := driver.requestsToSend[0].(*protocol.MemCopyD2HReq)
+		Expect(req.SrcAddress).To(Equal(uint64(0x2_0000_1000)))
+		Expect(req.Dst).To(Equal(driver.GPUs[1].AsRemote()))
+		Expect(driver.evictor.victims).To(BeEmpty())
+		Expect(driver.PageMigrationStats().Evictions).To(Equal(uint64(1)))
+		Expect(driver.PageMigrationStats().EvictedBytes).
+			To(Equal(uint64(4096)))
+	})
+
+	ginkgo.It("should not delay the migration without victims", func() {
+		Expect(driver.evictor.startEvictions()).To(BeFalse())
+	})
+
+	ginkgo.It("should write the evicted page to the host", func() {
+		req := protocol.NewMemCopyD2HReq(toGPUs, driver.GPUs[0],
+			0x1_0000_1000, []byte{1, 2, 3, 4})
+		other := protocol.NewMemCopyD2HReq(toGPUs, driver.GPUs[0],
+			0x1_0000_2000, []byte{0, 0, 0, 0})
+		driver.evictor.evictions = []*eviction{
+			{req: req, gpuPAddr: 0x1_0000_1000, hostPAddr: 0x5000},
+			{req: other, gpuPAddr: 0x1_0000_2000, hostPAddr: 0x6000},
+		}
+
+		rsp := sim.GeneralRspBuilder{}.WithOriginalReq(req).Build()
+		toGPUs.EXPECT().PeekIncoming().Return(rsp)
+		toGPUs.EXPECT().RetrieveIncoming()
+
+		madeProgress := driver.evictor.Tick()
+
+		Expect(madeProgress).To(BeTrue())
+		data, _ := storage.Read(0x5000, 4)
+		Expect(data).To(Equal([]byte{1, 2, 3, 4}))
+		Expect(driver.evictor.evictions).To(HaveLen(1))
+		Expect(driver.evictor.leftFrames).
+			To(Equal([]uint64{0x1_0000_1000}))
+	})
+
+	ginkgo.It("should copy a page on the host back to a GPU", func() {
+		storage.Write(0x5000, []byte{1, 2, 3, 4})
+
+		page := unifiedPage(0x1000, 0x5000, 0)
+		newPage := unifiedPage(0x1000, 0x2_0000_3000, 2)
+		memAllocator.EXPECT().
+			AllocatePageWithGivenVAddr(vm.PID(1), 2, uint64(0x1000), true).
+			Return(newPage)
+		newPage.IsMigrating = true
+		pageTable.EXPECT().Update(newPage)
+
+		driver.evictor.pageIn(page, context, 1)
+
+		Expect(driver.requestsToSend).To(HaveLen(1))
+		req := driver.requestsToSend[0].(*protocol.MemCopyH2DReq)
+		Expect(req.DstAddress).To(Equal(uint64(0x2_0000_3000)))
+		Expect(req.SrcBuffer[:4]).To(Equal([]byte{1, 2, 3, 4}))
+		Expect(driver.evictor.leftFrames).To(Equal([]uint64{0x5000}))
+		Expect(driver.numPagesMigratingACK).To(Equal(uint64(1)))
+		Expect(driver.PageMigrationStats().HostPageIns).To(Equal(uint64(1)))
+	})
+})
@@ -0,0 +1,446 @@
+package driver
+
+import (
+	"log"
+	"sync"
+
+	"github.com/sarchlab/akita/v4/mem/vm"
+)
+
+// A PlacementPolicy decides on which GPUs the pages of an allocation live.
+type PlacementPolicy interface {
+	// Place splits an allocation of numPages pages into ranges of
+	// consecutive pages and places each range on one of the gpuIDs.
+	Place(numPages uint64, gpuIDs []int) []PlacedRange
+}
+
+// A PlacedRange is a range of consecutive pages of an allocation.
+type PlacedRange struct {
+	FirstPage uint64
+	NumPages  uint64
+
+	// GPUID is the GPU that holds the pages. It is 0 if each page goes to
+	// the first GPU that touches it, which only unified memory supports.
+	GPUID int
+}
+
+// ChunkedPlacement splits the pages into one chunk of consecutive pages per
+// GPU. The pages that are left go to the last GPU that gets a chunk.
+type ChunkedPlacement struct{}
+
+// Place places the pages in chunks.
+func (ChunkedPlacement) Place(numPages uint64, gpuIDs []int) []PlacedRange {
+	numGPUs := uint64(len(gpuIDs))
+	numPagesPerGPU := numPages / numGPUs
+	numGPUsToUse := uint64(0)
+	if numPagesPerGPU > 0 {
+		numGPUsToUse = numPages / numPagesPerGPU
+	}
+	if numGPUsToUse > numGPUs {
+		numGPUsToUse = numGPUs
+	}
+	remainingPages := numPages % numGPUs
+
+	ranges := make([]PlacedRange, 0, numGPUsToUse+remainingPages)
+	lastAllocatedGPU := uint64(0)
+	for i := uint64(0); i < numGPUsToUse; i++ {
+		ranges = append(ranges, PlacedRange{
+			FirstPage: i * numPagesPerGPU,
+			NumPages:  numPagesPerGPU,
+			GPUID:     gpuIDs[i],
+		})
+		lastAllocatedGPU = i
+	}
+
+	for i := uint64(0); i < remainingPages; i++ {
+		ranges = append(ranges, PlacedRange{
+			FirstPage: numPagesPerGPU*numGPUsToUse + i,
+			NumPages:  1,
+			GPUID:     gpuIDs[lastAllocatedGPU],
+		})
+	}
+
+	return ranges
+}
+
+// InterleavedPlacement places the pages on the GPUs in turn, Granularity
+// pages at a time. A Granularity of 0 places one page at a time.
+type InterleavedPlacement struct {
+	Granularity uint64
+}
+
+// Place interleaves the pages.
+func (p InterleavedPlacement) Place(
+	numPages uint64,
+	gpuIDs []int,
+) []PlacedRange {
+	granularity := p.Granularity
+	if granularity == 0 {
+		granularity = 1
+	}
+
+	ranges := make([]PlacedRange, 0, (numPages-1)/granularity+1)
+	for first := uint64(0); first < numPages; first += granularity {
+		ranges = append(ranges, PlacedRange{
+			FirstPage: first,
+			NumPages:  min(granularity, numPages-first),
+			GPUID:     gpuIDs[int(first/granularity)%len(gpuIDs)],
+		})
+	}
+
+	return ranges
+}
+
+// FirstTouchPlacement moves each page to the first GPU that touches it. It
+// only applies to unified memory.
+type FirstTouchPlacement struct{}
+
+// Place leaves all the pages to be placed when they are touched.
+func (FirstTouchPlacement) Place(numPages uint64, _ []int) []PlacedRange {
+	return []PlacedRange{{NumPages: numPages}}
+}
+
+// AccessCounterPlacement places the pages with the Initial policy, or all on
+// the first GPU if Initial is nil, and lets the access counters move them to
+// the GPUs that access them the most. The pages of the allocations placed
+// with other policies stay where their policy puts them. It only applies to
+// unified memory, and the pages only move if the driver migrates the hot pages.
+// See EnableAccessCounterMigration.
+type AccessCounterPlacement struct {
+	Initial PlacementPolicy
+}
+
+// Place places the pages where they start.
+func (p AccessCounterPlacement) Place(
+	numPages uint64,
+	gpuIDs []int,
+) []PlacedRange {
+	if p.Initial == nil {
+		return []PlacedRange{{NumPages: numPages, GPUID: gpuIDs[0]}}
+	}
+
+	return p.Initial.Place(numPages, gpuIDs)
+}
+
+// MemAdvice is a hint about how the GPUs use a range of unified memory, like
+// the advice given to cudaMemAdvise.
+type MemAdvice int
+
+const (
+	// MemAdviseSetPreferredLocation makes the pages move to the given GPU the
+	// next time that they migrate, and stay there. Other GPUs access them
+	// remotely.
+	MemAdviseSetPreferredLocation MemAdvice = iota
+
+	// MemAdviseUnsetPreferredLocation removes the preferred location. The
+	// pages move to the next GPU that touches them remotely.
+	MemAdviseUnsetPreferredLocation
+)
+
+type pageKey struct {
+	pid   vm.PID
+	vAddr uint64
+}
+
+// placedPage is how the driver places a page of unified memory. A static page
+// stays on the GPU where its policy puts it, even if the access counters find
+// it hot.
+type placedPage struct {
+	preferredGPU int
+	static       bool
+}
+
+// placementTable records the pages of unified memory that the driver places
+// with a policy or an advice, with the GPU that they prefer, or 0.
+type placementTable struct {
+	mutex sync.Mutex
+	pages map[pageKey]placedPage
+}
+
+func newPlacementTable() *placementTable {
+	return &placementTable{pages: make(map[pageKey]placedPage)}
+}
+
+func (t *placementTable) place(pid vm.PID, vAddr uint64, static bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.pages[pageKey{pid, vAddr}] = placedPage{static: static}
+}
+
+// setPreferredGPU sets the preferred GPU of a page, or 0. An advice overrides
+// the policy, so the page is no longer static.
+func (t *placementTable) setPreferredGPU(pid vm.PID, vAddr uint64, gpuID int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.pages[pageKey{pid, vAddr}] = placedPage{preferredGPU: gpuID}
+}
+
+// lookup returns the preferred GPU of a page, and whether the driver places
+// the page.
+func (t *placementTable) lookup(pid vm.PID, vAddr uint64) (int, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	p, ok := t.pages[pageKey{pid, vAddr}]
+
+	return p.preferredGPU, ok
+}
+
+// isStatic returns true if the access counters must not move a page.
+func (t *placementTable) isStatic(pid vm.PID, vAddr uint64) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.pages[pageKey{pid, vAddr}].static
+}
+
+func (t *placementTable) remove(pid vm.PID, vAddr uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.pages, pageKey{pid, vAddr})
+}
+
+// SetDistributePlacement makes Distribute place the pages with the given
+// policy. A nil policy restores the ChunkedPlacement.
+func (d *Driver) SetDistributePlacement(policy PlacementPolicy) {
+	mustNotRequireUnifiedMemory(policy)
+
+	d.distributePlacement = policy
+}
+
+func mustNotRequireUnifiedMemory(policy PlacementPolicy) {
+	switch policy.(type) {
+	case FirstTouchPlacement:
+		log.Panic("first-touch placement requires unified memory")
+	case AccessCounterPlacement:
+		log.Panic("access counter placement requires unified memory")
+	}
+}
+
+// SetUnifiedMemoryPlacement makes AllocateUnifiedMemory place the pages on
+// the gpuIDs with the given policy. With a nil policy, the pages are on the
+// first GPU and move to the next GPU that touches them.
+func (d *Driver) SetUnifiedMemoryPlacement(
+	policy PlacementPolicy,
+	gpuIDs []int,
+) {
+	d.unifiedMemoryPlacement = policy
+	d.unifiedMemoryGPUIDs = gpuIDs
+}
+
+// DistributeWithPlacement is like Distribute, but places the pages with the
+// given policy.
+func (d *Driver) DistributeWithPlacement(
+	ctx *Context,
+	addr Ptr,
+	byteSize uint64,
+	gpuIDs []int,
+	policy PlacementPolicy,
+) []uint64 {
+	mustNotRequireUnifiedMemory(policy)
+
+	if len(gpuIDs) == 1 {
+		return []uint64{byteSize}
+	}
+
+	return d.distributor.DistributeWithPlacement(
+		ctx, uint64(addr), byteSize, gpuIDs, policy)
+}
+
+// AllocateUnifiedMemoryWithPlacement allocates unified memory with the pages
+// placed on the gpuIDs by the given policy. A page placed on a GPU stays
+// there and other GPUs access it remotely. A page left unplaced moves to the
+// first GPU that touches it; until then, it is kept on the first of the
+// gpuIDs, which stands for the memory of the host. With oversubscription, the
+// pages that do not fit on their GPU are left unplaced, in the actual memory
+// of the host. With an AccessCounterPlacement, the access counters may move
+// the placed pages; with other policies, they stay on their GPU.
+func (d *Driver) AllocateUnifiedMemoryWithPlacement(
+	ctx *Context,
+	byteSize uint64,
+	gpuIDs []int,
+	policy PlacementPolicy,
+) Ptr {
+	d.mustNotBeAnEmptyList(gpuIDs)
+
+	pageSize := uint64(1) << d.Log2PageSize
+	numPages := (byteSize-1)/pageSize + 1
+
+	deviceIDs := make([]int, numPages)
+	placed := make([]bool, numPages)
+	for _, r := range policy.Place(numPages, gpuIDs) {
+		for i := r.FirstPage; i < r.FirstPage+r.NumPages; i++ {
+			deviceIDs[i] = r.GPUID
+			placed[i] = r.GPUID != 0
+			if !placed[i] {
+				deviceIDs[i] = gpuIDs[0]
+			}
+		}
+	}
+
+	deviceIDs = d.evictor.placeOnDevices(deviceIDs)
+	for i, deviceID := range deviceIDs {
+		if deviceID == 0 {
+			placed[i] = false
+		}
+	}
+
+	_, byAccessCounters := policy.(AccessCounterPlacement)
+
+	ptr := Ptr(d.memAllocator.AllocateUnifiedOnDevices(
+		ctx.pid, byteSize, deviceIDs))
+	d.evictor.trackAllocation(ctx.pid, ptr, deviceIDs)
+
+	for i := uint64(0); i < numPages; i++ {
+		vAddr := uint64(ptr) + i*pageSize
+		page, found := d.pageTable.Find(ctx.pid, vAddr)
+		if !found {
+			panic("page not found")
+		}
+
+		// The MMU does not migrate the pinned pages. A page that is not
+		// placed is on no GPU, so that any GPU that touches it faults.
+		if placed[i] {
+			page.IsPinned = true
+		} else {
+			page.DeviceID = 0
+		}
+		d.pageTable.Update(page)
+
+		d.placement.place(ctx.pid, vAddr, placed[i] && !byAccessCounters)
+	}
+
+	ctx.buffers = append(ctx.buffers, &buffer{
+		vAddr:   ptr,
+		size:    byteSize,
+		freed:   false,
+		l2Dirty: false,
+	})
+
+	return ptr
+}
+
+// MemAdvise gives an advice about how the GPUs use a range of unified memory.
+// The advice applies when the pages migrate.
+func (d *Driver) MemAdvise(
+	ctx *Context,
+	ptr Ptr,
+	byteSize uint64,
+	advice MemAdvice,
+	gpuID int,
+) {
+	pageSize := uint64(1) << d.Log2PageSize
+	firstPage := uint64(ptr) &^ (pageSize - 1)
+
+	for vAddr := firstPage; vAddr < uint64(ptr)+byteSize; vAddr += pageSize {
+		page, found := d.pageTable.Find(ctx.pid, vAddr)
+		if !found {
+			panic("page not found")
+		}
+
+		if !page.Unified {
+			log.Panicf("memory advice requires unified memory, "+
+				"but page 0x%x is not unified", vAddr)
+		}
+
+		switch advice {
+		case MemAdviseSetPreferredLocation:
+			d.placement.setPreferredGPU(ctx.pid, vAddr, gpuID)
+			page.IsPinned = page.DeviceID == uint64(gpuID)
+		case MemAdviseUnsetPreferredLocation:
+			d.placement.setPreferredGPU(ctx.pid, vAddr, 0)
+			page.IsPinned = false
+		default:
+			log.Panicf("unknown memory advice %d", advice)
+		}
+
+		d.pageTable.Update(page)
+	}
+}
+
+func (d *Driver) removePlacement(ctx *Context, ptr Ptr, byteSize uint64) {
+	pageSize := uint64(1) << d.Log2PageSize
+	for vAddr := uint64(ptr); vAddr < uint64(ptr)+byteSize; vAddr += pageSize {
+		d.placement.remove(ctx.pid, vAddr)
+	}
+}
+
+// migrationDestination returns the GPU, counted from 0, that a page moves
+// to when the GPU requestingGPU, also counted from 0, touches it.
+func (d *Driver) migrationDestination(
+	pid vm.PID,
+	vAddr uint64,
+	requestingGPU uint64,
+) uint64 {
+	preferredGPU, _ := d.placement.lookup(pid, vAddr)
+	if preferredGPU > 0 {
+		return uint64(preferredGPU - 1)
+	}
+
+	return requestingGPU
+}
+
+// gpusAffectedByMigration returns the GPUs that may cache the pages that
+// migrate. The MMU reports the GPUs that hosted the pages. A page that is
+// not placed yet is cached by the GPU that keeps it. A page that the driver
+// placed or moved for a policy may be accessed remotely by any GPU.
+func (d *Driver) gpusAffectedByMigration() []uint64 {
+	req := d.currentPageMigrationReq
+	pageMask := ^(uint64(1)<<d.Log2PageSize - 1)
+
+	gpus := make([]uint64, 0, len(req.CurrAccessingGPUs)+1)
+	for _, gpuID := range req.CurrAccessingGPUs {
+		if gpuID != 0 {
+			gpus = append(gpus, gpuID)
+		}
+	}
+
+	for _, vAddrs := range req.MigrationInfo.GPUReqToVAddrMap {
+		for _, vAddr := range vAddrs {
+			if _, placed := d.placement.lookup(
+				req.PID, vAddr&pageMask); !placed {
+				continue
+			}
+
+			if req.CurrPageHostGPU != 0 {
+				return d.allGPUIDs()
+			}
+
+			page, found := d.pageTable.Find(req.PID, vAddr)
+			if !found {
+				panic("page not found")
+			}
+
+			gpus = append(gpus,
+				uint64(d.memAllocator.GetDeviceIDByPAddr(page.PAddr)))
+		}
+	}
+
+	return uniqueGPUIDs(gpus)
+}
+
+func (d *Driver) allGPUIDs() []uint64 {
+	gpus := make([]uint64, len(d.GPUs))
+	for i := range d.GPUs {
+		gpus[i] = uint64(i + 1)
+	}
+
+	return gpus
+}
+
+func uniqueGPUIDs(in []uint64) []uint64 {
+	keys := make(map[uint64]bool)
+	list := make([]uint64, 0, len(in))
+
+	for _, entry := range in {
+		if !keys[entry] {
+			keys[entry] = true
+			list = append(list, entry)
+		}
+	}
+
+	return list
+}
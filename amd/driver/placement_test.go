@@ -0,0 +1,266 @@
+package driver
+
+import (
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/mem/mem"
+	"github.com/sarchlab/akita/v4/mem/vm"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = ginkgo.Describe("Placement Policies", func() {
+	ginkgo.It("should place chunks", func() {
+		ranges := ChunkedPlacement{}.Place(5, []int{1, 2, 3})
+
+		Expect(ranges).To(Equal([]PlacedRange{
+			{FirstPage: 0, NumPages: 1, GPUID: 1},
+			{FirstPage: 1, NumPages: 1, GPUID: 2},
+			{FirstPage: 2, NumPages: 1, GPUID: 3},
+			{FirstPage: 3, NumPages: 1, GPUID: 3},
+			{FirstPage: 4, NumPages: 1, GPUID: 3},
+		}))
+	})
+
+	ginkgo.It("should place fewer pages than GPUs on the first GPU", func() {
+		ranges := ChunkedPlacement{}.Place(1, []int{2, 3})
+
+		Expect(ranges).To(Equal([]PlacedRange{
+			{FirstPage: 0, NumPages: 1, GPUID: 2},
+		}))
+	})
+
+	ginkgo.It("should interleave pages", func() {
+		ranges := InterleavedPlacement{Granularity: 2}.Place(5, []int{1, 2})
+
+		Expect(ranges).To(Equal([]PlacedRange{
+			{FirstPage: 0, NumPages: 2, GPUID: 1},
+			{FirstPage: 2, NumPages: 2, GPUID: 2},
+			{FirstPage: 4, NumPages: 1, GPUID: 1},
+		}))
+	})
+
+	ginkgo.It("should leave pages to the first touch", func() {
+		ranges := FirstTouchPlacement{}.Place(5, []int{1, 2})
+
+		Expect(ranges).To(Equal([]PlacedRange{{NumPages: 5}}))
+	})
+
+	ginkgo.It("should start access counter pages on the first GPU", func() {
+		ranges := AccessCounterPlacement{}.Place(5, []int{2, 1})
+
+		Expect(ranges).To(Equal([]PlacedRange{{NumPages: 5, GPUID: 2}}))
+	})
+
+	ginkgo.It("should start access counter pages with a policy", func() {
+		policy := AccessCounterPlacement{Initial: InterleavedPlacement{}}
+
+		ranges := policy.Place(2, []int{1, 2})
+
+		Expect(ranges).To(Equal([]PlacedRange{
+			{FirstPage: 0, NumPages: 1, GPUID: 1},
+			{FirstPage: 1, NumPages: 1, GPUID: 2},
+		}))
+	})
+})
+
+var _ = ginkgo.Describe("Driver Placement", func() {
+	var (
+		mockCtrl     *gomock.Controller
+		pageTable    *MockPageTable
+		memAllocator *MockMemoryAllocator
+		toGPUs       *MockPort
+		driver       *Driver
+		context      *Context
+	)
+
+	ginkgo.BeforeEach(func() {
+		f := newDriverFixture(nil)
+		mockCtrl = f.mockCtrl
+		pageTable = f.pageTable
+		memAllocator = f.memAllocator
+		toGPUs = f.toGPUs
+		driver = f.driver
+
+		for i := 0; i < 2; i++ {
+			pmcPort := NewMockPort(mockCtrl)
+			pmcPort.EXPECT().AsRemote().AnyTimes()
+			driver.RemotePMCPorts = append(driver.RemotePMCPorts, pmcPort)
+		}
+
+		context = driver.Init()
+		context.pid = 1
+	})
+
+	ginkgo.AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	unifiedPage := func(vAddr, pAddr, deviceID uint64) vm.Page {
+		return vm.Page{
+			PID:      1,
+			VAddr:    vAddr,
+			PAddr:    pAddr,
+			PageSize: 4096,
+			Valid:    true,
+			DeviceID: deviceID,
+			Unified:  true,
+		}
+	}
+
+	ginkgo.It("should pin the pages of placed unified memory", func() {
+		memAllocator.EXPECT().
+			AllocateUnifiedOnDevices(vm.PID(1), uint64(0x2000), []int{1, 2}).
+			Return(uint64(0x1000))
+
+		page1 := unifiedPage(0x1000, 0x1_0000_1000, 1)
+		page2 := unifiedPage(0x2000, 0x2_0000_1000, 2)
+		pageTable.EXPECT().Find(vm.PID(1), uint64(0x1000)).Return(page1, true)
+		pageTable.EXPECT().Find(vm.PID(1), uint64(0x2000)).Return(page2, true)
+		page1.IsPinned = true
+		page2.IsPinned = true
+		pageTable.EXPECT().Update(page1)
+		pageTable.EXPECT().Update(page2)
+
+		ptr := driver.AllocateUnifiedMemoryWithPlacement(
+			context, 0x2000, []int{1, 2}, InterleavedPlacement{})
+
+		Expect(ptr).To(Equal(Ptr(0x1000)))
+		_, placed := driver.placement.lookup(1, 0x2000)
+		Expect(placed).To(BeTrue())
+		Expect(driver.placement.isStatic(1, 0x2000)).To(BeTrue())
+	})
+
+	ginkgo.It("should let the access counters move their pages", func() {
+		memAllocator.EXPECT().
+			AllocateUnifiedOnDevices(vm.PID(1), uint64(0x1000), []int{2}).
+			Return(uint64(0x1000))
+
+		page := unifiedPage(0x1000, 0x2_0000_1000, 2)
+		pageTable.EXPECT().Find(vm.PID(1), uint64(0x1000)).Return(page, true)
+		page.IsPinned = true
+		pageTable.EXPECT().Update(page)
+
+		driver.AllocateUnifiedMemoryWithPlacement(
+			context, 0x1000, []int{2, 1}, AccessCounterPlacement{})
+
+		Expect(driver.placement.isStatic(1, 0x1000)).To(BeFalse())
+	})
+
+	ginkgo.It("should not distribute with the access counters", func() {
+		Expect(func() {
+			driver.SetDistributePlacement(AccessCounterPlacement{})
+		}).To(Panic())
+	})
+
+	ginkgo.It("should leave first-touch pages on no GPU", func() {
+		memAllocator.EXPECT().
+			AllocateUnifiedOnDevices(vm.PID(1), uint64(0x1000), []int{2}).
+			Return(uint64(0x1000))
+
+		page := unifiedPage(0x1000, 0x2_0000_1000, 2)
+		pageTable.EXPECT().Find(vm.PID(1), uint64(0x1000)).Return(page, true)
+		page.DeviceID = 0
+		pageTable.EXPECT().Update(page)
+
+		driver.AllocateUnifiedMemoryWithPlacement(
+			context, 0x1000, []int{2, 1}, FirstTouchPlacement{})
+	})
+
+	ginkgo.It("should unpin the pages that are not on the preferred GPU", func() {
+		page := unifiedPage(0x1000, 0x1_0000_1000, 1)
+		page.IsPinned = true
+		pageTable.EXPECT().Find(vm.PID(1), uint64(0x1000)).Return(page, true)
+		page.IsPinned = false
+		pageTable.EXPECT().Update(page)
+
+		driver.MemAdvise(context, 0x1000, 0x1000,
+			MemAdviseSetPreferredLocation, 2)
+
+		preferredGPU, _ := driver.placement.lookup(1, 0x1000)
+		Expect(preferredGPU).To(Equal(2))
+	})
+
+	ginkgo.Context("when migrating", func() {
+		var migrationReq *vm.PageMigrationReqToDriver
+
+		ginkgo.BeforeEach(func() {
+			migrationReq = vm.NewPageMigrationReqToDriver(
+				"", toGPUs.AsRemote())
+			migrationReq.PID = 1
+			migrationReq.PageSize = 4 * mem.KB
+			migrationReq.MigrationInfo = &vm.PageMigrationInfo{
+				GPUReqToVAddrMap: map[uint64][]uint64{2: {0x1000}},
+			}
+			driver.currentPageMigrationReq = migrationReq
+		})
+
+		ginkgo.It("should shoot down the GPU that keeps an unplaced page",
+			func() {
+				driver.placement.setPreferredGPU(1, 0x1000, 0)
+				migrationReq.CurrPageHostGPU = 0
+				migrationReq.CurrAccessingGPUs = []uint64{0}
+
+				page := unifiedPage(0x1000, 0x1_0000_1000, 0)
+				pageTable.EXPECT().
+					Find(vm.PID(1), uint64(0x1000)).Return(page, true)
+				memAllocator.EXPECT().
+					GetDeviceIDByPAddr(uint64(0x1_0000_1000)).Return(1)
+
+				driver.sendShootDownReqs()
+
+				Expect(migrationReq.CurrAccessingGPUs).
+					To(Equal([]uint64{1}))
+				Expect(driver.numShootDownACK).To(Equal(uint64(1)))
+			})
+
+		ginkgo.It("should not copy a page already on its GPU", func() {
+			migrationReq.CurrPageHostGPU = 0
+			migrationReq.CurrAccessingGPUs = []uint64{2}
+			driver.numShootDownACK = 1
+
+			page := unifiedPage(0x1000, 0x2_0000_1000, 0)
+			pageTable.EXPECT().
+				Find(vm.PID(1), uint64(0x1000)).Return(page, true)
+			memAllocator.EXPECT().
+				GetDeviceIDByPAddr(uint64(0x2_0000_1000)).Return(2)
+			page.DeviceID = 2
+			page.IsMigrating = true
+			pageTable.EXPECT().Update(page)
+
+			driver.processShootdownCompleteRsp(nil)
+
+			Expect(driver.migrationReqToSendToCP).To(BeEmpty())
+			Expect(driver.numRestartACK).To(Equal(uint64(1)))
+			Expect(driver.toSendToMMU).NotTo(BeNil())
+		})
+
+		ginkgo.It("should move a page to its preferred GPU", func() {
+			driver.placement.setPreferredGPU(1, 0x1000, 1)
+			migrationReq.CurrPageHostGPU = 2
+			migrationReq.CurrAccessingGPUs = []uint64{2}
+			driver.numShootDownACK = 1
+
+			page := unifiedPage(0x1000, 0x2_0000_1000, 2)
+			pageTable.EXPECT().
+				Find(vm.PID(1), uint64(0x1000)).Return(page, true)
+			newPage := unifiedPage(0x1000, 0x1_0000_2000, 1)
+			memAllocator.EXPECT().
+				AllocatePageWithGivenVAddr(vm.PID(1), 1, uint64(0x1000), true).
+				Return(newPage)
+			newPage.IsMigrating = true
+			pageTable.EXPECT().Update(newPage)
+
+			driver.processShootdownCompleteRsp(nil)
+
+			Expect(driver.migrationReqToSendToCP).To(HaveLen(1))
+			req := driver.migrationReqToSendToCP[0]
+			Expect(req.Dst).To(Equal(driver.GPUs[0].AsRemote()))
+			Expect(req.DestinationPMCPort).
+				To(Equal(driver.RemotePMCPorts[1]))
+			Expect(req.ToReadFromPhysicalAddress).
+				To(Equal(uint64(0x2_0000_1000)))
+			Expect(req.ToWriteToPhysicalAddress).
+				To(Equal(uint64(0x1_0000_2000)))
+		})
+	})
+})
@@ -136,6 +136,17 @@ func (cu *ComputeUnit) runEmulation(evt *emulationEvent) error {
 func (cu *ComputeUnit) runWG(
 	req *protocol.MapWGReq,
 ) error {
+	cu.executeWG(req)
+
+	now := cu.TickingComponent.TickScheduler.CurrentTime()
+	evt := NewWGCompleteEvent(cu.Freq.NextTick(now), cu, req)
+	cu.Engine.Schedule(evt)
+
+	return nil
+}
+
+// executeWG runs all the instructions of a work-group.
+func (cu *ComputeUnit) executeWG(req *protocol.MapWGReq) {
 	wg := req.WorkGroup
 	cu.initWfs(wg, req)
 
@@ -146,12 +157,6 @@ func (cu *ComputeUnit) runWG(
 		}
 		cu.resolveBarrier(wg)
 	}
-
-	now := cu.TickingComponent.TickScheduler.CurrentTime()
-	evt := NewWGCompleteEvent(cu.Freq.NextTick(now), cu, req)
-	cu.Engine.Schedule(evt)
-
-	return nil
 }
 
 func (cu *ComputeUnit) initWfs(
@@ -0,0 +1,55 @@
+package emu
+
+import (
+	"github.com/sarchlab/akita/v4/mem/mem"
+	"github.com/sarchlab/akita/v4/mem/vm"
+	"github.com/sarchlab/mgpusim/v4/amd/insts"
+	"github.com/sarchlab/mgpusim/v4/amd/kernels"
+	"github.com/sarchlab/mgpusim/v4/amd/protocol"
+)
+
+// A KernelRunner runs whole kernels functionally and immediately, outside of
+// the simulation. It allows a timing simulation to fast-forward kernels
+// while keeping the content of the memory correct.
+type KernelRunner struct {
+	cu          *ComputeUnit
+	gridBuilder kernels.GridBuilder
+}
+
+// NewKernelRunner creates a KernelRunner that accesses the given storage,
+// translating the addresses with the page table.
+func NewKernelRunner(
+	storage *mem.Storage,
+	pageTable vm.PageTable,
+	log2PageSize uint64,
+) *KernelRunner {
+	return &KernelRunner{
+		cu: BuildComputeUnit("KernelRunner.CU", nil,
+			insts.NewDisassembler(), pageTable, log2PageSize, storage, nil),
+		gridBuilder: kernels.NewGridBuilder(),
+	}
+}
+
+// Run executes all the work-groups of a kernel.
+func (r *KernelRunner) Run(
+	pid vm.PID,
+	co *insts.HsaCo,
+	packet *kernels.HsaKernelDispatchPacket,
+	packetAddr uint64,
+) {
+	r.gridBuilder.SetKernel(kernels.KernelLaunchInfo{
+		CodeObject: co,
+		Packet:     packet,
+		PacketAddr: packetAddr,
+	})
+
+	for {
+		wg := r.gridBuilder.NextWG()
+		if wg == nil {
+			return
+		}
+
+		r.cu.executeWG(&protocol.MapWGReq{WorkGroup: wg, PID: pid})
+		delete(r.cu.wfs, wg)
+	}
+}
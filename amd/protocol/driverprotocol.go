@@ -10,6 +10,10 @@ import (
 // FlushReq requests the GPU to flush all the cache to the main memory
 type FlushReq struct {
 	sim.MsgMeta
+
+	// InvalidateCaches also drops the content of the caches, for when the
+	// memory changes behind their back.
+	InvalidateCaches bool
 }
 
 // Meta returns the meta data associated with the message.
@@ -181,6 +185,45 @@ func NewMemCopyD2HReq(
 	return req
 }
 
+// A MemCopyPeerReq is a request that asks the DMAEngine to copy memory from
+// one address to another, both on GPUs. Addresses on other GPUs are accessed
+// through the RDMA engine.
+type MemCopyPeerReq struct {
+	sim.MsgMeta
+	SrcAddress uint64
+	DstAddress uint64
+	ByteSize   uint64
+}
+
+// Meta returns the meta data associated with the message.
+func (m *MemCopyPeerReq) Meta() *sim.MsgMeta {
+	return &m.MsgMeta
+}
+
+// Clone returns a clone of the MemCopyPeerReq with different ID.
+func (m *MemCopyPeerReq) Clone() sim.Msg {
+	cloneMsg := *m
+	cloneMsg.ID = sim.GetIDGenerator().Generate()
+
+	return &cloneMsg
+}
+
+// NewMemCopyPeerReq created a new MemCopyPeerReq
+func NewMemCopyPeerReq(
+	src, dst sim.Port,
+	srcAddress, dstAddress uint64,
+	byteSize uint64,
+) *MemCopyPeerReq {
+	req := new(MemCopyPeerReq)
+	req.ID = sim.GetIDGenerator().Generate()
+	req.Src = src.AsRemote()
+	req.Dst = dst.AsRemote()
+	req.SrcAddress = srcAddress
+	req.DstAddress = dstAddress
+	req.ByteSize = byteSize
+	return req
+}
+
 // ShootDownCommand requests the GPU to perform a TLB shootdown and invalidate
 // the corresponding PTE's
 type ShootDownCommand struct {
@@ -512,3 +555,41 @@ func NewRDMARestartRspToDriver(
 	cmd.Dst = dst.AsRemote()
 	return cmd
 }
+
+// AccessCounterNotificationToDriver tells the driver that a GPU has accessed a
+// page of remote memory as many times as the threshold of its access counters.
+type AccessCounterNotificationToDriver struct {
+	sim.MsgMeta
+
+	PAddr       uint64
+	NumAccesses uint64
+}
+
+// Meta returns the meta data associated with the message.
+func (m *AccessCounterNotificationToDriver) Meta() *sim.MsgMeta {
+	return &m.MsgMeta
+}
+
+// Clone returns a clone of the AccessCounterNotificationToDriver with
+// different ID.
+func (m *AccessCounterNotificationToDriver) Clone() sim.Msg {
+	cloneMsg := *m
+	cloneMsg.ID = sim.GetIDGenerator().Generate()
+
+	return &cloneMsg
+}
+
+// NewAccessCounterNotificationToDriver creates a new
+// AccessCounterNotificationToDriver
+func NewAccessCounterNotificationToDriver(
+	src, dst sim.Port,
+	pAddr, numAccesses uint64,
+) *AccessCounterNotificationToDriver {
+	msg := new(AccessCounterNotificationToDriver)
+	msg.ID = sim.GetIDGenerator().Generate()
+	msg.Src = src.AsRemote()
+	msg.Dst = dst.AsRemote()
+	msg.PAddr = pAddr
+	msg.NumAccesses = numAccesses
+	return msg
+}
@@ -0,0 +1,184 @@
+// Matriz de comunicación entre GPUs: quién accede a la memoria de quién, a
+// partir de los pedidos que reciben los motores RDMA (accesos remotos) y los
+// PMC (páginas migradas).
+
+package runner
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/sarchlab/akita/v4/mem/mem"
+	"github.com/sarchlab/akita/v4/tracing"
+	"github.com/sarchlab/mgpusim/v4/amd/timing/pagemigrationcontroller"
+)
+
+// gpuNamePattern toma el ID de la GPU de un componente o puerto, como
+// "GPU[2].RDMA.RDMARequestOutside".
+var gpuNamePattern = regexp.MustCompile(`^GPU\[(\d+)\]`)
+
+func gpuIDOf(name string) (int, bool) {
+	m := gpuNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+
+	return id, true
+}
+
+// commTraffic es el tráfico de una GPU que pide (fila) a la GPU dueña de la
+// memoria (columna).
+type commTraffic struct {
+	rdmaRequests   uint64
+	rdmaReadBytes  uint64
+	rdmaWriteBytes uint64
+	pmcRequests    uint64
+	pmcBytes       uint64
+}
+
+func (c commTraffic) requests() uint64 {
+	return c.rdmaRequests + c.pmcRequests
+}
+
+func (c commTraffic) bytes() uint64 {
+	return c.rdmaReadBytes + c.rdmaWriteBytes + c.pmcBytes
+}
+
+// commMatrixTracer counts the remote requests and bytes between each pair of
+// GPUs.
+type commMatrixTracer struct {
+	sync.Mutex
+
+	traffic map[[2]int]*commTraffic
+}
+
+func newCommMatrixTracer() *commMatrixTracer {
+	return &commMatrixTracer{
+		traffic: make(map[[2]int]*commTraffic),
+	}
+}
+
+func (t *commMatrixTracer) entry(requester, owner int) *commTraffic {
+	pair := [2]int{requester, owner}
+	e := t.traffic[pair]
+	if e == nil {
+		e = &commTraffic{}
+		t.traffic[pair] = e
+	}
+
+	return e
+}
+
+// StartTask counts the requests that come from another GPU.
+func (t *commMatrixTracer) StartTask(task tracing.Task) {
+	if task.Kind != "req_in" {
+		return
+	}
+
+	switch req := task.Detail.(type) {
+	case *mem.ReadReq:
+		t.count(task.Location, string(req.Src), func(e *commTraffic) {
+			e.rdmaRequests++
+			e.rdmaReadBytes += req.AccessByteSize
+		})
+	case *mem.WriteReq:
+		t.count(task.Location, string(req.Src), func(e *commTraffic) {
+			e.rdmaRequests++
+			e.rdmaWriteBytes += uint64(len(req.Data))
+		})
+	case *pagemigrationcontroller.DataPullReq:
+		t.count(task.Location, string(req.Src), func(e *commTraffic) {
+			e.pmcRequests++
+			e.pmcBytes += req.DataTransferSize
+		})
+	}
+}
+
+// El RDMA también registra los pedidos de su propia GPU hacia afuera
+// ("InsideOut"): esos no cuentan, solo los que llegan de otra GPU.
+func (t *commMatrixTracer) count(
+	location, src string,
+	add func(*commTraffic),
+) {
+	owner, ok := gpuIDOf(location)
+	if !ok {
+		return
+	}
+
+	requester, ok := gpuIDOf(src)
+	if !ok || requester == owner {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	add(t.entry(requester, owner))
+}
+
+// StepTask does nothing
+func (t *commMatrixTracer) StepTask(_ tracing.Task) {
+	// Do nothing
+}
+
+// AddMilestone does nothing
+func (t *commMatrixTracer) AddMilestone(_ tracing.Milestone) {
+	// Do nothing
+}
+
+// EndTask does nothing
+func (t *commMatrixTracer) EndTask(_ tracing.Task) {
+	// Do nothing
+}
+
+// writeMatrixCSV escribe la matriz de N×N GPUs: la fila es la GPU que pide y
+// la columna, la dueña de la memoria.
+func (t *commMatrixTracer) writeMatrixCSV(
+	path string,
+	gpuIDs []int,
+	value func(commTraffic) uint64,
+) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+
+	header := []string{"requester\\owner"}
+	for _, id := range gpuIDs {
+		header = append(header, fmt.Sprintf("GPU[%d]", id))
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, requester := range gpuIDs {
+		row := []string{fmt.Sprintf("GPU[%d]", requester)}
+		for _, owner := range gpuIDs {
+			v := uint64(0)
+			if c := t.traffic[[2]int{requester, owner}]; c != nil {
+				v = value(*c)
+			}
+			row = append(row, strconv.FormatUint(v, 10))
+		}
+
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
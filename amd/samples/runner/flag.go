@@ -29,6 +29,43 @@ var rdmaTransactionCountReportFlag = flag.Bool("report-rdma-transaction-count",
 	false, "Report the number of transactions going through the RDMA engines.")
 var dramTransactionCountReportFlag = flag.Bool("report-dram-transaction-count",
 	false, "Report the number of transactions accessing the DRAMs.")
+var opticalTrafficReportFlag = flag.Bool("report-optical-traffic", false,
+	"Report the bytes and messages carried by each optical fiber and "+
+		"between each pair of optical endpoints.")
+var networkReportFlag = flag.Bool("report-network", false,
+	"Report the traffic, utilization, latency and queueing delay of every "+
+		"PCIe and optical endpoint, switch and link.")
+var commMatrixReportFlag = flag.Bool("report-comm-matrix", false,
+	"Report the remote requests and bytes between each pair of GPUs, "+
+		"from the RDMA and page migration traffic, also as CSV matrices.")
+var checkpointAfterFlag = flag.Int("checkpoint-after", 0,
+	"Write a checkpoint at the first command after the given number of "+
+		"kernels, once the GPUs are idle and their caches are flushed. Only "+
+		"the memory and the commands are saved: the caches, TLBs, compute "+
+		"units, wavefronts and pending events are not. A warning tells if "+
+		"the run ends before the checkpoint. Not compatible with "+
+		"-eviction-policy. 0 disables checkpoints.")
+var checkpointFileFlag = flag.String("checkpoint-file", "checkpoint.gob",
+	"The file that -checkpoint-after writes.")
+var restoreFlag = flag.String("restore", "",
+	"Resume the simulation from a checkpoint written by -checkpoint-after. "+
+		"The benchmark and the rest of the flags must be the same as in the "+
+		"run that wrote it. The commands before the checkpoint are replayed "+
+		"without simulating them, then the memory is loaded and the "+
+		"simulation continues with cold caches and TLBs. Pages that were "+
+		"migrated before the checkpoint start where they were allocated.")
+var kernelSamplingFlag = flag.Bool("kernel-sampling", false,
+	"Run only some of the kernel launches in detailed timing and "+
+		"fast-forward the rest in the functional emulator, extrapolating "+
+		"their time. Requires -timing.")
+var kernelSamplingWarmUpFlag = flag.Int("kernel-sampling-warmup", 0,
+	"The number of kernel launches from the start that always run in detail.")
+var kernelSamplingSamplesFlag = flag.Int("kernel-sampling-samples", 3,
+	"The number of launches of each kernel that run in detail to estimate "+
+		"the time of the rest.")
+var kernelSamplingPeriodFlag = flag.Int("kernel-sampling-period", 0,
+	"Also run in detail one out of every given number of launches of each "+
+		"kernel. 0 disables it.")
 var gpuFlag = flag.String("gpus", "",
 	"The GPUs to use, use a format like 1,2,3,4. By default, GPU 1 is used.")
 var unifiedGPUFlag = flag.String("unified-gpus", "",
@@ -36,6 +73,38 @@ var unifiedGPUFlag = flag.String("unified-gpus", "",
 Use a format like 1,2,3,4. Cannot coexist with -gpus.`)
 var useUnifiedMemoryFlag = flag.Bool("use-unified-memory", false,
 	"Run benchmark with Unified Memory or not")
+var placementFlag = flag.String("placement", "",
+	"How the pages of the buffers are placed on the GPUs: chunked, "+
+		"interleaved, first-touch or access-counter (the last two only with "+
+		"-use-unified-memory). The chunked and interleaved pages stay on "+
+		"their GPU. The access-counter pages start on the first GPU and move "+
+		"to the GPUs that access them the most, with "+
+		"-access-counter-threshold. By default, Distribute places them in "+
+		"chunks and unified memory starts on the first GPU.")
+var placementGranularityFlag = flag.Uint64("placement-granularity", 1,
+	"The number of consecutive pages that -placement=interleaved places "+
+		"on each GPU in turn.")
+var accessCounterThresholdFlag = flag.Uint64("access-counter-threshold", 0,
+	"Make the GPUs count the accesses to each page of remote memory and the "+
+		"driver migrate a page of unified memory to a GPU once it has "+
+		"accessed it the given number of times. 0 disables the access "+
+		"counters. Requires -timing.")
+var accessCounterMinIntervalFlag = flag.Float64("access-counter-min-interval",
+	10, "The minimum time between two migrations of a page by the access "+
+		"counters, in us.")
+var accessCounterMaxMigrationsFlag = flag.Int("access-counter-max-migrations",
+	0, "The number of migrations after which the access counters no longer "+
+		"move a page. 0 means no limit.")
+var evictionPolicyFlag = flag.String("eviction-policy", "",
+	"Let unified memory exceed the memory of the GPUs, keeping the pages "+
+		"that do not fit on the host and evicting pages from a full GPU "+
+		"with the given policy: lru or clock. Requires -timing.")
+var evictionReservedPagesFlag = flag.Int("eviction-reserved-pages", 16,
+	"The pages of each GPU that unified memory leaves free for the memory "+
+		"that the driver allocates, with -eviction-policy.")
+var pageMigrationReportFlag = flag.Bool("report-page-migration", false,
+	"Report the pages migrated on faults and by the access counters, and "+
+		"the evictions.")
 var reportAll = flag.Bool("report-all", false, "Report all metrics to .csv file.")
 var filenameFlag = flag.String("metric-file-name", "metrics",
 	"Modify the name of the output csv file.")
@@ -59,6 +128,65 @@ var analyzerNameFlag = flag.String("analyzer-name", "",
 var analyzerPeriodFlag = flag.Float64("analyzer-period", 0.0,
 	"The period to dump the analyzer results.")
 
+var platformFlag = flag.String("platform", "",
+	"A YAML or JSON file describing the GPUs, caches, memory and interconnect "+
+		"of the timing simulation (see timingconfig.PlatformConfig). Flags "+
+		"given explicitly override the file.")
+var topologyFlag = flag.String("topology", "tree",
+	"How the GPUs are connected: tree (2 GPUs per PCIe switch), star, chain, "+
+		"clique, mesh, optical (tree + optical network) or custom.")
+var customTopologyFlag = flag.String("custom-topology", "",
+	"The PCIe switches of -topology=custom, as <switch parents>/<gpu switches>. "+
+		"For example, -1,0,0/1,1,2,2 hangs switch 0 from the root complex, "+
+		"switches 1 and 2 from switch 0, and 2 GPUs from each of them.")
+
+var opticalConsoleTraceFlag = flag.Bool("optical-console-trace", false,
+	"Print every message that crosses the optical switch and fibers.")
+var opticalLinkBandwidthFlag = flag.Float64("optical-link-bandwidth", 32,
+	"The per-direction line rate of each wavelength of an optical fiber, "+
+		"in GB/s. Use 0 for infinite bandwidth.")
+var opticalWavelengthsFlag = flag.Int("optical-wavelengths", 1,
+	"The number of WDM wavelengths carried by each optical fiber. With more "+
+		"than one, the optical switch assigns wavelengths to flows.")
+var opticalFabricFlag = flag.String("optical-fabric", "",
+	"Replace the single optical switch with a network of optical switches: "+
+		"leaf-spine:<leaves>x<spines>, torus:<dims> (e.g. torus:4x4) or "+
+		"dragonfly:<groups>x<routers per group>. The routes take the shortest "+
+		"paths, so most tori and dragonflies may deadlock; such a fabric is "+
+		"refused unless -optical-fabric-allow-deadlock is set. Not "+
+		"compatible with -optical-circuit-switching.")
+var opticalFabricAllowDeadlockFlag = flag.Bool("optical-fabric-allow-deadlock",
+	false, "Build an optical fabric whose routes may deadlock anyway, with a "+
+		"warning.")
+var opticalTrafficFlag = flag.String("optical-traffic", "rdma",
+	"The traffic that goes through the optical network, as a comma-separated "+
+		"list of port roles: driver (commands and memory copies), rdma, pmc "+
+		"(page migration), translation (L2 TLB <-> MMU), inter-gpu (rdma,pmc) "+
+		"or all. Everything else goes through PCIe.")
+var opticalLaserPowerFlag = flag.Float64("optical-laser-power", 10,
+	"The electrical power of each laser (one per wavelength and direction "+
+		"of every optical fiber), in mW.")
+var opticalModulatorEnergyFlag = flag.Float64("optical-modulator-energy", 50,
+	"The energy of the optical modulators, in fJ per bit.")
+var opticalDetectorEnergyFlag = flag.Float64("optical-detector-energy", 100,
+	"The energy of the optical detectors, in fJ per bit.")
+var opticalSwitchStaticPowerFlag = flag.Float64("optical-switch-static-power", 1,
+	"The static power of each optical switch, in W.")
+var opticalReconfigEnergyFlag = flag.Float64("optical-reconfig-energy", 10,
+	"The energy of each optical switch reconfiguration, in nJ.")
+var opticalCircuitSwitchingFlag = flag.Bool("optical-circuit-switching", false,
+	"Use a reconfigurable circuit-switched optical switch driven by a "+
+		"traffic-predicting controller.")
+var opticalReconfigDelayFlag = flag.Float64("optical-reconfig-delay", 820,
+	"The time the optical switch is offline during a reconfiguration, in ns.")
+var opticalEpochFlag = flag.Float64("optical-epoch", 10,
+	"The period of the optical topology controller, in us.")
+var opticalPolicyFlag = flag.String("optical-policy", "last-epoch",
+	"The traffic prediction policy of the optical topology controller. "+
+		"Possible values are last-epoch and ewma.")
+var opticalEWMAAlphaFlag = flag.Float64("optical-ewma-alpha", 0.5,
+	"The weight of the last epoch when -optical-policy=ewma.")
+
 var visTracing = flag.Bool("trace-vis", false,
 	"Generate trace for visualization purposes.")
 var visTracerDB = flag.String("trace-vis-db", "sqlite",
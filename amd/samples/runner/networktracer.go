@@ -0,0 +1,389 @@
+// Tráfico, latencia y colas de la red de interconexión (PCIe y óptica), a
+// partir de las tareas que emiten sus componentes.
+//
+// PCIe (akita/noc): el endpoint origen abre una tarea "flit_e2e" por flit que
+// cierra el endpoint destino, y cada switch abre una tarea "flit" mientras el
+// flit está adentro. Los enlaces PCIe no emiten nada, así que contamos cada
+// flit en el enlace por el que llega a un switch o al endpoint destino.
+//
+// Óptica (ver timing/optical/trace.go): el Switch emite "req_in" por mensaje
+// reenviado y cada fibra TaskKindTransmit por mensaje transmitido.
+
+package runner
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sarchlab/akita/v4/noc/messaging"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/akita/v4/tracing"
+	"github.com/sarchlab/mgpusim/v4/amd/timing/optical"
+)
+
+// Histograma logarítmico para los percentiles, sin guardar cada muestra:
+// 32 cubetas por octava desde 1 ps, o sea un error relativo menor al 2.2%.
+const (
+	latencyBucketsPerOctave = 32
+	latencyHistogramBase    = 1e-12
+)
+
+type latencyHistogram struct {
+	count    uint64
+	sum      float64
+	min, max float64
+	buckets  map[int]uint64
+}
+
+func (h *latencyHistogram) add(v sim.VTimeInSec) {
+	x := float64(v)
+
+	if h.buckets == nil {
+		h.buckets = make(map[int]uint64)
+		h.min, h.max = x, x
+	}
+
+	h.count++
+	h.sum += x
+	h.min = math.Min(h.min, x)
+	h.max = math.Max(h.max, x)
+	h.buckets[latencyBucket(x)]++
+}
+
+func latencyBucket(v float64) int {
+	if v <= latencyHistogramBase {
+		return 0
+	}
+
+	return 1 + int(math.Floor(
+		math.Log2(v/latencyHistogramBase)*latencyBucketsPerOctave))
+}
+
+func (h *latencyHistogram) average() float64 {
+	if h.count == 0 {
+		return 0
+	}
+
+	return h.sum / float64(h.count)
+}
+
+// percentile devuelve el centro de la cubeta del percentil p (0 a 1),
+// acotado por el mínimo y el máximo observados.
+func (h *latencyHistogram) percentile(p float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+
+	indexes := make([]int, 0, len(h.buckets))
+	for i := range h.buckets {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	rank := uint64(math.Ceil(p * float64(h.count)))
+	seen := uint64(0)
+	bucket := indexes[len(indexes)-1]
+	for _, i := range indexes {
+		seen += h.buckets[i]
+		if seen >= rank {
+			bucket = i
+			break
+		}
+	}
+
+	v := 0.0
+	if bucket > 0 {
+		v = latencyHistogramBase *
+			math.Exp2((float64(bucket-1)+0.5)/latencyBucketsPerOctave)
+	}
+
+	return math.Max(h.min, math.Min(h.max, v))
+}
+
+// networkNodeStats son las estadísticas de un endpoint o un switch.
+type networkNodeStats struct {
+	msgs, bytes, flits uint64 // Enviados (endpoint) o reenviados (switch).
+
+	receivedMsgs, receivedBytes uint64 // Solo endpoints.
+
+	// Endpoint: de que el mensaje entra a la red hasta que se entrega.
+	// Switch: lo que cada flit (PCIe) o mensaje (óptica) pasa adentro.
+	latency latencyHistogram
+
+	// Endpoint: lo que espera cada flit antes de llegar al primer switch.
+	// En los switches se estima con la latencia mínima (ver
+	// networkTracer.switchQueueing).
+	queueing latencyHistogram
+}
+
+// networkLinkStats son las estadísticas de un enlace. Los enlaces PCIe son
+// de una dirección (hay uno por sentido); las fibras, de las dos.
+type networkLinkStats struct {
+	msgs, bytes, flits uint64
+
+	busyTime sim.VTimeInSec // Tiempo transmitiendo, sumando canales.
+	channels int            // Transmisiones simultáneas posibles.
+
+	latency  latencyHistogram // Solo fibras: de que sale hasta que se entrega.
+	queueing latencyHistogram // Solo fibras: lo que excede transmisión + propagación.
+}
+
+// networkTask es lo que hace falta recordar de una tarea abierta.
+type networkTask struct {
+	start    sim.VTimeInSec
+	location string
+	flit     *messaging.Flit // Solo PCIe.
+	msg      sim.Msg         // Solo óptica.
+	injected bool            // Flit PCIe que ya llegó al primer switch.
+	expected sim.VTimeInSec  // Fibras: serialización + propagación.
+}
+
+// networkTracer collects the traffic, latency and queueing delay of every
+// network endpoint, switch and link.
+type networkTracer struct {
+	sync.Mutex
+	sim.TimeTeller
+
+	pcieFlitTime sim.VTimeInSec // Un flit por ciclo en cada enlace PCIe.
+
+	nodes map[string]*networkNodeStats
+	links map[string]*networkLinkStats
+
+	// Nombre de cada switch, para separarlos de los endpoints al reportar.
+	switches map[string]bool
+
+	opticalLinks map[string]*optical.Link
+
+	inflight map[string]*networkTask
+	msgStart map[string]*networkTask // Por ID de la tarea msg_e2e.
+}
+
+func newNetworkTracer(timeTeller sim.TimeTeller, pcieFreq sim.Freq) *networkTracer {
+	return &networkTracer{
+		TimeTeller:   timeTeller,
+		pcieFlitTime: pcieFreq.Period(),
+		nodes:        make(map[string]*networkNodeStats),
+		links:        make(map[string]*networkLinkStats),
+		switches:     make(map[string]bool),
+		opticalLinks: make(map[string]*optical.Link),
+		inflight:     make(map[string]*networkTask),
+		msgStart:     make(map[string]*networkTask),
+	}
+}
+
+// traceOpticalLink connects the tracer to an optical fiber.
+func (t *networkTracer) traceOpticalLink(l *optical.Link) {
+	t.opticalLinks[l.Name()] = l
+	tracing.CollectTrace(l, t)
+}
+
+// traceOpticalSwitch connects the tracer to an optical switch.
+func (t *networkTracer) traceOpticalSwitch(sw *optical.Switch) {
+	t.switches[sw.Name()] = true
+	tracing.CollectTrace(sw, t)
+}
+
+func (t *networkTracer) node(name string) *networkNodeStats {
+	n := t.nodes[name]
+	if n == nil {
+		n = &networkNodeStats{}
+		t.nodes[name] = n
+	}
+
+	return n
+}
+
+func (t *networkTracer) link(name string, channels int) *networkLinkStats {
+	l := t.links[name]
+	if l == nil {
+		l = &networkLinkStats{channels: channels}
+		t.links[name] = l
+	}
+
+	return l
+}
+
+func pcieLinkName(flit *messaging.Flit) string {
+	return string(flit.Src) + "->" + string(flit.Dst)
+}
+
+// countPCIeLink cuenta un flit en el enlace por el que acaba de llegar.
+func (t *networkTracer) countPCIeLink(flit *messaging.Flit) {
+	l := t.link(pcieLinkName(flit), 1)
+	l.flits++
+	l.busyTime += t.pcieFlitTime
+
+	if flit.SeqID == 0 {
+		l.msgs++
+		l.bytes += uint64(flit.Msg.Meta().TrafficBytes)
+	}
+}
+
+// StartTask opens the tasks of the network components.
+func (t *networkTracer) StartTask(task tracing.Task) {
+	t.Lock()
+	defer t.Unlock()
+
+	now := t.CurrentTime()
+
+	switch task.Kind {
+	case "flit_e2e":
+		t.startPCIeFlit(task, now)
+	case "flit":
+		t.startPCIeSwitch(task, now)
+	case "req_in":
+		msg, ok := task.Detail.(sim.Msg)
+		if !ok {
+			return
+		}
+
+		t.inflight[task.ID] = &networkTask{
+			start: now, location: task.Location, msg: msg,
+		}
+	case optical.TaskKindTransmit:
+		t.startOpticalTransmit(task, now)
+	}
+}
+
+// El endpoint origen crea los flits de un mensaje.
+func (t *networkTracer) startPCIeFlit(task tracing.Task, now sim.VTimeInSec) {
+	flit, ok := task.Detail.(*messaging.Flit)
+	if !ok {
+		return
+	}
+
+	endpoint := strings.TrimSuffix(task.Location, ".FlitBuf")
+	t.inflight[task.ID] = &networkTask{
+		start: now, location: endpoint, flit: flit,
+	}
+
+	n := t.node(endpoint)
+	n.flits++
+
+	if flit.SeqID == 0 {
+		n.msgs++
+		n.bytes += uint64(flit.Msg.Meta().TrafficBytes)
+
+		t.msgStart["msg_"+flit.Msg.Meta().ID+"_e2e"] = &networkTask{
+			start: now, location: endpoint,
+		}
+	}
+}
+
+// Un flit llega a un switch PCIe.
+func (t *networkTracer) startPCIeSwitch(task tracing.Task, now sim.VTimeInSec) {
+	flit, ok := task.Detail.(*messaging.Flit)
+	if !ok {
+		return
+	}
+
+	t.switches[task.Location] = true
+	t.countPCIeLink(flit)
+	t.inflight[task.ID] = &networkTask{
+		start: now, location: task.Location, flit: flit,
+	}
+
+	// El primer switch: termina la espera en el endpoint origen.
+	e2e := t.inflight[task.ParentID]
+	if e2e != nil && !e2e.injected {
+		e2e.injected = true
+		t.node(e2e.location).queueing.add(now - e2e.start)
+	}
+}
+
+func (t *networkTracer) startOpticalTransmit(task tracing.Task, now sim.VTimeInSec) {
+	msg, ok := task.Detail.(sim.Msg)
+	if !ok {
+		return
+	}
+
+	fiber := t.opticalLinks[task.Location]
+	if fiber == nil {
+		return
+	}
+
+	serialization := fiber.SerializationDelay(msg)
+	t.inflight[task.ID] = &networkTask{
+		start:    now,
+		location: task.Location,
+		msg:      msg,
+		expected: serialization + fiber.Latency,
+	}
+
+	l := t.link(task.Location, 2*fiber.Wavelengths)
+	l.msgs++
+	l.bytes += uint64(optical.WireBytes(msg))
+	l.busyTime += serialization
+}
+
+// StepTask does nothing
+func (t *networkTracer) StepTask(_ tracing.Task) {
+	// Do nothing
+}
+
+// AddMilestone does nothing
+func (t *networkTracer) AddMilestone(_ tracing.Milestone) {
+	// Do nothing
+}
+
+// EndTask closes the tasks of the network components.
+func (t *networkTracer) EndTask(task tracing.Task) {
+	t.Lock()
+	defer t.Unlock()
+
+	now := t.CurrentTime()
+
+	if start, ok := t.msgStart[task.ID]; ok { // Mensaje PCIe entregado.
+		t.node(start.location).latency.add(now - start.start)
+		delete(t.msgStart, task.ID)
+		return
+	}
+
+	open, ok := t.inflight[task.ID]
+	if !ok {
+		return
+	}
+	delete(t.inflight, task.ID)
+
+	latency := now - open.start
+
+	switch {
+	case open.flit != nil && t.switches[open.location]: // Sale de un switch PCIe.
+		n := t.node(open.location)
+		n.latency.add(latency)
+		n.flits++
+		if open.flit.SeqID == 0 {
+			n.msgs++
+			n.bytes += uint64(open.flit.Msg.Meta().TrafficBytes)
+		}
+	case open.flit != nil: // Llega al endpoint destino.
+		// El último switch ya cambió Src y Dst al último enlace.
+		t.countPCIeLink(open.flit)
+		if open.flit.SeqID == 0 {
+			n := t.node(strings.TrimSuffix(string(open.flit.Dst), ".NetworkPort"))
+			n.receivedMsgs++
+			n.receivedBytes += uint64(open.flit.Msg.Meta().TrafficBytes)
+		}
+	case t.opticalLinks[open.location] != nil:
+		l := t.links[open.location]
+		l.latency.add(latency)
+		l.queueing.add(max(0, latency-open.expected))
+	default: // Switch óptico.
+		n := t.node(open.location)
+		n.latency.add(latency)
+		n.msgs++
+		n.bytes += uint64(optical.WireBytes(open.msg))
+	}
+}
+
+// switchQueueing estima la espera promedio en un switch como lo que excede a
+// la latencia mínima, que es la de atravesarlo sin contención.
+func switchQueueing(n *networkNodeStats) float64 {
+	if n.latency.count == 0 {
+		return 0
+	}
+
+	return n.latency.average() - n.latency.min
+}
@@ -0,0 +1,80 @@
+// Tráfico de la red óptica, a partir de las tareas que emiten
+// el Switch y las fibras (ver timing/optical/trace.go).
+
+package runner
+
+import (
+	"sync"
+
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/akita/v4/tracing"
+	"github.com/sarchlab/mgpusim/v4/amd/timing/optical"
+)
+
+type opticalTraffic struct {
+	bytes uint64
+	msgs  uint64
+}
+
+// opticalTrafficTracer cuenta bytes y mensajes por fibra y por par
+// (origen, destino).
+type opticalTrafficTracer struct {
+	sync.Mutex
+
+	perLink map[string]*opticalTraffic
+	perPair map[[2]string]*opticalTraffic
+}
+
+func newOpticalTrafficTracer() *opticalTrafficTracer {
+	return &opticalTrafficTracer{
+		perLink: make(map[string]*opticalTraffic),
+		perPair: make(map[[2]string]*opticalTraffic),
+	}
+}
+
+// StartTask counts the message of the task.
+func (t *opticalTrafficTracer) StartTask(task tracing.Task) {
+	msg, ok := task.Detail.(sim.Msg)
+	if !ok {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	var entry *opticalTraffic
+
+	switch task.Kind {
+	case optical.TaskKindTransmit: // Una fibra.
+		if entry = t.perLink[task.Location]; entry == nil {
+			entry = &opticalTraffic{}
+			t.perLink[task.Location] = entry
+		}
+	case "req_in": // El Switch reenvió el mensaje original.
+		pair := [2]string{string(msg.Meta().Src), string(msg.Meta().Dst)}
+		if entry = t.perPair[pair]; entry == nil {
+			entry = &opticalTraffic{}
+			t.perPair[pair] = entry
+		}
+	default:
+		return
+	}
+
+	entry.bytes += uint64(optical.WireBytes(msg))
+	entry.msgs++
+}
+
+// StepTask does nothing
+func (t *opticalTrafficTracer) StepTask(_ tracing.Task) {
+	// Do nothing
+}
+
+// AddMilestone does nothing
+func (t *opticalTrafficTracer) AddMilestone(_ tracing.Milestone) {
+	// Do nothing
+}
+
+// EndTask does nothing
+func (t *opticalTrafficTracer) EndTask(_ tracing.Task) {
+	// Do nothing
+}
@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"flag"
+	"log"
+
+	"github.com/sarchlab/mgpusim/v4/amd/samples/runner/timingconfig"
+)
+
+// La plataforma sale de los valores por defecto, del archivo -platform (si se
+// da) y, por último, de los flags que se pasaron explícitamente.
+func (r *Runner) platformConfig() timingconfig.PlatformConfig {
+	config := timingconfig.DefaultPlatformConfig()
+
+	if *platformFlag != "" {
+		var err error
+		config, err = timingconfig.LoadPlatformConfig(*platformFlag)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		applyPlatformFlag(&config, f.Name)
+	})
+
+	if err := config.Validate(); err != nil {
+		log.Panic(err)
+	}
+
+	return config
+}
+
+func applyPlatformFlag(c *timingconfig.PlatformConfig, name string) {
+	o := &c.Interconnect.Optical
+
+	switch name {
+	case "topology":
+		c.Interconnect.Topology = *topologyFlag
+	case "custom-topology":
+		c.Interconnect.CustomTopology = *customTopologyFlag
+	case "optical-link-bandwidth":
+		o.LinkBandwidthGBps = *opticalLinkBandwidthFlag
+	case "optical-wavelengths":
+		o.Wavelengths = *opticalWavelengthsFlag
+	case "optical-fabric":
+		o.Fabric = *opticalFabricFlag
+	case "optical-fabric-allow-deadlock":
+		o.AllowDeadlock = *opticalFabricAllowDeadlockFlag
+	case "optical-traffic":
+		o.Traffic = *opticalTrafficFlag
+	case "optical-laser-power":
+		o.LaserPowerMW = *opticalLaserPowerFlag
+	case "optical-modulator-energy":
+		o.ModulatorEnergyFJ = *opticalModulatorEnergyFlag
+	case "optical-detector-energy":
+		o.DetectorEnergyFJ = *opticalDetectorEnergyFlag
+	case "optical-switch-static-power":
+		o.SwitchStaticPowerW = *opticalSwitchStaticPowerFlag
+	case "optical-reconfig-energy":
+		o.ReconfigEnergyNJ = *opticalReconfigEnergyFlag
+	case "optical-circuit-switching":
+		o.CircuitSwitching = *opticalCircuitSwitchingFlag
+	case "optical-reconfig-delay":
+		o.ReconfigDelayNs = *opticalReconfigDelayFlag
+	case "optical-epoch":
+		o.EpochUs = *opticalEpochFlag
+	case "optical-policy":
+		o.Policy = *opticalPolicyFlag
+	case "optical-ewma-alpha":
+		o.EWMAAlpha = *opticalEWMAAlphaFlag
+	case "access-counter-threshold":
+		c.Memory.AccessCounterThreshold = *accessCounterThresholdFlag
+	}
+}
@@ -3,6 +3,9 @@
 package runner
 
 import (
+	"fmt"
+	"log"
+	"math"
 	"sort"
 	"strings"
 
@@ -10,12 +13,22 @@ import (
 	"github.com/sarchlab/akita/v4/sim"
 	"github.com/sarchlab/akita/v4/simulation"
 	"github.com/sarchlab/akita/v4/tracing"
+	"github.com/sarchlab/mgpusim/v4/amd/driver"
+	"github.com/sarchlab/mgpusim/v4/amd/samples/runner/timingconfig"
 	"github.com/sarchlab/mgpusim/v4/amd/timing/cu"
+	"github.com/sarchlab/mgpusim/v4/amd/timing/optical"
+	"github.com/sarchlab/mgpusim/v4/amd/timing/pagemigrationcontroller"
 	"github.com/sarchlab/mgpusim/v4/amd/timing/rdma"
 )
 
 const (
 	tableName = "mgpusim_metrics"
+
+	platformTableName = "mgpusim_platform"
+
+	commMatrixTableName = "mgpusim_comm_matrix"
+
+	kernelSamplingTableName = "mgpusim_kernel_sampling"
 )
 
 type metric struct {
@@ -73,6 +86,7 @@ type cuCPIStackTracer struct {
 
 // contiene varios tracers para cada tipo de métrica recolectable.
 type reporter struct {
+	engine       sim.Engine
 	dataRecorder datarecording.DataRecorder
 
 	kernelTimeTracer        *kernelTimeTracer
@@ -85,6 +99,25 @@ type reporter struct {
 	rdmaTransactionCounters []*rdmaTransactionCountTracer
 	simdBusyTimeTracers     []*simdBusyTimeTracer
 	cuCPITraces             []*cuCPIStackTracer
+	opticalSwitches         []*optical.Switch
+	opticalLinks            []*optical.Link
+	opticalControllers      []*optical.Controller
+	opticalTrafficTracer    *opticalTrafficTracer
+	networkTracer           *networkTracer
+	commMatrixTracer        *commMatrixTracer
+	gpuIDs                  []int
+
+	// Tiempo simulado hasta el checkpoint restaurado (-restore). Las
+	// métricas solo cubren lo simulado después.
+	restoredTime sim.VTimeInSec
+
+	// Driver con -kernel-sampling, del que salen las estadísticas de los
+	// kernels muestreados.
+	kernelSampling *driver.Driver
+
+	// Driver del que salen las páginas migradas, con -report-page-migration
+	// o con contadores de accesos.
+	pageMigration *driver.Driver
 
 	ReportInstCount            bool
 	ReportCacheLatency         bool
@@ -98,6 +131,7 @@ type reporter struct {
 
 func newReporter(s *simulation.Simulation) *reporter {
 	r := &reporter{
+		engine:       s.GetEngine(),
 		dataRecorder: s.GetDataRecorder(),
 	}
 
@@ -108,6 +142,24 @@ func newReporter(s *simulation.Simulation) *reporter {
 	return r
 }
 
+type platformParam struct {
+	Key   string
+	Value string
+}
+
+// Guarda la configuración efectiva de la plataforma, para poder reproducir la
+// simulación.
+func (r *reporter) recordPlatformConfig(c timingconfig.PlatformConfig) {
+	r.dataRecorder.CreateTable(platformTableName, platformParam{})
+
+	for _, p := range c.Flatten() {
+		r.dataRecorder.InsertData(platformTableName, platformParam{
+			Key:   p[0],
+			Value: p[1],
+		})
+	}
+}
+
 func (r *reporter) injectTracers(s *simulation.Simulation) {
 	// estas func iteran sobre los componentes, y si
 	// encuentra el *su nombre* le inyecta AverageTimeTracer.
@@ -120,6 +172,9 @@ func (r *reporter) injectTracers(s *simulation.Simulation) {
 	r.injectRDMAEngineTracer(s)
 	r.injectDRAMTracer(s)
 	r.injectSIMDBusyTimeTracer(s)
+	r.collectOpticalComponents(s)
+	r.injectOpticalTrafficTracer()
+	r.injectCommMatrixTracer(s)
 }
 
 func (r *reporter) injectKernelTimeTracer(s *simulation.Simulation) {
@@ -357,6 +412,72 @@ func (r *reporter) injectSIMDBusyTimeTracer(s *simulation.Simulation) {
 	}
 }
 
+// El Switch y el Controller ya guardan sus estadísticas, solo hay que ubicarlos.
+func (r *reporter) collectOpticalComponents(s *simulation.Simulation) {
+	for _, comp := range s.Components() {
+		switch c := comp.(type) {
+		case *optical.Switch:
+			r.opticalSwitches = append(r.opticalSwitches, c)
+		case *optical.Link:
+			r.opticalLinks = append(r.opticalLinks, c)
+		case *optical.Controller:
+			r.opticalControllers = append(r.opticalControllers, c)
+		}
+	}
+}
+
+// Los componentes PCIe no están en la simulación: el tracer se conecta a ellos
+// al construir la plataforma (ver Runner.buildTimingPlatform). Acá solo falta
+// la red óptica.
+func (r *reporter) injectNetworkTracer(t *networkTracer) {
+	if t == nil {
+		return
+	}
+
+	r.networkTracer = t
+	for _, sw := range r.opticalSwitches {
+		t.traceOpticalSwitch(sw)
+	}
+	for _, link := range r.opticalLinks {
+		t.traceOpticalLink(link)
+	}
+}
+
+func (r *reporter) injectCommMatrixTracer(s *simulation.Simulation) {
+	if !*reportAll && !*commMatrixReportFlag {
+		return
+	}
+
+	r.commMatrixTracer = newCommMatrixTracer()
+	for _, comp := range s.Components() {
+		switch c := comp.(type) {
+		case *rdma.Comp:
+			// Una GPU, un RDMA: de acá sale el tamaño de la matriz.
+			if id, ok := gpuIDOf(c.Name()); ok {
+				r.gpuIDs = append(r.gpuIDs, id)
+			}
+			tracing.CollectTrace(c, r.commMatrixTracer)
+		case *pagemigrationcontroller.PageMigrationController:
+			tracing.CollectTrace(c, r.commMatrixTracer)
+		}
+	}
+	sort.Ints(r.gpuIDs)
+}
+
+func (r *reporter) injectOpticalTrafficTracer() {
+	if !*reportAll && !*opticalTrafficReportFlag {
+		return
+	}
+
+	r.opticalTrafficTracer = newOpticalTrafficTracer()
+	for _, sw := range r.opticalSwitches {
+		tracing.CollectTrace(sw, r.opticalTrafficTracer)
+	}
+	for _, link := range r.opticalLinks {
+		tracing.CollectTrace(link, r.opticalTrafficTracer)
+	}
+}
+
 // Toma los datos crudos de los tracers y calculan las métricas finales.
 func (r *reporter) report() {
 	r.reportKernelTime()
@@ -368,6 +489,16 @@ func (r *reporter) report() {
 	r.reportTLBHitRate()
 	r.reportRDMATransactionCount()
 	r.reportDRAMTransactionCount()
+	r.reportOpticalSwitch()
+	r.reportOpticalWavelengths()
+	r.reportOpticalDrops()
+	r.reportOpticalEnergy()
+	r.reportOpticalTraffic()
+	r.reportOpticalController()
+	r.reportNetwork()
+	r.reportCommMatrix()
+	r.reportKernelSampling()
+	r.reportPageMigration()
 }
 
 func (r *reporter) reportKernelTime() {
@@ -382,6 +513,18 @@ func (r *reporter) reportKernelTime() {
 		},
 	)
 
+	if r.restoredTime > 0 {
+		r.dataRecorder.InsertData(
+			tableName,
+			metric{
+				Location: r.kernelTimeTracer.comp.Name(),
+				What:     "restored_time",
+				Value:    float64(r.restoredTime),
+				Unit:     "second",
+			},
+		)
+	}
+
 	for _, t := range r.perGPUKernelTimeTracers {
 		kernelTime := float64(t.tracer.BusyTime())
 		r.dataRecorder.InsertData(
@@ -699,3 +842,564 @@ func (r *reporter) reportDRAMTransactionCount() {
 		)
 	}
 }
+
+func (r *reporter) reportOpticalSwitch() {
+	for _, sw := range r.opticalSwitches {
+		c := sw.Counters
+		values := []struct {
+			what  string
+			value float64
+			unit  string
+		}{
+			{"num_reconfigurations", float64(sw.NumReconfigurations), "count"},
+			{"forwarded_msgs", float64(c.ForwardedMsgs), "count"},
+			{"unroutable_msgs", float64(c.UnroutableMsgs), "count"},
+			{"stalls_output_full", float64(c.StallsOutputFull), "count"},
+			{"stalls_reconfiguring", float64(c.StallsReconfiguring), "count"},
+			{"stalls_no_circuit", float64(c.StallsNoCircuit), "count"},
+			{"stalls_no_wavelength", float64(c.StallsNoWavelength), "count"},
+			{"wavelength_assignments", float64(c.WavelengthAssignments), "count"},
+			{"stall_time", float64(c.StallTime), "second"},
+		}
+
+		for _, v := range values {
+			r.dataRecorder.InsertData(tableName, metric{
+				Location: sw.Name(),
+				What:     v.what,
+				Value:    v.value,
+				Unit:     v.unit,
+			})
+		}
+
+		for class, c := range sw.TrafficByClass() {
+			r.dataRecorder.InsertData(tableName, metric{
+				Location: sw.Name(),
+				What:     "class[" + class + "].bytes",
+				Value:    float64(c.Bytes),
+				Unit:     "bytes",
+			})
+			r.dataRecorder.InsertData(tableName, metric{
+				Location: sw.Name(),
+				What:     "class[" + class + "].msgs",
+				Value:    float64(c.Msgs),
+				Unit:     "count",
+			})
+		}
+	}
+}
+
+// Solo con WDM: con un canal por fibra no hay contención que reportar.
+func (r *reporter) reportOpticalWavelengths() {
+	for _, link := range r.opticalLinks {
+		if link.Wavelengths <= 1 {
+			continue
+		}
+
+		r.dataRecorder.InsertData(tableName, metric{
+			Location: link.Name(),
+			What:     "wavelength_contention",
+			Value:    float64(link.WavelengthContention),
+			Unit:     "count",
+		})
+
+		for i, bytes := range link.WavelengthBytes {
+			r.dataRecorder.InsertData(tableName, metric{
+				Location: link.Name(),
+				What:     fmt.Sprintf("wavelength[%d].bytes", i),
+				Value:    float64(bytes),
+				Unit:     "bytes",
+			})
+		}
+	}
+}
+
+// Solo las fibras con un extremo sin conectar descartan mensajes.
+func (r *reporter) reportOpticalDrops() {
+	for _, link := range r.opticalLinks {
+		if link.DroppedMsgs == 0 {
+			continue
+		}
+
+		r.dataRecorder.InsertData(tableName, metric{
+			Location: link.Name(),
+			What:     "dropped_msgs",
+			Value:    float64(link.DroppedMsgs),
+			Unit:     "count",
+		})
+	}
+}
+
+// Energía acumulada de cada Switch y fibra + el total de la red óptica.
+func (r *reporter) reportOpticalEnergy() {
+	if len(r.opticalSwitches) == 0 && len(r.opticalLinks) == 0 {
+		return
+	}
+
+	now := r.engine.CurrentTime()
+	var total optical.EnergyBreakdown
+
+	for _, sw := range r.opticalSwitches {
+		e := sw.Energy(now)
+		total = total.Add(e)
+		r.dataRecorder.InsertData(tableName, metric{
+			Location: sw.Name(),
+			What:     "energy",
+			Value:    e.Total(),
+			Unit:     "J",
+		})
+	}
+
+	for _, link := range r.opticalLinks {
+		e := link.Energy(now)
+		total = total.Add(e)
+		r.dataRecorder.InsertData(tableName, metric{
+			Location: link.Name(),
+			What:     "energy",
+			Value:    e.Total(),
+			Unit:     "J",
+		})
+	}
+
+	values := []struct {
+		what  string
+		value float64
+	}{
+		{"laser_energy", total.Laser},
+		{"modulator_energy", total.Modulator},
+		{"detector_energy", total.Detector},
+		{"switch_static_energy", total.SwitchStatic},
+		{"reconfiguration_energy", total.Reconfiguration},
+		{"total_energy", total.Total()},
+	}
+	for _, v := range values {
+		r.dataRecorder.InsertData(tableName, metric{
+			Location: "OpticalNetwork",
+			What:     v.what,
+			Value:    v.value,
+			Unit:     "J",
+		})
+	}
+
+	if now > 0 {
+		r.dataRecorder.InsertData(tableName, metric{
+			Location: "OpticalNetwork",
+			What:     "average_power",
+			Value:    total.Total() / float64(now),
+			Unit:     "W",
+		})
+	}
+}
+
+func (r *reporter) reportOpticalTraffic() {
+	t := r.opticalTrafficTracer
+	if t == nil {
+		return
+	}
+
+	links := make([]string, 0, len(t.perLink))
+	for name := range t.perLink {
+		links = append(links, name)
+	}
+	sort.Strings(links)
+
+	for _, name := range links {
+		r.insertOpticalTraffic(name, "traffic", t.perLink[name])
+	}
+
+	pairs := make([][2]string, 0, len(t.perPair))
+	for pair := range t.perPair {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+
+	for _, pair := range pairs {
+		r.insertOpticalTraffic("OpticalNetwork",
+			fmt.Sprintf("traffic[%s->%s]", pair[0], pair[1]), t.perPair[pair])
+	}
+}
+
+func (r *reporter) insertOpticalTraffic(
+	location, prefix string,
+	traffic *opticalTraffic,
+) {
+	r.dataRecorder.InsertData(tableName, metric{
+		Location: location,
+		What:     prefix + ".bytes",
+		Value:    float64(traffic.bytes),
+		Unit:     "bytes",
+	})
+	r.dataRecorder.InsertData(tableName, metric{
+		Location: location,
+		What:     prefix + ".msgs",
+		Value:    float64(traffic.msgs),
+		Unit:     "count",
+	})
+}
+
+func (r *reporter) reportOpticalController() {
+	for _, ctrl := range r.opticalControllers {
+		for _, e := range ctrl.Stats {
+			prefix := fmt.Sprintf("epoch[%d].", e.Epoch)
+			reconfigured := 0.0
+			if e.Reconfigured {
+				reconfigured = 1
+			}
+
+			r.dataRecorder.InsertData(tableName, metric{
+				Location: ctrl.Name(),
+				What:     prefix + "time",
+				Value:    float64(e.Time),
+				Unit:     "second",
+			})
+			r.dataRecorder.InsertData(tableName, metric{
+				Location: ctrl.Name(),
+				What:     prefix + "traffic",
+				Value:    float64(e.TrafficBytes),
+				Unit:     "bytes",
+			})
+			r.dataRecorder.InsertData(tableName, metric{
+				Location: ctrl.Name(),
+				What:     prefix + "pending",
+				Value:    float64(e.PendingBytes),
+				Unit:     "bytes",
+			})
+			r.dataRecorder.InsertData(tableName, metric{
+				Location: ctrl.Name(),
+				What:     prefix + "num_circuits",
+				Value:    float64(e.NumCircuits),
+				Unit:     "count",
+			})
+			r.dataRecorder.InsertData(tableName, metric{
+				Location: ctrl.Name(),
+				What:     prefix + "reconfigured",
+				Value:    reconfigured,
+				Unit:     "bool",
+			})
+			r.dataRecorder.InsertData(tableName, metric{
+				Location: ctrl.Name(),
+				What:     prefix + "prediction_error",
+				Value:    e.PredictionError,
+				Unit:     "ratio",
+			})
+		}
+	}
+}
+
+// commMatrixEntry is a row of the communication matrix table: the traffic of
+// the requests that one GPU sends to the memory of another.
+type commMatrixEntry struct {
+	Requester      string
+	Owner          string
+	Requests       uint64
+	Bytes          uint64
+	RDMARequests   uint64
+	RDMAReadBytes  uint64
+	RDMAWriteBytes uint64
+	PMCRequests    uint64
+	PMCBytes       uint64
+}
+
+// La tabla tiene todos los pares, incluso los que no se comunicaron, para
+// dibujar el mapa de calor sin completar huecos.
+func (r *reporter) reportCommMatrix() {
+	t := r.commMatrixTracer
+	if t == nil {
+		return
+	}
+
+	r.dataRecorder.CreateTable(commMatrixTableName, commMatrixEntry{})
+
+	for _, requester := range r.gpuIDs {
+		for _, owner := range r.gpuIDs {
+			if requester == owner {
+				continue
+			}
+
+			c := t.traffic[[2]int{requester, owner}]
+			if c == nil {
+				c = &commTraffic{}
+			}
+
+			pair := fmt.Sprintf("GPU[%d]->GPU[%d]", requester, owner)
+			r.dataRecorder.InsertData(commMatrixTableName, commMatrixEntry{
+				Requester:      fmt.Sprintf("GPU[%d]", requester),
+				Owner:          fmt.Sprintf("GPU[%d]", owner),
+				Requests:       c.requests(),
+				Bytes:          c.bytes(),
+				RDMARequests:   c.rdmaRequests,
+				RDMAReadBytes:  c.rdmaReadBytes,
+				RDMAWriteBytes: c.rdmaWriteBytes,
+				PMCRequests:    c.pmcRequests,
+				PMCBytes:       c.pmcBytes,
+			})
+
+			if c.requests() == 0 {
+				continue
+			}
+
+			r.dataRecorder.InsertData(tableName, metric{
+				Location: "CommMatrix",
+				What:     "requests[" + pair + "]",
+				Value:    float64(c.requests()),
+				Unit:     "count",
+			})
+			r.dataRecorder.InsertData(tableName, metric{
+				Location: "CommMatrix",
+				What:     "bytes[" + pair + "]",
+				Value:    float64(c.bytes()),
+				Unit:     "bytes",
+			})
+		}
+	}
+
+	files := map[string]func(commTraffic) uint64{
+		*filenameFlag + "_comm_bytes.csv":    commTraffic.bytes,
+		*filenameFlag + "_comm_requests.csv": commTraffic.requests,
+	}
+	for path, value := range files {
+		if err := t.writeMatrixCSV(path, r.gpuIDs, value); err != nil {
+			log.Printf("cannot write %s: %v", path, err)
+		}
+	}
+}
+
+type networkMetric struct {
+	what  string
+	value float64
+	unit  string
+}
+
+func (r *reporter) reportNetwork() {
+	t := r.networkTracer
+	if t == nil {
+		return
+	}
+
+	for _, name := range sortedKeys(t.nodes) {
+		r.reportNetworkNode(name, t.nodes[name], t.switches[name])
+	}
+
+	for _, name := range sortedKeys(t.links) {
+		r.reportNetworkLink(name, t.links[name])
+	}
+}
+
+func (r *reporter) reportNetworkNode(
+	name string,
+	n *networkNodeStats,
+	isSwitch bool,
+) {
+	values := []networkMetric{
+		{"msgs", float64(n.msgs), "count"},
+		{"bytes", float64(n.bytes), "bytes"},
+	}
+
+	if n.flits > 0 {
+		values = append(values, networkMetric{"flits", float64(n.flits), "count"})
+	}
+
+	if !isSwitch {
+		values = append(values,
+			networkMetric{"received_msgs", float64(n.receivedMsgs), "count"},
+			networkMetric{"received_bytes", float64(n.receivedBytes), "bytes"})
+	}
+
+	if isSwitch && n.latency.count > 0 {
+		values = append(values,
+			networkMetric{"avg_queueing_delay", switchQueueing(n), "second"})
+	}
+
+	r.insertNetworkMetrics(name, values)
+	r.insertLatency(name, "latency", &n.latency)
+
+	if !isSwitch {
+		r.insertLatency(name, "queueing_delay", &n.queueing)
+	}
+}
+
+// La utilización es el tiempo transmitiendo sobre el tiempo simulado, por
+// canal (PCIe: una dirección; fibra: direcciones × longitudes de onda).
+func (r *reporter) reportNetworkLink(name string, l *networkLinkStats) {
+	values := []networkMetric{
+		{"msgs", float64(l.msgs), "count"},
+		{"bytes", float64(l.bytes), "bytes"},
+	}
+
+	if l.flits > 0 {
+		values = append(values, networkMetric{"flits", float64(l.flits), "count"})
+	}
+
+	now := float64(r.engine.CurrentTime())
+	if now > 0 && l.channels > 0 {
+		values = append(values, networkMetric{
+			"utilization",
+			float64(l.busyTime) / (now * float64(l.channels)),
+			"ratio",
+		})
+	}
+
+	r.insertNetworkMetrics(name, values)
+	r.insertLatency(name, "latency", &l.latency)
+	r.insertLatency(name, "queueing_delay", &l.queueing)
+}
+
+func (r *reporter) insertNetworkMetrics(location string, values []networkMetric) {
+	for _, v := range values {
+		r.dataRecorder.InsertData(tableName, metric{
+			Location: location,
+			What:     v.what,
+			Value:    v.value,
+			Unit:     v.unit,
+		})
+	}
+}
+
+// insertLatency guarda el promedio, los percentiles y el máximo de un
+// histograma, si tiene muestras.
+func (r *reporter) insertLatency(location, what string, h *latencyHistogram) {
+	if h.count == 0 {
+		return
+	}
+
+	r.insertNetworkMetrics(location, []networkMetric{
+		{"avg_" + what, h.average(), "second"},
+		{"p50_" + what, h.percentile(0.50), "second"},
+		{"p95_" + what, h.percentile(0.95), "second"},
+		{"p99_" + what, h.percentile(0.99), "second"},
+		{"max_" + what, h.max, "second"},
+	})
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// kernelSamplingEntry is a row of the kernel sampling table: the launches of
+// a kernel and the time extrapolated for the fast-forwarded ones.
+type kernelSamplingEntry struct {
+	Kernel           string
+	Detailed         int
+	FastForwarded    int
+	DetailedTime     float64
+	MeanTime         float64
+	StdDevTime       float64
+	ExtrapolatedTime float64
+	Error            float64
+}
+
+// El error es la mitad del intervalo de confianza del 95% del tiempo
+// extrapolado. Los errores de los kernels se suman como varianzas
+// independientes. Si algún kernel no tiene muestras suficientes para estimarlo,
+// no se reporta el error total.
+func (r *reporter) reportKernelSampling() {
+	if r.kernelSampling == nil {
+		return
+	}
+
+	r.dataRecorder.CreateTable(kernelSamplingTableName, kernelSamplingEntry{})
+
+	var detailed, fastForwarded int
+	var detailedTime, extrapolatedTime, variance float64
+	for _, s := range r.kernelSampling.KernelSamplingStats() {
+		r.dataRecorder.InsertData(kernelSamplingTableName, kernelSamplingEntry{
+			Kernel:           s.Kernel,
+			Detailed:         s.Detailed,
+			FastForwarded:    s.FastForwarded,
+			DetailedTime:     float64(s.DetailedTime),
+			MeanTime:         float64(s.MeanTime),
+			StdDevTime:       nanToZero(float64(s.StdDevTime)),
+			ExtrapolatedTime: float64(s.ExtrapolatedTime),
+			Error:            nanToZero(float64(s.Error)),
+		})
+
+		detailed += s.Detailed
+		fastForwarded += s.FastForwarded
+		detailedTime += float64(s.DetailedTime)
+		extrapolatedTime += float64(s.ExtrapolatedTime)
+		variance += float64(s.Error) * float64(s.Error)
+	}
+
+	values := []metric{
+		{What: "detailed_kernels", Value: float64(detailed), Unit: "count"},
+		{What: "fast_forwarded_kernels", Value: float64(fastForwarded),
+			Unit: "count"},
+		{What: "detailed_time", Value: detailedTime, Unit: "second"},
+		{What: "extrapolated_time", Value: extrapolatedTime, Unit: "second"},
+	}
+
+	if !math.IsNaN(variance) {
+		estimatedError := math.Sqrt(variance)
+		values = append(values, metric{
+			What: "estimated_error", Value: estimatedError, Unit: "second",
+		})
+
+		total := detailedTime + extrapolatedTime
+		if total > 0 {
+			values = append(values, metric{
+				What: "relative_error", Value: estimatedError / total,
+				Unit: "ratio",
+			})
+		}
+	}
+
+	for _, v := range values {
+		v.Location = "KernelSampling"
+		r.dataRecorder.InsertData(tableName, v)
+	}
+}
+
+// Las páginas se migran en el driver, tanto por fallos como por los contadores
+// de accesos.
+func (r *reporter) reportPageMigration() {
+	if r.pageMigration == nil {
+		return
+	}
+
+	s := r.pageMigration.PageMigrationStats()
+	values := []metric{
+		{What: "fault_migrations", Value: float64(s.FaultMigrations),
+			Unit: "count"},
+		{What: "counter_migrations", Value: float64(s.CounterMigrations),
+			Unit: "count"},
+		{What: "access_counter_notifications",
+			Value: float64(s.Notifications), Unit: "count"},
+		{What: "throttled_migrations", Value: float64(s.Throttled),
+			Unit: "count"},
+		{What: "migrated_bytes", Value: float64(s.BytesMigrated),
+			Unit: "byte"},
+		{What: "evictions", Value: float64(s.Evictions), Unit: "count"},
+		{What: "evicted_bytes", Value: float64(s.EvictedBytes),
+			Unit: "byte"},
+		{What: "eviction_stall_time", Value: float64(s.EvictionStallTime),
+			Unit: "second"},
+		{What: "host_page_ins", Value: float64(s.HostPageIns),
+			Unit: "count"},
+		{What: "pages_allocated_on_host",
+			Value: float64(s.PagesAllocatedOnHost), Unit: "count"},
+	}
+
+	for _, v := range values {
+		v.Location = "PageMigration"
+		r.dataRecorder.InsertData(tableName, v)
+	}
+}
+
+// SQLite no admite NaN.
+func nanToZero(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+
+	return v
+}
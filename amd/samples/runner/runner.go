@@ -3,6 +3,7 @@ package runner
 
 import (
 	"log"
+	"os"
 
 	// Enable profiling
 	_ "net/http/pprof"
@@ -16,6 +17,7 @@ import (
 	"github.com/sarchlab/mgpusim/v4/amd/samples/runner/emusystem"
 	"github.com/sarchlab/mgpusim/v4/amd/samples/runner/timingconfig"
 	"github.com/sarchlab/mgpusim/v4/amd/sampling"
+	"github.com/sarchlab/mgpusim/v4/amd/timing/optical"
 )
 
 type verificationPreEnablingBenchmark interface {
@@ -54,7 +56,11 @@ func (r *Runner) Init() *Runner {
 		r.buildEmuPlatform()
 	}
 
+	r.configurePlacement()
+	r.configureOversubscription()
 	r.createUnifiedGPUs()
+	r.configureCheckpoint()
+	r.configureKernelSampling()
 
 	return r
 }
@@ -84,17 +90,31 @@ func (r *Runner) buildEmuPlatform() { // FUNCTIONAL simulation.
 func (r *Runner) buildTimingPlatform() { // TIMING simulation.
 	sampling.InitSampledEngine()
 
+	config := r.platformConfig() // Archivo -platform + flags (ver platform.go).
+
 	b := timingconfig.MakeBuilder().
 		WithSimulation(r.simulation).
-		WithNumGPUs(r.GPUIDs[len(r.GPUIDs)-1])
+		WithNumGPUs(r.GPUIDs[len(r.GPUIDs)-1]).
+		WithPlatformConfig(config)
 
 	if *magicMemoryCopy {
 		b = b.WithMagicMemoryCopy()
 	}
 
+	var networkTracer *networkTracer
+	if *reportAll || *networkReportFlag {
+		networkTracer = newNetworkTracer(
+			r.simulation.GetEngine(), timingconfig.PCIeFreq)
+		b = b.WithPCIeTracer(networkTracer)
+	}
+
 	r.platform = b.Build()
 	r.reporter = newReporter(r.simulation)
+	r.reporter.injectNetworkTracer(networkTracer)
+	r.reporter.recordPlatformConfig(config)
 	r.configureVisTracing()
+	r.configureOpticalConsoleTrace()
+	r.configurePageMigration(config)
 }
 
 func (r *Runner) configureVisTracing() {
@@ -108,6 +128,23 @@ func (r *Runner) configureVisTracing() {
 	}
 }
 
+// Imprime en la consola el paso de cada mensaje por la red óptica.
+func (r *Runner) configureOpticalConsoleTrace() {
+	if !*opticalConsoleTraceFlag {
+		return
+	}
+
+	tracer := optical.NewConsoleTracer(r.simulation.GetEngine(), os.Stdout)
+	for _, comp := range r.simulation.Components() {
+		switch c := comp.(type) {
+		case *optical.Switch:
+			tracing.CollectTrace(c, tracer)
+		case *optical.Link:
+			tracing.CollectTrace(c, tracer)
+		}
+	}
+}
+
 func (r *Runner) createUnifiedGPUs() {
 	if *unifiedGPUFlag == "" {
 		return
@@ -118,6 +155,130 @@ func (r *Runner) createUnifiedGPUs() {
 	r.GPUIDs = []int{unifiedGPUID}
 }
 
+// La colocación de páginas se hace en el driver (ver driver/placement.go). Se
+// configura antes de crear la GPU unificada, con los IDs de las GPUs reales.
+func (r *Runner) configurePlacement() {
+	var policy driver.PlacementPolicy
+
+	switch *placementFlag {
+	case "":
+		return
+	case "chunked":
+		policy = driver.ChunkedPlacement{}
+	case "interleaved":
+		policy = driver.InterleavedPlacement{
+			Granularity: *placementGranularityFlag,
+		}
+	case "first-touch":
+		if !r.UseUnifiedMemory {
+			log.Panic("-placement=first-touch requires -use-unified-memory")
+		}
+		policy = driver.FirstTouchPlacement{}
+	case "access-counter":
+		if !r.UseUnifiedMemory || !r.Timing {
+			log.Panic("-placement=access-counter requires " +
+				"-use-unified-memory and -timing")
+		}
+		policy = driver.AccessCounterPlacement{}
+	default:
+		log.Panicf("unknown placement %q", *placementFlag)
+	}
+
+	if r.UseUnifiedMemory {
+		r.Driver().SetUnifiedMemoryPlacement(policy, r.GPUIDs)
+		return
+	}
+
+	r.Driver().SetDistributePlacement(policy)
+}
+
+// Con contadores de accesos en las GPUs, el driver migra las páginas calientes
+// (ver driver/accesscounter.go).
+func (r *Runner) configurePageMigration(config timingconfig.PlatformConfig) {
+	if config.Memory.AccessCounterThreshold > 0 {
+		r.Driver().EnableAccessCounterMigration(driver.AccessCounterConfig{
+			MinInterval:   sim.VTimeInSec(*accessCounterMinIntervalFlag * 1e-6),
+			MaxMigrations: *accessCounterMaxMigrationsFlag,
+		})
+	}
+
+	if *reportAll || *pageMigrationReportFlag ||
+		config.Memory.AccessCounterThreshold > 0 {
+		r.reporter.pageMigration = r.Driver()
+	}
+}
+
+// Con sobresuscripción, la memoria unificada puede superar la de las GPUs: el
+// driver deja en el host las páginas que no caben y desaloja páginas de las
+// GPUs llenas (ver driver/oversubscription.go).
+func (r *Runner) configureOversubscription() {
+	var policy driver.EvictionPolicy
+
+	switch *evictionPolicyFlag {
+	case "":
+		return
+	case "lru":
+		policy = driver.EvictionPolicyLRU
+	case "clock":
+		policy = driver.EvictionPolicyClock
+	default:
+		log.Panicf("unknown eviction policy %q", *evictionPolicyFlag)
+	}
+
+	if !r.Timing {
+		log.Panic("-eviction-policy requires -timing")
+	}
+
+	r.Driver().EnableOversubscription(driver.OversubscriptionConfig{
+		Policy:        policy,
+		ReservedPages: *evictionReservedPagesFlag,
+	})
+	r.reporter.pageMigration = r.Driver()
+}
+
+// Los checkpoints se toman y se restauran en el driver (ver
+// driver/checkpoint.go).
+func (r *Runner) configureCheckpoint() {
+	if *checkpointAfterFlag > 0 {
+		r.Driver().SaveCheckpointAfter(*checkpointAfterFlag, *checkpointFileFlag)
+	}
+
+	if *restoreFlag == "" {
+		return
+	}
+
+	if err := r.Driver().RestoreCheckpoint(*restoreFlag); err != nil {
+		log.Panic(err)
+	}
+
+	if r.reporter != nil {
+		r.reporter.restoredTime = r.Driver().RestoredTime()
+	}
+}
+
+// El muestreo de kernels se hace en el driver (ver driver/kernelsampling.go).
+// Solo tiene sentido en timing: en emulación no hay tiempo que extrapolar.
+func (r *Runner) configureKernelSampling() {
+	if !*kernelSamplingFlag {
+		return
+	}
+
+	if !r.Timing {
+		log.Panic("-kernel-sampling requires -timing")
+	}
+
+	if *kernelSamplingSamplesFlag < 1 {
+		log.Panic("-kernel-sampling-samples must be at least 1")
+	}
+
+	r.Driver().EnableKernelSampling(driver.KernelSamplingConfig{
+		WarmUp:  *kernelSamplingWarmUpFlag,
+		Samples: *kernelSamplingSamplesFlag,
+		Period:  *kernelSamplingPeriodFlag,
+	})
+	r.reporter.kernelSampling = r.Driver()
+}
+
 // AddBenchmark adds an benchmark that the driver runs
 func (r *Runner) AddBenchmark(b benchmarks.Benchmark) {
 	b.SelectGPU(r.GPUIDs)
@@ -3,6 +3,7 @@ package timingconfig
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/sarchlab/akita/v4/mem/mem"
 	"github.com/sarchlab/akita/v4/mem/vm"
@@ -10,37 +11,95 @@ import (
 	"github.com/sarchlab/akita/v4/noc/networking/pcie"
 	"github.com/sarchlab/akita/v4/sim"
 	"github.com/sarchlab/akita/v4/simulation"
+	"github.com/sarchlab/akita/v4/tracing"
 	"github.com/sarchlab/mgpusim/v4/amd/driver"
 	"github.com/sarchlab/mgpusim/v4/amd/samples/runner/timingconfig/r9nano"
+
+	"github.com/sarchlab/mgpusim/v4/amd/timing/optical"
+	"github.com/tebeka/atexit"
 )
 
+// PCIeFreq is the frequency of the PCIe switches and endpoints. Each link
+// carries one flit per cycle in each direction.
+const PCIeFreq = 1 * sim.GHz
+
 // Builder builds a hardware platform for timing simulation.
 type Builder struct {
 	simulation *simulation.Simulation
 
 	numGPUs            int
+	freq               sim.Freq
 	numCUPerSA         int // Num Compute Units por Shader Array.
 	numSAPerGPU        int // Num Shader Array por GPU.
 	cpuMemSize         uint64
 	gpuMemSize         uint64
 	log2PageSize       uint64
 	useMagicMemoryCopy bool
+	topology           Topology
+	gpuSpecs           map[int]GPUSpec // GPUs distintas al resto (ver gpuspec.go).
+
+	// Umbral de los contadores de accesos remotos de cada GPU (0 = sin ellos).
+	accessCounterThreshold uint64
+
+	// Jerarquía de memoria de cada GPU (ver r9nano).
+	log2CacheLineSize              uint64
+	l2CacheSize                    uint64
+	l2CacheWays                    int
+	l2CacheMSHREntries             int
+	numMemoryBank                  int
+	log2MemoryBankInterleavingSize uint64
+	dramLatency                    int
 
 	platform          *sim.Domain
 	globalStorage     *mem.Storage
-	rdmaAddressMapper *mem.BankedAddressPortMapper
+	rdmaAddressMapper *AddressRangeMapper
+
+	// Los switches y endpoints PCIe no se registran en la simulación, así que
+	// la única forma de trazarlos es conectar el tracer al crearlos.
+	pcieTracer tracing.Tracer
+
+	opticalConnector     *optical.Connector // Referencia al CONECTOR óptico (un solo Switch).
+	opticalFabric        OpticalFabric      // Varios Switches en vez del CONECTOR.
+	opticalAllowDeadlock bool               // Construir igual un Fabric con rutas cíclicas.
+	opticalNetwork       opticalNetwork     // El CONECTOR o el Fabric, donde se cuelgan los puertos.
+	opticalLinkBandwidth float64            // Bytes/s por dirección y longitud de onda.
+	opticalWavelengths   int                // WDM: longitudes de onda por fibra.
+	opticalPower         optical.PowerModel // Consumo de Switch y fibras.
+	opticalPortRoles     []PortRole         // Qué tráfico va por la red óptica (el resto, por PCIe).
+
+	// Modo circuito + Controller (ver optical/controller.go).
+	opticalCircuitSwitching bool
+	opticalReconfigDelay    sim.VTimeInSec
+	opticalEpoch            sim.VTimeInSec
+	opticalPolicy           optical.PredictionPolicy
+	opticalMatching         optical.MatchingPolicy
 }
 
 // MakeBuilder creates a new Builder with default parameters.
 func MakeBuilder() Builder {
 	return Builder{
 		numGPUs:            1,
+		freq:               1 * sim.GHz,
 		numCUPerSA:         4,
 		numSAPerGPU:        16,
 		cpuMemSize:         4 * mem.GB,
 		gpuMemSize:         4 * mem.GB,
 		log2PageSize:       12,
 		useMagicMemoryCopy: false,
+		topology:           TreeTopology{GPUsPerSwitch: 2},
+
+		log2CacheLineSize:              6,
+		l2CacheSize:                    2 * mem.MB,
+		l2CacheWays:                    16,
+		l2CacheMSHREntries:             64,
+		numMemoryBank:                  16,
+		log2MemoryBankInterleavingSize: 7,
+		dramLatency:                    100,
+
+		opticalLinkBandwidth: optical.DefaultLinkBandwidth,
+		opticalWavelengths:   1,
+		opticalPower:         optical.DefaultPowerModel(),
+		opticalPortRoles:     []PortRole{PortRoleRDMA},
 	}
 }
 
@@ -56,20 +115,125 @@ func (b Builder) WithNumGPUs(numGPUs int) Builder {
 	return b
 }
 
+// WithCPUMemSize sets the size of the host memory.
+func (b Builder) WithCPUMemSize(size uint64) Builder {
+	b.cpuMemSize = size
+	return b
+}
+
+// WithGPUMemSize sets the DRAM size of the GPUs that do not set their own with
+// WithGPUSpec.
+func (b Builder) WithGPUMemSize(size uint64) Builder {
+	b.gpuMemSize = size
+	return b
+}
+
 // WithMagicMemoryCopy sets whether to use the magic memory copy middleware.
 func (b Builder) WithMagicMemoryCopy() Builder {
 	b.useMagicMemoryCopy = true
 	return b
 }
 
+// WithAccessCounterThreshold sets the number of accesses to a page of remote
+// memory after which the RDMA engine of a GPU notifies the driver. 0 disables
+// the access counters.
+func (b Builder) WithAccessCounterThreshold(n uint64) Builder {
+	b.accessCounterThreshold = n
+	return b
+}
+
+// WithTopology sets how the GPUs are connected to the host and to each other.
+func (b Builder) WithTopology(t Topology) Builder {
+	b.topology = t
+	return b
+}
+
+// WithPCIeTracer sets a tracer that collects the tasks of every PCIe switch
+// and endpoint.
+func (b Builder) WithPCIeTracer(t tracing.Tracer) Builder {
+	b.pcieTracer = t
+	return b
+}
+
+// WithOpticalLinkBandwidth sets the per-direction line rate of every
+// wavelength of an optical fiber, in bytes per second. A value of 0 means
+// infinite bandwidth.
+func (b Builder) WithOpticalLinkBandwidth(bytesPerSecond float64) Builder {
+	b.opticalLinkBandwidth = bytesPerSecond
+	return b
+}
+
+// WithOpticalWavelengths sets the number of WDM wavelengths carried by every
+// optical fiber.
+func (b Builder) WithOpticalWavelengths(n int) Builder {
+	b.opticalWavelengths = n
+	return b
+}
+
+// WithOpticalPowerModel sets the power model used to account the energy of the
+// optical switch and fibers.
+func (b Builder) WithOpticalPowerModel(m optical.PowerModel) Builder {
+	b.opticalPower = m
+	return b
+}
+
+// WithOpticalPortRoles sets which kinds of traffic go through the optical
+// network of an optical topology. The ports of every other role are plugged
+// into PCIe. By default, only RDMA traffic is optical.
+func (b Builder) WithOpticalPortRoles(roles ...PortRole) Builder {
+	b.opticalPortRoles = roles
+	return b
+}
+
+// WithOpticalFabric replaces the single optical switch with a network of
+// several switches. Circuit switching needs the single switch.
+func (b Builder) WithOpticalFabric(fabric OpticalFabric) Builder {
+	b.opticalFabric = fabric
+	return b
+}
+
+// WithOpticalFabricDeadlockAllowed builds the optical fabric even if its
+// routes have a cyclic channel dependency and may deadlock. Without it, such
+// a fabric is refused.
+func (b Builder) WithOpticalFabricDeadlockAllowed() Builder {
+	b.opticalAllowDeadlock = true
+	return b
+}
+
+// WithOpticalCircuitSwitching makes the optical switch circuit-switched. Every
+// reconfiguration takes the switch offline for reconfigDelay.
+func (b Builder) WithOpticalCircuitSwitching(
+	reconfigDelay sim.VTimeInSec,
+) Builder {
+	b.opticalCircuitSwitching = true
+	b.opticalReconfigDelay = reconfigDelay
+	return b
+}
+
+// WithOpticalController sets the epoch and the traffic prediction policy of
+// the controller that reconfigures the circuit-switched optical switch.
+func (b Builder) WithOpticalController(
+	epoch sim.VTimeInSec,
+	policy optical.PredictionPolicy,
+) Builder {
+	b.opticalEpoch = epoch
+	b.opticalPolicy = policy
+	return b
+}
+
+// WithOpticalMatching sets how the controller of the circuit-switched optical
+// switch chooses the circuits. By default, it uses optical.GreedyMatching.
+func (b Builder) WithOpticalMatching(matching optical.MatchingPolicy) Builder {
+	b.opticalMatching = matching
+	return b
+}
+
 // Build builds the hardware platform.
 func (b Builder) Build() *sim.Domain {
-	b.cpuGPUMemSizeMustEqual()
-
 	b.platform = &sim.Domain{}
 
-	b.globalStorage = mem.NewStorage( // Espacio de mem física TOTAL (para CPU y GPUs).
-		uint64(b.numGPUs)*b.gpuMemSize + b.cpuMemSize)
+	gpuMemOffsets, totalMemSize := b.memoryLayout()
+	b.globalStorage = mem.NewStorage(totalMemSize) // Espacio de mem física TOTAL (para CPU y GPUs).
 
 	mmuComp, pageTable := b.createMMU()      // Crea la MMU y PT.
 	gpuDriver := b.buildGPUDriver(pageTable) // Driver de la GPU.
@@ -83,25 +247,15 @@ func (b Builder) Build() *sim.Domain {
 
 	mmuComp.MigrationServiceProvider = gpuDriver.GetPortByName("MMU").AsRemote()
 
-	b.createRDMAAddrTable()
-	pmcAddressTable := b.createPMCPageTable()
-
 	b.createGPUs( // Se crean e interconectan las GPUs según la lógica definida.
 		rootComplexID, pcieConnector,
-		gpuBuilder, gpuDriver,
-		pmcAddressTable)
+		gpuBuilder, gpuDriver, gpuMemOffsets)
 
 	pcieConnector.EstablishRoute()
 
 	return b.platform
 }
 
-func (b *Builder) cpuGPUMemSizeMustEqual() {
-	if b.cpuMemSize != b.gpuMemSize {
-		panic("currently only support cpuMemSize == gpuMemSize")
-	}
-}
-
 func (b *Builder) createMMU() (*mmu.Comp, vm.PageTable) {
 	pageTable := vm.NewPageTable(b.log2PageSize)
 	mmuBuilder := mmu.MakeBuilder().
@@ -131,6 +285,7 @@ func (b *Builder) buildGPUDriver(
 		WithEngine(b.simulation.GetEngine()).
 		WithPageTable(pageTable).
 		WithLog2PageSize(b.log2PageSize).
+		WithCPUMemSize(b.cpuMemSize).
 		WithGlobalStorage(b.globalStorage).
 		WithD2HCycles(8500).
 		WithH2DCycles(14500).
@@ -146,15 +301,23 @@ func (b *Builder) createGPUBuilder(
 	mmuComponent *mmu.Comp,
 ) r9nano.Builder {
 	gpuBuilder := r9nano.MakeBuilder().
-		WithFreq(1 * sim.GHz).
+		WithFreq(b.freq).
 		WithSimulation(b.simulation).
 		WithMMU(mmuComponent).
 		WithNumCUPerShaderArray(b.numCUPerSA).
 		WithNumShaderArray(b.numSAPerGPU).
-		WithNumMemoryBank(16).
-		WithLog2MemoryBankInterleavingSize(7).
+		WithLog2CacheLineSize(b.log2CacheLineSize).
+		WithL2CacheSize(b.l2CacheSize).
+		WithL2CacheWayAssociativity(b.l2CacheWays).
+		WithL2CacheNumMSHREntry(b.l2CacheMSHREntries).
+		WithNumMemoryBank(b.numMemoryBank).
+		WithLog2MemoryBankInterleavingSize(b.log2MemoryBankInterleavingSize).
+		WithDRAMLatency(b.dramLatency).
+		WithDRAMSize(b.gpuMemSize).
 		WithLog2PageSize(b.log2PageSize).
-		WithGlobalStorage(b.globalStorage)
+		WithGlobalStorage(b.globalStorage).
+		WithDriverPort(gpuDriver.GetPortByName("GPU")).
+		WithAccessCounterThreshold(b.accessCounterThreshold)
 
 	b.createRDMAAddressMapper()
 
@@ -169,84 +332,173 @@ func (b *Builder) createGPUs(
 	pcieConnector *pcie.Connector,
 	gpuBuilder r9nano.Builder,
 	gpuDriver *driver.Driver,
-	pmcAddressTable *mem.BankedAddressPortMapper,
+	gpuMemOffsets []uint64,
 ) {
+	// Topología de Red: la topología decide a qué switch PCIe va cada GPU.
+	switches := b.topology.ConnectSwitches(
+		pcieConnector, rootComplexID, b.numGPUs)
 
-	// Topología de Red.
-	lastSwitchID := rootComplexID
 	for i := 1; i < b.numGPUs+1; i++ {
-		if i%2 == 1 { // Por cada 2 GPUs se crea un nuevo switch PCIe (conectado al Root Complex).
-			lastSwitchID = pcieConnector.AddSwitch(rootComplexID)
-		}
-
-		// Se conectan esas 2 GPUs al switch recién creado.
-		b.createGPU(i, gpuBuilder, gpuDriver, pmcAddressTable,
-			pcieConnector, lastSwitchID)
+		b.createGPU(i, gpuBuilder, gpuDriver,
+			pcieConnector, switches[i-1], gpuMemOffsets[i-1])
 	}
 }
 
-func (b *Builder) createPMCPageTable() *mem.BankedAddressPortMapper {
-	pmcAddressTable := new(mem.BankedAddressPortMapper)
-	pmcAddressTable.BankSize = 4 * mem.GB
-	pmcAddressTable.LowModules = append(pmcAddressTable.LowModules, "")
-	return pmcAddressTable
-}
-
-func (b *Builder) createRDMAAddrTable() *mem.BankedAddressPortMapper {
-	rdmaAddressTable := new(mem.BankedAddressPortMapper)
-	rdmaAddressTable.BankSize = 4 * mem.GB
-	rdmaAddressTable.LowModules = append(rdmaAddressTable.LowModules, "")
-	return rdmaAddressTable
-}
-
 func (b *Builder) createConnection(
 	gpuDriver *driver.Driver,
 	mmuComponent *mmu.Comp,
 ) (*pcie.Connector, int) {
 	// Utiliza un pcie.Connector para modelar una red PCIe versión 4 con 16 lanes
-	// (WithVersion(4, 16)) y una latencia de switch de 140 ciclos (WithSwitchLatency(140)).
+	// (WithVersion(4, 16)) y la latencia de switch que pida la topología.
 
 	// connection := sim.NewDirectConnection(engine)
 	// connection := noc.NewFixedBandwidthConnection(32, engine, 1*sim.GHz)
 	// connection.SrcBufferCapacity = 40960000
 	pcieConnector := pcie.NewConnector().
 		WithEngine(b.simulation.GetEngine()).
+		WithFrequency(PCIeFreq).
 		WithVersion(4, 16).
-		WithSwitchLatency(140)
+		WithSwitchLatency(b.topology.PCIeSwitchLatency())
+
+	if b.pcieTracer != nil {
+		pcieConnector.WithVisTracer(b.pcieTracer)
+	}
 
 	pcieConnector.CreateNetwork("PCIe")
 
+	b.createOpticalNetwork()
+
 	// Creación del Root Complex que conecta los puertos del driver y de la MMU.
-	rootComplexID := pcieConnector.AddRootComplex(
-		[]sim.Port{
-			gpuDriver.GetPortByName("GPU"),
-			gpuDriver.GetPortByName("MMU"),
-			mmuComponent.GetPortByName("Migration"),
-			mmuComponent.GetPortByName("Top"),
-		})
+	// Driver.MMU <-> MMU.Migration no salen del host: siempre por PCIe.
+	// Los otros dos hablan con las GPUs y siguen a los puertos de su rol.
+	hostRoles := map[sim.Port]PortRole{
+		gpuDriver.GetPortByName("GPU"):    PortRoleDriver,
+		mmuComponent.GetPortByName("Top"): PortRoleTranslation,
+	}
+	hostPorts := b.plugInByRole(hostRoles, []sim.Port{
+		gpuDriver.GetPortByName("GPU"),
+		mmuComponent.GetPortByName("Top"),
+	})
+	rootComplexID := pcieConnector.AddRootComplex(append(hostPorts,
+		gpuDriver.GetPortByName("MMU"),
+		mmuComponent.GetPortByName("Migration"),
+	))
 
 	return pcieConnector, rootComplexID
 }
 
+// --- RED ÓPTICA ---
+// Solo si la topología la usa. No necesitamos crear un "Root Complex" óptico
+// porque es P2P.
+func (b *Builder) createOpticalNetwork() {
+	if !b.topology.Optical() {
+		return
+	}
+
+	if b.opticalFabric.Kind != "" {
+		b.createOpticalFabric()
+		return
+	}
+
+	b.opticalConnector = optical.NewConnector(b.simulation).
+		WithLinkBandwidth(b.opticalLinkBandwidth).
+		WithWavelengths(b.opticalWavelengths).
+		WithPowerModel(b.opticalPower)
+	b.opticalConnector.Switch.NoRouteHandler = failOnNoRoute
+	b.simulation.RegisterComponent(b.opticalConnector.Switch)
+	b.opticalNetwork = b.opticalConnector
+	b.createOpticalController()
+}
+
+// El Controller sólo sabe manejar un Switch, así que el Fabric va en modo
+// paquete.
+func (b *Builder) createOpticalFabric() {
+	if b.opticalCircuitSwitching {
+		log.Panic("optical circuit switching needs the single optical " +
+			"switch, not a fabric")
+	}
+
+	fabric := b.opticalFabric.build(optical.MakeFabricBuilder().
+		WithSimulation(b.simulation).
+		WithLinkBandwidth(b.opticalLinkBandwidth).
+		WithWavelengths(b.opticalWavelengths).
+		WithPowerModel(b.opticalPower))
+	for _, sw := range fabric.Switches {
+		sw.NoRouteHandler = failOnNoRoute
+	}
+
+	// Sin canales virtuales no hay ruteo libre de deadlock para estos
+	// fabrics: se rechazan salvo que se pida explícitamente.
+	if fabric.CanDeadlock() {
+		if !b.opticalAllowDeadlock {
+			log.Panicf("the routes of the %s optical fabric have a cycle "+
+				"and may deadlock; set interconnect.optical.allow_deadlock "+
+				"(-optical-fabric-allow-deadlock) to build it anyway",
+				b.opticalFabric.Kind)
+		}
+
+		log.Printf("warning: the routes of the %s optical fabric have a "+
+			"cycle and may deadlock", b.opticalFabric.Kind)
+	}
+
+	b.opticalNetwork = fabric
+}
+
+// Un mensaje sin ruta nunca llega y quien lo envió lo esperaría para siempre:
+// la simulación termina con el error en vez de colgarse.
+func failOnNoRoute(err *optical.NoRouteError) {
+	log.Printf("%v", err)
+	atexit.Exit(1)
+}
+
+// En modo circuito alguien tiene que decidir los circuitos: el Controller.
+func (b *Builder) createOpticalController() {
+	if !b.opticalCircuitSwitching {
+		return
+	}
+
+	b.opticalConnector.WithCircuitSwitching(b.opticalReconfigDelay)
+
+	epoch := b.opticalEpoch
+	if epoch == 0 {
+		epoch = 10 * 1e-6
+	}
+
+	policy := b.opticalPolicy
+	if policy == nil {
+		policy = optical.NewLastEpochPolicy()
+	}
+
+	controller := optical.NewController("OpticalController",
+		b.simulation.GetEngine(), b.opticalConnector.Switch, epoch, policy)
+	if b.opticalMatching != nil {
+		controller.Matching = b.opticalMatching
+	}
+	b.simulation.RegisterComponent(controller)
+}
+
 func (b *Builder) createRDMAAddressMapper() {
-	b.rdmaAddressMapper = new(mem.BankedAddressPortMapper)
-	b.rdmaAddressMapper.BankSize = b.gpuMemSize
-	b.rdmaAddressMapper.LowModules = append(b.rdmaAddressMapper.LowModules,
-		sim.RemotePort("CPU"))
+	b.rdmaAddressMapper = new(AddressRangeMapper)
+	b.rdmaAddressMapper.Add(0, b.cpuMemSize, sim.RemotePort("CPU"))
 }
 
 func (b *Builder) createGPU(
 	index int,
 	gpuBuilder r9nano.Builder, // Utiliza la plantilla de 'r9nano' para construir un GPU con nombre y ID único.
 	gpuDriver *driver.Driver,
-	pmcAddressTable *mem.BankedAddressPortMapper,
 	pcieConnector *pcie.Connector,
 	pcieSwitchID int,
+	memAddrOffset uint64,
 ) *sim.Domain {
 	name := fmt.Sprintf("GPU[%d]", index)
-	memAddrOffset := uint64(index) * 4 * mem.GB // Asigna un desplazamiento de dirección de memoria
+	spec := b.gpuSpec(index) // Cada GPU puede ser distinta.
 	gpu := gpuBuilder.
 		WithGPUID(uint64(index)).
+		WithFreq(spec.Freq).
+		WithNumShaderArray(spec.NumSA).
+		WithNumCUPerShaderArray(spec.NumCUPerSA).
+		WithL2CacheSize(spec.L2CacheSize).
+		WithDRAMSize(spec.DRAMSize).
 		WithMemAddrOffset(memAddrOffset).
 		WithRDMAAddressMapper(b.rdmaAddressMapper).
 		Build(name)
@@ -254,39 +506,40 @@ func (b *Builder) createGPU(
 	gpuDriver.RegisterGPU(
 		gpu.GetPortByName("CommandProcessor"),
 		driver.DeviceProperties{
-			CUCount:  b.numCUPerSA * b.numSAPerGPU,
-			DRAMSize: 4 * mem.GB,
+			CUCount:  spec.NumCUPerSA * spec.NumSA,
+			DRAMSize: spec.DRAMSize,
+			Freq:     spec.Freq,
 		},
 	)
-	// gpu.CommandProcessor.Driver = gpuDriver.GetPortByName("GPU")
+	b.configRDMAEngine(gpu, memAddrOffset, spec.DRAMSize)
+	b.configPMC(gpu, gpuDriver)
 
-	b.configRDMAEngine(gpu)
-	// b.configPMC(gpu, gpuDriver, pmcAddressTable)
+	// pcieConnector.PlugInDevice(pcieSwitchID, gpu.Ports())
 
-	pcieConnector.PlugInDevice(pcieSwitchID, gpu.Ports())
+	// Cada puerto va a la red óptica o a PCIe según su rol (ver network.go).
+	pciePorts := b.plugInByRole(classifyGPUPorts(gpu), gpu.Ports())
 
-	// b.gpus = append(b.gpus, gpu)
+	// Si todo es óptico, la GPU no tiene nada que enchufar al Switch PCIe.
+	if len(pciePorts) > 0 {
+		pcieConnector.PlugInDevice(pcieSwitchID, pciePorts)
+	}
 
 	return gpu
 }
 
 func (b *Builder) configRDMAEngine(
 	gpu *sim.Domain,
+	memAddrOffset, dramSize uint64,
 ) {
-	b.rdmaAddressMapper.LowModules = append(
-		b.rdmaAddressMapper.LowModules,
+	b.rdmaAddressMapper.Add(memAddrOffset, dramSize,
 		gpu.GetPortByName("RDMAData").AsRemote())
 }
 
-// func (b *Builder) configPMC(
-// 	gpu *GPU,
-// 	gpuDriver *driver.Driver,
-// 	addrTable *mem.BankedAddressPortMapper,
-// ) {
-// 	gpu.PMC.RemotePMCAddressTable = addrTable
-// 	addrTable.LowModules = append(
-// 		addrTable.LowModules,
-// 		gpu.PMC.GetPortByName("Remote").AsRemote())
-// 	gpuDriver.RemotePMCPorts = append(
-// 		gpuDriver.RemotePMCPorts, gpu.PMC.GetPortByName("Remote"))
-// }
+// El driver le dice a cada PMC de qué PMC remoto traer la página (ver
+// Driver.RemotePMCPorts), así que no hace falta una tabla de direcciones.
+func (b *Builder) configPMC(gpu *sim.Domain, gpuDriver *driver.Driver) {
+	pmcPort := gpu.GetPortByName("PageMigrationController")
+
+	gpuDriver.RemotePMCPorts = append(
+		gpuDriver.RemotePMCPorts, pmcPort)
+}
@@ -0,0 +1,104 @@
+package timingconfig
+
+import (
+	"log"
+	"sort"
+
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+// GPUSpec holds the parameters that can change from one GPU to another. A
+// field left at 0 takes the value that the Builder uses for every GPU.
+type GPUSpec struct {
+	Freq        sim.Freq
+	NumSA       int // Shader Arrays.
+	NumCUPerSA  int
+	L2CacheSize uint64
+	DRAMSize    uint64
+}
+
+// WithGPUSpec changes the parameters of the GPU with the given ID (the first
+// GPU is 1).
+func (b Builder) WithGPUSpec(id int, spec GPUSpec) Builder {
+	specs := make(map[int]GPUSpec, len(b.gpuSpecs)+1)
+	for k, v := range b.gpuSpecs {
+		specs[k] = v
+	}
+	specs[id] = spec
+
+	b.gpuSpecs = specs
+	return b
+}
+
+// Parámetros efectivos de una GPU: los propios + los comunes a todas.
+func (b *Builder) gpuSpec(id int) GPUSpec {
+	spec := b.gpuSpecs[id]
+
+	if spec.Freq == 0 {
+		spec.Freq = b.freq
+	}
+	if spec.NumSA == 0 {
+		spec.NumSA = b.numSAPerGPU
+	}
+	if spec.NumCUPerSA == 0 {
+		spec.NumCUPerSA = b.numCUPerSA
+	}
+	if spec.L2CacheSize == 0 {
+		spec.L2CacheSize = b.l2CacheSize
+	}
+	if spec.DRAMSize == 0 {
+		spec.DRAMSize = b.gpuMemSize
+	}
+
+	return spec
+}
+
+// --- MAPA DE MEMORIA FÍSICA ---
+// [0, cpuMemSize) es del CPU y después viene la DRAM de cada GPU, una detrás
+// de otra. Con DRAMs de distinto tamaño ya no alcanza con dividir la
+// dirección por el tamaño del banco (mem.BankedAddressPortMapper).
+
+// Dirección física donde empieza la DRAM de cada GPU (índice 0 = GPU[1]) y el
+// tamaño total de la memoria.
+func (b *Builder) memoryLayout() (gpuOffsets []uint64, total uint64) {
+	total = b.cpuMemSize
+	for i := 1; i <= b.numGPUs; i++ {
+		gpuOffsets = append(gpuOffsets, total)
+		total += b.gpuSpec(i).DRAMSize
+	}
+
+	return gpuOffsets, total
+}
+
+type addressRange struct {
+	start, end uint64
+	port       sim.RemotePort
+}
+
+// AddressRangeMapper finds the port of the device that owns a physical
+// address. The ranges must be added in increasing order.
+type AddressRangeMapper struct {
+	ranges []addressRange
+}
+
+// Add assigns [start, start+size) to the port.
+func (m *AddressRangeMapper) Add(start, size uint64, port sim.RemotePort) {
+	if n := len(m.ranges); n > 0 && start < m.ranges[n-1].end {
+		log.Panicf("address range 0x%x overlaps the previous one", start)
+	}
+
+	m.ranges = append(m.ranges, addressRange{start, start + size, port})
+}
+
+// Find returns the port of the range that contains the address.
+func (m *AddressRangeMapper) Find(address uint64) sim.RemotePort {
+	i := sort.Search(len(m.ranges), func(i int) bool {
+		return m.ranges[i].end > address
+	})
+
+	if i == len(m.ranges) || address < m.ranges[i].start {
+		log.Panicf("address 0x%x is not mapped to any device", address)
+	}
+
+	return m.ranges[i].port
+}
@@ -0,0 +1,238 @@
+package timingconfig
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/timing/optical"
+)
+
+// PortRole tells what kind of traffic an external port carries. The builder
+// uses it to decide whether the port goes to the optical network or to PCIe.
+type PortRole int
+
+const (
+	// PortRoleDriver ports carry the driver commands and memory copies
+	// (Driver <-> Command Processor).
+	PortRoleDriver PortRole = iota
+	// PortRoleRDMA ports carry the remote memory accesses between GPUs.
+	PortRoleRDMA
+	// PortRolePageMigration ports carry the pages moved between the Page
+	// Migration Controllers.
+	PortRolePageMigration
+	// PortRoleTranslation ports carry the address translations between the
+	// L2 TLBs and the MMU.
+	PortRoleTranslation
+
+	numPortRoles
+)
+
+var portRoleNames = [numPortRoles]string{
+	PortRoleDriver:        "driver",
+	PortRoleRDMA:          "rdma",
+	PortRolePageMigration: "pmc",
+	PortRoleTranslation:   "translation",
+}
+
+func (r PortRole) String() string {
+	if r < 0 || r >= numPortRoles {
+		return "unknown"
+	}
+
+	return portRoleNames[r]
+}
+
+// InterGPUPortRoles are the roles of the ports that move data between GPUs.
+var InterGPUPortRoles = []PortRole{PortRoleRDMA, PortRolePageMigration}
+
+// ParsePortRoles parses a comma-separated list of role names (driver, rdma,
+// pmc, translation). "inter-gpu" stands for rdma and pmc, and "all" for every
+// role.
+func ParsePortRoles(spec string) []PortRole {
+	roles, err := parsePortRoles(spec)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return roles
+}
+
+func parsePortRoles(spec string) ([]PortRole, error) {
+	roles := []PortRole{}
+
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+
+		switch name {
+		case "":
+			continue
+		case "all":
+			for r := PortRole(0); r < numPortRoles; r++ {
+				roles = append(roles, r)
+			}
+			continue
+		case "inter-gpu":
+			roles = append(roles, InterGPUPortRoles...)
+			continue
+		}
+
+		found := false
+		for r, n := range portRoleNames {
+			if n == name {
+				roles = append(roles, PortRole(r))
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unknown port role %s", name)
+		}
+	}
+
+	return roles, nil
+}
+
+// Rol de los puertos externos de la GPU (ver r9nano.populateExternalPorts).
+// Los que no están acá son los Translation_XX de los L2 TLB.
+var gpuPortRoles = map[string]PortRole{
+	"CommandProcessor":        PortRoleDriver,
+	"RDMARequest":             PortRoleRDMA,
+	"RDMAData":                PortRoleRDMA,
+	"PageMigrationController": PortRolePageMigration,
+}
+
+func classifyGPUPorts(gpu *sim.Domain) map[sim.Port]PortRole {
+	roles := make(map[sim.Port]PortRole)
+	for name, role := range gpuPortRoles {
+		roles[gpu.GetPortByName(name)] = role
+	}
+
+	for _, p := range gpu.Ports() {
+		if _, ok := roles[p]; !ok {
+			roles[p] = PortRoleTranslation
+		}
+	}
+
+	return roles
+}
+
+// Lo que tienen en común optical.Connector y optical.Fabric.
+type opticalNetwork interface {
+	PlugIn(gpuPort sim.Port)
+}
+
+func (b *Builder) isOptical(role PortRole) bool {
+	if b.opticalNetwork == nil { // Topología sin red óptica.
+		return false
+	}
+
+	for _, r := range b.opticalPortRoles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reparte los puertos entre la red óptica y PCIe según su rol. Devuelve los
+// que quedan para PCIe.
+func (b *Builder) plugInByRole(roles map[sim.Port]PortRole, ports []sim.Port) []sim.Port {
+	var pciePorts []sim.Port
+
+	for _, p := range ports {
+		role, ok := roles[p]
+		if !ok { // Sin rol iría a parar a PortRoleDriver sin que nadie lo note.
+			log.Panicf("port %s has no role", p.Name())
+		}
+
+		if b.isOptical(role) {
+			b.opticalNetwork.PlugIn(p)
+		} else {
+			pciePorts = append(pciePorts, p)
+		}
+	}
+
+	return pciePorts
+}
+
+// OpticalFabric describes an optical network of several switches (see
+// optical.FabricBuilder). The zero value is the single optical switch.
+type OpticalFabric struct {
+	Kind string // leaf-spine, torus o dragonfly.
+	Dims []int  // Leaves x spines, dimensiones del torus o grupos x routers.
+}
+
+// OpticalFabricKinds are the names accepted by ParseOpticalFabric.
+var OpticalFabricKinds = []string{"leaf-spine", "torus", "dragonfly"}
+
+// ParseOpticalFabric parses "<kind>:<dims>", where the dimensions are
+// separated by "x": "leaf-spine:4x2" (4 leaves, 2 spines), "torus:4x4" or
+// "dragonfly:4x2" (4 groups of 2 routers). An empty spec or "star" is the
+// single optical switch.
+func ParseOpticalFabric(spec string) OpticalFabric {
+	f, err := parseOpticalFabric(spec)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return f
+}
+
+func parseOpticalFabric(spec string) (OpticalFabric, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "star" {
+		return OpticalFabric{}, nil
+	}
+
+	kind, dims, found := strings.Cut(spec, ":")
+	if !found {
+		return OpticalFabric{}, fmt.Errorf(
+			"optical fabric %q must be <kind>:<dims>", spec)
+	}
+
+	f := OpticalFabric{Kind: kind}
+	for _, field := range strings.Split(dims, "x") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return OpticalFabric{}, fmt.Errorf(
+				"optical fabric %q has invalid size %q", spec, field)
+		}
+
+		f.Dims = append(f.Dims, n)
+	}
+
+	switch {
+	case kind == "torus":
+	case kind == "leaf-spine" || kind == "dragonfly":
+		if len(f.Dims) != 2 {
+			return OpticalFabric{}, fmt.Errorf(
+				"optical fabric %q needs two sizes", spec)
+		}
+	default:
+		return OpticalFabric{}, fmt.Errorf(
+			"unknown optical fabric %s, must be one of %s", kind,
+			strings.Join(OpticalFabricKinds, ", "))
+	}
+
+	return f, nil
+}
+
+// Kind tiene que ser uno de OpticalFabricKinds (ver parseOpticalFabric).
+func (f OpticalFabric) build(b optical.FabricBuilder) *optical.Fabric {
+	switch f.Kind {
+	case "leaf-spine":
+		return b.BuildLeafSpine("OpticalFabric", f.Dims[0], f.Dims[1])
+	case "torus":
+		return b.BuildTorus("OpticalFabric", f.Dims...)
+	case "dragonfly":
+		return b.BuildDragonfly("OpticalFabric", f.Dims[0], f.Dims[1])
+	}
+
+	log.Panicf("unknown optical fabric %s", f.Kind)
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package timingconfig
+
+import (
+	"fmt"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/akita/v4/simulation"
+)
+
+type pluggedPorts struct {
+	ports []sim.Port
+}
+
+func (n *pluggedPorts) PlugIn(gpuPort sim.Port) {
+	n.ports = append(n.ports, gpuPort)
+}
+
+var _ = Describe("Port Roles", func() {
+	DescribeTable("should parse valid specs",
+		func(spec string, expected []PortRole) {
+			roles, err := parsePortRoles(spec)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(roles).To(Equal(expected))
+		},
+		Entry("empty", "", []PortRole{}),
+		Entry("one role", "rdma", []PortRole{PortRoleRDMA}),
+		Entry("several roles", "driver, pmc,,translation",
+			[]PortRole{PortRoleDriver, PortRolePageMigration,
+				PortRoleTranslation}),
+		Entry("inter-gpu", "inter-gpu",
+			[]PortRole{PortRoleRDMA, PortRolePageMigration}),
+		Entry("all", "all",
+			[]PortRole{PortRoleDriver, PortRoleRDMA, PortRolePageMigration,
+				PortRoleTranslation}),
+	)
+
+	DescribeTable("should reject unknown roles",
+		func(spec, msg string) {
+			_, err := parsePortRoles(spec)
+
+			Expect(err).To(MatchError(msg))
+		},
+		Entry("unknown", "rdma,gpu", "unknown port role gpu"),
+		Entry("wrong case", "RDMA", "unknown port role RDMA"),
+	)
+
+	Context("when classifying the ports of a GPU", func() {
+		var (
+			gpu   *sim.Domain
+			ports map[string]sim.Port
+		)
+
+		BeforeEach(func() {
+			gpu = sim.NewDomain("GPU")
+			ports = make(map[string]sim.Port)
+			for i, name := range []string{"CommandProcessor", "RDMARequest",
+				"RDMAData", "PageMigrationController", "Translation_00"} {
+				ports[name] = sim.NewPort(nil, 1, 1,
+					fmt.Sprintf("GPU.Port[%d]", i))
+				gpu.AddPort(name, ports[name])
+			}
+		})
+
+		DescribeTable("should give each port its role",
+			func(name string, role PortRole) {
+				Expect(classifyGPUPorts(gpu)).To(
+					HaveKeyWithValue(ports[name], role))
+			},
+			Entry("command processor", "CommandProcessor", PortRoleDriver),
+			Entry("RDMA request", "RDMARequest", PortRoleRDMA),
+			Entry("RDMA data", "RDMAData", PortRoleRDMA),
+			Entry("PMC", "PageMigrationController", PortRolePageMigration),
+			Entry("L2 TLB", "Translation_00", PortRoleTranslation),
+		)
+
+		It("should plug the ports of the optical roles into the optical "+
+			"network", func() {
+			network := &pluggedPorts{}
+			b := MakeBuilder().WithOpticalPortRoles(InterGPUPortRoles...)
+			b.opticalNetwork = network
+
+			pciePorts := b.plugInByRole(classifyGPUPorts(gpu), gpu.Ports())
+
+			Expect(network.ports).To(ConsistOf(ports["RDMARequest"],
+				ports["RDMAData"], ports["PageMigrationController"]))
+			Expect(pciePorts).To(ConsistOf(ports["CommandProcessor"],
+				ports["Translation_00"]))
+		})
+
+		It("should panic if a host port has no role", func() {
+			b := MakeBuilder()
+			b.opticalNetwork = &pluggedPorts{}
+			driverPort := sim.NewPort(nil, 1, 1, "Driver.GPU")
+			mmuPort := sim.NewPort(nil, 1, 1, "MMU.Top")
+			roles := map[sim.Port]PortRole{driverPort: PortRoleDriver}
+
+			Expect(func() {
+				b.plugInByRole(roles, []sim.Port{driverPort, mmuPort})
+			}).To(PanicWith(ContainSubstring("MMU.Top has no role")))
+		})
+	})
+})
+
+var _ = Describe("Optical Fabric", func() {
+	DescribeTable("should parse valid fabrics",
+		func(spec string, expected OpticalFabric) {
+			f, err := parseOpticalFabric(spec)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f).To(Equal(expected))
+		},
+		Entry("empty", "", OpticalFabric{}),
+		Entry("star", "star", OpticalFabric{}),
+		Entry("leaf-spine", "leaf-spine:4x2",
+			OpticalFabric{Kind: "leaf-spine", Dims: []int{4, 2}}),
+		Entry("ring", "torus:8", OpticalFabric{Kind: "torus", Dims: []int{8}}),
+		Entry("torus", "torus:4x4",
+			OpticalFabric{Kind: "torus", Dims: []int{4, 4}}),
+		Entry("dragonfly", " dragonfly:4x2 ",
+			OpticalFabric{Kind: "dragonfly", Dims: []int{4, 2}}),
+	)
+
+	DescribeTable("should reject invalid fabrics",
+		func(spec, msg string) {
+			_, err := parseOpticalFabric(spec)
+
+			Expect(err).To(MatchError(ContainSubstring(msg)))
+		},
+		Entry("no sizes", "torus", "must be <kind>:<dims>"),
+		Entry("unknown kind", "ring:4", "unknown optical fabric ring"),
+		Entry("bad size", "torus:4xa", `invalid size "a"`),
+		Entry("zero size", "torus:0", `invalid size "0"`),
+		Entry("one size", "leaf-spine:4", "needs two sizes"),
+		Entry("three sizes", "dragonfly:4x2x1", "needs two sizes"),
+	)
+
+	Describe("building", func() {
+		var b Builder
+
+		BeforeEach(func() {
+			simulation := simulation.MakeBuilder().
+				WithoutMonitoring().
+				WithOutputFileName(filepath.Join(GinkgoT().TempDir(), "sim")).
+				Build()
+			b = MakeBuilder().WithSimulation(simulation)
+		})
+
+		It("should build a fabric that cannot deadlock", func() {
+			b = b.WithOpticalFabric(ParseOpticalFabric("leaf-spine:4x2"))
+
+			b.createOpticalFabric()
+
+			Expect(b.opticalNetwork).NotTo(BeNil())
+		})
+
+		It("should refuse a fabric that may deadlock", func() {
+			b = b.WithOpticalFabric(ParseOpticalFabric("torus:4x4"))
+
+			Expect(b.createOpticalFabric).
+				To(PanicWith(ContainSubstring("may deadlock")))
+		})
+
+		It("should build a fabric that may deadlock if allowed", func() {
+			b = b.WithOpticalFabric(ParseOpticalFabric("dragonfly:4x2")).
+				WithOpticalFabricDeadlockAllowed()
+
+			b.createOpticalFabric()
+
+			Expect(b.opticalNetwork).NotTo(BeNil())
+		})
+	})
+})
@@ -0,0 +1,520 @@
+package timingconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sarchlab/akita/v4/mem/mem"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/timing/optical"
+	"go.yaml.in/yaml/v3"
+)
+
+// PlatformConfig describes the hardware of a timing simulation. The types of
+// this file are the schema of the platform files read by LoadPlatformConfig:
+// unknown keys are rejected, missing keys keep their default value, and
+// Validate checks the values.
+type PlatformConfig struct {
+	GPU          GPUConfig          `json:"gpu" yaml:"gpu"`
+	GPUs         []GPUOverride      `json:"gpus,omitempty" yaml:"gpus"` // GPUs distintas a "gpu".
+	Memory       MemoryConfig       `json:"memory" yaml:"memory"`
+	Interconnect InterconnectConfig `json:"interconnect" yaml:"interconnect"`
+}
+
+// GPUConfig describes every GPU of the platform.
+type GPUConfig struct {
+	FreqMHz             float64 `json:"freq_mhz" yaml:"freq_mhz"`
+	NumShaderArrays     int     `json:"num_shader_arrays" yaml:"num_shader_arrays"`
+	NumCUPerShaderArray int     `json:"num_cu_per_shader_array" yaml:"num_cu_per_shader_array"`
+	Log2CacheLineSize   uint64  `json:"log2_cache_line_size" yaml:"log2_cache_line_size"`
+
+	L2Cache CacheConfig `json:"l2_cache" yaml:"l2_cache"`
+
+	NumMemoryBanks                 int    `json:"num_memory_banks" yaml:"num_memory_banks"`
+	Log2MemoryBankInterleavingSize uint64 `json:"log2_memory_bank_interleaving_size" yaml:"log2_memory_bank_interleaving_size"`
+	DRAMLatency                    int    `json:"dram_latency" yaml:"dram_latency"` // Ciclos.
+}
+
+// GPUOverride changes some parameters of some of the GPUs (the first GPU is
+// 1), so that a platform can mix different GPUs. The fields left at 0 keep the
+// value of GPUConfig and MemoryConfig.GPUSize.
+type GPUOverride struct {
+	IDs                 []int    `json:"ids" yaml:"ids"`
+	FreqMHz             float64  `json:"freq_mhz,omitempty" yaml:"freq_mhz"`
+	NumShaderArrays     int      `json:"num_shader_arrays,omitempty" yaml:"num_shader_arrays"`
+	NumCUPerShaderArray int      `json:"num_cu_per_shader_array,omitempty" yaml:"num_cu_per_shader_array"`
+	L2CacheSize         ByteSize `json:"l2_cache_size,omitempty" yaml:"l2_cache_size"`
+	DRAMSize            ByteSize `json:"dram_size,omitempty" yaml:"dram_size"`
+}
+
+// CacheConfig describes a cache. Size is the total of all banks.
+type CacheConfig struct {
+	Size        ByteSize `json:"size" yaml:"size"`
+	Ways        int      `json:"ways" yaml:"ways"`
+	MSHREntries int      `json:"mshr_entries" yaml:"mshr_entries"`
+}
+
+// MemoryConfig describes the memory of the CPU and of each GPU.
+type MemoryConfig struct {
+	CPUSize      ByteSize `json:"cpu_size" yaml:"cpu_size"`
+	GPUSize      ByteSize `json:"gpu_size" yaml:"gpu_size"`
+	Log2PageSize uint64   `json:"log2_page_size" yaml:"log2_page_size"`
+
+	// Accesos remotos a una página tras los que la GPU avisa al Driver para
+	// que la migre (ver rdma). 0 = sin contadores de accesos.
+	AccessCounterThreshold uint64 `json:"access_counter_threshold" yaml:"access_counter_threshold"`
+}
+
+// InterconnectConfig describes how the GPUs are connected.
+type InterconnectConfig struct {
+	Topology       string        `json:"topology" yaml:"topology"`
+	CustomTopology string        `json:"custom_topology" yaml:"custom_topology"` // Ver ParseCustomTopology.
+	Optical        OpticalConfig `json:"optical" yaml:"optical"`
+}
+
+// OpticalConfig describes the optical network of the optical topology. The
+// units are the same as the ones of the runner flags.
+type OpticalConfig struct {
+	LinkBandwidthGBps  float64 `json:"link_bandwidth_gbps" yaml:"link_bandwidth_gbps"` // 0 = infinito.
+	Wavelengths        int     `json:"wavelengths" yaml:"wavelengths"`
+	Fabric             string  `json:"fabric" yaml:"fabric"`                 // Ver ParseOpticalFabric.
+	AllowDeadlock      bool    `json:"allow_deadlock" yaml:"allow_deadlock"` // Ver WithOpticalFabricDeadlockAllowed.
+	Traffic            string  `json:"traffic" yaml:"traffic"`               // Ver ParsePortRoles.
+	LaserPowerMW       float64 `json:"laser_power_mw" yaml:"laser_power_mw"`
+	ModulatorEnergyFJ  float64 `json:"modulator_energy_fj" yaml:"modulator_energy_fj"`
+	DetectorEnergyFJ   float64 `json:"detector_energy_fj" yaml:"detector_energy_fj"`
+	SwitchStaticPowerW float64 `json:"switch_static_power_w" yaml:"switch_static_power_w"`
+	ReconfigEnergyNJ   float64 `json:"reconfig_energy_nj" yaml:"reconfig_energy_nj"`
+	CircuitSwitching   bool    `json:"circuit_switching" yaml:"circuit_switching"`
+	ReconfigDelayNs    float64 `json:"reconfig_delay_ns" yaml:"reconfig_delay_ns"`
+	EpochUs            float64 `json:"epoch_us" yaml:"epoch_us"`
+	Policy             string  `json:"policy" yaml:"policy"` // last-epoch o ewma.
+	EWMAAlpha          float64 `json:"ewma_alpha" yaml:"ewma_alpha"`
+}
+
+// DefaultPlatformConfig returns the platform that the runner simulates when
+// no platform file is given.
+func DefaultPlatformConfig() PlatformConfig {
+	power := optical.DefaultPowerModel()
+
+	return PlatformConfig{
+		GPU: GPUConfig{
+			FreqMHz:             1000,
+			NumShaderArrays:     16,
+			NumCUPerShaderArray: 4,
+			Log2CacheLineSize:   6,
+			L2Cache: CacheConfig{
+				Size:        ByteSize(2 * mem.MB),
+				Ways:        16,
+				MSHREntries: 64,
+			},
+			NumMemoryBanks:                 16,
+			Log2MemoryBankInterleavingSize: 7,
+			DRAMLatency:                    100,
+		},
+		Memory: MemoryConfig{
+			CPUSize:      ByteSize(4 * mem.GB),
+			GPUSize:      ByteSize(4 * mem.GB),
+			Log2PageSize: 12,
+		},
+		Interconnect: InterconnectConfig{
+			Topology: "tree",
+			Optical: OpticalConfig{
+				LinkBandwidthGBps:  optical.DefaultLinkBandwidth / 1e9,
+				Wavelengths:        1,
+				Traffic:            "rdma",
+				LaserPowerMW:       power.LaserPower * 1e3,
+				ModulatorEnergyFJ:  power.ModulatorEnergyPerBit * 1e15,
+				DetectorEnergyFJ:   power.DetectorEnergyPerBit * 1e15,
+				SwitchStaticPowerW: power.SwitchStaticPower,
+				ReconfigEnergyNJ:   power.ReconfigurationEnergy * 1e9,
+				ReconfigDelayNs:    820,
+				EpochUs:            10,
+				Policy:             "last-epoch",
+				EWMAAlpha:          0.5,
+			},
+		},
+	}
+}
+
+// LoadPlatformConfig reads a platform file on top of the default platform.
+// Files ending in .json are read as JSON and any other file as YAML.
+func LoadPlatformConfig(path string) (PlatformConfig, error) {
+	c := DefaultPlatformConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&c)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&c)
+	}
+
+	if err != nil {
+		return c, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if err := c.Validate(); err != nil {
+		return c, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return c, nil
+}
+
+// Validate checks that the platform can be built.
+func (c PlatformConfig) Validate() error {
+	g := c.GPU
+	m := c.Memory
+	lineSize := uint64(1) << g.Log2CacheLineSize
+
+	checks := []struct {
+		ok  bool
+		msg string
+	}{
+		{g.FreqMHz > 0, "gpu.freq_mhz must be positive"},
+		{g.NumShaderArrays > 0, "gpu.num_shader_arrays must be positive"},
+		{g.NumCUPerShaderArray > 0, "gpu.num_cu_per_shader_array must be positive"},
+		{g.Log2CacheLineSize >= 2 && g.Log2CacheLineSize <= 12,
+			"gpu.log2_cache_line_size must be between 2 and 12"},
+		{g.NumMemoryBanks > 0, "gpu.num_memory_banks must be positive"},
+		{g.Log2MemoryBankInterleavingSize >= g.Log2CacheLineSize,
+			"gpu.log2_memory_bank_interleaving_size must not be smaller " +
+				"than gpu.log2_cache_line_size"},
+		{g.DRAMLatency > 0, "gpu.dram_latency must be positive"},
+		{g.L2Cache.Ways > 0, "gpu.l2_cache.ways must be positive"},
+		{g.L2Cache.MSHREntries > 0, "gpu.l2_cache.mshr_entries must be positive"},
+		{g.NumMemoryBanks > 0 && g.L2Cache.Ways > 0 && g.L2Cache.Size > 0 &&
+			uint64(g.L2Cache.Size)%(uint64(g.NumMemoryBanks)*
+				uint64(g.L2Cache.Ways)*lineSize) == 0,
+			"gpu.l2_cache.size must be a multiple of " +
+				"num_memory_banks * ways * cache line size"},
+		{m.Log2PageSize >= 12 && m.Log2PageSize <= 21,
+			"memory.log2_page_size must be between 12 and 21"},
+		{m.GPUSize > 0 && uint64(m.GPUSize)%(1<<m.Log2PageSize) == 0,
+			"memory.gpu_size must be a positive multiple of the page size"},
+		{m.CPUSize > 1<<m.Log2PageSize &&
+			uint64(m.CPUSize)%(1<<m.Log2PageSize) == 0,
+			"memory.cpu_size must be a multiple of the page size larger " +
+				"than one page"},
+	}
+
+	for _, check := range checks {
+		if !check.ok {
+			return fmt.Errorf("%s", check.msg)
+		}
+	}
+
+	if err := c.validateGPUOverrides(); err != nil {
+		return err
+	}
+
+	if err := c.Interconnect.validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c PlatformConfig) validateGPUOverrides() error {
+	g := c.GPU
+	l2Granularity := uint64(g.NumMemoryBanks) * uint64(g.L2Cache.Ways) *
+		(uint64(1) << g.Log2CacheLineSize)
+	seen := map[int]bool{}
+
+	for i, o := range c.GPUs {
+		for _, id := range o.IDs {
+			if id < 1 {
+				return fmt.Errorf("gpus[%d].ids: GPU IDs start at 1", i)
+			}
+
+			if seen[id] {
+				return fmt.Errorf("gpus[%d].ids: GPU %d is given twice", i, id)
+			}
+			seen[id] = true
+		}
+
+		checks := []struct {
+			ok  bool
+			msg string
+		}{
+			{len(o.IDs) > 0, "ids must not be empty"},
+			{o.FreqMHz >= 0, "freq_mhz must not be negative"},
+			{o.NumShaderArrays >= 0, "num_shader_arrays must not be negative"},
+			{o.NumCUPerShaderArray >= 0,
+				"num_cu_per_shader_array must not be negative"},
+			{uint64(o.L2CacheSize)%l2Granularity == 0,
+				"l2_cache_size must be a multiple of " +
+					"gpu.num_memory_banks * gpu.l2_cache.ways * cache line size"},
+			{uint64(o.DRAMSize)%(1<<c.Memory.Log2PageSize) == 0,
+				"dram_size must be a multiple of the page size"},
+		}
+
+		for _, check := range checks {
+			if !check.ok {
+				return fmt.Errorf("gpus[%d].%s", i, check.msg)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c InterconnectConfig) validate() error {
+	known := c.Topology == "custom"
+	for _, name := range TopologyNames {
+		known = known || c.Topology == name
+	}
+
+	if !known {
+		return fmt.Errorf("interconnect.topology must be one of %s or custom",
+			strings.Join(TopologyNames, ", "))
+	}
+
+	if c.Topology == "custom" {
+		if _, err := parseCustomTopology(c.CustomTopology); err != nil {
+			return fmt.Errorf("interconnect.custom_topology: %v", err)
+		}
+	}
+
+	o := c.Optical
+	if _, err := parsePortRoles(o.Traffic); err != nil {
+		return fmt.Errorf("interconnect.optical.traffic: %v", err)
+	}
+
+	fabric, err := parseOpticalFabric(o.Fabric)
+	if err != nil {
+		return fmt.Errorf("interconnect.optical.fabric: %v", err)
+	}
+
+	checks := []struct {
+		ok  bool
+		msg string
+	}{
+		{o.LinkBandwidthGBps >= 0,
+			"interconnect.optical.link_bandwidth_gbps must not be negative"},
+		{o.Wavelengths > 0, "interconnect.optical.wavelengths must be positive"},
+		{o.LaserPowerMW >= 0 && o.ModulatorEnergyFJ >= 0 &&
+			o.DetectorEnergyFJ >= 0 && o.SwitchStaticPowerW >= 0 &&
+			o.ReconfigEnergyNJ >= 0,
+			"interconnect.optical power and energy must not be negative"},
+		{o.ReconfigDelayNs >= 0,
+			"interconnect.optical.reconfig_delay_ns must not be negative"},
+		{o.EpochUs > 0, "interconnect.optical.epoch_us must be positive"},
+		{!o.CircuitSwitching || fabric.Kind == "",
+			"interconnect.optical.circuit_switching needs the single " +
+				"optical switch, not a fabric"},
+		{o.Policy == "last-epoch" || o.Policy == "ewma",
+			"interconnect.optical.policy must be last-epoch or ewma"},
+		{o.EWMAAlpha > 0 && o.EWMAAlpha <= 1,
+			"interconnect.optical.ewma_alpha must be in (0, 1]"},
+	}
+
+	for _, check := range checks {
+		if !check.ok {
+			return fmt.Errorf("%s", check.msg)
+		}
+	}
+
+	return nil
+}
+
+// TopologyFor returns the topology selected by the configuration.
+func (c InterconnectConfig) TopologyFor() Topology {
+	if c.Topology == "custom" {
+		return ParseCustomTopology(c.CustomTopology)
+	}
+
+	return TopologyByName(c.Topology)
+}
+
+// OpticalPolicy creates the traffic prediction policy of the optical
+// controller.
+func (o OpticalConfig) OpticalPolicy() optical.PredictionPolicy {
+	if o.Policy == "ewma" {
+		return optical.NewEWMAPolicy(o.EWMAAlpha)
+	}
+
+	return optical.NewLastEpochPolicy()
+}
+
+// PowerModel converts the flag units to the units of optical.PowerModel.
+func (o OpticalConfig) PowerModel() optical.PowerModel {
+	return optical.PowerModel{
+		LaserPower:            o.LaserPowerMW * 1e-3,
+		ModulatorEnergyPerBit: o.ModulatorEnergyFJ * 1e-15,
+		DetectorEnergyPerBit:  o.DetectorEnergyFJ * 1e-15,
+		SwitchStaticPower:     o.SwitchStaticPowerW,
+		ReconfigurationEnergy: o.ReconfigEnergyNJ * 1e-9,
+	}
+}
+
+// WithPlatformConfig sets every hardware parameter from a platform
+// configuration. It panics if the configuration is not valid.
+func (b Builder) WithPlatformConfig(c PlatformConfig) Builder {
+	if err := c.Validate(); err != nil {
+		log.Panic(err)
+	}
+
+	b.freq = sim.Freq(c.GPU.FreqMHz) * sim.MHz
+	b.numSAPerGPU = c.GPU.NumShaderArrays
+	b.numCUPerSA = c.GPU.NumCUPerShaderArray
+	b.log2CacheLineSize = c.GPU.Log2CacheLineSize
+	b.l2CacheSize = uint64(c.GPU.L2Cache.Size)
+	b.l2CacheWays = c.GPU.L2Cache.Ways
+	b.l2CacheMSHREntries = c.GPU.L2Cache.MSHREntries
+	b.numMemoryBank = c.GPU.NumMemoryBanks
+	b.log2MemoryBankInterleavingSize = c.GPU.Log2MemoryBankInterleavingSize
+	b.dramLatency = c.GPU.DRAMLatency
+
+	b.cpuMemSize = uint64(c.Memory.CPUSize)
+	b.gpuMemSize = uint64(c.Memory.GPUSize)
+	b.log2PageSize = c.Memory.Log2PageSize
+	b.accessCounterThreshold = c.Memory.AccessCounterThreshold
+
+	for _, o := range c.GPUs {
+		for _, id := range o.IDs {
+			b = b.WithGPUSpec(id, GPUSpec{
+				Freq:        sim.Freq(o.FreqMHz) * sim.MHz,
+				NumSA:       o.NumShaderArrays,
+				NumCUPerSA:  o.NumCUPerShaderArray,
+				L2CacheSize: uint64(o.L2CacheSize),
+				DRAMSize:    uint64(o.DRAMSize),
+			})
+		}
+	}
+
+	o := c.Interconnect.Optical
+	b = b.WithTopology(c.Interconnect.TopologyFor()).
+		WithOpticalLinkBandwidth(o.LinkBandwidthGBps * 1e9).
+		WithOpticalWavelengths(o.Wavelengths).
+		WithOpticalFabric(ParseOpticalFabric(o.Fabric)).
+		WithOpticalPortRoles(ParsePortRoles(o.Traffic)...).
+		WithOpticalPowerModel(o.PowerModel())
+
+	if o.AllowDeadlock {
+		b = b.WithOpticalFabricDeadlockAllowed()
+	}
+
+	if o.CircuitSwitching {
+		b = b.
+			WithOpticalCircuitSwitching(sim.VTimeInSec(o.ReconfigDelayNs*1e-9)).
+			WithOpticalController(
+				sim.VTimeInSec(o.EpochUs*1e-6), o.OpticalPolicy())
+	}
+
+	return b
+}
+
+// Flatten lists every parameter as "section.key" = value, sorted by key, so
+// that the effective configuration can be stored next to the metrics.
+func (c PlatformConfig) Flatten() [][2]string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		panic(err)
+	}
+
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		panic(err)
+	}
+
+	var params [][2]string
+	flatten("", tree, &params)
+	sort.Slice(params, func(i, j int) bool { return params[i][0] < params[j][0] })
+
+	return params
+}
+
+func flatten(prefix string, v interface{}, params *[][2]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(key, child, params)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), child, params)
+		}
+	case float64:
+		*params = append(*params,
+			[2]string{prefix, strconv.FormatFloat(v, 'g', -1, 64)})
+	default:
+		*params = append(*params, [2]string{prefix, fmt.Sprint(v)})
+	}
+}
+
+// ByteSize is a size in bytes. In a platform file it can be a number of bytes
+// or a string with a unit, like "2MB" or "4GB" (powers of 1024).
+type ByteSize uint64
+
+var byteUnits = []struct {
+	suffix string
+	size   uint64
+}{
+	{"GB", mem.GB},
+	{"MB", mem.MB},
+	{"KB", mem.KB},
+	{"B", 1},
+}
+
+func (s ByteSize) String() string {
+	for _, u := range byteUnits {
+		if s != 0 && uint64(s)%u.size == 0 {
+			return fmt.Sprintf("%d%s", uint64(s)/u.size, u.suffix)
+		}
+	}
+
+	return "0B"
+}
+
+// MarshalText writes the size with the largest exact unit.
+func (s ByteSize) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads a number of bytes or a size with a unit.
+func (s *ByteSize) UnmarshalText(text []byte) error {
+	str := strings.ToUpper(strings.TrimSpace(string(text)))
+
+	multiplier := uint64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(str, u.suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, u.suffix))
+			multiplier = u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseUint(str, 10, 64)
+	if err != nil || n > math.MaxUint64/multiplier {
+		return fmt.Errorf("invalid size %q", string(text))
+	}
+
+	*s = ByteSize(n * multiplier)
+
+	return nil
+}
+
+// UnmarshalJSON accepts both JSON numbers and strings.
+func (s *ByteSize) UnmarshalJSON(data []byte) error {
+	return s.UnmarshalText(bytes.Trim(data, `"`))
+}
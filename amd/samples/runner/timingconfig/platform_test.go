@@ -0,0 +1,277 @@
+package timingconfig
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/mem/mem"
+)
+
+var _ = Describe("Platform Config", func() {
+	writeFile := func(name, content string) string {
+		path := filepath.Join(GinkgoT().TempDir(), name)
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+
+		return path
+	}
+
+	Context("when loading a platform file", func() {
+		It("should read YAML on top of the default platform", func() {
+			path := writeFile("platform.yaml", `
+gpu:
+  freq_mhz: 1500
+  l2_cache:
+    size: 4MB
+memory:
+  gpu_size: 8GB
+gpus:
+  - ids: [2]
+    dram_size: 2GB
+interconnect:
+  topology: optical
+  optical:
+    fabric: torus:4
+    allow_deadlock: true
+`)
+
+			c, err := LoadPlatformConfig(path)
+
+			Expect(err).NotTo(HaveOccurred())
+			expected := DefaultPlatformConfig()
+			expected.GPU.FreqMHz = 1500
+			expected.GPU.L2Cache.Size = ByteSize(4 * mem.MB)
+			expected.Memory.GPUSize = ByteSize(8 * mem.GB)
+			expected.GPUs = []GPUOverride{
+				{IDs: []int{2}, DRAMSize: ByteSize(2 * mem.GB)}}
+			expected.Interconnect.Topology = "optical"
+			expected.Interconnect.Optical.Fabric = "torus:4"
+			expected.Interconnect.Optical.AllowDeadlock = true
+			Expect(c).To(Equal(expected))
+		})
+
+		It("should read JSON with sizes as numbers or strings", func() {
+			path := writeFile("platform.JSON", `{
+				"gpu": {"num_shader_arrays": 8, "l2_cache": {"size": 1048576}},
+				"memory": {"cpu_size": "2GB"}
+			}`)
+
+			c, err := LoadPlatformConfig(path)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.GPU.NumShaderArrays).To(Equal(8))
+			Expect(c.GPU.L2Cache.Size).To(Equal(ByteSize(mem.MB)))
+			Expect(c.Memory.CPUSize).To(Equal(ByteSize(2 * mem.GB)))
+			Expect(c.GPU.NumCUPerShaderArray).To(Equal(4))
+		})
+
+		It("should reject unknown YAML keys", func() {
+			path := writeFile("platform.yaml", "gpu:\n  freq: 1500\n")
+
+			_, err := LoadPlatformConfig(path)
+
+			Expect(err).To(MatchError(ContainSubstring("field freq not found")))
+		})
+
+		It("should reject unknown JSON keys", func() {
+			path := writeFile("platform.json", `{"gpu": {"freq": 1500}}`)
+
+			_, err := LoadPlatformConfig(path)
+
+			Expect(err).To(MatchError(ContainSubstring(`unknown field "freq"`)))
+		})
+
+		It("should reject invalid values", func() {
+			path := writeFile("platform.yaml", "gpu:\n  dram_latency: 0\n")
+
+			_, err := LoadPlatformConfig(path)
+
+			Expect(err).To(MatchError(
+				path + ": gpu.dram_latency must be positive"))
+		})
+
+		It("should fail if the file does not exist", func() {
+			_, err := LoadPlatformConfig(
+				filepath.Join(GinkgoT().TempDir(), "missing.yaml"))
+
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+	})
+
+	DescribeTable("should read byte sizes",
+		func(text string, expected ByteSize) {
+			var s ByteSize
+
+			Expect(s.UnmarshalText([]byte(text))).To(Succeed())
+			Expect(s).To(Equal(expected))
+		},
+		Entry("bytes", "512", ByteSize(512)),
+		Entry("bytes with unit", "512B", ByteSize(512)),
+		Entry("KB", "64KB", ByteSize(64*mem.KB)),
+		Entry("MB with space", " 2 MB ", ByteSize(2*mem.MB)),
+		Entry("lower case GB", "4gb", ByteSize(4*mem.GB)),
+		Entry("zero", "0", ByteSize(0)),
+	)
+
+	DescribeTable("should reject bad byte sizes",
+		func(text string) {
+			var s ByteSize
+
+			Expect(s.UnmarshalText([]byte(text))).To(
+				MatchError(`invalid size "` + text + `"`))
+		},
+		Entry("unknown unit", "4TB"),
+		Entry("unit without B", "4G"),
+		Entry("unit without number", "GB"),
+		Entry("unit before number", "MB4"),
+		Entry("fraction", "1.5GB"),
+		Entry("negative", "-1MB"),
+		Entry("overflow", "17179869184GB"),
+	)
+
+	DescribeTable("should write byte sizes with the largest exact unit",
+		func(s ByteSize, expected string) {
+			Expect(s.String()).To(Equal(expected))
+		},
+		Entry("GB", ByteSize(4*mem.GB), "4GB"),
+		Entry("MB", ByteSize(1536*mem.MB), "1536MB"),
+		Entry("bytes", ByteSize(1000), "1000B"),
+		Entry("zero", ByteSize(0), "0B"),
+	)
+
+	It("should accept the default platform", func() {
+		Expect(DefaultPlatformConfig().Validate()).To(Succeed())
+	})
+
+	DescribeTable("should reject invalid platforms",
+		func(change func(c *PlatformConfig), msg string) {
+			c := DefaultPlatformConfig()
+			change(&c)
+
+			Expect(c.Validate()).To(MatchError(ContainSubstring(msg)))
+		},
+		Entry("frequency", func(c *PlatformConfig) { c.GPU.FreqMHz = 0 },
+			"gpu.freq_mhz must be positive"),
+		Entry("shader arrays",
+			func(c *PlatformConfig) { c.GPU.NumShaderArrays = 0 },
+			"gpu.num_shader_arrays must be positive"),
+		Entry("CUs", func(c *PlatformConfig) { c.GPU.NumCUPerShaderArray = 0 },
+			"gpu.num_cu_per_shader_array must be positive"),
+		Entry("cache line size",
+			func(c *PlatformConfig) { c.GPU.Log2CacheLineSize = 13 },
+			"gpu.log2_cache_line_size must be between 2 and 12"),
+		Entry("memory banks", func(c *PlatformConfig) { c.GPU.NumMemoryBanks = 0 },
+			"gpu.num_memory_banks must be positive"),
+		Entry("bank interleaving",
+			func(c *PlatformConfig) { c.GPU.Log2MemoryBankInterleavingSize = 5 },
+			"gpu.log2_memory_bank_interleaving_size must not be smaller"),
+		Entry("DRAM latency", func(c *PlatformConfig) { c.GPU.DRAMLatency = 0 },
+			"gpu.dram_latency must be positive"),
+		Entry("L2 ways", func(c *PlatformConfig) { c.GPU.L2Cache.Ways = 0 },
+			"gpu.l2_cache.ways must be positive"),
+		Entry("L2 MSHR", func(c *PlatformConfig) { c.GPU.L2Cache.MSHREntries = 0 },
+			"gpu.l2_cache.mshr_entries must be positive"),
+		Entry("L2 size", func(c *PlatformConfig) { c.GPU.L2Cache.Size += 64 },
+			"gpu.l2_cache.size must be a multiple"),
+		Entry("page size", func(c *PlatformConfig) { c.Memory.Log2PageSize = 22 },
+			"memory.log2_page_size must be between 12 and 21"),
+		Entry("GPU memory", func(c *PlatformConfig) { c.Memory.GPUSize++ },
+			"memory.gpu_size must be a positive multiple of the page size"),
+		Entry("CPU memory",
+			func(c *PlatformConfig) { c.Memory.CPUSize = ByteSize(4 * mem.KB) },
+			"memory.cpu_size must be a multiple of the page size larger"),
+
+		Entry("GPU ID 0", func(c *PlatformConfig) {
+			c.GPUs = []GPUOverride{{IDs: []int{0}}}
+		}, "gpus[0].ids: GPU IDs start at 1"),
+		Entry("GPU given twice", func(c *PlatformConfig) {
+			c.GPUs = []GPUOverride{{IDs: []int{1}}, {IDs: []int{2, 1}}}
+		}, "gpus[1].ids: GPU 1 is given twice"),
+		Entry("override without GPUs", func(c *PlatformConfig) {
+			c.GPUs = []GPUOverride{{FreqMHz: 500}}
+		}, "gpus[0].ids must not be empty"),
+		Entry("override frequency", func(c *PlatformConfig) {
+			c.GPUs = []GPUOverride{{IDs: []int{1}, FreqMHz: -1}}
+		}, "gpus[0].freq_mhz must not be negative"),
+		Entry("override shader arrays", func(c *PlatformConfig) {
+			c.GPUs = []GPUOverride{{IDs: []int{1}, NumShaderArrays: -1}}
+		}, "gpus[0].num_shader_arrays must not be negative"),
+		Entry("override CUs", func(c *PlatformConfig) {
+			c.GPUs = []GPUOverride{{IDs: []int{1}, NumCUPerShaderArray: -1}}
+		}, "gpus[0].num_cu_per_shader_array must not be negative"),
+		Entry("override L2 size", func(c *PlatformConfig) {
+			c.GPUs = []GPUOverride{{IDs: []int{1}, L2CacheSize: 1000}}
+		}, "gpus[0].l2_cache_size must be a multiple"),
+		Entry("override DRAM size", func(c *PlatformConfig) {
+			c.GPUs = []GPUOverride{{IDs: []int{1}, DRAMSize: 1000}}
+		}, "gpus[0].dram_size must be a multiple of the page size"),
+
+		Entry("topology", func(c *PlatformConfig) {
+			c.Interconnect.Topology = "ring"
+		}, "interconnect.topology must be one of"),
+		Entry("custom topology", func(c *PlatformConfig) {
+			c.Interconnect.Topology = "custom"
+			c.Interconnect.CustomTopology = "-1"
+		}, "interconnect.custom_topology: custom topology"),
+		Entry("optical traffic", func(c *PlatformConfig) {
+			c.Interconnect.Optical.Traffic = "gpu"
+		}, "interconnect.optical.traffic: unknown port role gpu"),
+		Entry("optical fabric", func(c *PlatformConfig) {
+			c.Interconnect.Optical.Fabric = "ring:4"
+		}, "interconnect.optical.fabric: unknown optical fabric ring"),
+		Entry("optical bandwidth", func(c *PlatformConfig) {
+			c.Interconnect.Optical.LinkBandwidthGBps = -1
+		}, "interconnect.optical.link_bandwidth_gbps must not be negative"),
+		Entry("wavelengths", func(c *PlatformConfig) {
+			c.Interconnect.Optical.Wavelengths = 0
+		}, "interconnect.optical.wavelengths must be positive"),
+		Entry("optical power", func(c *PlatformConfig) {
+			c.Interconnect.Optical.LaserPowerMW = -1
+		}, "interconnect.optical power and energy must not be negative"),
+		Entry("optical energy", func(c *PlatformConfig) {
+			c.Interconnect.Optical.ReconfigEnergyNJ = -1
+		}, "interconnect.optical power and energy must not be negative"),
+		Entry("reconfiguration delay", func(c *PlatformConfig) {
+			c.Interconnect.Optical.ReconfigDelayNs = -1
+		}, "interconnect.optical.reconfig_delay_ns must not be negative"),
+		Entry("epoch", func(c *PlatformConfig) {
+			c.Interconnect.Optical.EpochUs = 0
+		}, "interconnect.optical.epoch_us must be positive"),
+		Entry("circuit switching with a fabric", func(c *PlatformConfig) {
+			c.Interconnect.Optical.CircuitSwitching = true
+			c.Interconnect.Optical.Fabric = "torus:4"
+		}, "interconnect.optical.circuit_switching needs the single"),
+		Entry("prediction policy", func(c *PlatformConfig) {
+			c.Interconnect.Optical.Policy = "oracle"
+		}, "interconnect.optical.policy must be last-epoch or ewma"),
+		Entry("EWMA alpha", func(c *PlatformConfig) {
+			c.Interconnect.Optical.EWMAAlpha = 0
+		}, "interconnect.optical.ewma_alpha must be in (0, 1]"),
+	)
+
+	It("should flatten every parameter with sorted keys", func() {
+		c := DefaultPlatformConfig()
+		c.GPUs = []GPUOverride{{IDs: []int{3, 4}, FreqMHz: 1500}}
+
+		params := c.Flatten()
+
+		Expect(params).To(ContainElements(
+			[2]string{"gpu.freq_mhz", "1000"},
+			[2]string{"gpu.l2_cache.size", "2MB"},
+			[2]string{"gpus[0].freq_mhz", "1500"},
+			[2]string{"gpus[0].ids[0]", "3"},
+			[2]string{"gpus[0].ids[1]", "4"},
+			[2]string{"interconnect.optical.circuit_switching", "false"},
+			[2]string{"interconnect.optical.ewma_alpha", "0.5"},
+			[2]string{"memory.cpu_size", "4GB"},
+		))
+		keys := make([]string, len(params))
+		for i, p := range params {
+			keys[i] = p[0]
+		}
+		Expect(keys).NotTo(ContainElement("gpus[0].num_shader_arrays"))
+		Expect(sort.StringsAreSorted(keys)).To(BeTrue())
+	})
+})
@@ -9,6 +9,7 @@ package r9nano
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/sarchlab/akita/v4/mem/cache/writeback"
 	"github.com/sarchlab/akita/v4/mem/dram"
@@ -35,15 +36,20 @@ type Builder struct {
 	numCUPerShaderArray            int
 	numShaderArray                 int
 	l2CacheSize                    uint64
+	l2CacheWays                    int
+	l2CacheMSHREntries             int
 	numMemoryBank                  int
 	log2CacheLineSize              uint64
 	log2PageSize                   uint64
 	log2MemoryBankInterleavingSize uint64
 	memAddrOffset                  uint64
 	dramSize                       uint64
+	dramLatency                    int
 	globalStorage                  *mem.Storage
 	mmu                            *mmu.Comp
 	rdmaAddressMapper              mem.AddressToPortMapper
+	driverPort                     sim.Port
+	accessCounterThreshold         uint64
 
 	gpu                *sim.Domain
 	cp                 *cp.CommandProcessor
@@ -68,12 +74,15 @@ func MakeBuilder() Builder {
 		numCUPerShaderArray:            4,
 		numShaderArray:                 16,
 		l2CacheSize:                    2 * mem.MB,
+		l2CacheWays:                    16,
+		l2CacheMSHREntries:             64,
 		numMemoryBank:                  16,
 		log2CacheLineSize:              6,
 		log2PageSize:                   12,
 		log2MemoryBankInterleavingSize: 7,
 		memAddrOffset:                  0,
 		dramSize:                       4 * mem.GB,
+		dramLatency:                    100,
 	}
 }
 
@@ -138,6 +147,19 @@ func (b Builder) WithL2CacheSize(size uint64) Builder {
 	return b
 }
 
+// WithL2CacheWayAssociativity sets the way associativity of the L2 cache.
+func (b Builder) WithL2CacheWayAssociativity(ways int) Builder {
+	b.l2CacheWays = ways
+	return b
+}
+
+// WithL2CacheNumMSHREntry sets the number of MSHR entries of each L2 cache
+// bank.
+func (b Builder) WithL2CacheNumMSHREntry(n int) Builder {
+	b.l2CacheMSHREntries = n
+	return b
+}
+
 // WithNumMemoryBank sets the number of memory banks.
 func (b Builder) WithNumMemoryBank(numMemoryBank int) Builder {
 	b.numMemoryBank = numMemoryBank
@@ -150,6 +172,12 @@ func (b Builder) WithDramSize(size uint64) Builder {
 	return b
 }
 
+// WithDRAMLatency sets the latency of the memory controllers, in cycles.
+func (b Builder) WithDRAMLatency(cycles int) Builder {
+	b.dramLatency = cycles
+	return b
+}
+
 // WithMMU sets the MMU that can provide the ultimate address translation.
 func (b Builder) WithMMU(mmu *mmu.Comp) Builder {
 	b.mmu = mmu
@@ -176,6 +204,21 @@ func (b Builder) WithRDMAAddressMapper(mapper mem.AddressToPortMapper) Builder {
 	return b
 }
 
+// WithDriverPort sets the port of the driver that the Command Processor
+// responds to.
+func (b Builder) WithDriverPort(port sim.Port) Builder {
+	b.driverPort = port
+	return b
+}
+
+// WithAccessCounterThreshold sets the number of accesses to a page of remote
+// memory after which the RDMA engine notifies the driver. 0 disables the
+// access counters.
+func (b Builder) WithAccessCounterThreshold(n uint64) Builder {
+	b.accessCounterThreshold = n
+	return b
+}
+
 // Build builds the hardware platform.
 func (b Builder) Build(name string) *sim.Domain {
 	b.name = name
@@ -265,6 +308,11 @@ func (b *Builder) connectL1ToL2() {
 	l1ToL2Conn.PlugIn(b.rdmaEngine.RDMARequestInside)
 	l1ToL2Conn.PlugIn(b.rdmaEngine.RDMADataInside)
 
+	// Las copias entre GPUs del DMA acceden a la memoria como un L1: la
+	// local por la L2 y la de otras GPUs por el RDMA.
+	b.dmaEngine.SetL2DataSource(b.l1AddressMapper)
+	l1ToL2Conn.PlugIn(b.dmaEngine.ToL2)
+
 	for _, l2 := range b.l2Caches {
 		l1ToL2Conn.PlugIn(l2.GetPortByName("Top"))
 	}
@@ -387,46 +435,71 @@ func (b *Builder) connectCPWithCUs() {
 	}
 }
 
+// Los ROB también descartan sus transacciones en el shootdown; si no, las
+// respuestas que nunca llegan bloquean las peticiones que el CU reenvía.
 func (b *Builder) connectCPWithAddressTranslators() {
 	for _, sa := range b.sas {
 		for i := range b.numCUPerShaderArray {
-			at := sa.GetPortByName(fmt.Sprintf("L1VAddrTransCtrl[%d]", i))
-			b.cp.AddressTranslators = append(b.cp.AddressTranslators, at)
-			b.internalConn.PlugIn(at)
+			b.connectCPWithAddressTranslator(
+				sa.GetPortByName(fmt.Sprintf("L1VROBCtrl[%d]", i)))
+			b.connectCPWithAddressTranslator(
+				sa.GetPortByName(fmt.Sprintf("L1VAddrTransCtrl[%d]", i)))
 		}
 
-		l1sAT := sa.GetPortByName("L1SAddrTransCtrl")
-		b.cp.AddressTranslators = append(b.cp.AddressTranslators, l1sAT)
-		b.internalConn.PlugIn(l1sAT)
-
-		l1iAT := sa.GetPortByName("L1IAddrTransCtrl")
-		b.cp.AddressTranslators = append(b.cp.AddressTranslators, l1iAT)
-		b.internalConn.PlugIn(l1iAT)
+		b.connectCPWithAddressTranslator(sa.GetPortByName("L1SROBCtrl"))
+		b.connectCPWithAddressTranslator(sa.GetPortByName("L1SAddrTransCtrl"))
+		b.connectCPWithAddressTranslator(sa.GetPortByName("L1IROBCtrl"))
+		b.connectCPWithAddressTranslator(sa.GetPortByName("L1IAddrTransCtrl"))
 	}
 }
 
+func (b *Builder) connectCPWithAddressTranslator(ctrlPort sim.Port) {
+	b.cp.AddressTranslators = append(b.cp.AddressTranslators, ctrlPort)
+	b.internalConn.PlugIn(ctrlPort)
+}
+
 func (b *Builder) connectCPWithTLBs() {
 	for _, sa := range b.sas {
 		for i := range b.numCUPerShaderArray {
-			tlb := sa.GetPortByName(fmt.Sprintf("L1VTLBCtrl[%d]", i))
-			b.cp.TLBs = append(b.cp.TLBs, tlb)
-			b.internalConn.PlugIn(tlb)
+			l1vTLB := sa.GetPortByName(fmt.Sprintf("L1VTLBCtrl[%d]", i))
+			b.connectCPWithTLB(l1vTLB)
 		}
 
-		l1sTLB := sa.GetPortByName("L1STLBCtrl")
-		b.cp.TLBs = append(b.cp.TLBs, l1sTLB)
-		b.internalConn.PlugIn(l1sTLB)
+		b.connectCPWithTLB(sa.GetPortByName("L1STLBCtrl"))
+		b.connectCPWithTLB(sa.GetPortByName("L1ITLBCtrl"))
+	}
 
-		l1iTLB := sa.GetPortByName("L1ITLBCtrl")
-		b.cp.TLBs = append(b.cp.TLBs, l1iTLB)
-		b.internalConn.PlugIn(l1iTLB)
+	for _, l2TLB := range b.l2TLBs {
+		b.connectCPWithTLB(l2TLB.GetPortByName("Control"))
 	}
+}
 
-	for _, tlb := range b.l2TLBs {
-		ctrlPort := tlb.GetPortByName("Control")
-		b.cp.TLBs = append(b.cp.TLBs, ctrlPort)
-		b.internalConn.PlugIn(ctrlPort)
+func (b *Builder) connectCPWithTLB(ctrlPort sim.Port) {
+	if t, ok := ctrlPort.Component().(*tlb.Comp); ok {
+		disableTLBCtrlMiddleware(t)
 	}
+
+	b.cp.TLBs = append(b.cp.TLBs, ctrlPort)
+	b.internalConn.PlugIn(ctrlPort)
+}
+
+// disableTLBCtrlMiddleware quita el efecto del ctrlMiddleware de la TLB de
+// Akita. Se ejecuta antes que el tlbMiddleware y entra en pánico con los
+// FlushReq y RestartReq del shootdown de la migración de páginas, que el
+// tlbMiddleware sí procesa. Con los mensajes de control no hace nada.
+func disableTLBCtrlMiddleware(t *tlb.Comp) {
+	middlewares := t.Middlewares()
+	for i, m := range middlewares {
+		if reflect.TypeOf(m).String() == "*tlb.ctrlMiddleware" {
+			middlewares[i] = noopMiddleware{}
+		}
+	}
+}
+
+type noopMiddleware struct{}
+
+func (noopMiddleware) Tick() bool {
+	return false
 }
 
 func (b *Builder) connectCPWithCaches() {
@@ -486,9 +559,9 @@ func (b *Builder) buildL2Caches() {
 		WithEngine(b.simulation.GetEngine()).
 		WithFreq(b.freq).
 		WithLog2BlockSize(b.log2CacheLineSize).
-		WithWayAssociativity(16).
+		WithWayAssociativity(b.l2CacheWays).
 		WithByteSize(byteSize).
-		WithNumMSHREntry(64).
+		WithNumMSHREntry(b.l2CacheMSHREntries).
 		WithNumReqPerCycle(16)
 
 	for i := 0; i < b.numMemoryBank; i++ {
@@ -523,7 +596,7 @@ func (b *Builder) buildDRAMControllers() {
 		dram := idealmemcontroller.MakeBuilder().
 			WithEngine(b.simulation.GetEngine()).
 			WithFreq(b.freq).
-			WithLatency(100).
+			WithLatency(b.dramLatency).
 			WithStorage(b.globalStorage).
 			Build(dramName)
 		b.simulation.RegisterComponent(dram)
@@ -536,8 +609,8 @@ func (b *Builder) buildDRAMControllers() {
 }
 
 func (b *Builder) createDramControllerBuilder() dram.Builder {
-	memBankSize := 4 * mem.GB / uint64(b.numMemoryBank)
-	if 4*mem.GB%uint64(b.numMemoryBank) != 0 {
+	memBankSize := b.dramSize / uint64(b.numMemoryBank)
+	if b.dramSize%uint64(b.numMemoryBank) != 0 {
 		panic("GPU memory size is not a multiple of the number of memory banks")
 	}
 
@@ -598,9 +671,13 @@ func (b *Builder) buildRDMAEngine() {
 		WithEngine(b.simulation.GetEngine()).
 		WithFreq(1 * sim.GHz).
 		WithLocalModules(b.l1AddressMapper).
+		WithAccessCounterThreshold(b.accessCounterThreshold).
+		WithLog2PageSize(b.log2PageSize).
 		Build(name)
 
 	b.rdmaEngine.RemoteRDMAAddressTable = b.rdmaAddressMapper
+	// Los contadores de accesos avisan al Driver a través del CP.
+	b.rdmaEngine.NotifyPort = b.cp.ToRDMA.AsRemote()
 
 	b.simulation.RegisterComponent(b.rdmaEngine)
 }
@@ -631,6 +708,8 @@ func (b *Builder) buildCP() {
 		WithFreq(b.freq).
 		WithMonitor(b.simulation.GetMonitor()).
 		Build(b.name + ".CommandProcessor")
+	// El CP responde al Driver en la migración de páginas.
+	b.cp.Driver = b.driverPort
 
 	b.simulation.RegisterComponent(b.cp)
 
@@ -640,12 +719,19 @@ func (b *Builder) buildCP() {
 }
 
 func (b *Builder) buildL2TLB() {
+	// La L2 TLB cubre toda la DRAM, hasta 4GB. Con DRAMs más grandes (16-80GB)
+	// no crece más: los sets se crean al construirla y ocuparían GBs del host.
+	reach := b.dramSize
+	if reach > 4*mem.GB {
+		reach = 4 * mem.GB
+	}
+
 	numWays := 64
 	builder := tlb.MakeBuilder().
 		WithEngine(b.simulation.GetEngine()).
 		WithFreq(b.freq).
 		WithNumWays(numWays).
-		WithNumSets(int(b.dramSize / (1 << b.log2PageSize) / uint64(numWays))).
+		WithNumSets(int(reach / (1 << b.log2PageSize) / uint64(numWays))).
 		WithNumMSHREntry(64).
 		WithNumReqPerCycle(1024).
 		WithPageSize(1 << b.log2PageSize).
@@ -0,0 +1,362 @@
+package timingconfig
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/sarchlab/akita/v4/noc/networking/pcie"
+)
+
+// Topology decides how the GPUs are connected to the host and to each other.
+type Topology interface {
+	// Name returns the name used to select the topology.
+	Name() string
+
+	// PCIeSwitchLatency returns the latency of every PCIe switch, in cycles.
+	PCIeSwitchLatency() int
+
+	// Optical tells if the ports of the roles set with
+	// Builder.WithOpticalPortRoles go through an optical network.
+	Optical() bool
+
+	// ConnectSwitches creates the PCIe switches below the root complex and
+	// returns the switch that each GPU is plugged into (index 0 is GPU[1]).
+	ConnectSwitches(
+		pcieConnector *pcie.Connector,
+		rootComplexID int,
+		numGPUs int,
+	) []int
+}
+
+// TopologyNames lists the topologies that TopologyByName knows.
+var TopologyNames = []string{
+	"tree", "star", "chain", "clique", "mesh", "optical",
+}
+
+// TopologyByName returns one of the built-in topologies. Custom topologies
+// are created with ParseCustomTopology.
+func TopologyByName(name string) Topology {
+	switch name {
+	case "tree":
+		return TreeTopology{GPUsPerSwitch: 2}
+	case "star":
+		return StarTopology{}
+	case "chain":
+		return ChainTopology{}
+	case "clique":
+		return CliqueTopology{}
+	case "mesh":
+		return MeshTopology{Rows: 2}
+	case "optical":
+		return OpticalTopology{PCIe: TreeTopology{GPUsPerSwitch: 2}}
+	default:
+		log.Panicf("unknown topology %s", name)
+		return nil
+	}
+}
+
+// Latencia de los switches PCIe de todas las topologías "reales".
+const defaultPCIeSwitchLatency = 140
+
+// TreeTopology hangs a new PCIe switch from the root complex every
+// GPUsPerSwitch GPUs.
+type TreeTopology struct {
+	GPUsPerSwitch int
+}
+
+// Name returns "tree".
+func (t TreeTopology) Name() string { return "tree" }
+
+// PCIeSwitchLatency returns the default PCIe switch latency.
+func (t TreeTopology) PCIeSwitchLatency() int { return defaultPCIeSwitchLatency }
+
+// Optical returns false.
+func (t TreeTopology) Optical() bool { return false }
+
+// ConnectSwitches creates one switch per GPUsPerSwitch GPUs.
+func (t TreeTopology) ConnectSwitches(
+	pcieConnector *pcie.Connector,
+	rootComplexID int,
+	numGPUs int,
+) []int {
+	if t.GPUsPerSwitch <= 0 {
+		log.Panicf("tree topology needs at least 1 GPU per switch")
+	}
+
+	switches := make([]int, numGPUs)
+	lastSwitchID := rootComplexID
+	for i := 0; i < numGPUs; i++ {
+		if i%t.GPUsPerSwitch == 0 {
+			lastSwitchID = pcieConnector.AddSwitch(rootComplexID)
+		}
+
+		switches[i] = lastSwitchID
+	}
+
+	return switches
+}
+
+// StarTopology plugs every GPU into a single PCIe switch.
+type StarTopology struct{}
+
+// Name returns "star".
+func (t StarTopology) Name() string { return "star" }
+
+// PCIeSwitchLatency returns the default PCIe switch latency.
+func (t StarTopology) PCIeSwitchLatency() int { return defaultPCIeSwitchLatency }
+
+// Optical returns false.
+func (t StarTopology) Optical() bool { return false }
+
+// ConnectSwitches creates the only switch.
+func (t StarTopology) ConnectSwitches(
+	pcieConnector *pcie.Connector,
+	rootComplexID int,
+	numGPUs int,
+) []int {
+	uniqueSwitchID := pcieConnector.AddSwitch(rootComplexID)
+
+	switches := make([]int, numGPUs)
+	for i := range switches {
+		switches[i] = uniqueSwitchID
+	}
+
+	return switches
+}
+
+// CliqueTopology is a star with ideal switches, as if every GPU had a direct
+// link to every other GPU.
+type CliqueTopology struct{}
+
+// Name returns "clique".
+func (t CliqueTopology) Name() string { return "clique" }
+
+// PCIeSwitchLatency returns 1 cycle.
+func (t CliqueTopology) PCIeSwitchLatency() int { return 1 } // Simulando perfección.
+
+// Optical returns false.
+func (t CliqueTopology) Optical() bool { return false }
+
+// ConnectSwitches creates the only switch.
+func (t CliqueTopology) ConnectSwitches(
+	pcieConnector *pcie.Connector,
+	rootComplexID int,
+	numGPUs int,
+) []int {
+	return StarTopology{}.ConnectSwitches(pcieConnector, rootComplexID, numGPUs)
+}
+
+// ChainTopology gives each GPU its own PCIe switch, connected to the switch of
+// the previous GPU.
+type ChainTopology struct{}
+
+// Name returns "chain".
+func (t ChainTopology) Name() string { return "chain" }
+
+// PCIeSwitchLatency returns the default PCIe switch latency.
+func (t ChainTopology) PCIeSwitchLatency() int { return defaultPCIeSwitchLatency }
+
+// Optical returns false.
+func (t ChainTopology) Optical() bool { return false }
+
+// ConnectSwitches creates one switch per GPU.
+func (t ChainTopology) ConnectSwitches(
+	pcieConnector *pcie.Connector,
+	rootComplexID int,
+	numGPUs int,
+) []int {
+	switches := make([]int, numGPUs)
+	lastSwitchID := rootComplexID
+	for i := range switches {
+		// Creamos un nuevo switch conectado al anterior.
+		lastSwitchID = pcieConnector.AddSwitch(lastSwitchID)
+		switches[i] = lastSwitchID
+	}
+
+	return switches
+}
+
+// MeshTopology lays the GPUs out in Rows rows. The GPUs of a row share a PCIe
+// switch connected to the root complex.
+type MeshTopology struct {
+	Rows int
+}
+
+// Name returns "mesh".
+func (t MeshTopology) Name() string { return "mesh" }
+
+// PCIeSwitchLatency returns the default PCIe switch latency.
+func (t MeshTopology) PCIeSwitchLatency() int { return defaultPCIeSwitchLatency }
+
+// Optical returns false.
+func (t MeshTopology) Optical() bool { return false }
+
+// ConnectSwitches creates one switch per row and fills the rows in order.
+func (t MeshTopology) ConnectSwitches(
+	pcieConnector *pcie.Connector,
+	rootComplexID int,
+	numGPUs int,
+) []int {
+	if t.Rows <= 0 {
+		log.Panicf("mesh topology needs at least 1 row")
+	}
+
+	numCols := (numGPUs + t.Rows - 1) / t.Rows // Columnas necesarias.
+
+	rowSwitches := make([]int, t.Rows)
+	for i := range rowSwitches {
+		rowSwitches[i] = pcieConnector.AddSwitch(rootComplexID)
+	}
+
+	switches := make([]int, numGPUs)
+	for i := range switches {
+		switches[i] = rowSwitches[i/numCols]
+	}
+
+	return switches
+}
+
+// OpticalTopology connects the GPUs with a PCIe topology and adds an optical
+// network for the ports of the optical roles.
+type OpticalTopology struct {
+	PCIe Topology
+}
+
+// Name returns "optical".
+func (t OpticalTopology) Name() string { return "optical" }
+
+// PCIeSwitchLatency returns the latency of the PCIe topology.
+func (t OpticalTopology) PCIeSwitchLatency() int { return t.PCIe.PCIeSwitchLatency() }
+
+// Optical returns true.
+func (t OpticalTopology) Optical() bool { return true }
+
+// ConnectSwitches builds the PCIe topology.
+func (t OpticalTopology) ConnectSwitches(
+	pcieConnector *pcie.Connector,
+	rootComplexID int,
+	numGPUs int,
+) []int {
+	return t.PCIe.ConnectSwitches(pcieConnector, rootComplexID, numGPUs)
+}
+
+// CustomTopology is a tree of PCIe switches given by hand.
+type CustomTopology struct {
+	// SwitchParents[i] is the switch that switch i hangs from, or -1 for the
+	// root complex. A switch can only hang from a previous one.
+	SwitchParents []int
+
+	// GPUSwitches[i] is the switch of GPU[i+1].
+	GPUSwitches []int
+
+	SwitchLatency int
+}
+
+// ParseCustomTopology parses a custom topology written as
+// "<switch parents>/<gpu switches>". For example, "-1,0,0/1,1,2,2" hangs
+// switch 0 from the root complex and switches 1 and 2 from switch 0, and
+// plugs GPU[1] and GPU[2] into switch 1 and GPU[3] and GPU[4] into switch 2.
+func ParseCustomTopology(spec string) CustomTopology {
+	t, err := parseCustomTopology(spec)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return t
+}
+
+func parseCustomTopology(spec string) (CustomTopology, error) {
+	parts := strings.Split(spec, "/")
+	if len(parts) != 2 {
+		return CustomTopology{}, fmt.Errorf(
+			"custom topology %q must be <switch parents>/<gpu switches>", spec)
+	}
+
+	parents, err := parseIntList(parts[0])
+	if err != nil {
+		return CustomTopology{}, err
+	}
+
+	gpuSwitches, err := parseIntList(parts[1])
+	if err != nil {
+		return CustomTopology{}, err
+	}
+
+	t := CustomTopology{
+		SwitchParents: parents,
+		GPUSwitches:   gpuSwitches,
+		SwitchLatency: defaultPCIeSwitchLatency,
+	}
+
+	for i, parent := range t.SwitchParents {
+		if parent < -1 || parent >= i {
+			return CustomTopology{}, fmt.Errorf(
+				"switch %d of custom topology %q has invalid parent %d",
+				i, spec, parent)
+		}
+	}
+
+	for i, sw := range t.GPUSwitches {
+		if sw < 0 || sw >= len(t.SwitchParents) {
+			return CustomTopology{}, fmt.Errorf(
+				"GPU[%d] of custom topology %q has invalid switch %d",
+				i+1, spec, sw)
+		}
+	}
+
+	return t, nil
+}
+
+func parseIntList(s string) ([]int, error) {
+	list := []int{}
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %v", field, err)
+		}
+
+		list = append(list, n)
+	}
+
+	return list, nil
+}
+
+// Name returns "custom".
+func (t CustomTopology) Name() string { return "custom" }
+
+// PCIeSwitchLatency returns SwitchLatency.
+func (t CustomTopology) PCIeSwitchLatency() int { return t.SwitchLatency }
+
+// Optical returns false.
+func (t CustomTopology) Optical() bool { return false }
+
+// ConnectSwitches creates the switches in order.
+func (t CustomTopology) ConnectSwitches(
+	pcieConnector *pcie.Connector,
+	rootComplexID int,
+	numGPUs int,
+) []int {
+	if numGPUs > len(t.GPUSwitches) {
+		log.Panicf("custom topology has %d GPUs, but %d are simulated",
+			len(t.GPUSwitches), numGPUs)
+	}
+
+	switchIDs := make([]int, len(t.SwitchParents))
+	for i, parent := range t.SwitchParents {
+		parentID := rootComplexID
+		if parent >= 0 {
+			parentID = switchIDs[parent]
+		}
+
+		switchIDs[i] = pcieConnector.AddSwitch(parentID)
+	}
+
+	switches := make([]int, numGPUs)
+	for i := range switches {
+		switches[i] = switchIDs[t.GPUSwitches[i]]
+	}
+
+	return switches
+}
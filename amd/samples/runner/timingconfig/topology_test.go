@@ -0,0 +1,61 @@
+package timingconfig
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Custom Topology", func() {
+	It("should parse the switch parents and the GPU switches", func() {
+		t, err := parseCustomTopology(" -1, 0,0 /1,1,2,2")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(t.SwitchParents).To(Equal([]int{-1, 0, 0}))
+		Expect(t.GPUSwitches).To(Equal([]int{1, 1, 2, 2}))
+		Expect(t.SwitchLatency).To(Equal(defaultPCIeSwitchLatency))
+	})
+
+	DescribeTable("should reject malformed specs",
+		func(spec, msg string) {
+			_, err := parseCustomTopology(spec)
+
+			Expect(err).To(MatchError(ContainSubstring(msg)))
+		},
+		Entry("no GPU switches", "-1,0",
+			"must be <switch parents>/<gpu switches>"),
+		Entry("too many parts", "-1/0/0",
+			"must be <switch parents>/<gpu switches>"),
+		Entry("empty switch parents", "/0", `invalid number ""`),
+		Entry("empty GPU switches", "-1/", `invalid number ""`),
+		Entry("trailing comma", "-1,0,/1", `invalid number ""`),
+		Entry("not a number", "-1,a/0", `invalid number "a"`),
+	)
+
+	DescribeTable("should reject unknown switches",
+		func(spec, msg string) {
+			_, err := parseCustomTopology(spec)
+
+			Expect(err).To(MatchError(ContainSubstring(msg)))
+		},
+		Entry("parent below the root complex", "-2/0",
+			"switch 0 of custom topology \"-2/0\" has invalid parent -2"),
+		Entry("parent that does not exist", "-1,5/0",
+			"switch 1 of custom topology \"-1,5/0\" has invalid parent 5"),
+		Entry("negative GPU switch", "-1/-1",
+			"GPU[1] of custom topology \"-1/-1\" has invalid switch -1"),
+		Entry("GPU switch that does not exist", "-1,0/1,2",
+			"GPU[2] of custom topology \"-1,0/1,2\" has invalid switch 2"),
+	)
+
+	DescribeTable("should reject links that do not make a tree",
+		func(spec, msg string) {
+			_, err := parseCustomTopology(spec)
+
+			Expect(err).To(MatchError(ContainSubstring(msg)))
+		},
+		Entry("switch linked to itself", "-1,1/0",
+			"switch 1 of custom topology \"-1,1/0\" has invalid parent 1"),
+		Entry("loop between two switches", "-1,2,1/0",
+			"switch 1 of custom topology \"-1,2,1/0\" has invalid parent 2"),
+	)
+})
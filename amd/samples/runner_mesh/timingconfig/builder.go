@@ -1,307 +0,0 @@
-// Package timingconfig contains the configuration for the timing simulation.
-package timingconfig
-
-import (
-	"fmt"
-
-	"github.com/sarchlab/akita/v4/mem/mem"
-	"github.com/sarchlab/akita/v4/mem/vm"
-	"github.com/sarchlab/akita/v4/mem/vm/mmu"
-	"github.com/sarchlab/akita/v4/noc/networking/pcie"
-	"github.com/sarchlab/akita/v4/sim"
-	"github.com/sarchlab/akita/v4/simulation"
-	"github.com/sarchlab/mgpusim/v4/amd/driver"
-	"github.com/sarchlab/mgpusim/v4/amd/samples/runner/timingconfig/r9nano"
-)
-
-// Builder builds a hardware platform for timing simulation.
-type Builder struct {
-	simulation *simulation.Simulation
-
-	numGPUs            int
-	numCUPerSA         int // Num Compute Units por Shader Array.
-	numSAPerGPU        int // Num Shader Array por GPU.
-	cpuMemSize         uint64
-	gpuMemSize         uint64
-	log2PageSize       uint64
-	useMagicMemoryCopy bool
-
-	platform          *sim.Domain
-	globalStorage     *mem.Storage
-	rdmaAddressMapper *mem.BankedAddressPortMapper
-}
-
-// MakeBuilder creates a new Builder with default parameters.
-func MakeBuilder() Builder {
-	return Builder{
-		numGPUs:            1,
-		numCUPerSA:         4,
-		numSAPerGPU:        16,
-		cpuMemSize:         4 * mem.GB,
-		gpuMemSize:         4 * mem.GB,
-		log2PageSize:       12,
-		useMagicMemoryCopy: false,
-	}
-}
-
-// WithSimulation sets the simulation to use.
-func (b Builder) WithSimulation(sim *simulation.Simulation) Builder {
-	b.simulation = sim
-	return b
-}
-
-// WithNumGPUs sets the number of GPUs to simulate.
-func (b Builder) WithNumGPUs(numGPUs int) Builder {
-	b.numGPUs = numGPUs
-	return b
-}
-
-// WithMagicMemoryCopy sets whether to use the magic memory copy middleware.
-func (b Builder) WithMagicMemoryCopy() Builder {
-	b.useMagicMemoryCopy = true
-	return b
-}
-
-// Build builds the hardware platform.
-func (b Builder) Build() *sim.Domain {
-	b.cpuGPUMemSizeMustEqual()
-
-	b.platform = &sim.Domain{}
-
-	b.globalStorage = mem.NewStorage( // Espacio de mem física TOTAL (para CPU y GPUs).
-		uint64(b.numGPUs)*b.gpuMemSize + b.cpuMemSize)
-
-	mmuComp, pageTable := b.createMMU()      // Crea la MMU y PT.
-	gpuDriver := b.buildGPUDriver(pageTable) // Driver de la GPU.
-
-	gpuBuilder := b.createGPUBuilder(gpuDriver, mmuComp) // Constructor de GPU a partir del paquete 'r9nano'.
-
-	// Crea el conector PCIe, el Root Complex (punto de origen de PCIe en la CPU),
-	// y la red PCIe a la que se conectarán las GPUs.
-	pcieConnector, rootComplexID :=
-		b.createConnection(gpuDriver, mmuComp)
-
-	mmuComp.MigrationServiceProvider = gpuDriver.GetPortByName("MMU").AsRemote()
-
-	b.createRDMAAddrTable()
-	pmcAddressTable := b.createPMCPageTable()
-
-	b.createGPUs( // Se crean e interconectan las GPUs según la lógica definida.
-		rootComplexID, pcieConnector,
-		gpuBuilder, gpuDriver,
-		pmcAddressTable)
-
-	pcieConnector.EstablishRoute()
-
-	return b.platform
-}
-
-func (b *Builder) cpuGPUMemSizeMustEqual() {
-	if b.cpuMemSize != b.gpuMemSize {
-		panic("currently only support cpuMemSize == gpuMemSize")
-	}
-}
-
-func (b *Builder) createMMU() (*mmu.Comp, vm.PageTable) {
-	pageTable := vm.NewPageTable(b.log2PageSize)
-	mmuBuilder := mmu.MakeBuilder().
-		WithEngine(b.simulation.GetEngine()).
-		WithFreq(1 * sim.GHz).
-		WithPageWalkingLatency(100).
-		WithLog2PageSize(b.log2PageSize).
-		WithPageTable(pageTable)
-
-	mmuComponent := mmuBuilder.Build("MMU")
-
-	b.simulation.RegisterComponent(mmuComponent)
-
-	return mmuComponent, pageTable
-}
-
-func (b *Builder) buildGPUDriver(
-	pageTable vm.PageTable,
-) *driver.Driver {
-	gpuDriverBuilder := driver.MakeBuilder()
-
-	if b.useMagicMemoryCopy {
-		gpuDriverBuilder = gpuDriverBuilder.WithMagicMemoryCopyMiddleware()
-	}
-
-	gpuDriver := gpuDriverBuilder.
-		WithEngine(b.simulation.GetEngine()).
-		WithPageTable(pageTable).
-		WithLog2PageSize(b.log2PageSize).
-		WithGlobalStorage(b.globalStorage).
-		WithD2HCycles(8500).
-		WithH2DCycles(14500).
-		Build("Driver")
-
-	b.simulation.RegisterComponent(gpuDriver)
-
-	return gpuDriver
-}
-
-func (b *Builder) createGPUBuilder(
-	gpuDriver *driver.Driver,
-	mmuComponent *mmu.Comp,
-) r9nano.Builder {
-	gpuBuilder := r9nano.MakeBuilder().
-		WithFreq(1 * sim.GHz).
-		WithSimulation(b.simulation).
-		WithMMU(mmuComponent).
-		WithNumCUPerShaderArray(b.numCUPerSA).
-		WithNumShaderArray(b.numSAPerGPU).
-		WithNumMemoryBank(16).
-		WithLog2MemoryBankInterleavingSize(7).
-		WithLog2PageSize(b.log2PageSize).
-		WithGlobalStorage(b.globalStorage)
-
-	b.createRDMAAddressMapper()
-
-	// gpuBuilder = b.setMemTracer(gpuBuilder)
-	// gpuBuilder = b.setISADebugger(gpuBuilder)
-
-	return gpuBuilder
-}
-
-func (b *Builder) createGPUs(
-	rootComplexID int,
-	pcieConnector *pcie.Connector,
-	gpuBuilder r9nano.Builder,
-	gpuDriver *driver.Driver,
-	pmcAddressTable *mem.BankedAddressPortMapper,
-) {
-
-	const numRows = 2                              // Dimension de la topología.
-	numCols := (b.numGPUs + numRows - 1) / numRows // Columnas necesarias.
-
-	// Todos los switches estarán conectados al Root Complex.
-	rowSwitches := make([]int, numRows)
-	for i := 0; i < numRows; i++ {
-		rowSwitches[i] = pcieConnector.AddSwitch(rootComplexID)
-	}
-
-	// Crear GPUs y conectarlas a su switch de fila.
-	gpuID := 1
-	for f := 0; f < numRows; f++ { // Iterar por filas
-		for c := 0; c < numCols; c++ { // Iterar por columnas
-
-			// Salir si ya hemos creado todas las GPUs
-			if gpuID > b.numGPUs {
-				break
-			}
-
-			// Conectar esta GPU al switch de su fila
-			b.createGPU(gpuID, gpuBuilder, gpuDriver, pmcAddressTable,
-				pcieConnector, rowSwitches[f])
-
-			gpuID++
-		}
-	}
-}
-
-func (b *Builder) createPMCPageTable() *mem.BankedAddressPortMapper {
-	pmcAddressTable := new(mem.BankedAddressPortMapper)
-	pmcAddressTable.BankSize = 4 * mem.GB
-	pmcAddressTable.LowModules = append(pmcAddressTable.LowModules, "")
-	return pmcAddressTable
-}
-
-func (b *Builder) createRDMAAddrTable() *mem.BankedAddressPortMapper {
-	rdmaAddressTable := new(mem.BankedAddressPortMapper)
-	rdmaAddressTable.BankSize = 4 * mem.GB
-	rdmaAddressTable.LowModules = append(rdmaAddressTable.LowModules, "")
-	return rdmaAddressTable
-}
-
-func (b *Builder) createConnection(
-	gpuDriver *driver.Driver,
-	mmuComponent *mmu.Comp,
-) (*pcie.Connector, int) {
-	// Utiliza un pcie.Connector para modelar una red PCIe versión 4 con 16 lanes
-	// (WithVersion(4, 16)) y una latencia de switch de 140 ciclos (WithSwitchLatency(140)).
-
-	// connection := sim.NewDirectConnection(engine)
-	// connection := noc.NewFixedBandwidthConnection(32, engine, 1*sim.GHz)
-	// connection.SrcBufferCapacity = 40960000
-	pcieConnector := pcie.NewConnector().
-		WithEngine(b.simulation.GetEngine()).
-		WithVersion(4, 16).
-		WithSwitchLatency(140)
-
-	pcieConnector.CreateNetwork("PCIe")
-
-	// Creación del Root Complex que conecta los puertos del driver y de la MMU.
-	rootComplexID := pcieConnector.AddRootComplex(
-		[]sim.Port{
-			gpuDriver.GetPortByName("GPU"),
-			gpuDriver.GetPortByName("MMU"),
-			mmuComponent.GetPortByName("Migration"),
-			mmuComponent.GetPortByName("Top"),
-		})
-
-	return pcieConnector, rootComplexID
-}
-
-func (b *Builder) createRDMAAddressMapper() {
-	b.rdmaAddressMapper = new(mem.BankedAddressPortMapper)
-	b.rdmaAddressMapper.BankSize = b.gpuMemSize
-	b.rdmaAddressMapper.LowModules = append(b.rdmaAddressMapper.LowModules,
-		sim.RemotePort("CPU"))
-}
-
-func (b *Builder) createGPU(
-	index int,
-	gpuBuilder r9nano.Builder, // Utiliza la plantilla de 'r9nano' para construir un GPU con nombre y ID único.
-	gpuDriver *driver.Driver,
-	pmcAddressTable *mem.BankedAddressPortMapper,
-	pcieConnector *pcie.Connector,
-	pcieSwitchID int,
-) *sim.Domain {
-	name := fmt.Sprintf("GPU[%d]", index)
-	memAddrOffset := uint64(index) * 4 * mem.GB // Asigna un desplazamiento de dirección de memoria
-	gpu := gpuBuilder.
-		WithGPUID(uint64(index)).
-		WithMemAddrOffset(memAddrOffset).
-		WithRDMAAddressMapper(b.rdmaAddressMapper).
-		Build(name)
-
-	gpuDriver.RegisterGPU(
-		gpu.GetPortByName("CommandProcessor"),
-		driver.DeviceProperties{
-			CUCount:  b.numCUPerSA * b.numSAPerGPU,
-			DRAMSize: 4 * mem.GB,
-		},
-	)
-	// gpu.CommandProcessor.Driver = gpuDriver.GetPortByName("GPU")
-
-	b.configRDMAEngine(gpu)
-	// b.configPMC(gpu, gpuDriver, pmcAddressTable)
-
-	pcieConnector.PlugInDevice(pcieSwitchID, gpu.Ports())
-
-	// b.gpus = append(b.gpus, gpu)
-
-	return gpu
-}
-
-func (b *Builder) configRDMAEngine(
-	gpu *sim.Domain,
-) {
-	b.rdmaAddressMapper.LowModules = append(
-		b.rdmaAddressMapper.LowModules,
-		gpu.GetPortByName("RDMAData").AsRemote())
-}
-
-// func (b *Builder) configPMC(
-// 	gpu *GPU,
-// 	gpuDriver *driver.Driver,
-// 	addrTable *mem.BankedAddressPortMapper,
-// ) {
-// 	gpu.PMC.RemotePMCAddressTable = addrTable
-// 	addrTable.LowModules = append(
-// 		addrTable.LowModules,
-// 		gpu.PMC.GetPortByName("Remote").AsRemote())
-// 	gpuDriver.RemotePMCPorts = append(
-// 		gpuDriver.RemotePMCPorts, gpu.PMC.GetPortByName("Remote"))
-// }
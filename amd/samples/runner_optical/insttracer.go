@@ -1,75 +0,0 @@
-package runner
-
-import (
-	"github.com/sarchlab/akita/v4/tracing"
-	"github.com/tebeka/atexit"
-)
-
-// instTracer can trace the NUMBER OF INSTRUCTIONS completed.
-type instTracer struct {
-	count     uint64
-	simdInst  bool
-	simdCount uint64
-	maxCount  uint64 // Detener la simulación tras la ejecución de X instr.
-
-	inflightInst map[string]tracing.Task
-}
-
-// newInstTracer creates a tracer that can count the number of instructions.
-func newInstTracer() *instTracer {
-	t := &instTracer{
-		inflightInst: map[string]tracing.Task{},
-	}
-	return t
-}
-
-// newInstStopper with stop the execution after a given number of instructions
-// is retired.
-func newInstStopper(maxInst uint64) *instTracer {
-	t := &instTracer{
-		maxCount:     maxInst,
-		inflightInst: map[string]tracing.Task{},
-	}
-	return t
-}
-
-func (t *instTracer) StartTask(task tracing.Task) {
-	if task.Kind != "inst" {
-		return
-	}
-
-	if task.What == "VALU" {
-		t.simdInst = true
-	} else {
-		t.simdInst = false
-	}
-
-	t.inflightInst[task.ID] = task
-}
-
-func (t *instTracer) StepTask(task tracing.Task) {
-	// Do nothing
-}
-
-func (t *instTracer) AddMilestone(milestone tracing.Milestone) {
-	// Do nothing
-}
-
-func (t *instTracer) EndTask(task tracing.Task) {
-	_, found := t.inflightInst[task.ID]
-	if !found {
-		return
-	}
-
-	if t.simdInst {
-		t.simdCount++
-	}
-
-	delete(t.inflightInst, task.ID)
-
-	t.count++
-
-	if t.maxCount > 0 && t.count >= t.maxCount {
-		atexit.Exit(0)
-	}
-}
@@ -78,6 +78,8 @@ func (b Builder) Build(name string) *CommandProcessor {
 		make(map[string]*protocol.MemCopyH2DReq)
 	cp.bottomMemCopyD2HReqIDToTopReqMap =
 		make(map[string]*protocol.MemCopyD2HReq)
+	cp.bottomMemCopyPeerReqIDToTopReqMap =
+		make(map[string]*protocol.MemCopyPeerReq)
 
 	b.buildDispatchers(cp)
 
@@ -51,6 +51,7 @@ type CommandProcessor struct {
 	bottomKernelLaunchReqIDToTopReqMap map[string]*protocol.LaunchKernelReq
 	bottomMemCopyH2DReqIDToTopReqMap   map[string]*protocol.MemCopyH2DReq
 	bottomMemCopyD2HReqIDToTopReqMap   map[string]*protocol.MemCopyD2HReq
+	bottomMemCopyPeerReqIDToTopReqMap  map[string]*protocol.MemCopyPeerReq
 
 	middleware     *cpMiddleware
 	ctrlMiddleware *ctrlMiddleware
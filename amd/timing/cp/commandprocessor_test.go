@@ -213,6 +213,28 @@ var _ = Describe("CommandProcessor", func() {
 		Expect(madeProgress).To(BeTrue())
 	})
 
+	It("should forward an access counter notification to the driver",
+		func() {
+			notification := rdma.AccessCounterNotificationBuilder{}.
+				WithPAddr(0x1000).
+				WithNumAccesses(4).
+				Build()
+
+			toDriver.EXPECT().
+				Send(gomock.AssignableToTypeOf(
+					&protocol.AccessCounterNotificationToDriver{})).
+				Do(func(msg *protocol.AccessCounterNotificationToDriver) {
+					Expect(msg.PAddr).To(Equal(uint64(0x1000)))
+					Expect(msg.NumAccesses).To(Equal(uint64(4)))
+				})
+			toRDMA.EXPECT().RetrieveIncoming()
+
+			madeProgress := commandProcessor.ctrlMiddleware.
+				processAccessCounterNotification(notification)
+
+			Expect(madeProgress).To(BeTrue())
+		})
+
 	It("should handle a shootdown cmd from Driver", func() {
 		vAddr := make([]uint64, 0)
 		vAddr = append(vAddr, 100)
@@ -28,7 +28,8 @@ func (m *cpMiddleware) Handle() bool {
 		return m.processLaunchKernelReq(req)
 	case *protocol.FlushReq:
 		return m.processFlushReq(req)
-	case *protocol.MemCopyH2DReq, *protocol.MemCopyD2HReq:
+	case *protocol.MemCopyH2DReq, *protocol.MemCopyD2HReq,
+		*protocol.MemCopyPeerReq:
 		return m.processMemCopyReq(req)
 	}
 	return false
@@ -91,6 +92,12 @@ func (m *cpMiddleware) findAndRemoveOriginalMemCopyRequest(
 		return originalD2HReq
 	}
 
+	originalPeerReq, ok := m.bottomMemCopyPeerReqIDToTopReqMap[rspTo]
+	if ok {
+		delete(m.bottomMemCopyPeerReqIDToTopReqMap, rspTo)
+		return originalPeerReq
+	}
+
 	panic("never")
 }
 
@@ -132,19 +139,19 @@ func (m *cpMiddleware) processFlushReq(
 	}
 
 	for _, port := range m.L1ICaches {
-		m.flushCache(port)
+		m.flushCache(port, req.InvalidateCaches)
 	}
 
 	for _, port := range m.L1SCaches {
-		m.flushCache(port)
+		m.flushCache(port, req.InvalidateCaches)
 	}
 
 	for _, port := range m.L1VCaches {
-		m.flushCache(port)
+		m.flushCache(port, req.InvalidateCaches)
 	}
 
 	for _, port := range m.L2Caches {
-		m.flushCache(port)
+		m.flushCache(port, req.InvalidateCaches)
 	}
 
 	m.currFlushRequest = req
@@ -177,6 +184,8 @@ func (m *cpMiddleware) processMemCopyReq(
 		cloned = m.cloneMemCopyH2DReq(req)
 	case *protocol.MemCopyD2HReq:
 		cloned = m.cloneMemCopyD2HReq(req)
+	case *protocol.MemCopyPeerReq:
+		cloned = m.cloneMemCopyPeerReq(req)
 	default:
 		panic("unknown type")
 	}
@@ -211,11 +220,25 @@ func (m *cpMiddleware) cloneMemCopyD2HReq(
 	return &cloned
 }
 
-func (m *cpMiddleware) flushCache(port sim.Port) {
-	flushReq := cache.FlushReqBuilder{}.
+func (m *cpMiddleware) cloneMemCopyPeerReq(
+	req *protocol.MemCopyPeerReq,
+) *protocol.MemCopyPeerReq {
+	cloned := *req
+	cloned.ID = sim.GetIDGenerator().Generate()
+	m.bottomMemCopyPeerReqIDToTopReqMap[cloned.ID] = req
+	return &cloned
+}
+
+func (m *cpMiddleware) flushCache(port sim.Port, invalidate bool) {
+	builder := cache.FlushReqBuilder{}.
 		WithSrc(m.ToCaches.AsRemote()).
-		WithDst(port.AsRemote()).
-		Build()
+		WithDst(port.AsRemote())
+
+	if invalidate {
+		builder = builder.InvalidateAllCacheLines()
+	}
+
+	flushReq := builder.Build()
 
 	err := m.ToCaches.Send(flushReq)
 	if err != nil {
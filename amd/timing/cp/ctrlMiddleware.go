@@ -62,6 +62,8 @@ func (m *ctrlMiddleware) processRspFromRDMAs() bool {
 		return m.processRDMADrainRsp(req)
 	case *rdma.RestartRsp:
 		return m.processRDMARestartRsp(req)
+	case *rdma.AccessCounterNotification:
+		return m.processAccessCounterNotification(req)
 	}
 
 	panic("never")
@@ -318,6 +320,22 @@ func (m *ctrlMiddleware) processRDMARestartRsp(rsp *rdma.RestartRsp) bool {
 	return true
 }
 
+func (m *ctrlMiddleware) processAccessCounterNotification(
+	notification *rdma.AccessCounterNotification,
+) bool {
+	msg := protocol.NewAccessCounterNotificationToDriver(m.ToDriver, m.Driver,
+		notification.PAddr, notification.NumAccesses)
+
+	err := m.ToDriver.Send(msg)
+	if err != nil {
+		return false
+	}
+
+	m.ToRDMA.RetrieveIncoming()
+
+	return true
+}
+
 func (m *ctrlMiddleware) processCacheRestartRsp(
 	rsp *cache.RestartRsp,
 ) bool {
@@ -68,17 +68,20 @@ type DMAEngine struct {
 	Log2AccessSize uint64
 
 	localDataSource mem.AddressToPortMapper
+	l2DataSource    mem.AddressToPortMapper
 
 	processingReqs []*RequestCollection
 
 	maxRequestCount uint64
 
 	toSendToMem []sim.Msg
+	toSendToL2  []sim.Msg
 	toSendToCP  []sim.Msg
 	pendingReqs []sim.Msg
 
 	ToCP  sim.Port
 	ToMem sim.Port
+	ToL2  sim.Port
 }
 
 // SetLocalDataSource sets the table that maps from addresses to port that can
@@ -87,13 +90,21 @@ func (dma *DMAEngine) SetLocalDataSource(s mem.AddressToPortMapper) {
 	dma.localDataSource = s
 }
 
+// SetL2DataSource sets the table that peer-to-peer copies use. It maps the
+// addresses of the GPU to its L2 caches and the rest to the RDMA engine.
+func (dma *DMAEngine) SetL2DataSource(s mem.AddressToPortMapper) {
+	dma.l2DataSource = s
+}
+
 // Tick ticks
 func (dma *DMAEngine) Tick() bool {
 	madeProgress := false
 
 	madeProgress = dma.send(dma.ToCP, &dma.toSendToCP) || madeProgress
 	madeProgress = dma.send(dma.ToMem, &dma.toSendToMem) || madeProgress
+	madeProgress = dma.send(dma.ToL2, &dma.toSendToL2) || madeProgress
 	madeProgress = dma.parseFromMem() || madeProgress
+	madeProgress = dma.parseFromL2() || madeProgress
 	madeProgress = dma.parseFromCP() || madeProgress
 
 	return madeProgress
@@ -135,6 +146,85 @@ func (dma *DMAEngine) parseFromMem() bool {
 	return true
 }
 
+// Peer-to-peer copies read the source and then write the data to the
+// destination, both through the L2 port.
+func (dma *DMAEngine) parseFromL2() bool {
+	rsp := dma.ToL2.RetrieveIncoming()
+	if rsp == nil {
+		return false
+	}
+
+	switch rsp := rsp.(type) {
+	case *mem.DataReadyRsp:
+		dma.processPeerDataReadyRsp(rsp)
+	case *mem.WriteDoneRsp:
+		dma.processPeerWriteDoneRsp(rsp)
+	default:
+		log.Panicf("cannot handle request of type %s", reflect.TypeOf(rsp))
+	}
+
+	return true
+}
+
+func (dma *DMAEngine) processPeerDataReadyRsp(
+	rsp *mem.DataReadyRsp,
+) {
+	read := dma.removeReqFromPendingReqList(rsp.RespondTo).(*mem.ReadReq)
+	tracing.TraceReqFinalize(read, dma)
+
+	rqC := dma.findRequestCollection(read.ID)
+	processing := rqC.getSuperior().(*protocol.MemCopyPeerReq)
+
+	addr := processing.DstAddress + (read.Address - processing.SrcAddress)
+	write := mem.WriteReqBuilder{}.
+		WithSrc(dma.ToL2.AsRemote()).
+		WithDst(dma.l2DataSource.Find(addr)).
+		WithAddress(addr).
+		WithData(rsp.Data).
+		Build()
+	dma.toSendToL2 = append(dma.toSendToL2, write)
+	dma.pendingReqs = append(dma.pendingReqs, write)
+	rqC.appendSubordinateID(write.Meta().ID)
+
+	tracing.TraceReqInitiate(write, dma,
+		tracing.MsgIDAtReceiver(processing, dma))
+}
+
+func (dma *DMAEngine) processPeerWriteDoneRsp(
+	rsp *mem.WriteDoneRsp,
+) {
+	write := dma.removeReqFromPendingReqList(rsp.RespondTo)
+	tracing.TraceReqFinalize(write, dma)
+
+	rqC := dma.findRequestCollection(write.Meta().ID)
+	if !rqC.isFinished() {
+		return
+	}
+
+	processing := rqC.getSuperior()
+	tracing.TraceReqComplete(processing, dma)
+	dma.removeReqFromProcessingReqList(processing.Meta().ID)
+
+	done := sim.GeneralRspBuilder{}.
+		WithDst(processing.Meta().Src).
+		WithSrc(processing.Meta().Dst).
+		WithOriginalReq(processing).
+		Build()
+	dma.toSendToCP = append(dma.toSendToCP, done)
+}
+
+// findRequestCollection returns the collection of a subordinate request and
+// counts the request as completed.
+func (dma *DMAEngine) findRequestCollection(id string) *RequestCollection {
+	for _, rc := range dma.processingReqs {
+		if rc.decrementCountIfExists(id) {
+			return rc
+		}
+	}
+
+	panic("couldn't find requestcollection")
+}
+
 func (dma *DMAEngine) processDataReadyRsp(
 	rsp *mem.DataReadyRsp,
 ) {
@@ -261,6 +351,8 @@ func (dma *DMAEngine) parseFromCP() bool {
 		dma.parseMemCopyH2D(req, rqC)
 	case *protocol.MemCopyD2HReq:
 		dma.parseMemCopyD2H(req, rqC)
+	case *protocol.MemCopyPeerReq:
+		dma.parseMemCopyPeer(req, rqC)
 	default:
 		log.Panicf("cannot process request of type %s", reflect.TypeOf(req))
 	}
@@ -344,6 +436,45 @@ func (dma *DMAEngine) parseMemCopyD2H(
 	}
 }
 
+// parseMemCopyPeer reads the source in pieces that do not cross an access
+// unit, neither in the source nor in the destination, so that each write
+// fits in a cache line.
+func (dma *DMAEngine) parseMemCopyPeer(
+	req *protocol.MemCopyPeerReq,
+	rqC *RequestCollection,
+) {
+	if dma.l2DataSource == nil {
+		log.Panicf("%s cannot copy between GPUs without an L2 data source",
+			dma.Name())
+	}
+
+	unitSize := uint64(1) << dma.Log2AccessSize
+	offset := uint64(0)
+	for offset < req.ByteSize {
+		src := req.SrcAddress + offset
+		dst := req.DstAddress + offset
+
+		length := req.ByteSize - offset
+		length = min(length, unitSize-src%unitSize)
+		length = min(length, unitSize-dst%unitSize)
+
+		reqToBottom := mem.ReadReqBuilder{}.
+			WithSrc(dma.ToL2.AsRemote()).
+			WithDst(dma.l2DataSource.Find(src)).
+			WithAddress(src).
+			WithByteSize(length).
+			Build()
+		dma.toSendToL2 = append(dma.toSendToL2, reqToBottom)
+		dma.pendingReqs = append(dma.pendingReqs, reqToBottom)
+		rqC.appendSubordinateID(reqToBottom.Meta().ID)
+
+		tracing.TraceReqInitiate(reqToBottom, dma,
+			tracing.MsgIDAtReceiver(req, dma))
+
+		offset += length
+	}
+}
+
 // NewDMAEngine creates a DMAEngine, injecting a engine and a "LowModuleFinder"
 // that helps with locating the module that holds the data.
 func NewDMAEngine(
@@ -362,6 +493,7 @@ func NewDMAEngine(
 
 	dma.ToCP = sim.NewPort(dma, 40960000, 40960000, name+".ToCP")
 	dma.ToMem = sim.NewPort(dma, 64, 64, name+".ToMem")
+	dma.ToL2 = sim.NewPort(dma, 64, 64, name+".ToL2")
 
 	return dma
 }
@@ -15,6 +15,7 @@ var _ = Describe("DMAEngine", func() {
 		engine            *MockEngine
 		toCP              *MockPort
 		toMem             *MockPort
+		toL2              *MockPort
 		localModuleFinder *mem.SinglePortMapper
 		dmaEngine         *DMAEngine
 	)
@@ -24,14 +25,18 @@ var _ = Describe("DMAEngine", func() {
 		engine = NewMockEngine(mockCtrl)
 		toCP = NewMockPort(mockCtrl)
 		toMem = NewMockPort(mockCtrl)
+		toL2 = NewMockPort(mockCtrl)
 
 		toCP.EXPECT().AsRemote().AnyTimes()
 		toMem.EXPECT().AsRemote().AnyTimes()
+		toL2.EXPECT().AsRemote().AnyTimes()
 
 		localModuleFinder = new(mem.SinglePortMapper)
 		dmaEngine = NewDMAEngine("DMA", engine, localModuleFinder)
 		dmaEngine.ToCP = toCP
 		dmaEngine.ToMem = toMem
+		dmaEngine.ToL2 = toL2
+		dmaEngine.SetL2DataSource(new(mem.SinglePortMapper))
 	})
 
 	AfterEach(func() {
@@ -279,4 +284,93 @@ var _ = Describe("DMAEngine", func() {
 		Expect(dmaEngine.toSendToCP[0].(*sim.GeneralRsp).OriginalReq).
 			To(BeIdenticalTo(req))
 	})
+
+	It("should parse MemCopyPeer from CP", func() {
+		nilPort := NewMockPort(mockCtrl)
+		nilPort.EXPECT().AsRemote().AnyTimes()
+
+		req := protocol.NewMemCopyPeerReq(nilPort, toCP, 20, 0x1000+40, 100)
+
+		toCP.EXPECT().RetrieveIncoming().Return(req)
+
+		madeProgress := dmaEngine.parseFromCP()
+
+		Expect(madeProgress).To(BeTrue())
+		Expect(dmaEngine.toSendToMem).To(BeEmpty())
+		Expect(dmaEngine.toSendToL2).To(HaveLen(4))
+
+		reads := []struct{ addr, size uint64 }{
+			{20, 24}, {44, 20}, {64, 44}, {108, 12},
+		}
+		for i, r := range reads {
+			read := dmaEngine.toSendToL2[i].(*mem.ReadReq)
+			Expect(read.Address).To(Equal(r.addr))
+			Expect(read.AccessByteSize).To(Equal(r.size))
+		}
+	})
+
+	It("should write the data read by a MemCopyPeer", func() {
+		nilPort := NewMockPort(mockCtrl)
+		nilPort.EXPECT().AsRemote().AnyTimes()
+
+		req := protocol.NewMemCopyPeerReq(nilPort, toCP, 64, 0x1000, 64)
+		rqC := NewRequestCollection(req)
+		dmaEngine.processingReqs = append(dmaEngine.processingReqs, rqC)
+
+		read := mem.ReadReqBuilder{}.
+			WithSrc(toL2.AsRemote()).
+			WithAddress(64).
+			WithByteSize(64).
+			Build()
+		dmaEngine.pendingReqs = append(dmaEngine.pendingReqs, read)
+		rqC.appendSubordinateID(read.Meta().ID)
+
+		dataReady := mem.DataReadyRspBuilder{}.
+			WithDst(toL2.AsRemote()).
+			WithRspTo(read.ID).
+			WithData(make([]byte, 64)).
+			Build()
+		toL2.EXPECT().RetrieveIncoming().Return(dataReady)
+
+		madeProgress := dmaEngine.parseFromL2()
+
+		Expect(madeProgress).To(BeTrue())
+		Expect(dmaEngine.processingReqs).To(HaveLen(1))
+		Expect(dmaEngine.pendingReqs).NotTo(ContainElement(read))
+		Expect(dmaEngine.toSendToL2).To(HaveLen(1))
+		write := dmaEngine.toSendToL2[0].(*mem.WriteReq)
+		Expect(write.Address).To(Equal(uint64(0x1000)))
+		Expect(write.Data).To(Equal(dataReady.Data))
+		Expect(dmaEngine.pendingReqs).To(ContainElement(write))
+	})
+
+	It("should respond MemCopyPeer when all the writes are done", func() {
+		nilPort := NewMockPort(mockCtrl)
+		nilPort.EXPECT().AsRemote().AnyTimes()
+
+		req := protocol.NewMemCopyPeerReq(nilPort, toCP, 64, 0x1000, 64)
+		rqC := NewRequestCollection(req)
+		dmaEngine.processingReqs = append(dmaEngine.processingReqs, rqC)
+
+		write := mem.WriteReqBuilder{}.
+			WithSrc(toL2.AsRemote()).
+			WithAddress(0x1000).
+			Build()
+		dmaEngine.pendingReqs = append(dmaEngine.pendingReqs, write)
+		rqC.appendSubordinateID(write.Meta().ID)
+
+		done := mem.WriteDoneRspBuilder{}.
+			WithDst(toL2.AsRemote()).
+			WithRspTo(write.ID).
+			Build()
+		toL2.EXPECT().RetrieveIncoming().Return(done)
+
+		madeProgress := dmaEngine.parseFromL2()
+
+		Expect(madeProgress).To(BeTrue())
+		Expect(dmaEngine.processingReqs).To(BeEmpty())
+		Expect(dmaEngine.pendingReqs).To(BeEmpty())
+		Expect(dmaEngine.toSendToCP[0].(*sim.GeneralRsp).OriginalReq).
+			To(BeIdenticalTo(req))
+	})
 })
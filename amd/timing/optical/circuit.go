@@ -0,0 +1,146 @@
+package optical
+
+import (
+	"log"
+
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+// --- MODO DE CONMUTACIÓN ---
+
+type SwitchMode int
+
+const (
+	// Cualquier entrada llega a cualquier salida (comportamiento original).
+	PacketSwitched SwitchMode = iota
+
+	// Solo se reenvía por los circuitos activos (puerto a puerto).
+	// Es el modelo de los switches ópticos reconfigurables (OCS).
+	CircuitSwitched
+)
+
+// Circuito óptico unidireccional entre dos puertos del Switch.
+type Circuit struct {
+	In  sim.Port // Puerto de entrada (donde llega la luz).
+	Out sim.Port // Puerto de salida.
+}
+
+// -- EVENTO : *Fin de la reconfiguración* --
+type SwitchReconfigDoneEvent struct {
+	*sim.EventBase
+	Circuits []Circuit
+	gen      uint64
+}
+
+func NewSwitchReconfigDoneEvent(
+	time sim.VTimeInSec,
+	handler sim.Handler,
+	circuits []Circuit,
+	gen uint64,
+) *SwitchReconfigDoneEvent {
+	return &SwitchReconfigDoneEvent{
+		EventBase: sim.NewEventBase(time, handler),
+		Circuits:  circuits,
+		gen:       gen,
+	}
+}
+
+// --- RECONFIGURACIÓN ---
+
+// Cambia el conjunto de circuitos activos.
+// El Switch queda "a oscuras" durante ReconfigurationDelay: todos los circuitos
+// se caen y los paquetes que lleguen esperan en los buffers de entrada
+// (InputBufferCapacity está dimensionado para esto, ver switch.go).
+// Si se llama durante otra reconfiguración, la nueva reemplaza a la anterior.
+func (s *Switch) Reconfigure(newCircuits []Circuit) {
+	circuitsMustBeValid(s, newCircuits)
+
+	s.reconfiguring = true
+	s.reconfigGen++
+	s.circuits = make(map[sim.Port]sim.Port)
+
+	now := s.Engine.CurrentTime()
+	evt := NewSwitchReconfigDoneEvent(
+		now+s.ReconfigurationDelay, s, newCircuits, s.reconfigGen)
+	s.Engine.Schedule(evt)
+}
+
+// ¿El Switch está en su periodo oscuro?
+func (s *Switch) IsReconfiguring() bool {
+	return s.reconfiguring
+}
+
+// Copia de los circuitos activos.
+func (s *Switch) Circuits() []Circuit {
+	circuits := make([]Circuit, 0, len(s.circuits))
+	for _, p := range s.connectedPorts { // Orden determinista.
+		if out, ok := s.circuits[p]; ok {
+			circuits = append(circuits, Circuit{In: p, Out: out})
+		}
+	}
+	return circuits
+}
+
+func (s *Switch) finishReconfiguration(evt *SwitchReconfigDoneEvent) error {
+	if evt.gen != s.reconfigGen { // Hubo otra reconfiguración después.
+		return nil
+	}
+
+	for _, c := range evt.Circuits {
+		s.circuits[c.In] = c.Out
+	}
+	s.reconfiguring = false
+	s.NumReconfigurations++
+
+	// Despachando lo que quedó esperando en los buffers de entrada.
+	s.Tick()
+
+	return nil
+}
+
+// ¿Cada puerto lleva a exactamente un destino? Así es el Switch del Connector.
+// Los puertos entre Switches de un Fabric llevan a varios (o a ninguno).
+func (s *Switch) isStar() bool {
+	destinations := make(map[sim.Port]int, len(s.connectedPorts))
+	for _, p := range s.RouteTable {
+		destinations[p]++
+	}
+
+	for _, p := range s.connectedPorts {
+		if destinations[p] != 1 {
+			return false
+		}
+	}
+
+	return len(s.ecmpRoutes) == 0
+}
+
+// ¿Hay luz de inPort a outPort? En modo paquete siempre.
+func (s *Switch) circuitUp(inPort, outPort sim.Port) bool {
+	if s.Mode == PacketSwitched {
+		return true
+	}
+
+	return s.circuits[inPort] == outPort
+}
+
+// Una entrada solo puede ir a una salida y una salida solo recibe de una entrada.
+func circuitsMustBeValid(s *Switch, circuits []Circuit) {
+	usedIn := make(map[sim.Port]bool)
+	usedOut := make(map[sim.Port]bool)
+
+	for _, c := range circuits {
+		if c.In == c.Out {
+			log.Panicf("[OPTICAL_SWITCH] %s: circuit loops back to port %s",
+				s.Name(), c.In.Name())
+		}
+
+		if usedIn[c.In] || usedOut[c.Out] {
+			log.Panicf("[OPTICAL_SWITCH] %s: port used by more than one circuit (%s -> %s)",
+				s.Name(), c.In.Name(), c.Out.Name())
+		}
+
+		usedIn[c.In] = true
+		usedOut[c.Out] = true
+	}
+}
@@ -0,0 +1,91 @@
+package optical
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+var _ = Describe("Circuit switching", func() {
+	var (
+		engine  *sim.SerialEngine
+		sw      *Switch
+		a, b, c *endpoint
+	)
+
+	plug := func(ep *endpoint) {
+		swPort := sw.CreatePort(ep.Name() + ".SwitchPort")
+		link := NewLink(ep.Name()+".Fiber", engine, 10e-9, 0)
+		link.PlugIn(ep.port)
+		link.PlugIn(swPort)
+		sw.RegisterDestination(ep.port.AsRemote(), swPort)
+	}
+
+	BeforeEach(func() {
+		engine = sim.NewSerialEngine()
+		sw = NewSwitch("Switch", engine)
+		a = newEndpoint("A", engine, 4, 4)
+		b = newEndpoint("B", engine, 4, 4)
+		c = newEndpoint("C", engine, 4, 4)
+		plug(a)
+		plug(b)
+		plug(c)
+	})
+
+	It("should buffer packets while the switch is dark", func() {
+		sw.Reconfigure(nil)
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(b.recvTimes).To(HaveLen(1))
+		Expect(float64(b.recvTimes[0])).To(
+			BeNumerically("~", 820e-9+10e-9, 1e-12))
+		Expect(sw.NumReconfigurations).To(Equal(uint64(1)))
+	})
+
+	It("should only forward through active circuits", func() {
+		sw.Mode = CircuitSwitched
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+		Expect(a.port.Send(newSampleMsg(a.port, c.port, 64))).To(BeNil())
+
+		Expect(engine.Run()).To(Succeed())
+		Expect(b.recvMsgs).To(BeEmpty())
+
+		sw.Reconfigure([]Circuit{
+			{In: sw.PortTo(a.port.AsRemote()), Out: sw.PortTo(b.port.AsRemote())},
+		})
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(b.recvMsgs).To(HaveLen(1))
+		Expect(c.recvMsgs).To(BeEmpty())
+		Expect(sw.Circuits()).To(HaveLen(1))
+	})
+
+	It("should keep only the latest of overlapping reconfigurations", func() {
+		aPort := sw.PortTo(a.port.AsRemote())
+		bPort := sw.PortTo(b.port.AsRemote())
+		cPort := sw.PortTo(c.port.AsRemote())
+
+		sw.Reconfigure([]Circuit{{In: aPort, Out: bPort}})
+		sw.Reconfigure([]Circuit{{In: aPort, Out: cPort}})
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(sw.Circuits()).To(Equal([]Circuit{{In: aPort, Out: cPort}}))
+		Expect(sw.NumReconfigurations).To(Equal(uint64(1)))
+	})
+
+	It("should reject circuits sharing an output", func() {
+		aPort := sw.PortTo(a.port.AsRemote())
+		bPort := sw.PortTo(b.port.AsRemote())
+		cPort := sw.PortTo(c.port.AsRemote())
+
+		Expect(func() {
+			sw.Reconfigure([]Circuit{
+				{In: aPort, Out: cPort},
+				{In: bPort, Out: cPort},
+			})
+		}).To(Panic())
+	})
+})
@@ -12,6 +12,11 @@ type Connector struct {
 	Simulation *simulation.Simulation // Ref a la simulación completa.
 	Switch     *Switch
 	portID     int
+
+	linkLatency   sim.VTimeInSec // Propagación GPU <-> Switch.
+	linkBandwidth float64        // Bytes/s por dirección y longitud de onda.
+	wavelengths   int            // WDM: canales por fibra.
+	power         PowerModel
 }
 
 func NewConnector(sim *simulation.Simulation) *Connector {
@@ -22,9 +27,55 @@ func NewConnector(sim *simulation.Simulation) *Connector {
 		Simulation: sim,
 		Switch:     sw,
 		portID:     0,
+
+		// Velocidad de la luz en el vacío (c) = 3 x 10^8 m/s aprox.
+		// Índice de refracción del silicio (n) = 1,5 aprox.
+		// Vel. de la luz en fibra = c/n = 2 x 10^8.
+		//
+		// Vamos a considerar que el GPU y el Switch Óptico se encuentran en un mismo rack.
+		// En ese caso, la distancia del cable que los conecta (d) es entre 1-3 metros. Elegimos d=2.
+		// d = v x t, es decir t = d/v.
+		// t = 20 m / 2 x 10^8 m/s  =>  1 x 10^{-8}
+		linkLatency:   1e-8,
+		linkBandwidth: DefaultLinkBandwidth,
+		wavelengths:   1,
+		power:         DefaultPowerModel(),
 	}
 }
 
+// Latencia de propagación de las fibras que se creen a partir de ahora.
+func (c *Connector) WithLinkLatency(latency sim.VTimeInSec) *Connector {
+	c.linkLatency = latency
+	return c
+}
+
+// Line rate por dirección (bytes/s) de cada longitud de onda. 0 = infinito.
+func (c *Connector) WithLinkBandwidth(bytesPerSecond float64) *Connector {
+	c.linkBandwidth = bytesPerSecond
+	return c
+}
+
+// WDM: cada fibra lleva n longitudes de onda y el Switch las asigna a flujos.
+func (c *Connector) WithWavelengths(n int) *Connector {
+	c.wavelengths = n
+	c.Switch.Wavelengths = n
+	return c
+}
+
+// Modelo de consumo del Switch y de las fibras que se creen a partir de ahora.
+func (c *Connector) WithPowerModel(m PowerModel) *Connector {
+	c.power = m
+	c.Switch.Power = m
+	return c
+}
+
+// Pone el Switch en modo circuito con el tiempo de reconfiguración dado.
+func (c *Connector) WithCircuitSwitching(reconfigDelay sim.VTimeInSec) *Connector {
+	c.Switch.Mode = CircuitSwitched
+	c.Switch.ReconfigurationDelay = reconfigDelay
+	return c
+}
+
 // Conecta un Port de GPU/Memoria al Switch Óptico.
 func (c *Connector) PlugIn(gpuPort sim.Port) {
 	// 1. Crear Port genérico en el Switch.
@@ -34,16 +85,9 @@ func (c *Connector) PlugIn(gpuPort sim.Port) {
 
 	// 2. Crear el Link (fibra óptica).
 	cableName := fmt.Sprintf("Fiber[%d]", c.portID)
-
-	// Velocidad de la luz en el vacío (c) = 3 x 10^8 m/s aprox.
-	// Índice de refracción del silicio (n) = 1,5 aprox.
-	// Vel. de la luz en fibra = c/n = 2 x 10^8.
-	//
-	// Vamos a considerar que el GPU y el Switch Óptico se encuentran en un mismo rack.
-	// En ese caso, la distancia del cable que los conecta (d) es entre 1-3 metros. Elegimos d=2.
-	// d = v x t, es decir t = d/v.
-	// t = 20 m / 2 x 10^8 m/s  =>  1 x 10^{-8}
-	cable := NewLink(cableName, c.Simulation.GetEngine(), 1e-8)
+	cable := NewLink(cableName, c.Simulation.GetEngine(), c.linkLatency, c.linkBandwidth)
+	cable.Wavelengths = c.wavelengths
+	cable.Power = c.power
 
 	// 3. Registrar el Link en la simulación (para que procese eventos).
 	c.Simulation.RegisterComponent(cable)
@@ -52,7 +96,7 @@ func (c *Connector) PlugIn(gpuPort sim.Port) {
 	cable.PlugIn(gpuPort)    // Link con GPU.
 	cable.PlugIn(switchPort) // Link con Switch.
 
-	// 5. Ruta... (por ahora, estática).
-	// TODO. Hacerlo para topología reconfigurable.
+	// 5. Ruta estática. La topología se reconfigura cambiando los circuitos
+	// del Switch con Reconfigure / Controller, no la RouteTable.
 	c.Switch.RegisterDestination(gpuPort.AsRemote(), switchPort)
 }
@@ -0,0 +1,247 @@
+package optical
+
+import (
+	"log"
+	"math"
+	"sort"
+
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+// --- CONTROLADOR DE TOPOLOGÍA ---
+
+// Estadísticas de una época del Controller.
+type EpochStats struct {
+	Epoch           int
+	Time            sim.VTimeInSec
+	TrafficBytes    uint64  // Bytes reenviados por el Switch en la época.
+	PendingBytes    uint64  // Bytes esperando circuito al final de la época.
+	NumCircuits     int     // Circuitos elegidos para la próxima época.
+	Reconfigured    bool    // ¿Se pidió una reconfiguración?
+	PredictionError float64 // Error relativo de la predicción anterior (0 = perfecta).
+}
+
+// Cada época el Controller:
+//  1. Lee cuánto tráfico pasó por el Switch (delta del TrafficMatrix).
+//  2. Le pide a la política una predicción para la próxima época.
+//  3. Elige circuitos con la política de asignación (Matching, por defecto
+//     GreedyMatching) y reconfigura el Switch si cambiaron.
+//
+// El reloj del componente ES la época (Freq = 1 / Epoch). Igual que el resto de
+// componentes de Akita, deja de hacer tick cuando no hay trabajo y el Switch lo
+// despierta al recibir mensajes, así no mantiene viva la simulación.
+//
+// Sólo maneja un Switch en estrella: cada puerto lleva a un único endpoint.
+// En un Fabric los puertos entre Switches llevan a muchos destinos y un
+// circuito no alcanza para elegir el camino completo.
+type Controller struct {
+	*sim.TickingComponent
+
+	Switch   *Switch
+	Policy   PredictionPolicy
+	Matching MatchingPolicy
+	Epoch    sim.VTimeInSec
+	Stats    []EpochStats
+
+	lastSnapshot   TrafficMatrix
+	lastPrediction DemandMatrix
+}
+
+func NewController(
+	name string,
+	engine sim.Engine,
+	sw *Switch,
+	epoch sim.VTimeInSec,
+	policy PredictionPolicy,
+) *Controller {
+	c := &Controller{
+		Switch:       sw,
+		Policy:       policy,
+		Matching:     &GreedyMatchingPolicy{},
+		Epoch:        epoch,
+		lastSnapshot: make(TrafficMatrix),
+	}
+	c.TickingComponent = sim.NewTickingComponent(
+		name, engine, sim.Freq(1/float64(epoch)), c)
+
+	sw.controller = c
+
+	return c
+}
+
+// Fin de una época.
+func (c *Controller) Tick() bool {
+	snapshot := c.Switch.SnapshotTraffic()
+	epochTraffic := trafficDelta(snapshot, c.lastSnapshot)
+	c.lastSnapshot = snapshot
+
+	pending := c.Switch.PendingDemand()
+	stats := EpochStats{
+		Epoch:           len(c.Stats),
+		Time:            c.CurrentTime(),
+		TrafficBytes:    totalBytes(epochTraffic),
+		PendingBytes:    totalBytes(pending),
+		PredictionError: predictionError(c.lastPrediction, epochTraffic),
+	}
+
+	prediction := c.Policy.Predict(epochTraffic)
+	c.lastPrediction = prediction
+
+	circuits := c.circuitsFor(c.Matching.Match(pending, prediction))
+	stats.NumCircuits = len(circuits)
+
+	if !c.Switch.IsReconfiguring() && !sameCircuits(circuits, c.Switch.Circuits()) {
+		c.Switch.Reconfigure(circuits)
+		stats.Reconfigured = true
+	}
+
+	c.Stats = append(c.Stats, stats)
+
+	return stats.TrafficBytes > 0 || stats.PendingBytes > 0 ||
+		c.Switch.IsReconfiguring()
+}
+
+// Traduce pares (Source, Destination) a puertos del Switch.
+func (c *Controller) circuitsFor(pairs [][2]string) []Circuit {
+	if !c.Switch.isStar() {
+		log.Panicf("[OPTICAL_CONTROLLER] %s: %s has ports that lead to "+
+			"other switches, circuits need one port per endpoint",
+			c.Name(), c.Switch.Name())
+	}
+
+	circuits := make([]Circuit, 0, len(pairs))
+	for _, pair := range pairs {
+		in := c.Switch.PortTo(sim.RemotePort(pair[0]))
+		out := c.Switch.PortTo(sim.RemotePort(pair[1]))
+		if in == nil || out == nil || in == out {
+			continue
+		}
+
+		circuits = append(circuits, Circuit{In: in, Out: out})
+	}
+	return circuits
+}
+
+// --- ASIGNACIÓN DE CIRCUITOS (estilo Hedera: Global First Fit voraz) ---
+
+// Elige pares (Source, Destination) tal que cada origen y cada destino se use
+// una sola vez. Primero se atiende lo que está bloqueado en los buffers
+// (si no, un paquete en la cabeza podría esperar para siempre), después la
+// demanda predicha de mayor a menor.
+func GreedyMatching(pending TrafficMatrix, demand DemandMatrix) [][2]string {
+	type candidate struct {
+		src, dst string
+		pending  bool
+		weight   float64
+	}
+
+	var candidates []candidate
+	for src, row := range pending {
+		for dst, bytes := range row {
+			candidates = append(candidates, candidate{src, dst, true,
+				float64(bytes) + demand.Get(src, dst)})
+		}
+	}
+	for src, row := range demand {
+		for dst, bytes := range row {
+			if bytes <= 0 || pending[src][dst] > 0 {
+				continue
+			}
+			candidates = append(candidates, candidate{src, dst, false, bytes})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.pending != b.pending {
+			return a.pending
+		}
+		if a.weight != b.weight {
+			return a.weight > b.weight
+		}
+		if a.src != b.src {
+			return a.src < b.src
+		}
+		return a.dst < b.dst
+	})
+
+	usedSrc := make(map[string]bool)
+	usedDst := make(map[string]bool)
+	var pairs [][2]string
+	for _, cand := range candidates {
+		if usedSrc[cand.src] || usedDst[cand.dst] {
+			continue
+		}
+
+		usedSrc[cand.src] = true
+		usedDst[cand.dst] = true
+		pairs = append(pairs, [2]string{cand.src, cand.dst})
+	}
+
+	return pairs
+}
+
+// --- AUXILIARES ---
+
+func trafficDelta(now, before TrafficMatrix) TrafficMatrix {
+	delta := make(TrafficMatrix)
+	for src, row := range now {
+		for dst, bytes := range row {
+			if d := bytes - before[src][dst]; d > 0 {
+				delta.Add(src, dst, d)
+			}
+		}
+	}
+	return delta
+}
+
+func totalBytes(m TrafficMatrix) uint64 {
+	total := uint64(0)
+	for _, row := range m {
+		for _, bytes := range row {
+			total += bytes
+		}
+	}
+	return total
+}
+
+// Suma |predicho - real| / suma real. 0 si no hubo tráfico real.
+func predictionError(prediction DemandMatrix, actual TrafficMatrix) float64 {
+	actualTotal := float64(totalBytes(actual))
+	if actualTotal == 0 {
+		return 0
+	}
+
+	diff := 0.0
+	for src, row := range actual {
+		for dst, bytes := range row {
+			diff += math.Abs(prediction.Get(src, dst) - float64(bytes))
+		}
+	}
+	for src, row := range prediction {
+		for dst, bytes := range row {
+			if _, ok := actual[src][dst]; !ok {
+				diff += bytes
+			}
+		}
+	}
+
+	return diff / actualTotal
+}
+
+func sameCircuits(a, b []Circuit) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	set := make(map[Circuit]bool, len(a))
+	for _, c := range a {
+		set[c] = true
+	}
+	for _, c := range b {
+		if !set[c] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package optical
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+var _ = Describe("Prediction policies", func() {
+	It("should predict the last epoch", func() {
+		traffic := TrafficMatrix{"A": {"B": 100}}
+
+		demand := NewLastEpochPolicy().Predict(traffic)
+
+		Expect(demand.Get("A", "B")).To(Equal(100.0))
+	})
+
+	It("should smooth the traffic with EWMA", func() {
+		p := NewEWMAPolicy(0.5)
+
+		p.Predict(TrafficMatrix{"A": {"B": 100}})
+		demand := p.Predict(TrafficMatrix{"A": {"C": 100}})
+
+		Expect(demand.Get("A", "B")).To(Equal(25.0))
+		Expect(demand.Get("A", "C")).To(Equal(50.0))
+	})
+})
+
+var _ = Describe("GreedyMatching", func() {
+	It("should give each source and destination a single circuit", func() {
+		demand := DemandMatrix{
+			"A": {"B": 10, "C": 30},
+			"B": {"C": 20, "A": 5},
+		}
+
+		pairs := GreedyMatching(nil, demand)
+
+		Expect(pairs).To(Equal([][2]string{{"A", "C"}, {"B", "A"}}))
+	})
+
+	It("should serve blocked packets before the predicted demand", func() {
+		pending := TrafficMatrix{"A": {"B": 1}}
+		demand := DemandMatrix{"A": {"C": 1000}}
+
+		pairs := GreedyMatching(pending, demand)
+
+		Expect(pairs).To(Equal([][2]string{{"A", "B"}}))
+	})
+})
+
+var _ = Describe("Controller", func() {
+	var (
+		engine  *sim.SerialEngine
+		sw      *Switch
+		ctrl    *Controller
+		a, b, c *endpoint
+	)
+
+	plug := func(ep *endpoint) {
+		swPort := sw.CreatePort(ep.Name() + ".SwitchPort")
+		link := NewLink(ep.Name()+".Fiber", engine, 10e-9, 0)
+		link.PlugIn(ep.port)
+		link.PlugIn(swPort)
+		sw.RegisterDestination(ep.port.AsRemote(), swPort)
+	}
+
+	BeforeEach(func() {
+		engine = sim.NewSerialEngine()
+		sw = NewSwitch("Switch", engine)
+		sw.Mode = CircuitSwitched
+		ctrl = NewController("Controller", engine, sw, 1e-6,
+			NewLastEpochPolicy())
+		a = newEndpoint("A", engine, 4, 4)
+		b = newEndpoint("B", engine, 4, 4)
+		c = newEndpoint("C", engine, 4, 4)
+		plug(a)
+		plug(b)
+		plug(c)
+	})
+
+	It("should set up circuits until every packet is delivered", func() {
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+		Expect(a.port.Send(newSampleMsg(a.port, c.port, 64))).To(BeNil())
+		Expect(b.port.Send(newSampleMsg(b.port, c.port, 64))).To(BeNil())
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(b.recvMsgs).To(HaveLen(1))
+		Expect(c.recvMsgs).To(HaveLen(2))
+		Expect(sw.NumReconfigurations).To(BeNumerically(">=", 2))
+		Expect(ctrl.Stats).NotTo(BeEmpty())
+		Expect(ctrl.Stats[0].Reconfigured).To(BeTrue())
+	})
+
+	It("should choose the circuits with its matching policy", func() {
+		matching := &countingMatching{}
+		ctrl.Matching = matching
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(b.recvMsgs).To(HaveLen(1))
+		Expect(matching.calls).To(BeNumerically(">", 0))
+	})
+
+	It("should refuse a switch with ports to other switches", func() {
+		f := MakeFabricBuilder().
+			WithEngine(engine).
+			BuildLeafSpine("LS", 2, 2)
+		f.PlugIn(newEndpoint("EP", engine, 4, 4).port)
+		leafCtrl := NewController("LeafController", engine, f.Switches[0],
+			1e-6, NewLastEpochPolicy())
+
+		Expect(func() { leafCtrl.circuitsFor(nil) }).To(Panic())
+	})
+
+	It("should stop ticking when there is no traffic", func() {
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(ctrl.Stats).To(BeEmpty())
+	})
+})
+
+type countingMatching struct {
+	calls int
+}
+
+func (m *countingMatching) Name() string {
+	return "counting"
+}
+
+func (m *countingMatching) Match(
+	pending TrafficMatrix,
+	demand DemandMatrix,
+) [][2]string {
+	m.calls++
+	return GreedyMatching(pending, demand)
+}
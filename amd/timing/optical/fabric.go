@@ -0,0 +1,367 @@
+package optical
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/akita/v4/simulation"
+)
+
+// --- FABRIC MULTI-SWITCH ---
+
+// Un Fabric es un grafo de Switches unidos por fibras (cada una con su propia
+// latencia, es decir, su propia longitud). Los endpoints (puertos de GPU) se
+// cuelgan de los Switches de borde y las rutas se calculan solas: camino más
+// corto en latencia y, si hay empate, ECMP.
+//
+// Los Switches del Fabric trabajan en modo paquete: el Controller (ver
+// controller.go) asume un único Switch que ve todo el tráfico. Con topologías
+// cíclicas (torus, dragonfly) las rutas pueden trabarse, ver CanDeadlock.
+type Fabric struct {
+	Name     string
+	Switches []*Switch
+	Links    []*Link // Fibras entre Switches (sin contar las de los endpoints).
+	allLinks []*Link // Incluye las de los endpoints.
+
+	engine      sim.Engine
+	simulation  *simulation.Simulation // nil = no se registran componentes.
+	hostLatency sim.VTimeInSec
+	bandwidth   float64
+	wavelengths int
+	power       PowerModel
+	ecmp        bool
+
+	edges        [][]fabricEdge // Vecinos de cada Switch.
+	edgeSwitches []int          // Donde PlugIn cuelga endpoints (round-robin).
+	nextEdge     int
+	hosts        map[sim.RemotePort]int // Endpoint -> Switch del que cuelga.
+	numHosts     int
+
+	// dist[d][s] = latencia de s a d. Se calcula al colgar el primer endpoint;
+	// a partir de ahí la topología queda congelada.
+	dist [][]sim.VTimeInSec
+}
+
+type fabricEdge struct {
+	to      int
+	port    sim.Port // Puerto de ESTE Switch hacia el vecino.
+	latency sim.VTimeInSec
+}
+
+// Agrega un Switch vacío y devuelve su índice.
+func (f *Fabric) AddSwitch() int {
+	f.mustNotBeFrozen()
+
+	id := len(f.Switches)
+	sw := NewSwitch(fmt.Sprintf("%s.Switch[%d]", f.Name, id), f.engine)
+	sw.Wavelengths = f.wavelengths
+	sw.Power = f.power
+	f.Switches = append(f.Switches, sw)
+	f.edges = append(f.edges, nil)
+
+	if f.simulation != nil {
+		f.simulation.RegisterComponent(sw)
+	}
+
+	return id
+}
+
+// Une dos Switches con una fibra full-duplex.
+func (f *Fabric) Connect(a, b int, latency sim.VTimeInSec) *Link {
+	f.mustNotBeFrozen()
+
+	if a == b {
+		log.Panicf("[OPTICAL_FABRIC] %s: cannot connect switch %d to itself", f.Name, a)
+	}
+
+	portA := f.createPort(a)
+	portB := f.createPort(b)
+
+	link := NewLink(fmt.Sprintf("%s.Fiber[%d][%d]", f.Name, a, b),
+		f.engine, latency, f.bandwidth)
+	link.Wavelengths = f.wavelengths
+	link.Power = f.power
+	f.register(link)
+	link.PlugIn(portA)
+	link.PlugIn(portB)
+	f.Links = append(f.Links, link)
+
+	f.edges[a] = append(f.edges[a], fabricEdge{to: b, port: portA, latency: latency})
+	f.edges[b] = append(f.edges[b], fabricEdge{to: a, port: portB, latency: latency})
+
+	return link
+}
+
+// Switches de los que PlugIn cuelga endpoints. Por defecto, todos.
+func (f *Fabric) SetEdgeSwitches(ids []int) {
+	f.edgeSwitches = append([]int(nil), ids...)
+}
+
+// Cuelga un Port de GPU/Memoria del siguiente Switch de borde (round-robin).
+// Misma firma que Connector.PlugIn.
+func (f *Fabric) PlugIn(gpuPort sim.Port) {
+	if len(f.Switches) == 0 {
+		log.Panicf("[OPTICAL_FABRIC] %s: no switches to plug %s into",
+			f.Name, gpuPort.Name())
+	}
+
+	edge := f.nextEdge
+	if len(f.edgeSwitches) > 0 {
+		edge = f.edgeSwitches[f.nextEdge%len(f.edgeSwitches)]
+	} else {
+		edge %= len(f.Switches)
+	}
+	f.nextEdge++
+
+	f.PlugInto(edge, gpuPort)
+}
+
+// Cuelga un Port de GPU/Memoria de un Switch concreto y publica la ruta hacia
+// él en todos los Switches.
+func (f *Fabric) PlugInto(swID int, gpuPort sim.Port) {
+	f.freeze()
+
+	hostPort := f.createPort(swID)
+	link := NewLink(fmt.Sprintf("%s.HostFiber[%d]", f.Name, f.numHosts),
+		f.engine, f.hostLatency, f.bandwidth)
+	link.Wavelengths = f.wavelengths
+	link.Power = f.power
+	f.numHosts++
+	f.register(link)
+	link.PlugIn(gpuPort)
+	link.PlugIn(hostPort)
+
+	dst := gpuPort.AsRemote()
+	f.hosts[dst] = swID
+
+	for s, sw := range f.Switches {
+		if s == swID {
+			sw.RegisterDestination(dst, hostPort)
+			continue
+		}
+
+		if ports := f.nextHops(s, swID); len(ports) > 0 {
+			sw.RegisterRoutes(dst, ports)
+		}
+	}
+}
+
+// Energía de todos los Switches y fibras del Fabric hasta now.
+func (f *Fabric) Energy(now sim.VTimeInSec) EnergyBreakdown {
+	var total EnergyBreakdown
+	for _, sw := range f.Switches {
+		total = total.Add(sw.Energy(now))
+	}
+	for _, link := range f.allLinks {
+		total = total.Add(link.Energy(now))
+	}
+	return total
+}
+
+// Switch del que cuelga un endpoint (nil si no está en el Fabric).
+func (f *Fabric) SwitchOf(dst sim.RemotePort) *Switch {
+	id, ok := f.hosts[dst]
+	if !ok {
+		return nil
+	}
+	return f.Switches[id]
+}
+
+// Latencia de Switch a Switch por el camino más corto (+Inf si no hay camino):
+// las fibras más la Latency de cada Switch que se cruza después de from. De
+// endpoint a endpoint hay que sumar las dos fibras de los hosts y la Latency
+// de from.
+func (f *Fabric) PathLatency(from, to int) sim.VTimeInSec {
+	f.freeze()
+	return f.dist[to][from]
+}
+
+func (f *Fabric) createPort(swID int) sim.Port {
+	sw := f.Switches[swID]
+	return sw.CreatePort(fmt.Sprintf("%s.Port[%d]", sw.Name(), len(sw.connectedPorts)))
+}
+
+func (f *Fabric) register(link *Link) {
+	f.allLinks = append(f.allLinks, link)
+	if f.simulation != nil {
+		f.simulation.RegisterComponent(link)
+	}
+}
+
+func (f *Fabric) mustNotBeFrozen() {
+	if f.dist != nil {
+		log.Panicf("[OPTICAL_FABRIC] %s: topology cannot change after "+
+			"endpoints are plugged in", f.Name)
+	}
+}
+
+// --- ENRUTAMIENTO ---
+
+// Costo de cruzar la fibra s -> n: propagación + paso por el Switch n.
+func (f *Fabric) hopCost(e fabricEdge) sim.VTimeInSec {
+	return e.latency + f.Switches[e.to].Latency
+}
+
+// Dijkstra desde cada destino (O(n²) por destino; los fabrics son chicos).
+func (f *Fabric) freeze() {
+	if f.dist != nil {
+		return
+	}
+
+	n := len(f.Switches)
+	f.dist = make([][]sim.VTimeInSec, n)
+	for d := 0; d < n; d++ {
+		f.dist[d] = f.distancesTo(d)
+	}
+}
+
+func (f *Fabric) distancesTo(d int) []sim.VTimeInSec {
+	n := len(f.Switches)
+	dist := make([]sim.VTimeInSec, n)
+	done := make([]bool, n)
+	for i := range dist {
+		dist[i] = sim.VTimeInSec(math.Inf(1))
+	}
+	dist[d] = 0
+
+	for {
+		u := -1
+		for i := 0; i < n; i++ {
+			if !done[i] && !math.IsInf(float64(dist[i]), 1) &&
+				(u == -1 || dist[i] < dist[u]) {
+				u = i
+			}
+		}
+		if u == -1 {
+			return dist
+		}
+		done[u] = true
+
+		// Las fibras son simétricas: la arista u -> v también existe como v -> u.
+		for _, e := range f.edges[u] {
+			v := e.to
+			back := fabricEdge{to: u, latency: e.latency}
+			if c := dist[u] + f.hopCost(back); c < dist[v] {
+				dist[v] = c
+			}
+		}
+	}
+}
+
+// Puertos de s que están en algún camino más corto hacia el Switch d.
+func (f *Fabric) nextHops(s, d int) []sim.Port {
+	var ports []sim.Port
+	for _, i := range f.nextEdges(s, d) {
+		ports = append(ports, f.edges[s][i].port)
+	}
+	return ports
+}
+
+// Índices (en f.edges[s]) de las fibras de s que usan las rutas hacia d.
+func (f *Fabric) nextEdges(s, d int) []int {
+	best := f.dist[d][s]
+	if s == d || math.IsInf(float64(best), 1) {
+		return nil
+	}
+
+	var edges []int
+	for i, e := range f.edges[s] {
+		if !sameCost(f.hopCost(e)+f.dist[d][e.to], best) {
+			continue
+		}
+
+		edges = append(edges, i)
+		if !f.ecmp {
+			break
+		}
+	}
+	return edges
+}
+
+// --- DEADLOCK ---
+
+// Los Switches usan control de flujo por créditos: un mensaje espera en su
+// buffer de entrada hasta que la fibra de salida tenga lugar, y no hay canales
+// virtuales. Si las rutas forman un ciclo de dependencias entre fibras (una
+// fibra llena espera a la siguiente del camino), el tráfico que llena los
+// buffers del ciclo se bloquea para siempre (Dally y Seitz, 1987). Con caminos
+// más cortos pasa en casi todos los torus (no en un anillo de 3 Switches) y en
+// los dragonflies de más de 2 grupos con varios routers por grupo. Leaf-spine
+// no tiene ciclos.
+//
+// CanDeadlock arma el grafo de dependencias de las rutas hacia los Switches
+// con endpoints (o hacia los de borde si todavía no hay) y busca un ciclo. Que
+// exista no significa que la simulación se vaya a trabar, sólo que puede.
+func (f *Fabric) CanDeadlock() bool {
+	f.freeze()
+
+	type channel struct{ sw, edge int }
+
+	destinations := make(map[int]bool)
+	for _, sw := range f.hosts {
+		destinations[sw] = true
+	}
+	if len(destinations) == 0 {
+		for _, d := range f.edgeSwitches {
+			destinations[d] = true
+		}
+	}
+	if len(destinations) == 0 {
+		for d := range f.Switches {
+			destinations[d] = true
+		}
+	}
+
+	deps := make(map[channel][]channel)
+	for d := range destinations {
+		for s := range f.Switches {
+			for _, i := range f.nextEdges(s, d) {
+				next := f.edges[s][i].to
+				for _, j := range f.nextEdges(next, d) {
+					from := channel{s, i}
+					deps[from] = append(deps[from], channel{next, j})
+				}
+			}
+		}
+	}
+
+	// DFS: un ciclo es una arista hacia un canal que está en la pila.
+	const (
+		unvisited = iota
+		inStack
+		finished
+	)
+	state := make(map[channel]int)
+
+	var visit func(c channel) bool
+	visit = func(c channel) bool {
+		state[c] = inStack
+		for _, next := range deps[c] {
+			switch state[next] {
+			case inStack:
+				return true
+			case unvisited:
+				if visit(next) {
+					return true
+				}
+			}
+		}
+		state[c] = finished
+		return false
+	}
+
+	for c := range deps {
+		if state[c] == unvisited && visit(c) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Las latencias se suman en distinto orden por cada camino.
+func sameCost(a, b sim.VTimeInSec) bool {
+	return math.Abs(float64(a-b)) <= 1e-9*math.Max(float64(a), float64(b))
+}
@@ -0,0 +1,214 @@
+package optical
+
+import (
+	"log"
+
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/akita/v4/simulation"
+)
+
+// Arma Fabrics con topologías conocidas.
+type FabricBuilder struct {
+	engine     sim.Engine
+	simulation *simulation.Simulation
+
+	hostLatency   sim.VTimeInSec // Endpoint <-> Switch de borde.
+	switchLatency sim.VTimeInSec // Entre Switches del mismo rack / grupo.
+	globalLatency sim.VTimeInSec // Entre grupos (solo dragonfly).
+	bandwidth     float64
+	wavelengths   int
+	power         PowerModel
+	ecmp          bool
+}
+
+func MakeFabricBuilder() FabricBuilder {
+	return FabricBuilder{
+		// Mismo cálculo que en NewConnector: t = d / (2 x 10^8 m/s).
+		hostLatency:   1e-8, // 2 m, mismo rack.
+		switchLatency: 1e-8, // 2 m, mismo rack.
+		globalLatency: 5e-8, // 10 m, entre racks.
+		bandwidth:     DefaultLinkBandwidth,
+		wavelengths:   1,
+		power:         DefaultPowerModel(),
+		ecmp:          true,
+	}
+}
+
+// Motor de la simulación (los componentes no se registran en ninguna parte).
+func (b FabricBuilder) WithEngine(engine sim.Engine) FabricBuilder {
+	b.engine = engine
+	return b
+}
+
+// Toma el motor de la simulación y registra en ella Switches y fibras.
+func (b FabricBuilder) WithSimulation(s *simulation.Simulation) FabricBuilder {
+	b.simulation = s
+	b.engine = s.GetEngine()
+	return b
+}
+
+func (b FabricBuilder) WithHostLinkLatency(latency sim.VTimeInSec) FabricBuilder {
+	b.hostLatency = latency
+	return b
+}
+
+func (b FabricBuilder) WithSwitchLinkLatency(latency sim.VTimeInSec) FabricBuilder {
+	b.switchLatency = latency
+	return b
+}
+
+func (b FabricBuilder) WithGlobalLinkLatency(latency sim.VTimeInSec) FabricBuilder {
+	b.globalLatency = latency
+	return b
+}
+
+// Line rate por dirección (bytes/s) de cada longitud de onda. 0 = infinito.
+func (b FabricBuilder) WithLinkBandwidth(bytesPerSecond float64) FabricBuilder {
+	b.bandwidth = bytesPerSecond
+	return b
+}
+
+// WDM: longitudes de onda por fibra (ver wdm.go).
+func (b FabricBuilder) WithWavelengths(n int) FabricBuilder {
+	b.wavelengths = n
+	return b
+}
+
+// Modelo de consumo de todos los Switches y fibras.
+func (b FabricBuilder) WithPowerModel(m PowerModel) FabricBuilder {
+	b.power = m
+	return b
+}
+
+// Con ECMP desactivado cada destino usa un único camino más corto.
+func (b FabricBuilder) WithECMP(enabled bool) FabricBuilder {
+	b.ecmp = enabled
+	return b
+}
+
+// Fabric vacío: los Switches y fibras se agregan a mano (AddSwitch, Connect).
+func (b FabricBuilder) Build(name string) *Fabric {
+	if b.engine == nil {
+		log.Panicf("[OPTICAL_FABRIC] %s: engine not set", name)
+	}
+
+	return &Fabric{
+		Name:        name,
+		engine:      b.engine,
+		simulation:  b.simulation,
+		hostLatency: b.hostLatency,
+		bandwidth:   b.bandwidth,
+		wavelengths: b.wavelengths,
+		power:       b.power,
+		ecmp:        b.ecmp,
+		hosts:       make(map[sim.RemotePort]int),
+	}
+}
+
+// Cada leaf se une con cada spine. Los endpoints cuelgan de los leaves.
+func (b FabricBuilder) BuildLeafSpine(name string, numLeaves, numSpines int) *Fabric {
+	if numLeaves < 1 || numSpines < 1 {
+		log.Panicf("[OPTICAL_FABRIC] %s: leaf-spine needs at least one leaf and one spine", name)
+	}
+
+	f := b.Build(name)
+
+	leaves := make([]int, numLeaves)
+	for i := range leaves {
+		leaves[i] = f.AddSwitch()
+	}
+	for s := 0; s < numSpines; s++ {
+		spine := f.AddSwitch()
+		for _, leaf := range leaves {
+			f.Connect(leaf, spine, b.switchLatency)
+		}
+	}
+
+	f.SetEdgeSwitches(leaves)
+
+	return f
+}
+
+// Torus de N dimensiones (p. ej. 4x4). Cada Switch se une con sus vecinos en
+// cada dimensión, con vuelta (wraparound). Los endpoints cuelgan de todos.
+// Salvo en un anillo de 3 Switches, las rutas pueden trabarse, ver
+// Fabric.CanDeadlock.
+func (b FabricBuilder) BuildTorus(name string, dims ...int) *Fabric {
+	if len(dims) == 0 {
+		log.Panicf("[OPTICAL_FABRIC] %s: torus needs at least one dimension", name)
+	}
+
+	n := 1
+	for _, d := range dims {
+		if d < 1 {
+			log.Panicf("[OPTICAL_FABRIC] %s: invalid torus dimension %d", name, d)
+		}
+		n *= d
+	}
+
+	f := b.Build(name)
+	for i := 0; i < n; i++ {
+		f.AddSwitch()
+	}
+
+	// Índice lineal: dims[0] es la dimensión que varía más rápido.
+	stride := 1
+	for _, size := range dims {
+		for id := 0; id < n; id++ {
+			coord := (id / stride) % size
+			if coord+1 < size {
+				f.Connect(id, id+stride, b.switchLatency)
+			} else if size > 2 { // Vuelta. Con 2 ya son vecinos directos.
+				f.Connect(id, id-coord*stride, b.switchLatency)
+			}
+		}
+		stride *= size
+	}
+
+	return f
+}
+
+// Dragonfly: dentro de cada grupo los routers forman un clique; entre cada
+// par de grupos hay una fibra global (más larga). El router de i que mira a j
+// se elige repartiendo los grupos entre los routers. Los endpoints cuelgan de
+// todos los routers. Con más de 2 grupos y varios routers por grupo las
+// rutas pueden trabarse, ver Fabric.CanDeadlock.
+func (b FabricBuilder) BuildDragonfly(name string, numGroups, routersPerGroup int) *Fabric {
+	if numGroups < 1 || routersPerGroup < 1 {
+		log.Panicf("[OPTICAL_FABRIC] %s: dragonfly needs at least one group "+
+			"and one router per group", name)
+	}
+
+	f := b.Build(name)
+	router := func(group, r int) int {
+		return group*routersPerGroup + r
+	}
+
+	for g := 0; g < numGroups; g++ {
+		for r := 0; r < routersPerGroup; r++ {
+			f.AddSwitch()
+		}
+		for r1 := 0; r1 < routersPerGroup; r1++ {
+			for r2 := r1 + 1; r2 < routersPerGroup; r2++ {
+				f.Connect(router(g, r1), router(g, r2), b.switchLatency)
+			}
+		}
+	}
+
+	// Router de g que tiene la fibra global hacia el grupo other.
+	gateway := func(g, other int) int {
+		slot := other
+		if other > g {
+			slot--
+		}
+		return router(g, slot%routersPerGroup)
+	}
+
+	for g1 := 0; g1 < numGroups; g1++ {
+		for g2 := g1 + 1; g2 < numGroups; g2++ {
+			f.Connect(gateway(g1, g2), gateway(g2, g1), b.globalLatency)
+		}
+	}
+
+	return f
+}
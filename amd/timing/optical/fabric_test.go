@@ -0,0 +1,165 @@
+package optical
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+var _ = Describe("Fabric", func() {
+	var (
+		engine  *sim.SerialEngine
+		builder FabricBuilder
+	)
+
+	newEndpoints := func(n int) []*endpoint {
+		eps := make([]*endpoint, n)
+		for i := range eps {
+			eps[i] = newEndpoint(fmt.Sprintf("EP[%d]", i), engine, 64, 64)
+		}
+		return eps
+	}
+
+	BeforeEach(func() {
+		engine = sim.NewSerialEngine()
+		builder = MakeFabricBuilder().
+			WithEngine(engine).
+			WithLinkBandwidth(0)
+	})
+
+	It("should route across a leaf-spine fabric", func() {
+		f := builder.BuildLeafSpine("LS", 2, 2)
+		eps := newEndpoints(4)
+		for _, ep := range eps {
+			f.PlugIn(ep.port)
+		}
+
+		Expect(f.SwitchOf(eps[0].port.AsRemote())).To(Equal(f.Switches[0]))
+		Expect(f.SwitchOf(eps[1].port.AsRemote())).To(Equal(f.Switches[1]))
+
+		Expect(eps[0].port.Send(newSampleMsg(eps[0].port, eps[1].port, 64))).To(BeNil())
+		Expect(engine.Run()).To(Succeed())
+
+		// Host -> leaf -> spine -> leaf -> host: 4 fibras y 3 Switches.
+		Expect(eps[1].recvTimes).To(HaveLen(1))
+		Expect(float64(eps[1].recvTimes[0])).To(BeNumerically("~", 160e-9, 1e-12))
+		Expect(float64(eps[1].recvTimes[0])).To(BeNumerically("~",
+			float64(2*10e-9+f.Switches[0].Latency+f.PathLatency(0, 1)), 1e-12))
+	})
+
+	It("should spread flows over all spines with ECMP", func() {
+		f := builder.BuildLeafSpine("LS", 2, 2)
+		eps := newEndpoints(2)
+		for _, ep := range eps {
+			f.PlugIn(ep.port)
+		}
+
+		Expect(f.Switches[0].Routes(eps[1].port.AsRemote())).To(HaveLen(2))
+		Expect(f.Switches[2].Routes(eps[1].port.AsRemote())).To(HaveLen(1))
+	})
+
+	It("should use a single path without ECMP", func() {
+		f := builder.WithECMP(false).BuildLeafSpine("LS", 2, 2)
+		eps := newEndpoints(2)
+		for _, ep := range eps {
+			f.PlugIn(ep.port)
+		}
+
+		Expect(f.Switches[0].Routes(eps[1].port.AsRemote())).To(HaveLen(1))
+	})
+
+	It("should wrap around a torus", func() {
+		f := builder.BuildTorus("Torus", 4, 4)
+		Expect(f.Switches).To(HaveLen(16))
+		Expect(f.Links).To(HaveLen(32))
+
+		eps := newEndpoints(16)
+		for _, ep := range eps {
+			f.PlugIn(ep.port)
+		}
+
+		// Del 0 al 3 hay un salto gracias a la vuelta.
+		Expect(f.PathLatency(0, 3)).To(Equal(f.PathLatency(0, 1)))
+		// Del 0 al 2 hay dos caminos igual de cortos en esa dimensión.
+		Expect(f.Switches[0].Routes(eps[2].port.AsRemote())).To(HaveLen(2))
+	})
+
+	It("should deliver all-to-all traffic on a dragonfly", func() {
+		f := builder.BuildDragonfly("DF", 3, 2)
+		Expect(f.Links).To(HaveLen(3 + 3))
+
+		eps := newEndpoints(6)
+		for _, ep := range eps {
+			f.PlugIn(ep.port)
+		}
+
+		for _, src := range eps {
+			for _, dst := range eps {
+				if src != dst {
+					Expect(src.port.Send(newSampleMsg(src.port, dst.port, 64))).To(BeNil())
+				}
+			}
+		}
+		Expect(engine.Run()).To(Succeed())
+
+		for _, ep := range eps {
+			Expect(ep.recvMsgs).To(HaveLen(5))
+		}
+		for _, sw := range f.Switches {
+			Expect(sw.Counters.UnroutableMsgs).To(BeZero())
+		}
+	})
+
+	It("should prefer the lowest-latency path", func() {
+		f := builder.Build("Custom")
+		a, b, c := f.AddSwitch(), f.AddSwitch(), f.AddSwitch()
+		f.Connect(a, b, 500e-9) // Fibra larga directa.
+		f.Connect(a, c, 10e-9)
+		f.Connect(c, b, 10e-9)
+
+		eps := newEndpoints(2)
+		f.PlugInto(a, eps[0].port)
+		f.PlugInto(b, eps[1].port)
+
+		Expect(eps[0].port.Send(newSampleMsg(eps[0].port, eps[1].port, 64))).To(BeNil())
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(f.Switches[c].Counters.ForwardedMsgs).To(Equal(uint64(1)))
+		Expect(float64(eps[1].recvTimes[0])).To(BeNumerically("~", 160e-9, 1e-12))
+		Expect(float64(eps[1].recvTimes[0])).To(BeNumerically("~",
+			float64(2*10e-9+f.Switches[a].Latency+f.PathLatency(a, b)), 1e-12))
+	})
+
+	It("should find the cyclic routes that may deadlock", func() {
+		Expect(builder.BuildLeafSpine("LS", 4, 2).CanDeadlock()).To(BeFalse())
+		Expect(builder.BuildTorus("Ring", 3).CanDeadlock()).To(BeFalse())
+		Expect(builder.BuildTorus("Ring", 5).CanDeadlock()).To(BeTrue())
+		Expect(builder.BuildTorus("Torus", 4, 4).CanDeadlock()).To(BeTrue())
+		Expect(builder.BuildDragonfly("DF", 2, 2).CanDeadlock()).To(BeFalse())
+		Expect(builder.BuildDragonfly("DF", 4, 2).CanDeadlock()).To(BeTrue())
+	})
+
+	It("should only follow the routes to the switches with endpoints",
+		func() {
+			f := builder.BuildTorus("Ring", 5)
+			f.PlugInto(0, newEndpoints(1)[0].port)
+
+			Expect(f.CanDeadlock()).To(BeFalse())
+		})
+
+	It("should not plug endpoints into a fabric without switches", func() {
+		f := builder.Build("Empty")
+
+		Expect(func() { f.PlugIn(newEndpoints(1)[0].port) }).
+			To(PanicWith(ContainSubstring("no switches")))
+	})
+
+	It("should not change the topology after plugging endpoints", func() {
+		f := builder.BuildLeafSpine("LS", 1, 1)
+		f.PlugIn(newEndpoints(1)[0].port)
+
+		Expect(func() { f.AddSwitch() }).To(Panic())
+	})
+})
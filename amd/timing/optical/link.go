@@ -1,10 +1,11 @@
 package optical
 
 import (
-	"fmt"
 	"log"
+	"reflect"
 
 	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/akita/v4/tracing"
 )
 
 // -- EVENTO : *Entrega de luz llegando* --
@@ -12,20 +13,75 @@ type LinkDeliveryEvent struct {
 	*sim.EventBase // Cumpliendo con interfaz Event.
 	Msg            sim.Msg
 	DstPort        sim.Port
+	Wavelength     int
+	gen            uint64
 }
 
-func NewLinkDeliveryEvent(time sim.VTimeInSec, handler sim.Handler, msg sim.Msg, dst sim.Port) *LinkDeliveryEvent {
+func NewLinkDeliveryEvent(time sim.VTimeInSec, handler sim.Handler, msg sim.Msg, dst sim.Port, wavelength int, gen uint64) *LinkDeliveryEvent {
 	return &LinkDeliveryEvent{
+		EventBase:  sim.NewEventBase(time, handler),
+		Msg:        msg,
+		DstPort:    dst,
+		Wavelength: wavelength,
+		gen:        gen,
+	}
+}
+
+// -- EVENTO : *Fin de serialización* --
+// El último bit del mensaje salió del transmisor, esa longitud de onda queda libre.
+type LinkTransmitDoneEvent struct {
+	*sim.EventBase
+	Msg        sim.Msg
+	SrcPort    sim.Port
+	Wavelength int
+	gen        uint64
+}
+
+func NewLinkTransmitDoneEvent(time sim.VTimeInSec, handler sim.Handler, msg sim.Msg, src sim.Port, wavelength int, gen uint64) *LinkTransmitDoneEvent {
+	return &LinkTransmitDoneEvent{
+		EventBase:  sim.NewEventBase(time, handler),
+		Msg:        msg,
+		SrcPort:    src,
+		Wavelength: wavelength,
+		gen:        gen,
+	}
+}
+
+// -- EVENTO : *Reintento de entrega* --
+// El Port destino liberó espacio. No podemos entregar dentro de NotifyAvailable
+// porque el Port todavía tiene su lock tomado, así que lo hacemos en un evento.
+type LinkRetryEvent struct {
+	*sim.EventBase
+	DstPort sim.Port
+	gen     uint64
+}
+
+func NewLinkRetryEvent(time sim.VTimeInSec, handler sim.Handler, dst sim.Port, gen uint64) *LinkRetryEvent {
+	return &LinkRetryEvent{
 		EventBase: sim.NewEventBase(time, handler),
-		Msg:       msg,
 		DstPort:   dst,
+		gen:       gen,
 	}
 }
 
-// Metiendo un mensaje en el INPUT BUFFER del PORT DESTINO.
-func (e *LinkDeliveryEvent) Execute(engine sim.Engine) error {
-	e.DstPort.Deliver(e.Msg) // Asumimos que la cola no estará llena.
-	return nil
+// Estado de UNA dirección de la fibra (full-duplex: A->B y B->A son independientes).
+type linkDirection struct {
+	src, dst sim.Port
+
+	transmitting   []bool      // Por longitud de onda: el transmisor está serializando.
+	inFlight       [][]sim.Msg // Por longitud de onda: viajando por la fibra, en orden de salida.
+	arrived        []sim.Msg   // Llegaron al otro extremo pero el Port destino estaba lleno.
+	retryScheduled bool        // Ya hay un LinkRetryEvent pendiente.
+	contendedMsg   string      // Cabeza ya contada como contención (ver channelFor).
+}
+
+func newLinkDirection(src, dst sim.Port, wavelengths int) *linkDirection {
+	return &linkDirection{
+		src:          src,
+		dst:          dst,
+		transmitting: make([]bool, wavelengths),
+		inFlight:     make([][]sim.Msg, wavelengths),
+	}
 }
 
 // --- DEFINICIÓN DEL COMPONENTE FIBRA ÓPTICA ---
@@ -34,14 +90,40 @@ type Link struct {
 
 	SideA   sim.Port
 	SideB   sim.Port
-	Latency sim.VTimeInSec
+	Latency sim.VTimeInSec // Propagación (depende de la longitud de la fibra).
+
+	// Line rate de CADA longitud de onda, por dirección, en bytes/segundo.
+	// 0 = ancho de banda infinito.
+	Bandwidth float64
+
+	// WDM: cantidad de longitudes de onda (canales independientes) por
+	// dirección. Se fija antes de PlugIn. 1 = fibra de un solo canal.
+	Wavelengths int
+
+	// Mensajes que esperaron porque SU longitud de onda estaba ocupada
+	// mientras otra estaba libre (el Switch la asignó así, ver wdm.go).
+	WavelengthContention uint64
+	WavelengthBytes      []uint64 // Bytes transmitidos por longitud de onda (ambas direcciones).
+
+	// Mensajes descartados porque la fibra no tiene nada del otro lado.
+	DroppedMsgs uint64
+
+	Power PowerModel // Consumo (ver power.go).
+
+	directions []*linkDirection
+	plugGen    uint64 // Invalida los eventos de antes del último Unplug.
 }
 
-// TODO. Justo ahora la latencia es estática y el bandwidth infinito.
-func NewLink(name string, engine sim.Engine, latency sim.VTimeInSec) *Link {
+// Ancho de banda por defecto (ver switch.go): PCIe 4 x16 ~= 32 GB/s.
+const DefaultLinkBandwidth = 32e9
+
+func NewLink(name string, engine sim.Engine, latency sim.VTimeInSec, bandwidth float64) *Link {
 	l := &Link{
 		TickingComponent: sim.NewTickingComponent(name, engine, 1*sim.GHz, nil),
 		Latency:          latency,
+		Bandwidth:        bandwidth,
+		Wavelengths:      1,
+		Power:            DefaultPowerModel(),
 	}
 	return l
 }
@@ -50,17 +132,45 @@ func NewLink(name string, engine sim.Engine, latency sim.VTimeInSec) *Link {
 func (l *Link) Handle(e sim.Event) error {
 	switch evt := e.(type) {
 	case *LinkDeliveryEvent: // Es nuestro evento de entrega.
-		return evt.Execute(l.Engine)
+		if evt.gen != l.plugGen { // Se desenchufó después: ya se descartó.
+			return nil
+		}
+		return evt.Execute(l)
+	case *LinkTransmitDoneEvent: // El transmisor quedó libre.
+		if evt.gen != l.plugGen {
+			l.releaseSwitchWavelength(evt.SrcPort, evt.Msg)
+			return nil
+		}
+		return l.finishTransmit(evt)
+	case *LinkRetryEvent: // El destino tiene espacio otra vez.
+		if evt.gen != l.plugGen {
+			return nil
+		}
+		dir := l.directionTo(evt.DstPort)
+		dir.retryScheduled = false
+		l.deliverArrived(dir)
+		return nil
 	default: // Otra cosa = tick.
 		return l.TickingComponent.Handle(e)
 	}
 }
 
+// Metiendo un mensaje en el INPUT BUFFER del PORT DESTINO.
+// Si el buffer está lleno el mensaje espera en la fibra (no se pierde).
+// Los eventos con el mismo tiempo pueden ejecutarse en cualquier orden, por eso
+// se entrega siempre el más antiguo en vuelo de esa longitud de onda y no
+// e.Msg directamente.
+func (e *LinkDeliveryEvent) Execute(l *Link) error {
+	dir := l.directionTo(e.DstPort)
+	dir.arrived = append(dir.arrived, dir.inFlight[e.Wavelength][0])
+	dir.inFlight[e.Wavelength] = dir.inFlight[e.Wavelength][1:]
+	l.deliverArrived(dir)
+	return nil
+}
+
 // --- REQUISITOS INTERFACE CONNECTION ---
 func (l *Link) PlugIn(port sim.Port) {
 
-	fmt.Printf("[DEBUG_PLUGIN] Link '%s' connected to Port '%s' (Pointer: %p).\n", l.Name(), port.Name(), port)
-
 	if l.SideA == nil {
 		l.SideA = port
 	} else if l.SideB == nil {
@@ -69,19 +179,69 @@ func (l *Link) PlugIn(port sim.Port) {
 		log.Panicf("OpticalLink %s supports only 2 ports.", l.Name())
 	}
 
+	// Con los dos extremos (también al volver a enchufar uno) se arman las
+	// dos direcciones.
+	if l.SideA != nil && l.SideB != nil {
+		if l.Wavelengths < 1 {
+			l.Wavelengths = 1
+		}
+		l.directions = []*linkDirection{
+			newLinkDirection(l.SideA, l.SideB, l.Wavelengths),
+			newLinkDirection(l.SideB, l.SideA, l.Wavelengths),
+		}
+		if len(l.WavelengthBytes) != l.Wavelengths {
+			l.WavelengthBytes = make([]uint64, l.Wavelengths)
+		}
+	}
+
 	port.SetConnection(l) // Guardando un puntero a este cable.
 }
 
+// Desenchufa un extremo. Lo que viajaba por la fibra se pierde: los eventos
+// pendientes quedan invalidados (plugGen) y sus mensajes se cuentan como
+// descartados, igual que lo que esperaba en los dos ports para salir.
 func (l *Link) Unplug(port sim.Port) {
 	if l.SideA == port {
 		l.SideA = nil
 	} else if l.SideB == port {
 		l.SideB = nil
+	} else {
+		return
+	}
+
+	l.plugGen++
+	for _, dir := range l.directions {
+		for _, msgs := range dir.inFlight {
+			for _, msg := range msgs {
+				l.drop(msg)
+			}
+		}
+		for _, msg := range dir.arrived {
+			l.drop(msg)
+		}
+	}
+	l.directions = nil
+
+	l.dropUnconnected(port)
+	if l.SideA != nil {
+		l.dropUnconnected(l.SideA)
+	}
+	if l.SideB != nil {
+		l.dropUnconnected(l.SideB)
 	}
 }
 
+// El Port destino liberó espacio en su INPUT BUFFER: reintentamos entregar.
 func (l *Link) NotifyAvailable(port sim.Port) {
-	// No-op.
+	for _, dir := range l.directions {
+		if dir.dst != port || dir.retryScheduled || len(dir.arrived) == 0 {
+			continue
+		}
+
+		dir.retryScheduled = true
+		evt := NewLinkRetryEvent(l.Engine.CurrentTime(), l, port, l.plugGen)
+		l.Engine.Schedule(evt)
+	}
 }
 
 // Llamado automáticamente cuando alguien quiere enviar algo.
@@ -97,29 +257,190 @@ func (l *Link) NotifySend() {
 	}
 }
 
-// --- LÓGICA DE DESEMPAQUETADO ---
-func (l *Link) CheckAndForward(now sim.VTimeInSec, src, dst sim.Port) {
-	msg := src.RetrieveOutgoing() // Extrayendo el mensaje del port origen.
+// Sin destino el mensaje nunca llegaría: se saca para no bloquear el origen,
+// como hace el Switch con los que no tienen ruta.
+func (l *Link) dropUnconnected(src sim.Port) {
+	for {
+		msg := src.RetrieveOutgoing()
+		if msg == nil {
+			return
+		}
 
-	if msg == nil {
-		return
+		tracing.StartTask(tracing.MsgIDAtReceiver(msg, l),
+			msg.Meta().ID+"_req_out", l, TaskKindTransmit,
+			reflect.TypeOf(msg).String(), msg)
+		l.drop(msg)
 	}
+}
 
+// Cuenta un mensaje descartado y cierra su tarea de transmisión.
+func (l *Link) drop(msg sim.Msg) {
+	l.DroppedMsgs++
+	taskID := tracing.MsgIDAtReceiver(msg, l)
+	tracing.AddTaskStep(taskID, l, "not_connected")
+	tracing.EndTask(taskID, l)
+}
+
+// --- LÓGICA DE SERIALIZACIÓN + DESEMPAQUETADO ---
+func (l *Link) CheckAndForward(now sim.VTimeInSec, src, dst sim.Port) {
 	if dst == nil {
-		fmt.Printf("[LINK_ERROR] %s: Destination not connected.\n", l.Name())
+		l.dropUnconnected(src)
 		return
 	}
 
-	msgToDeliver := msg
+	dir := l.directionFrom(src)
 
-	// Si el mensaje viene del Switch (sobre), lo abrimos.
+	// BACK-PRESSURE: mientras el destino está lleno, o no hay transmisor libre,
+	// el mensaje se queda en el OUTPUT BUFFER del origen.
+	for len(dir.arrived) == 0 {
+		msg := src.PeekOutgoing()
+		if msg == nil {
+			return
+		}
+
+		wavelength, ok := l.channelFor(dir, msg)
+		if !ok {
+			return
+		}
+
+		// Marcamos ocupado ANTES de extraer: RetrieveOutgoing puede despertar
+		// al componente origen y que vuelva a llamar a NotifySend.
+		dir.transmitting[wavelength] = true
+		serialization := l.SerializationDelay(msg)
+		src.RetrieveOutgoing() // Extrayendo el mensaje del port origen.
+		l.WavelengthBytes[wavelength] += uint64(WireBytes(msg))
+
+		msgToDeliver := msg
+
+		// Si el mensaje viene del Switch (sobre), lo abrimos.
+		// Si viene de la GPU (mensaje normal), lo pasamos tal cual.
+		if pkt, ok := msg.(*OpticalPacket); ok {
+			msgToDeliver = pkt.InnerMsg
+		}
+
+		// Tarea: desde que sale del buffer hasta que se entrega (ver trace.go).
+		tracing.StartTask(tracing.MsgIDAtReceiver(msgToDeliver, l),
+			msgToDeliver.Meta().ID+"_req_out", l, TaskKindTransmit,
+			reflect.TypeOf(msgToDeliver).String(), msg)
+
+		// t_llegada = t_serialización + t_propagación.
+		doneEvt := NewLinkTransmitDoneEvent(now+serialization, l, msg, src, wavelength, l.plugGen)
+		l.Engine.Schedule(doneEvt)
+
+		// Diciéndole al motor (Engine) que ejecute luego.
+		dir.inFlight[wavelength] = append(dir.inFlight[wavelength], msgToDeliver)
+		evt := NewLinkDeliveryEvent(now+serialization+l.Latency, l, msgToDeliver, dst, wavelength, l.plugGen)
+		l.Engine.Schedule(evt) // "Despiértenme en now+s+l"
+	}
+}
+
+// Longitud de onda por la que sale el mensaje.
+// Un OpticalPacket trae la que le asignó el Switch; si está ocupada espera
+// aunque haya otras libres (contención). Los mensajes de la GPU salen por
+// cualquier transmisor libre.
+func (l *Link) channelFor(dir *linkDirection, msg sim.Msg) (int, bool) {
+	if pkt, ok := msg.(*OpticalPacket); ok {
+		wavelength := pkt.Wavelength % len(dir.transmitting)
+		if !dir.transmitting[wavelength] {
+			return wavelength, true
+		}
+
+		if dir.contendedMsg != msg.Meta().ID && l.anyIdle(dir) {
+			dir.contendedMsg = msg.Meta().ID
+			l.WavelengthContention++
+		}
+		return 0, false
+	}
+
+	for wavelength, busy := range dir.transmitting {
+		if !busy {
+			return wavelength, true
+		}
+	}
+	return 0, false
+}
+
+func (l *Link) anyIdle(dir *linkDirection) bool {
+	for _, busy := range dir.transmitting {
+		if !busy {
+			return true
+		}
+	}
+	return false
+}
+
+// Tiempo que tarda en salir el mensaje completo: bytes / line rate.
+func (l *Link) SerializationDelay(msg sim.Msg) sim.VTimeInSec {
+	if l.Bandwidth <= 0 {
+		return 0
+	}
+
+	return sim.VTimeInSec(float64(WireBytes(msg)) / l.Bandwidth)
+}
+
+// Bytes que realmente viajan por la fibra.
+// Un OpticalPacket lleva su propio encabezado + el mensaje encapsulado.
+func WireBytes(msg sim.Msg) int {
+	return MessageBytes(msg)
+}
+
+func (l *Link) finishTransmit(evt *LinkTransmitDoneEvent) error {
+	dir := l.directionFrom(evt.SrcPort)
+	dir.transmitting[evt.Wavelength] = false
+	l.releaseSwitchWavelength(dir.src, evt.Msg)
+
+	l.CheckAndForward(l.Engine.CurrentTime(), dir.src, dir.dst)
+
+	return nil
+}
+
+// El Switch reserva la longitud de onda mientras el flujo tenga mensajes sin
+// transmitir (ver wdm.go). Se libera aunque la fibra se haya desenchufado.
+func (l *Link) releaseSwitchWavelength(src sim.Port, msg sim.Msg) {
 	if pkt, ok := msg.(*OpticalPacket); ok {
-		msgToDeliver = pkt.InnerMsg
-	} else { // Viene de la GPU (mensaje normal), lo pasamos tal cual.
-		fmt.Printf("[LINK_TRAFFIC] %s: %s -> %s (Type: %T)\n", l.Name(), src.Name(), dst.Name(), msg)
+		if sw, ok := src.Component().(*Switch); ok {
+			sw.releaseWavelength(src, pkt)
+		}
 	}
+}
 
-	// Diciéndole al motor (Engine) que ejecute luego.
-	evt := NewLinkDeliveryEvent(now+l.Latency, l, msgToDeliver, dst)
-	l.Engine.Schedule(evt) // "Despiértenme en now+l"
+// Entrega en orden lo que ya llegó. Se saca de la cola ANTES de entregar porque
+// Deliver despierta al componente destino, que puede volver a llamarnos.
+func (l *Link) deliverArrived(dir *linkDirection) {
+	for len(dir.arrived) > 0 {
+		msg := dir.arrived[0]
+		dir.arrived = dir.arrived[1:]
+
+		if err := dir.dst.Deliver(msg); err != nil {
+			dir.arrived = append([]sim.Msg{msg}, dir.arrived...)
+			return
+		}
+
+		tracing.EndTask(tracing.MsgIDAtReceiver(msg, l), l)
+	}
+
+	// Destino libre otra vez: si el origen tenía algo esperando, sigue.
+	l.CheckAndForward(l.Engine.CurrentTime(), dir.src, dir.dst)
+}
+
+func (l *Link) directionFrom(src sim.Port) *linkDirection {
+	for _, dir := range l.directions {
+		if dir.src == src {
+			return dir
+		}
+	}
+
+	log.Panicf("OpticalLink %s: port %s is not plugged in.", l.Name(), src.Name())
+	return nil
+}
+
+func (l *Link) directionTo(dst sim.Port) *linkDirection {
+	for _, dir := range l.directions {
+		if dir.dst == dst {
+			return dir
+		}
+	}
+
+	log.Panicf("OpticalLink %s: port %s is not plugged in.", l.Name(), dst.Name())
+	return nil
 }
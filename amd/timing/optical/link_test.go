@@ -0,0 +1,121 @@
+package optical
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+var _ = Describe("Link", func() {
+	var (
+		engine *sim.SerialEngine
+		a, b   *endpoint
+		link   *Link
+	)
+
+	BeforeEach(func() {
+		engine = sim.NewSerialEngine()
+		a = newEndpoint("A", engine, 4, 4)
+		b = newEndpoint("B", engine, 1, 4)
+		link = NewLink("Fiber", engine, 10e-9, 1e9) // 1 byte/ns.
+		link.PlugIn(a.port)
+		link.PlugIn(b.port)
+	})
+
+	It("should add serialization delay on top of the latency", func() {
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 100))).To(BeNil())
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(b.recvTimes).To(HaveLen(1))
+		Expect(float64(b.recvTimes[0])).To(BeNumerically("~", 110e-9, 1e-12))
+	})
+
+	It("should serialize messages in the same direction", func() {
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 100))).To(BeNil())
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 50))).To(BeNil())
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(b.recvTimes).To(HaveLen(2))
+		Expect(float64(b.recvTimes[0])).To(BeNumerically("~", 110e-9, 1e-12))
+		Expect(float64(b.recvTimes[1])).To(BeNumerically("~", 160e-9, 1e-12))
+	})
+
+	It("should transmit both directions independently", func() {
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 100))).To(BeNil())
+		Expect(b.port.Send(newSampleMsg(b.port, a.port, 100))).To(BeNil())
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(float64(a.recvTimes[0])).To(BeNumerically("~", 110e-9, 1e-12))
+		Expect(float64(b.recvTimes[0])).To(BeNumerically("~", 110e-9, 1e-12))
+	})
+
+	It("should apply back-pressure when the source port is busy", func() {
+		// The first message goes on the fiber, the other 4 fill the buffer.
+		for i := 0; i < 5; i++ {
+			Expect(a.port.Send(newSampleMsg(a.port, b.port, 100))).To(BeNil())
+		}
+
+		Expect(a.port.CanSend()).To(BeFalse())
+	})
+
+	It("should hold messages until the destination has space", func() {
+		b.autoRetrieve = false
+		msg1 := newSampleMsg(a.port, b.port, 10)
+		msg2 := newSampleMsg(a.port, b.port, 10)
+		Expect(a.port.Send(msg1)).To(BeNil())
+		Expect(a.port.Send(msg2)).To(BeNil())
+		scheduleDrain(engine, 1e-6, b)
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(b.recvMsgs).To(Equal([]sim.Msg{msg1}))
+		Expect(b.port.PeekIncoming()).To(BeIdenticalTo(msg2))
+	})
+
+	It("should drop the messages of a fiber with one side unconnected", func() {
+		c := newEndpoint("C", engine, 4, 4)
+		half := NewLink("HalfFiber", engine, 10e-9, 1e9)
+		half.PlugIn(c.port)
+		Expect(c.port.Send(newSampleMsg(c.port, b.port, 10))).To(BeNil())
+		Expect(c.port.Send(newSampleMsg(c.port, b.port, 10))).To(BeNil())
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(half.DroppedMsgs).To(Equal(uint64(2)))
+		Expect(c.port.PeekOutgoing()).To(BeNil())
+		Expect(b.recvMsgs).To(BeEmpty())
+	})
+
+	It("should drop the messages in flight when a side is unplugged", func() {
+		for i := 0; i < 3; i++ {
+			Expect(a.port.Send(newSampleMsg(a.port, b.port, 100))).To(BeNil())
+		}
+		scheduleCall(engine, 50e-9, func() { link.Unplug(b.port) })
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(link.DroppedMsgs).To(Equal(uint64(3)))
+		Expect(a.port.PeekOutgoing()).To(BeNil())
+		Expect(b.recvMsgs).To(BeEmpty())
+	})
+
+	It("should deliver to a port plugged in place of the old one", func() {
+		c := newEndpoint("C", engine, 4, 4)
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 100))).To(BeNil())
+		scheduleCall(engine, 50e-9, func() {
+			link.Unplug(b.port)
+			link.PlugIn(c.port)
+			Expect(a.port.Send(newSampleMsg(a.port, c.port, 100))).To(BeNil())
+		})
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(link.DroppedMsgs).To(Equal(uint64(1)))
+		Expect(b.recvMsgs).To(BeEmpty())
+		Expect(c.recvTimes).To(HaveLen(1))
+		Expect(float64(c.recvTimes[0])).To(BeNumerically("~", 160e-9, 1e-12))
+	})
+})
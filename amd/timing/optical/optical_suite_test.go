@@ -0,0 +1,127 @@
+package optical
+
+import (
+	"log"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+func TestOptical(t *testing.T) {
+	log.SetOutput(GinkgoWriter)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Optical Suite")
+}
+
+type sampleMsg struct {
+	sim.MsgMeta
+}
+
+func (m *sampleMsg) Meta() *sim.MsgMeta {
+	return &m.MsgMeta
+}
+
+func (m *sampleMsg) Clone() sim.Msg {
+	cloneMsg := *m
+	cloneMsg.ID = sim.GetIDGenerator().Generate()
+	return &cloneMsg
+}
+
+func newSampleMsg(src, dst sim.Port, bytes int) *sampleMsg {
+	return &sampleMsg{
+		MsgMeta: sim.MsgMeta{
+			ID:           sim.GetIDGenerator().Generate(),
+			Src:          src.AsRemote(),
+			Dst:          dst.AsRemote(),
+			TrafficBytes: bytes,
+		},
+	}
+}
+
+// endpoint is a component that records when each message arrives. It only
+// drains its incoming buffer when autoRetrieve is set.
+type endpoint struct {
+	*sim.ComponentBase
+	engine sim.Engine
+
+	port         sim.Port
+	autoRetrieve bool
+	recvTimes    []sim.VTimeInSec
+	recvMsgs     []sim.Msg
+}
+
+func newEndpoint(name string, engine sim.Engine, inBuf, outBuf int) *endpoint {
+	e := &endpoint{
+		ComponentBase: sim.NewComponentBase(name),
+		engine:        engine,
+		autoRetrieve:  true,
+	}
+	e.port = sim.NewPort(e, inBuf, outBuf, name+".Port")
+	e.AddPort("Port", e.port)
+
+	return e
+}
+
+func (e *endpoint) Handle(_ sim.Event) error {
+	return nil
+}
+
+func (e *endpoint) NotifyRecv(_ sim.Port) {
+	if e.autoRetrieve {
+		e.drain()
+	}
+}
+
+func (e *endpoint) NotifyPortFree(_ sim.Port) {}
+
+func (e *endpoint) drain() {
+	for {
+		msg := e.port.RetrieveIncoming()
+		if msg == nil {
+			return
+		}
+
+		e.recvTimes = append(e.recvTimes, e.engine.CurrentTime())
+		e.recvMsgs = append(e.recvMsgs, msg)
+	}
+}
+
+// drainEvent lets a test retrieve incoming messages at a given time.
+type drainEvent struct {
+	*sim.EventBase
+	endpoint *endpoint
+}
+
+func (e *drainEvent) Handler() sim.Handler {
+	return e
+}
+
+func (e *drainEvent) Handle(_ sim.Event) error {
+	e.endpoint.drain()
+	return nil
+}
+
+func scheduleDrain(engine sim.Engine, t sim.VTimeInSec, ep *endpoint) {
+	evt := &drainEvent{endpoint: ep}
+	evt.EventBase = sim.NewEventBase(t, evt)
+	engine.Schedule(evt)
+}
+
+// callEvent lets a test run a function at a given time.
+type callEvent struct {
+	*sim.EventBase
+	f func()
+}
+
+func (e *callEvent) Handle(_ sim.Event) error {
+	e.f()
+	return nil
+}
+
+func scheduleCall(engine sim.Engine, t sim.VTimeInSec, f func()) {
+	evt := &callEvent{f: f}
+	evt.EventBase = sim.NewEventBase(t, evt)
+	engine.Schedule(evt)
+}
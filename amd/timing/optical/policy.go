@@ -0,0 +1,113 @@
+package optical
+
+// --- POLÍTICAS DE PREDICCIÓN DE TRÁFICO ---
+
+// Demanda estimada por par (Source -> Destination -> Bytes).
+type DemandMatrix map[string]map[string]float64
+
+func (m DemandMatrix) Add(src, dst string, bytes float64) {
+	if _, ok := m[src]; !ok {
+		m[src] = make(map[string]float64)
+	}
+	m[src][dst] += bytes
+}
+
+func (m DemandMatrix) Get(src, dst string) float64 {
+	return m[src][dst]
+}
+
+// Una política recibe el tráfico observado en la última época y estima el de
+// la próxima. El Controller decide los circuitos a partir de esa estimación.
+type PredictionPolicy interface {
+	Name() string
+	Predict(epochTraffic TrafficMatrix) DemandMatrix
+}
+
+// Una política de asignación elige los circuitos de la próxima época: pares
+// (Source, Destination) donde cada origen y cada destino aparece una sola vez.
+// Recibe lo que espera en los buffers del Switch y la demanda predicha.
+type MatchingPolicy interface {
+	Name() string
+	Match(pending TrafficMatrix, demand DemandMatrix) [][2]string
+}
+
+// Asignación voraz, ver GreedyMatching.
+type GreedyMatchingPolicy struct{}
+
+func (p *GreedyMatchingPolicy) Name() string {
+	return "greedy"
+}
+
+func (p *GreedyMatchingPolicy) Match(
+	pending TrafficMatrix,
+	demand DemandMatrix,
+) [][2]string {
+	return GreedyMatching(pending, demand)
+}
+
+// La próxima época será igual a la última.
+type LastEpochPolicy struct{}
+
+func NewLastEpochPolicy() *LastEpochPolicy {
+	return &LastEpochPolicy{}
+}
+
+func (p *LastEpochPolicy) Name() string {
+	return "last-epoch"
+}
+
+func (p *LastEpochPolicy) Predict(epochTraffic TrafficMatrix) DemandMatrix {
+	demand := make(DemandMatrix)
+	for src, row := range epochTraffic {
+		for dst, bytes := range row {
+			demand.Add(src, dst, float64(bytes))
+		}
+	}
+	return demand
+}
+
+// Promedio móvil exponencial: pred = Alpha * observado + (1 - Alpha) * pred_anterior.
+// Alpha cercano a 1 reacciona rápido, cercano a 0 suaviza ráfagas.
+type EWMAPolicy struct {
+	Alpha float64
+
+	prediction DemandMatrix
+}
+
+func NewEWMAPolicy(alpha float64) *EWMAPolicy {
+	return &EWMAPolicy{
+		Alpha:      alpha,
+		prediction: make(DemandMatrix),
+	}
+}
+
+func (p *EWMAPolicy) Name() string {
+	return "ewma"
+}
+
+func (p *EWMAPolicy) Predict(epochTraffic TrafficMatrix) DemandMatrix {
+	next := make(DemandMatrix)
+
+	for src, row := range p.prediction {
+		for dst, old := range row {
+			next.Add(src, dst, (1-p.Alpha)*old)
+		}
+	}
+
+	for src, row := range epochTraffic {
+		for dst, bytes := range row {
+			next.Add(src, dst, p.Alpha*float64(bytes))
+		}
+	}
+
+	p.prediction = next
+
+	// Copia: el Controller no debe poder modificar el estado interno.
+	demand := make(DemandMatrix)
+	for src, row := range next {
+		for dst, bytes := range row {
+			demand.Add(src, dst, bytes)
+		}
+	}
+	return demand
+}
@@ -0,0 +1,48 @@
+package optical
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+var _ = Describe("Power model", func() {
+	var engine *sim.SerialEngine
+
+	BeforeEach(func() {
+		engine = sim.NewSerialEngine()
+	})
+
+	It("should charge lasers for time and transceivers for bits", func() {
+		a := newEndpoint("A", engine, 4, 4)
+		b := newEndpoint("B", engine, 4, 4)
+		link := NewLink("Fiber", engine, 10e-9, 0)
+		link.Wavelengths = 2
+		link.PlugIn(a.port)
+		link.PlugIn(b.port)
+
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+		Expect(engine.Run()).To(Succeed())
+
+		e := link.Energy(1e-6)
+		bits := 2 * 64 * 8.0
+		Expect(e.Laser).To(BeNumerically("~", 10e-3*2*2*1e-6, 1e-18))
+		Expect(e.Modulator).To(BeNumerically("~", 50e-15*bits, 1e-24))
+		Expect(e.Detector).To(BeNumerically("~", 100e-15*bits, 1e-24))
+		Expect(e.SwitchStatic).To(BeZero())
+	})
+
+	It("should charge switches for static power and reconfigurations", func() {
+		sw := NewSwitch("Switch", engine)
+		sw.Power.ReconfigurationEnergy = 1e-6
+
+		sw.Reconfigure(nil)
+		Expect(engine.Run()).To(Succeed())
+
+		e := sw.Energy(2e-6)
+		Expect(e.SwitchStatic).To(BeNumerically("~", 2e-6, 1e-18))
+		Expect(e.Reconfiguration).To(BeNumerically("~", 1e-6, 1e-18))
+		Expect(e.Total()).To(BeNumerically("~", 3e-6, 1e-18))
+	})
+})
@@ -0,0 +1,117 @@
+package optical
+
+import (
+	"reflect"
+
+	"github.com/sarchlab/akita/v4/mem/mem"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/timing/pagemigrationcontroller"
+)
+
+// --- TAMAÑO DE LOS MENSAJES ---
+
+// Encabezado del OpticalPacket: ID del Paquete (8B) + Origen (4B) + Destino (4B).
+const OpticalPacketHeaderBytes = 16
+
+// Encabezados de los protocolos de memoria cuando el creador del mensaje no
+// completó TrafficBytes (mismos valores que usa akita/mem y la PMC).
+const (
+	memReqHeaderBytes = 12 // Dirección + PID + tamaño.
+	memRspHeaderBytes = 4  // ID de la respuesta.
+)
+
+// Bytes de un mensaje en la red (payload + encabezado de su protocolo).
+// Se usa el TrafficBytes de la metadata, que completa quien crea el mensaje;
+// si vale 0 se estima según el tipo. Un OpticalPacket suma su propio
+// encabezado al mensaje que encapsula.
+func MessageBytes(msg sim.Msg) int {
+	if pkt, ok := msg.(*OpticalPacket); ok {
+		bytes := pkt.TrafficBytes
+		if pkt.InnerMsg != nil {
+			bytes += MessageBytes(pkt.InnerMsg)
+		}
+		return bytes
+	}
+
+	if bytes := msg.Meta().TrafficBytes; bytes > 0 {
+		return bytes
+	}
+
+	switch m := msg.(type) {
+	case *mem.ReadReq:
+		return memReqHeaderBytes
+	case *mem.WriteReq:
+		return memReqHeaderBytes + len(m.Data)
+	case *mem.DataReadyRsp:
+		return memRspHeaderBytes + len(m.Data)
+	case *mem.WriteDoneRsp:
+		return memRspHeaderBytes
+	case *pagemigrationcontroller.DataPullReq:
+		return memReqHeaderBytes
+	case *pagemigrationcontroller.DataPullRsp:
+		return memRspHeaderBytes + len(m.Data)
+	default:
+		return memRspHeaderBytes // Al menos un encabezado.
+	}
+}
+
+// Clase de tráfico: la de la metadata o, si no tiene, el tipo del mensaje
+// (p. ej. "pagemigrationcontroller.DataPullRsp").
+func TrafficClassOf(msg sim.Msg) string {
+	if class := msg.Meta().TrafficClass; class != "" {
+		return class
+	}
+
+	t := reflect.TypeOf(msg)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.String()
+}
+
+// Bytes y mensajes acumulados.
+type TrafficCount struct {
+	Bytes uint64
+	Msgs  uint64
+}
+
+// Source -> Destination -> Clase de tráfico -> Conteo.
+type ClassTrafficMatrix map[string]map[string]map[string]TrafficCount
+
+func (m ClassTrafficMatrix) Add(src, dst, class string, bytes uint64) {
+	if _, ok := m[src]; !ok {
+		m[src] = make(map[string]map[string]TrafficCount)
+	}
+	if _, ok := m[src][dst]; !ok {
+		m[src][dst] = make(map[string]TrafficCount)
+	}
+
+	c := m[src][dst][class]
+	c.Bytes += bytes
+	c.Msgs++
+	m[src][dst][class] = c
+}
+
+// Total por clase, sumando todos los pares.
+func (m ClassTrafficMatrix) ByClass() map[string]TrafficCount {
+	total := make(map[string]TrafficCount)
+	for _, row := range m {
+		for _, classes := range row {
+			for class, c := range classes {
+				t := total[class]
+				t.Bytes += c.Bytes
+				t.Msgs += c.Msgs
+				total[class] = t
+			}
+		}
+	}
+	return total
+}
+
+// Bytes y mensajes que pasaron por el Switch, por clase de tráfico.
+func (s *Switch) TrafficByClass() map[string]TrafficCount {
+	s.matrixLock.Lock()
+	defer s.matrixLock.Unlock()
+
+	return s.ClassTraffic.ByClass()
+}
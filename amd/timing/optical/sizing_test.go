@@ -0,0 +1,67 @@
+package optical
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/mem/mem"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/mgpusim/v4/amd/timing/pagemigrationcontroller"
+)
+
+var _ = Describe("Message sizing", func() {
+	It("should use the traffic bytes set by the sender", func() {
+		read := (mem.ReadReqBuilder{}).WithAddress(0x100).WithByteSize(64).Build()
+		write := (mem.WriteReqBuilder{}).WithData(make([]byte, 64)).Build()
+
+		Expect(MessageBytes(read)).To(Equal(12))
+		Expect(MessageBytes(write)).To(Equal(76))
+		Expect(TrafficClassOf(write)).To(Equal("mem.WriteReq"))
+	})
+
+	It("should estimate protocol headers when the sender did not", func() {
+		rsp := &mem.DataReadyRsp{Data: make([]byte, 64)}
+		pull := &pagemigrationcontroller.DataPullRsp{Data: make([]byte, 4096)}
+
+		Expect(MessageBytes(rsp)).To(Equal(68))
+		Expect(MessageBytes(pull)).To(Equal(4100))
+		Expect(TrafficClassOf(pull)).To(
+			Equal("pagemigrationcontroller.DataPullRsp"))
+	})
+
+	It("should add the packet header to the encapsulated message", func() {
+		pkt := &OpticalPacket{
+			MsgMeta:  sim.MsgMeta{TrafficBytes: OpticalPacketHeaderBytes},
+			InnerMsg: &mem.WriteDoneRsp{},
+		}
+
+		Expect(MessageBytes(pkt)).To(Equal(OpticalPacketHeaderBytes + 4))
+	})
+
+	It("should count bytes and messages per traffic class", func() {
+		engine := sim.NewSerialEngine()
+		sw := NewSwitch("Switch", engine)
+		a := newEndpoint("A", engine, 4, 4)
+		b := newEndpoint("B", engine, 4, 4)
+		for _, ep := range []*endpoint{a, b} {
+			swPort := sw.CreatePort(ep.Name() + ".SwitchPort")
+			link := NewLink(ep.Name()+".Fiber", engine, 10e-9, 0)
+			link.PlugIn(ep.port)
+			link.PlugIn(swPort)
+			sw.RegisterDestination(ep.port.AsRemote(), swPort)
+		}
+
+		msg := newSampleMsg(a.port, b.port, 64)
+		msg.TrafficClass = "test"
+		Expect(a.port.Send(msg)).To(BeNil())
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+		Expect(engine.Run()).To(Succeed())
+
+		wire := uint64(64 + OpticalPacketHeaderBytes)
+		Expect(sw.SnapshotTraffic()[string(a.port.AsRemote())]).To(
+			HaveKeyWithValue(string(b.port.AsRemote()), 2*wire))
+		Expect(sw.TrafficByClass()).To(Equal(map[string]TrafficCount{
+			"test":              {Bytes: wire, Msgs: 1},
+			"optical.sampleMsg": {Bytes: wire, Msgs: 1},
+		}))
+	})
+})
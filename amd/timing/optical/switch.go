@@ -1,12 +1,14 @@
 package optical
 
 import (
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"log"
 	"sync"
 
-	"github.com/sarchlab/akita/v4/mem/mem"
 	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/akita/v4/tracing"
 )
 
 // --- DEFINICIÓN DEL WRAPPER ---
@@ -17,6 +19,7 @@ import (
 type OpticalPacket struct {
 	sim.MsgMeta         // Metadata (ID, Src, Dst).
 	InnerMsg    sim.Msg // Mensaje real.
+	Wavelength  int     // Canal asignado por el Switch (WDM, ver wdm.go).
 }
 
 // Requisito de interfaz sim.Msg
@@ -40,30 +43,139 @@ func (p *OpticalPacket) Clone() sim.Msg {
 type Switch struct {
 	*sim.TickingComponent // Habilitando un reloj. ES COMPONENTE.
 
-	Latency sim.VTimeInSec // Habilitando Latencia.
+	// Cada mensaje pasa al menos Latency en el Switch desde que llega a un
+	// buffer de entrada hasta que sale (ver processInput).
+	Latency        sim.VTimeInSec
+	arrivals       map[string]sim.VTimeInSec // ID del mensaje -> llegada.
+	latencyWakeups map[sim.Port]string       // Cabeza que ya tiene su evento.
 
 	// Mapea ID de DESTINO (String) -> Puerto de SALIDA (Objeto).
-	// TODO. Que sea modificada dinámicamente por el Controller.
+	// Es estática: el Controller no la toca, cambia los circuitos con
+	// Reconfigure (ver circuit.go y controller.go).
 	RouteTable map[sim.RemotePort]sim.Port
 
+	// Destinos con varios caminos de igual costo (ECMP, ver fabric.go).
+	// RouteTable guarda el primero de cada lista.
+	ecmpRoutes map[sim.RemotePort][]sim.Port
+
 	connectedPorts []sim.Port
 
 	// Recolectamos quién habla con quién (Source -> Destination -> Bytes).
-	// TODO. Que la use el predictor.
-	TrafficMatrix map[string]map[string]uint64
-	matrixLock    sync.Mutex // Lock. La usará el Switch (escribir) + Controller (leer).
+	// La lee el Controller (ver controller.go).
+	TrafficMatrix TrafficMatrix
+	ClassTraffic  ClassTrafficMatrix // Igual, separado por clase de tráfico (con conteo de mensajes).
+	matrixLock    sync.Mutex         // Lock. La usará el Switch (escribir) + Controller (leer).
+	controller    *Controller
+
+	// Parte de RECONFIGURACIÓN (ver circuit.go).
+	Mode                 SwitchMode
+	ReconfigurationDelay sim.VTimeInSec // Tiempo "a oscuras" al cambiar circuitos.
+	NumReconfigurations  uint64
+
+	circuits      map[sim.Port]sim.Port // Puerto ENTRADA -> Puerto SALIDA.
+	reconfiguring bool
+	reconfigGen   uint64 // Invalida eventos de reconfiguraciones superadas.
+
+	// WDM (ver wdm.go). 0 o 1 = un solo canal por fibra, sin asignación.
+	Wavelengths int
+	lightpaths  map[sim.Port]map[flowKey]*lightpath // Salida -> flujo -> longitud de onda.
+
+	Power PowerModel // Consumo (ver power.go).
+
+	// Control de flujo: ningún mensaje se descarta, espera en su buffer de entrada.
+	// Sólo se sacan los que no tienen ruta, y cada uno se avisa a NoRouteHandler.
+	// Sin handler se avisa por el log, una vez por par (Source, Destination).
+	Counters       SwitchCounters
+	NoRouteHandler func(err *NoRouteError)
+	noRouteLogged  map[flowKey]bool
+	stalls         map[sim.Port]stall // Mensaje en la cabeza de cada entrada que está esperando.
+}
+
+// Contadores de control de flujo del Switch.
+type SwitchCounters struct {
+	ForwardedMsgs         uint64
+	UnroutableMsgs        uint64
+	StallsOutputFull      uint64         // Mensajes que esperaron porque la fibra de salida estaba llena.
+	StallsReconfiguring   uint64         // ... porque el Switch estaba a oscuras.
+	StallsNoCircuit       uint64         // ... porque no había circuito hacia su destino.
+	StallsNoWavelength    uint64         // ... porque no había longitud de onda libre (bloqueo WDM).
+	WavelengthAssignments uint64         // Longitudes de onda asignadas a flujos.
+	StallTime             sim.VTimeInSec // Suma de lo que esperaron todos los mensajes.
+}
+
+type stall struct {
+	msgID string
+	since sim.VTimeInSec
+}
+
+type stallReason int
+
+const (
+	noStall stallReason = iota
+	stallReconfiguring
+	stallNoCircuit
+	stallOutputFull
+	stallNoWavelength
+)
+
+func (r stallReason) String() string {
+	switch r {
+	case stallReconfiguring:
+		return "stall_reconfiguring"
+	case stallNoCircuit:
+		return "stall_no_circuit"
+	case stallOutputFull:
+		return "stall_output_full"
+	case stallNoWavelength:
+		return "stall_no_wavelength"
+	default:
+		return "no_stall"
+	}
+}
+
+// El destino no está en la RouteTable.
+type NoRouteError struct {
+	Switch   string
+	Src, Dst sim.RemotePort
+}
+
+func (e *NoRouteError) Error() string {
+	return fmt.Sprintf("[OPTICAL_SWITCH] %s: no route from %s to %s",
+		e.Switch, e.Src, e.Dst)
+}
+
+// La salida no tiene espacio. El mensaje NO se consumió, hay que reintentar.
+var ErrOutputFull = errors.New("[OPTICAL_SWITCH] output port full")
 
-	// TODO.
-	// Parte de RECONFIGURACIÓN.
+// Bytes por par (Source -> Destination).
+type TrafficMatrix map[string]map[string]uint64
+
+func (m TrafficMatrix) Add(src, dst string, bytes uint64) {
+	if _, ok := m[src]; !ok {
+		m[src] = make(map[string]uint64)
+	}
+	m[src][dst] += bytes
 }
 
 func NewSwitch(name string, engine sim.Engine) *Switch {
 	s := &Switch{
-		TickingComponent: sim.NewTickingComponent(name, engine, 1*sim.GHz, nil),
-		Latency:          40 * 1e-9, // (~40ns según Flexfly).
-		RouteTable:       make(map[sim.RemotePort]sim.Port),
-		TrafficMatrix:    make(map[string]map[string]uint64),
+		Latency:        40 * 1e-9, // (~40ns según Flexfly).
+		RouteTable:     make(map[sim.RemotePort]sim.Port),
+		arrivals:       make(map[string]sim.VTimeInSec),
+		latencyWakeups: make(map[sim.Port]string),
+		ecmpRoutes:     make(map[sim.RemotePort][]sim.Port),
+		TrafficMatrix:  make(TrafficMatrix),
+		ClassTraffic:   make(ClassTrafficMatrix),
+
+		Mode:                 PacketSwitched,
+		ReconfigurationDelay: 820 * 1e-9, // (Anderson et al.).
+		circuits:             make(map[sim.Port]sim.Port),
+		stalls:               make(map[sim.Port]stall),
+		noRouteLogged:        make(map[flowKey]bool),
+		lightpaths:           make(map[sim.Port]map[flowKey]*lightpath),
+		Power:                DefaultPowerModel(),
 	}
+	s.TickingComponent = sim.NewTickingComponent(name, engine, 1*sim.GHz, s)
 	return s
 }
 
@@ -87,53 +199,114 @@ const OutputBufferCapacity = 16 // Prevenimos deadlock.
 
 func (s *Switch) CreatePort(name string) sim.Port {
 	port := sim.NewPort(s, InputBufferCapacity, OutputBufferCapacity, name)
+	port.AcceptHook(switchArrivalHook{s})             // Para la Latency.
 	s.connectedPorts = append(s.connectedPorts, port) // Pa la lista de connected ports.
 	return port
 }
 
 // Configuración de la tabla de enrutamiento estática inicial.
 func (s *Switch) RegisterDestination(dstName sim.RemotePort, outputPort sim.Port) {
-	// TODO. El Controlador debe modificar dinámicamente esto.
+	// La ruta queda fija; lo dinámico son los circuitos (ver Reconfigure).
 	s.RouteTable[dstName] = outputPort
+	delete(s.ecmpRoutes, dstName)
 }
 
-// --- LÓGICA PRINCIPAL DEL SWITCH ---
-func (s *Switch) ProcessMsg(now sim.VTimeInSec, msg sim.Msg) {
-	// TODO. Verificar en caso haya RECONFIGURACIÓN.
-	// Podríamos descartar paquetes o ponerlos en buffers.
+// Varios puertos de salida igual de buenos hacia un destino. Cada flujo
+// (Source, Destination) siempre usa el mismo, así no se reordenan sus mensajes.
+func (s *Switch) RegisterRoutes(dstName sim.RemotePort, outputPorts []sim.Port) {
+	if len(outputPorts) == 0 {
+		log.Panicf("[OPTICAL_SWITCH] %s: no output ports for %s", s.Name(), dstName)
+	}
 
+	s.RouteTable[dstName] = outputPorts[0]
+	delete(s.ecmpRoutes, dstName)
+	if len(outputPorts) > 1 {
+		s.ecmpRoutes[dstName] = append([]sim.Port(nil), outputPorts...)
+	}
+}
+
+// Todos los puertos de salida hacia un destino.
+func (s *Switch) Routes(dst sim.RemotePort) []sim.Port {
+	if ports, ok := s.ecmpRoutes[dst]; ok {
+		return ports
+	}
+
+	if port, ok := s.RouteTable[dst]; ok {
+		return []sim.Port{port}
+	}
+	return nil
+}
+
+// Puerto de salida para un mensaje. Con ECMP se elige por hash del flujo.
+func (s *Switch) route(msg sim.Msg) (sim.Port, bool) {
 	dst := msg.Meta().Dst
-	src := msg.Meta().Src
 
-	// 1. Registrar Tráfico (para el predictor).
-	s.RecordTraffic(now, src, dst, msg)
+	if ports, ok := s.ecmpRoutes[dst]; ok {
+		h := fnv.New32a()
+		h.Write([]byte(msg.Meta().Src))
+		h.Write([]byte(dst))
+		return ports[h.Sum32()%uint32(len(ports))], true
+	}
+
+	port, found := s.RouteTable[dst]
+	return port, found
+}
 
-	// 2. Enrutar.
-	outPort, found := s.RouteTable[dst]
+// Puerto del Switch por el que se llega a un destino (nil si no hay ruta).
+func (s *Switch) PortTo(dst sim.RemotePort) sim.Port {
+	return s.RouteTable[dst]
+}
+
+// --- LÓGICA PRINCIPAL DEL SWITCH ---
+// Reenvía un mensaje que ya salió de su buffer de entrada.
+// Devuelve *NoRouteError si el destino no existe, ErrOutputFull si la salida
+// está llena y ErrNoWavelength si no hay canal libre; en todos los casos el
+// mensaje no se envió y sigue siendo del llamador.
+func (s *Switch) ProcessMsg(now sim.VTimeInSec, msg sim.Msg) error {
+	dst := msg.Meta().Dst
+	src := msg.Meta().Src
+
+	// 1. Enrutar.
+	outPort, found := s.route(msg)
 	if !found { // Destino inexistente en la RouteTable.
-		log.Panicf("[OPTICAL_SWITCH] Panic: No route from %s to %s", src, dst)
+		return &NoRouteError{Switch: s.Name(), Src: src, Dst: dst}
 	}
 
-	// 3. Verificando congestión de la salida.
+	// 2. Verificando congestión de la salida.
 	if !outPort.CanSend() {
-		fmt.Printf("[OPTICAL_SWITCH] DROP! Port %s full sending to %s\n", outPort.Name(), dst)
-		return
+		return ErrOutputFull
 	}
 
+	wavelength := 0
+	if s.wdmEnabled() {
+		var ok bool
+		if wavelength, ok = s.wavelengthFor(outPort, msg); !ok {
+			return ErrNoWavelength
+		}
+		s.holdWavelength(outPort, msg, wavelength)
+	}
+
+	// 3. Registrar Tráfico (para el predictor).
+	s.RecordTraffic(now, src, dst, msg)
+
 	// 4. Reenviar (+ encapsulamiento).
 	packet := &OpticalPacket{
 		MsgMeta: sim.MsgMeta{
 			ID:           sim.GetIDGenerator().Generate(), // Generamos ID válido
 			Src:          outPort.AsRemote(),              // Switch firma como remitente.
 			Dst:          dst,                             // Destino final se mantiene para el Link.
-			TrafficBytes: 16,                              // ID del Paquete (8B) + Origen (4B) + Destino (4B).
+			TrafficBytes: OpticalPacketHeaderBytes,        // Solo el encabezado, ver sizing.go.
 			TrafficClass: "OpticalPacket",
 		},
-		InnerMsg: msg, // El mensaje es el original.
+		InnerMsg:   msg, // El mensaje es el original.
+		Wavelength: wavelength,
 	}
 
 	// Poniendo el packet en el buffer de salida -> el Port avisa al Link.
 	outPort.Send(packet)
+	s.Counters.ForwardedMsgs++
+
+	return nil
 }
 
 // --- EXTRACIÓN DE MÉTRICAS (tráfico) ---
@@ -141,35 +314,45 @@ func (s *Switch) RecordTraffic(now sim.VTimeInSec, src, dst sim.RemotePort, msg
 	s.matrixLock.Lock()         // Tomamos el lock o esperamos.
 	defer s.matrixLock.Unlock() // Devolvemos cuando termine la func.
 
-	size := uint64(1)
-	typeStr := "Unknown"
-
-	// Para saber cuánto pesa cada mensaje, depende de su tipo.
-	switch m := msg.(type) {
-	case *mem.ReadReq:
-		size = 8 // Arquitectura de 64 bits = 8 Bytes.
-		typeStr = "ReadReq"
-	case *mem.WriteReq:
-		size = uint64(len(m.Data))
-		typeStr = "WriteReq"
-	case *mem.DataReadyRsp:
-		size = uint64(len(m.Data))
-		typeStr = "DataReady"
-	case *mem.WriteDoneRsp:
-		typeStr = "WriteDone"
-	}
+	// Lo que realmente sale por la fibra: el mensaje + el encabezado del
+	// OpticalPacket que lo envuelve (ver sizing.go).
+	size := uint64(MessageBytes(msg) + OpticalPacketHeaderBytes)
 
 	srcName := string(src)
 	dstName := string(dst)
 
-	// Inicialización del mapa.
-	if _, ok := s.TrafficMatrix[srcName]; !ok {
-		s.TrafficMatrix[srcName] = make(map[string]uint64)
+	s.TrafficMatrix.Add(srcName, dstName, size)
+	s.ClassTraffic.Add(srcName, dstName, TrafficClassOf(msg), size)
+}
+
+// Copia del TrafficMatrix acumulado (para el Controller).
+func (s *Switch) SnapshotTraffic() TrafficMatrix {
+	s.matrixLock.Lock()
+	defer s.matrixLock.Unlock()
+
+	snapshot := make(TrafficMatrix)
+	for src, row := range s.TrafficMatrix {
+		for dst, bytes := range row {
+			snapshot.Add(src, dst, bytes)
+		}
 	}
-	s.TrafficMatrix[srcName][dstName] += size
+	return snapshot
+}
+
+// Demanda que está esperando AHORA en la cabeza de cada buffer de entrada
+// (no se ve en el TrafficMatrix porque todavía no se reenvió).
+func (s *Switch) PendingDemand() TrafficMatrix {
+	pending := make(TrafficMatrix)
+	for _, p := range s.connectedPorts {
+		msg := p.PeekIncoming()
+		if msg == nil {
+			continue
+		}
 
-	fmt.Printf("[SWITCH_MONITOR] Time: %.6f | %s (%s) -> %s | Size: %d bytes\n",
-		now, typeStr, src, dst, size)
+		pending.Add(string(msg.Meta().Src), string(msg.Meta().Dst),
+			uint64(WireBytes(msg)))
+	}
+	return pending
 }
 
 // --- REQUISITOS COMO COMPONENT: SWITCH ---
@@ -177,20 +360,205 @@ func (s *Switch) RecordTraffic(now sim.VTimeInSec, src, dst sim.RemotePort, msg
 func (s *Switch) NotifyRecv(port sim.Port) {
 	now := s.Engine.CurrentTime()
 
+	if s.controller != nil { // Hay actividad: el Controller debe estar despierto.
+		s.controller.TickLater()
+	}
+
+	s.processInput(now, port)
+}
+
+// Despacha los mensajes de un puerto de entrada hasta que uno no pueda salir.
+func (s *Switch) processInput(now sim.VTimeInSec, port sim.Port) bool {
+	madeProgress := false
+
 	for { // Mientras hayan mensajes, procesa todos.
-		req := port.RetrieveIncoming()
+		req := port.PeekIncoming()
 		if req == nil {
-			return
+			return madeProgress
+		}
+
+		// Todavía atravesando el Switch. Los que vienen detrás llegaron después.
+		if ready := s.readyAt(now, req); ready > now {
+			s.wakeUpAt(ready, port, req)
+			return madeProgress
+		}
+
+		outPort, found := s.route(req)
+		if !found { // Nunca podrá salir: se saca para no bloquear la entrada.
+			s.retrieve(port)
+			s.Counters.UnroutableMsgs++
+			tracing.TraceReqReceive(req, s)
+			tracing.AddTaskStep(tracing.MsgIDAtReceiver(req, s), s, "no_route")
+			tracing.TraceReqComplete(req, s)
+			s.reportNoRoute(&NoRouteError{Switch: s.Name(),
+				Src: req.Meta().Src, Dst: req.Meta().Dst})
+			madeProgress = true
+			continue
+		}
+
+		// Reconfigurando, sin circuito o salida llena: se queda en el INPUT BUFFER.
+		if reason := s.stallReason(port, outPort, req); reason != noStall {
+			s.startStall(now, port, req, reason)
+			return madeProgress
 		}
 
-		s.ProcessMsg(now, req)
+		s.retrieve(port)
+		if !s.endStall(now, port, req) {
+			tracing.TraceReqReceive(req, s)
+		}
+		if err := s.ProcessMsg(now, req); err != nil {
+			log.Panicf("%v", err) // stallReason ya lo verificó.
+		}
+		tracing.TraceReqComplete(req, s)
+		madeProgress = true
+	}
+}
+
+// -- EVENTO : *Fin de la latencia del Switch* --
+// La cabeza de un buffer de entrada ya puede salir.
+type SwitchLatencyDoneEvent struct {
+	*sim.EventBase
+	Port sim.Port
+}
+
+func NewSwitchLatencyDoneEvent(time sim.VTimeInSec, handler sim.Handler, port sim.Port) *SwitchLatencyDoneEvent {
+	return &SwitchLatencyDoneEvent{
+		EventBase: sim.NewEventBase(time, handler),
+		Port:      port,
 	}
 }
 
+// Anota cuándo llega cada mensaje a un buffer de entrada. Akita sólo avisa
+// (NotifyRecv) cuando el buffer estaba vacío, el hook ve todos.
+type switchArrivalHook struct {
+	s *Switch
+}
+
+func (h switchArrivalHook) Func(ctx sim.HookCtx) {
+	if ctx.Pos != sim.HookPosPortMsgRecvd {
+		return
+	}
+
+	msg := ctx.Item.(sim.Msg)
+	h.s.arrivals[msg.Meta().ID] = h.s.Engine.CurrentTime()
+}
+
+// Momento en el que el mensaje termina de atravesar el Switch.
+func (s *Switch) readyAt(now sim.VTimeInSec, msg sim.Msg) sim.VTimeInSec {
+	arrival, ok := s.arrivals[msg.Meta().ID]
+	if !ok {
+		return now
+	}
+	return arrival + s.Latency
+}
+
+func (s *Switch) wakeUpAt(t sim.VTimeInSec, port sim.Port, msg sim.Msg) {
+	if s.latencyWakeups[port] == msg.Meta().ID {
+		return
+	}
+
+	s.latencyWakeups[port] = msg.Meta().ID
+	s.Engine.Schedule(NewSwitchLatencyDoneEvent(t, s, port))
+}
+
+func (s *Switch) retrieve(port sim.Port) {
+	msg := port.RetrieveIncoming()
+	delete(s.arrivals, msg.Meta().ID)
+}
+
+// El que envió el mensaje lo va a esperar para siempre: hay que avisar.
+func (s *Switch) reportNoRoute(err *NoRouteError) {
+	if s.NoRouteHandler != nil {
+		s.NoRouteHandler(err)
+		return
+	}
+
+	key := flowKey{src: err.Src, dst: err.Dst}
+	if s.noRouteLogged[key] {
+		return
+	}
+	s.noRouteLogged[key] = true
+	log.Printf("%v", err)
+}
+
+func (s *Switch) stallReason(inPort, outPort sim.Port, msg sim.Msg) stallReason {
+	switch {
+	case s.reconfiguring:
+		return stallReconfiguring
+	case !s.circuitUp(inPort, outPort):
+		return stallNoCircuit
+	case !outPort.CanSend(): // Back-pressure desde la fibra de salida.
+		return stallOutputFull
+	case s.wdmEnabled() && !s.hasWavelength(outPort, msg):
+		return stallNoWavelength
+	default:
+		return noStall
+	}
+}
+
+// Cada mensaje cuenta una sola vez, aunque se reintente muchas veces.
+func (s *Switch) startStall(now sim.VTimeInSec, port sim.Port, msg sim.Msg, reason stallReason) {
+	if st, ok := s.stalls[port]; ok && st.msgID == msg.Meta().ID {
+		return
+	}
+
+	s.stalls[port] = stall{msgID: msg.Meta().ID, since: now}
+
+	// La tarea empieza cuando el mensaje llega a la cabeza, así su duración
+	// incluye la espera.
+	tracing.TraceReqReceive(msg, s)
+	tracing.AddTaskStep(tracing.MsgIDAtReceiver(msg, s), s, reason.String())
+
+	switch reason {
+	case stallReconfiguring:
+		s.Counters.StallsReconfiguring++
+	case stallNoCircuit:
+		s.Counters.StallsNoCircuit++
+	case stallOutputFull:
+		s.Counters.StallsOutputFull++
+	case stallNoWavelength:
+		s.Counters.StallsNoWavelength++
+	}
+}
+
+// Devuelve si el mensaje había estado esperando.
+func (s *Switch) endStall(now sim.VTimeInSec, port sim.Port, msg sim.Msg) bool {
+	st, ok := s.stalls[port]
+	if !ok || st.msgID != msg.Meta().ID {
+		return false
+	}
+
+	s.Counters.StallTime += now - st.since
+	delete(s.stalls, port)
+	return true
+}
+
+// Reintenta todas las entradas (p. ej. cuando se liberó una salida).
+func (s *Switch) Tick() bool {
+	now := s.Engine.CurrentTime()
+
+	madeProgress := false
+	for _, p := range s.connectedPorts {
+		madeProgress = s.processInput(now, p) || madeProgress
+	}
+
+	return madeProgress
+}
+
+// Una salida tiene espacio otra vez. Akita llama esto con el lock del puerto
+// tomado, así que no podemos enviar aquí: reintentamos en el próximo tick.
 func (s *Switch) NotifyPortFree(port sim.Port) {
-	// No-op.
+	s.TickLater()
 }
 
 func (s *Switch) Handle(e sim.Event) error {
-	return s.TickingComponent.Handle(e)
+	switch evt := e.(type) {
+	case *SwitchReconfigDoneEvent:
+		return s.finishReconfiguration(evt)
+	case *SwitchLatencyDoneEvent:
+		s.processInput(s.Engine.CurrentTime(), evt.Port)
+		return nil
+	default:
+		return s.TickingComponent.Handle(e)
+	}
 }
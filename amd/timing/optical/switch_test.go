@@ -0,0 +1,110 @@
+package optical
+
+import (
+	"bytes"
+	"log"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+var _ = Describe("Switch flow control", func() {
+	var (
+		engine *sim.SerialEngine
+		sw     *Switch
+		a, b   *endpoint
+	)
+
+	plug := func(ep *endpoint, bandwidth float64) {
+		swPort := sw.CreatePort(ep.Name() + ".SwitchPort")
+		link := NewLink(ep.Name()+".Fiber", engine, 10e-9, bandwidth)
+		link.PlugIn(ep.port)
+		link.PlugIn(swPort)
+		sw.RegisterDestination(ep.port.AsRemote(), swPort)
+	}
+
+	BeforeEach(func() {
+		engine = sim.NewSerialEngine()
+		sw = NewSwitch("Switch", engine)
+		a = newEndpoint("A", engine, 4, 64)
+		b = newEndpoint("B", engine, 4, 4)
+	})
+
+	It("should hold messages while the output fiber is busy", func() {
+		plug(a, 0)
+		plug(b, 6.4e9) // 64 B -> 10 ns por mensaje.
+
+		for i := 0; i < 40; i++ {
+			Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+		}
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(b.recvMsgs).To(HaveLen(40))
+		Expect(sw.Counters.ForwardedMsgs).To(Equal(uint64(40)))
+		Expect(sw.Counters.StallsOutputFull).To(BeNumerically(">", 0))
+		Expect(sw.Counters.StallTime).To(BeNumerically(">", 0))
+	})
+
+	It("should report unroutable messages instead of panicking", func() {
+		plug(a, 0)
+		plug(b, 0)
+		lost := newEndpoint("Lost", engine, 4, 4)
+		var errs []*NoRouteError
+		sw.NoRouteHandler = func(err *NoRouteError) {
+			errs = append(errs, err)
+		}
+
+		Expect(a.port.Send(newSampleMsg(a.port, lost.port, 64))).To(BeNil())
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(b.recvMsgs).To(HaveLen(1))
+		Expect(sw.Counters.UnroutableMsgs).To(Equal(uint64(1)))
+		Expect(sw.Counters.ForwardedMsgs).To(Equal(uint64(1)))
+		Expect(errs).To(Equal([]*NoRouteError{{Switch: sw.Name(),
+			Src: a.port.AsRemote(), Dst: lost.port.AsRemote()}}))
+	})
+
+	It("should log a missing route once per flow", func() {
+		plug(a, 0)
+		lost := newEndpoint("Lost", engine, 4, 4)
+		var logged bytes.Buffer
+		log.SetOutput(&logged)
+		defer log.SetOutput(GinkgoWriter)
+
+		Expect(a.port.Send(newSampleMsg(a.port, lost.port, 64))).To(BeNil())
+		Expect(a.port.Send(newSampleMsg(a.port, lost.port, 64))).To(BeNil())
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(sw.Counters.UnroutableMsgs).To(Equal(uint64(2)))
+		Expect(strings.Count(logged.String(), "no route from")).To(Equal(1))
+	})
+
+	It("should count a stalled message only once", func() {
+		plug(a, 0)
+		plug(b, 0)
+
+		sw.Reconfigure(nil)
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(b.recvMsgs).To(HaveLen(1))
+		Expect(sw.Counters.StallsReconfiguring).To(Equal(uint64(1)))
+		Expect(float64(sw.Counters.StallTime)).To(
+			BeNumerically("~", 820e-9-10e-9-float64(sw.Latency), 1e-12))
+	})
+
+	It("should report a missing route as an error", func() {
+		lost := newEndpoint("Lost", engine, 4, 4)
+
+		err := sw.ProcessMsg(0, newSampleMsg(a.port, lost.port, 64))
+
+		Expect(err).To(BeAssignableToTypeOf(&NoRouteError{}))
+	})
+})
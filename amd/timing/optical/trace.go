@@ -0,0 +1,57 @@
+package optical
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/akita/v4/tracing"
+)
+
+// --- TRAZAS ---
+
+// Los componentes ópticos emiten tareas de akita/tracing en lugar de imprimir:
+//   - Switch: "req_in" por cada mensaje reenviado (tracing.TraceReqReceive),
+//     desde que llega a la cabeza del buffer de entrada hasta que sale. Si
+//     esperó, la tarea tiene un paso con el motivo ("stall_output_full", ...).
+//   - Link: TaskKindTransmit por cada mensaje, desde que sale del buffer del
+//     origen hasta que se entrega. Detail es el mensaje tal como viaja por la
+//     fibra (WireBytes sirve para medirlo). Si la fibra no tiene destino, la
+//     tarea termina enseguida con el paso "not_connected".
+//
+// Sin tracers conectados no cuesta nada. Para verlas en la consola, conectar un
+// ConsoleTracer con tracing.CollectTrace.
+
+const TaskKindTransmit = "optical_transmit"
+
+// Imprime las tareas de los componentes ópticos, una línea por evento.
+type ConsoleTracer struct {
+	sim.TimeTeller
+	w io.Writer
+}
+
+func NewConsoleTracer(timeTeller sim.TimeTeller, w io.Writer) *ConsoleTracer {
+	return &ConsoleTracer{TimeTeller: timeTeller, w: w}
+}
+
+func (t *ConsoleTracer) StartTask(task tracing.Task) {
+	msg, ok := task.Detail.(sim.Msg)
+	if !ok {
+		return
+	}
+
+	fmt.Fprintf(t.w, "[OPTICAL] %.9f %s %s %s: %s -> %s (%d bytes)\n",
+		t.CurrentTime(), task.Location, task.Kind, task.What,
+		msg.Meta().Src, msg.Meta().Dst, WireBytes(msg))
+}
+
+func (t *ConsoleTracer) StepTask(task tracing.Task) {
+	for _, step := range task.Steps {
+		fmt.Fprintf(t.w, "[OPTICAL] %.9f %s %s\n",
+			t.CurrentTime(), task.ID, step.What)
+	}
+}
+
+func (t *ConsoleTracer) AddMilestone(_ tracing.Milestone) {}
+
+func (t *ConsoleTracer) EndTask(_ tracing.Task) {}
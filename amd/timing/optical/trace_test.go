@@ -0,0 +1,103 @@
+package optical
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/akita/v4/tracing"
+)
+
+// taskRecorder keeps every task that starts or steps.
+type taskRecorder struct {
+	started []tracing.Task
+	steps   []string
+	ended   []string
+}
+
+func (r *taskRecorder) StartTask(task tracing.Task) {
+	r.started = append(r.started, task)
+}
+
+func (r *taskRecorder) StepTask(task tracing.Task) {
+	for _, s := range task.Steps {
+		r.steps = append(r.steps, s.What)
+	}
+}
+
+func (r *taskRecorder) AddMilestone(_ tracing.Milestone) {}
+
+func (r *taskRecorder) EndTask(task tracing.Task) {
+	r.ended = append(r.ended, task.ID)
+}
+
+var _ = Describe("Tracing", func() {
+	var (
+		engine *sim.SerialEngine
+		sw     *Switch
+		a, b   *endpoint
+		links  []*Link
+	)
+
+	plug := func(ep *endpoint) {
+		swPort := sw.CreatePort(ep.Name() + ".SwitchPort")
+		link := NewLink(ep.Name()+".Fiber", engine, 10e-9, 0)
+		link.PlugIn(ep.port)
+		link.PlugIn(swPort)
+		sw.RegisterDestination(ep.port.AsRemote(), swPort)
+		links = append(links, link)
+	}
+
+	BeforeEach(func() {
+		engine = sim.NewSerialEngine()
+		sw = NewSwitch("Switch", engine)
+		a = newEndpoint("A", engine, 4, 4)
+		b = newEndpoint("B", engine, 4, 4)
+		links = nil
+		plug(a)
+		plug(b)
+	})
+
+	It("should emit a task per hop", func() {
+		rec := &taskRecorder{}
+		tracing.CollectTrace(sw, rec)
+		for _, l := range links {
+			tracing.CollectTrace(l, rec)
+		}
+
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+		Expect(engine.Run()).To(Succeed())
+
+		kinds := []string{}
+		for _, t := range rec.started {
+			kinds = append(kinds, t.Kind)
+		}
+		Expect(kinds).To(Equal(
+			[]string{TaskKindTransmit, "req_in", TaskKindTransmit}))
+		Expect(rec.ended).To(HaveLen(3))
+	})
+
+	It("should record why a message waited", func() {
+		rec := &taskRecorder{}
+		tracing.CollectTrace(sw, rec)
+
+		sw.Reconfigure(nil)
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(rec.steps).To(Equal([]string{"stall_reconfiguring"}))
+		Expect(rec.started).To(HaveLen(1))
+	})
+
+	It("should only print to the console when asked to", func() {
+		buf := &bytes.Buffer{}
+		tracing.CollectTrace(sw, NewConsoleTracer(engine, buf))
+
+		Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(buf.String()).To(ContainSubstring("[OPTICAL]"))
+		Expect(buf.String()).To(ContainSubstring("Switch req_in"))
+	})
+})
@@ -0,0 +1,104 @@
+package optical
+
+import (
+	"errors"
+
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+// --- WDM: ASIGNACIÓN DE LONGITUDES DE ONDA ---
+
+// Con Wavelengths > 1 cada fibra de salida lleva varios canales. El Switch le
+// asigna a cada flujo (Source, Destination) una longitud de onda libre de esa
+// salida (first-fit) y la reserva mientras el flujo tenga mensajes sin
+// transmitir. Si todas están tomadas por otros flujos, el mensaje queda
+// BLOQUEADO en el buffer de entrada hasta que alguna se libere.
+//
+// Cada Switch asigna por su cuenta en cada salida: suponemos conversión de
+// longitud de onda entre saltos (sin restricción de continuidad).
+
+// No hay longitud de onda libre en la salida. El mensaje NO se consumió.
+var ErrNoWavelength = errors.New("[OPTICAL_SWITCH] no free wavelength")
+
+type flowKey struct {
+	src, dst sim.RemotePort
+}
+
+// Longitud de onda reservada por un flujo en una salida.
+type lightpath struct {
+	wavelength  int
+	outstanding int // Mensajes del flujo sin terminar de transmitir.
+}
+
+func (s *Switch) wdmEnabled() bool {
+	return s.Wavelengths > 1
+}
+
+func flowOf(msg sim.Msg) flowKey {
+	return flowKey{src: msg.Meta().Src, dst: msg.Meta().Dst}
+}
+
+// Longitud de onda que usaría el mensaje en outPort: la del flujo, o la
+// primera libre. No reserva nada.
+func (s *Switch) wavelengthFor(outPort sim.Port, msg sim.Msg) (int, bool) {
+	paths := s.lightpaths[outPort]
+	if lp, ok := paths[flowOf(msg)]; ok {
+		return lp.wavelength, true
+	}
+
+	used := make([]bool, s.Wavelengths)
+	for _, lp := range paths {
+		used[lp.wavelength] = true
+	}
+	for wavelength, taken := range used {
+		if !taken {
+			return wavelength, true
+		}
+	}
+	return 0, false
+}
+
+func (s *Switch) hasWavelength(outPort sim.Port, msg sim.Msg) bool {
+	_, ok := s.wavelengthFor(outPort, msg)
+	return ok
+}
+
+func (s *Switch) holdWavelength(outPort sim.Port, msg sim.Msg, wavelength int) {
+	paths, ok := s.lightpaths[outPort]
+	if !ok {
+		paths = make(map[flowKey]*lightpath)
+		s.lightpaths[outPort] = paths
+	}
+
+	flow := flowOf(msg)
+	lp, ok := paths[flow]
+	if !ok {
+		lp = &lightpath{wavelength: wavelength}
+		paths[flow] = lp
+		s.Counters.WavelengthAssignments++
+	}
+	lp.outstanding++
+}
+
+// El Link terminó de transmitir un paquete que salió por outPort.
+func (s *Switch) releaseWavelength(outPort sim.Port, pkt *OpticalPacket) {
+	if !s.wdmEnabled() || pkt.InnerMsg == nil {
+		return
+	}
+
+	paths := s.lightpaths[outPort]
+	flow := flowOf(pkt.InnerMsg)
+	lp, ok := paths[flow]
+	if !ok {
+		return
+	}
+
+	lp.outstanding--
+	if lp.outstanding > 0 {
+		return
+	}
+
+	// Longitud de onda libre: algún mensaje bloqueado puede salir.
+	delete(paths, flow)
+	s.TickLater()
+}
@@ -0,0 +1,87 @@
+package optical
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/sim"
+)
+
+var _ = Describe("WDM", func() {
+	var (
+		engine *sim.SerialEngine
+		sw     *Switch
+		links  map[*endpoint]*Link
+	)
+
+	plug := func(ep *endpoint, wavelengths int) {
+		swPort := sw.CreatePort(ep.Name() + ".SwitchPort")
+		link := NewLink(ep.Name()+".Fiber", engine, 10e-9, 6.4e9) // 64 B -> 10 ns.
+		link.Wavelengths = wavelengths
+		link.PlugIn(ep.port)
+		link.PlugIn(swPort)
+		sw.RegisterDestination(ep.port.AsRemote(), swPort)
+		links[ep] = link
+	}
+
+	BeforeEach(func() {
+		engine = sim.NewSerialEngine()
+		sw = NewSwitch("Switch", engine)
+		sw.Wavelengths = 2
+		links = make(map[*endpoint]*Link)
+	})
+
+	It("should transmit on all wavelengths in parallel", func() {
+		a := newEndpoint("A", engine, 4, 4)
+		b := newEndpoint("B", engine, 4, 4)
+		link := NewLink("Fiber", engine, 10e-9, 6.4e9)
+		link.Wavelengths = 4
+		link.PlugIn(a.port)
+		link.PlugIn(b.port)
+
+		for i := 0; i < 4; i++ {
+			Expect(a.port.Send(newSampleMsg(a.port, b.port, 64))).To(BeNil())
+		}
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(b.recvTimes).To(HaveLen(4))
+		for _, t := range b.recvTimes {
+			Expect(float64(t)).To(BeNumerically("~", 20e-9, 1e-12))
+		}
+		Expect(link.WavelengthBytes).To(Equal([]uint64{64, 64, 64, 64}))
+	})
+
+	It("should block a flow when every wavelength is taken", func() {
+		a := newEndpoint("A", engine, 4, 4)
+		b := newEndpoint("B", engine, 4, 4)
+		c := newEndpoint("C", engine, 4, 4)
+		d := newEndpoint("D", engine, 4, 4)
+		for _, ep := range []*endpoint{a, b, c, d} {
+			plug(ep, 2)
+		}
+
+		for _, src := range []*endpoint{a, b, c} {
+			Expect(src.port.Send(newSampleMsg(src.port, d.port, 64))).To(BeNil())
+		}
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(d.recvMsgs).To(HaveLen(3))
+		Expect(sw.Counters.WavelengthAssignments).To(Equal(uint64(3)))
+		Expect(sw.Counters.StallsNoWavelength).To(Equal(uint64(1)))
+		Expect(sw.lightpaths[sw.PortTo(d.port.AsRemote())]).To(BeEmpty())
+	})
+
+	It("should report contention on a flow's own wavelength", func() {
+		a := newEndpoint("A", engine, 4, 4)
+		d := newEndpoint("D", engine, 4, 4)
+		plug(a, 2)
+		plug(d, 2)
+
+		Expect(a.port.Send(newSampleMsg(a.port, d.port, 64))).To(BeNil())
+		Expect(a.port.Send(newSampleMsg(a.port, d.port, 64))).To(BeNil())
+		Expect(engine.Run()).To(Succeed())
+
+		Expect(d.recvMsgs).To(HaveLen(2))
+		Expect(sw.Counters.WavelengthAssignments).To(Equal(uint64(1)))
+		Expect(links[d].WavelengthContention).To(Equal(uint64(1)))
+	})
+})
@@ -6,6 +6,7 @@ import (
 
 	"github.com/sarchlab/akita/v4/mem/mem"
 	"github.com/sarchlab/akita/v4/sim"
+	"github.com/sarchlab/akita/v4/tracing"
 )
 
 // PageMigrationController control page migration
@@ -179,6 +180,7 @@ func (e *PageMigrationController) handleDataPullReq(
 	req *DataPullReq,
 ) bool {
 	e.remotePort.RetrieveIncoming()
+	tracing.TraceReqReceive(req, e)
 	e.currentPullReqFromAnotherPMC = append(e.currentPullReqFromAnotherPMC, req)
 	e.requestingPMCtrlPort = req.Src
 	return true
@@ -287,6 +289,8 @@ func (e *PageMigrationController) sendDataReadyRspToRequestingPMC() bool {
 		sendPacket := e.toRspToAnotherPMC[i]
 		sendErr := e.remotePort.Send(sendPacket)
 		if sendErr == nil {
+			// The response carries the ID of the pull request.
+			tracing.EndTask(tracing.MsgIDAtReceiver(sendPacket, e), e)
 			madeProgress = true
 		} else {
 			newInToSendRspToAnotherPMC = append(newInToSendRspToAnotherPMC, sendPacket)
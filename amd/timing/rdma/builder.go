@@ -17,6 +17,9 @@ type Builder struct {
 	incomingRspPerCycle int
 	outgoingReqPerCycle int
 	outgoingRspPerCycle int
+
+	accessCounterThreshold uint64
+	log2PageSize           uint64
 }
 
 // MakeBuilder creates a new builder with default configuration values.
@@ -28,6 +31,7 @@ func MakeBuilder() Builder {
 		incomingRspPerCycle: 1,
 		outgoingReqPerCycle: 1,
 		outgoingRspPerCycle: 1,
+		log2PageSize:        12,
 	}
 }
 
@@ -81,6 +85,20 @@ func (b Builder) WithOutgoingRspPerCycle(n int) Builder {
 	return b
 }
 
+// WithAccessCounterThreshold sets the number of accesses to a page of remote
+// memory after which the RDMA engine notifies the driver. 0 disables the
+// access counters.
+func (b Builder) WithAccessCounterThreshold(n uint64) Builder {
+	b.accessCounterThreshold = n
+	return b
+}
+
+// WithLog2PageSize sets the page size that the access counters count at.
+func (b Builder) WithLog2PageSize(n uint64) Builder {
+	b.log2PageSize = n
+	return b
+}
+
 // Build creates a RDMA with the given parameters.
 func (b Builder) Build(name string) *Comp {
 	rdma := &Comp{}
@@ -93,6 +111,9 @@ func (b Builder) Build(name string) *Comp {
 	rdma.incomingRspPerCycle = b.incomingRspPerCycle
 	rdma.outgoingReqPerCycle = b.outgoingReqPerCycle
 	rdma.outgoingRspPerCycle = b.outgoingRspPerCycle
+	rdma.accessCounterThreshold = b.accessCounterThreshold
+	rdma.log2PageSize = b.log2PageSize
+	rdma.accessCounters = make(map[uint64]uint64)
 
 	rdma.RDMARequestInside = sim.NewPort(rdma, b.bufferSize, b.bufferSize, name+".RDMARequestInside")
 	rdma.RDMARequestOutside = sim.NewPort(rdma, b.bufferSize, b.bufferSize, name+".RDMARequestOutside")
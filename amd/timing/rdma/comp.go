@@ -31,6 +31,9 @@ type Comp struct {
 
 	CtrlPort sim.Port
 
+	// NotifyPort receives the access counter notifications.
+	NotifyPort sim.RemotePort
+
 	isDraining              bool
 	pauseIncomingReqsFromL1 bool
 	currentDrainReq         *DrainReq
@@ -45,6 +48,18 @@ type Comp struct {
 	incomingRspPerCycle int
 	outgoingReqPerCycle int
 	outgoingRspPerCycle int
+
+	// Like the access counters of NVIDIA Volta, the RDMA engine counts the
+	// accesses to each page of remote memory. Once a page reaches the
+	// threshold, the driver is notified, so that it can move the page to this
+	// GPU. The counters stop at the threshold and are cleared when the driver
+	// restarts the RDMA engine after moving pages. The notifications wait
+	// while the RDMA engine is drained. A threshold of 0 disables the
+	// counters.
+	accessCounterThreshold uint64
+	log2PageSize           uint64
+	accessCounters         map[uint64]uint64
+	notificationsToSend    []*AccessCounterNotification
 }
 
 // SetLocalModuleFinder sets the table to lookup for local data.
@@ -57,6 +72,7 @@ func (c *Comp) Tick() bool {
 	madeProgress := false
 
 	madeProgress = c.processFromCtrlPort() || madeProgress
+	madeProgress = c.sendAccessCounterNotification() || madeProgress
 	if c.isDraining {
 		madeProgress = c.drainRDMA() || madeProgress
 	}
@@ -113,6 +129,7 @@ func (c *Comp) processRDMARestartReq() bool {
 	}
 	c.currentDrainReq = nil
 	c.pauseIncomingReqsFromL1 = false
+	clear(c.accessCounters)
 
 	return true
 }
@@ -182,6 +199,7 @@ func (c *Comp) processReqFromL1(
 		c.RDMARequestInside.RetrieveIncoming()
 
 		c.traceInsideOutStart(req, cloned)
+		c.countRemoteAccess(req.GetAddress())
 
 		trans := transaction{
 			fromInside: req,
@@ -195,6 +213,45 @@ func (c *Comp) processReqFromL1(
 	return false
 }
 
+func (c *Comp) countRemoteAccess(addr uint64) {
+	if c.accessCounterThreshold == 0 {
+		return
+	}
+
+	page := addr >> c.log2PageSize << c.log2PageSize
+	if c.accessCounters[page] >= c.accessCounterThreshold {
+		return
+	}
+
+	c.accessCounters[page]++
+	if c.accessCounters[page] < c.accessCounterThreshold {
+		return
+	}
+
+	notification := AccessCounterNotificationBuilder{}.
+		WithSrc(c.CtrlPort.AsRemote()).
+		WithDst(c.NotifyPort).
+		WithPAddr(page).
+		WithNumAccesses(c.accessCounters[page]).
+		Build()
+	c.notificationsToSend = append(c.notificationsToSend, notification)
+}
+
+func (c *Comp) sendAccessCounterNotification() bool {
+	if len(c.notificationsToSend) == 0 || c.pauseIncomingReqsFromL1 {
+		return false
+	}
+
+	err := c.CtrlPort.Send(c.notificationsToSend[0])
+	if err != nil {
+		return false
+	}
+
+	c.notificationsToSend = c.notificationsToSend[1:]
+
+	return true
+}
+
 func (c *Comp) processFromL2() bool {
 	for {
 		req := c.RDMADataInside.PeekIncoming()
@@ -118,6 +118,86 @@ var _ = Describe("Engine", func() {
 		})
 	})
 
+	Context("Access counters", func() {
+		var read *mem.ReadReq
+
+		BeforeEach(func() {
+			rdmaEngine.accessCounterThreshold = 2
+			rdmaEngine.NotifyPort = controllingComponent.AsRemote()
+
+			read = mem.ReadReqBuilder{}.
+				WithSrc(localCache.AsRemote()).
+				WithDst(rdmaEngine.RDMARequestOutside.AsRemote()).
+				WithAddress(0x1100).
+				WithByteSize(64).
+				Build()
+		})
+
+		It("should count the accesses sent to outside", func() {
+			RDMARequestInside.EXPECT().PeekIncoming().Return(read)
+			RDMARequestOutside.EXPECT().
+				Send(gomock.AssignableToTypeOf(&mem.ReadReq{})).
+				Return(nil)
+			RDMARequestInside.EXPECT().RetrieveIncoming().Return(read)
+			RDMARequestInside.EXPECT().PeekIncoming().Return(nil)
+
+			rdmaEngine.processFromL1()
+
+			Expect(rdmaEngine.accessCounters).
+				To(Equal(map[uint64]uint64{0x1000: 1}))
+		})
+
+		It("should notify when a page reaches the threshold", func() {
+			rdmaEngine.countRemoteAccess(0x1100)
+			Expect(rdmaEngine.notificationsToSend).To(BeEmpty())
+
+			rdmaEngine.countRemoteAccess(0x1fc0)
+			Expect(rdmaEngine.notificationsToSend).To(HaveLen(1))
+			Expect(rdmaEngine.notificationsToSend[0].PAddr).
+				To(Equal(uint64(0x1000)))
+		})
+
+		It("should notify a page once until restarted", func() {
+			rdmaEngine.countRemoteAccess(0x1100)
+			rdmaEngine.countRemoteAccess(0x1100)
+			rdmaEngine.countRemoteAccess(0x1100)
+			rdmaEngine.countRemoteAccess(0x1100)
+			Expect(rdmaEngine.notificationsToSend).To(HaveLen(1))
+
+			rdmaEngine.currentDrainReq = DrainReqBuilder{}.
+				WithSrc(controllingComponent.AsRemote()).
+				WithDst(ctrlPort.AsRemote()).
+				Build()
+			ctrlPort.EXPECT().
+				Send(gomock.AssignableToTypeOf(&RestartRsp{})).
+				Return(nil)
+			rdmaEngine.processRDMARestartReq()
+
+			Expect(rdmaEngine.accessCounters).To(BeEmpty())
+		})
+
+		It("should send notifications", func() {
+			rdmaEngine.countRemoteAccess(0x1100)
+			rdmaEngine.countRemoteAccess(0x1200)
+
+			ctrlPort.EXPECT().
+				Send(gomock.AssignableToTypeOf(&AccessCounterNotification{})).
+				Return(nil)
+
+			Expect(rdmaEngine.sendAccessCounterNotification()).To(BeTrue())
+			Expect(rdmaEngine.notificationsToSend).To(BeEmpty())
+		})
+
+		It("should not send notifications while drained", func() {
+			rdmaEngine.countRemoteAccess(0x1100)
+			rdmaEngine.countRemoteAccess(0x1200)
+			rdmaEngine.pauseIncomingReqsFromL1 = true
+
+			Expect(rdmaEngine.sendAccessCounterNotification()).To(BeFalse())
+			Expect(rdmaEngine.notificationsToSend).To(HaveLen(1))
+		})
+	})
+
 	Context("Read from outside", func() {
 		var read *mem.ReadReq
 
@@ -179,3 +179,75 @@ func (b RestartRspBuilder) Build() *RestartRsp {
 	r.Dst = b.dst
 	return r
 }
+
+// AccessCounterNotification tells that the GPU has accessed a page of remote
+// memory as many times as the threshold of the access counters.
+type AccessCounterNotification struct {
+	sim.MsgMeta
+
+	PAddr       uint64
+	NumAccesses uint64
+}
+
+// Meta returns the meta data associated with the message.
+func (r *AccessCounterNotification) Meta() *sim.MsgMeta {
+	return &r.MsgMeta
+}
+
+// Clone returns a clone of the AccessCounterNotification with different ID.
+func (r *AccessCounterNotification) Clone() sim.Msg {
+	cloneMsg := *r
+	cloneMsg.ID = sim.GetIDGenerator().Generate()
+
+	return &cloneMsg
+}
+
+// AccessCounterNotificationBuilder can build access counter notifications
+type AccessCounterNotificationBuilder struct {
+	src, dst    sim.RemotePort
+	pAddr       uint64
+	numAccesses uint64
+}
+
+// WithSrc sets the source of the notification to build.
+func (b AccessCounterNotificationBuilder) WithSrc(
+	src sim.RemotePort,
+) AccessCounterNotificationBuilder {
+	b.src = src
+	return b
+}
+
+// WithDst sets the destination of the notification to build.
+func (b AccessCounterNotificationBuilder) WithDst(
+	dst sim.RemotePort,
+) AccessCounterNotificationBuilder {
+	b.dst = dst
+	return b
+}
+
+// WithPAddr sets the physical address of the page that is accessed.
+func (b AccessCounterNotificationBuilder) WithPAddr(
+	pAddr uint64,
+) AccessCounterNotificationBuilder {
+	b.pAddr = pAddr
+	return b
+}
+
+// WithNumAccesses sets the number of accesses counted.
+func (b AccessCounterNotificationBuilder) WithNumAccesses(
+	n uint64,
+) AccessCounterNotificationBuilder {
+	b.numAccesses = n
+	return b
+}
+
+// Build creates a new AccessCounterNotification
+func (b AccessCounterNotificationBuilder) Build() *AccessCounterNotification {
+	r := &AccessCounterNotification{}
+	r.ID = sim.GetIDGenerator().Generate()
+	r.Src = b.src
+	r.Dst = b.dst
+	r.PAddr = b.pAddr
+	r.NumAccesses = b.numAccesses
+	return r
+}
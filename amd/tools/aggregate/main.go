@@ -0,0 +1,339 @@
+// Aggregate computes statistics over the databases of many simulations, like
+// the ones of a sweep (see amd/tools/sweep).
+//
+// The simulations are grouped by benchmark, variant, topology and number of
+// GPUs. The variant is the rest of the flags that change the result, such as
+// the problem size or the platform file. For every group and metric it
+// computes the mean, standard deviation, minimum and maximum, the speedup
+// against the same group with the baseline topology, and the speedup and
+// efficiency against the same group with the fewest GPUs (strong scaling).
+// Speedups assume that lower values are better, like times.
+//
+// It writes, in the output directory:
+//
+//	runs.csv      One row per simulation and metric.
+//	groups.csv    One row per group and metric, with the statistics.
+//	<metric>.csv  One row per benchmark, variant and GPU count, and columns
+//	              with the mean, standard deviation and speedup of each
+//	              topology, ready to plot.
+//
+// Usage:
+//
+//	go run ./amd/tools/aggregate -metrics=Driver/kernel_time,wall_time exps/sweep/runs
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sarchlab/mgpusim/v4/amd/tools/simdb"
+)
+
+var outputFlag = flag.String("output", "aggregate",
+	"The directory to write the CSV files to.")
+var metricsFlag = flag.String("metrics", "Driver/kernel_time,wall_time",
+	"Comma-separated metrics to aggregate, as Location/What of the "+
+		"mgpusim_metrics table, */What for the sum over every location, or "+
+		"wall_time for the time the simulator took.")
+var baselineFlag = flag.String("baseline", "tree",
+	"The topology that the speedups are computed against.")
+
+// RunRow is a row of runs.csv.
+type RunRow struct {
+	Benchmark string
+	Variant   string
+	Topology  string
+	NumGPUs   int
+	Metric    string
+	Value     float64
+	Path      string
+}
+
+// GroupRow is a row of groups.csv.
+type GroupRow struct {
+	Benchmark         string
+	Variant           string
+	Topology          string
+	NumGPUs           int
+	Metric            string
+	N                 int
+	Mean              float64
+	StdDev            float64
+	Min               float64
+	Max               float64
+	Speedup           float64 // Contra la topología base.
+	ScalingSpeedup    float64 // Contra el menor número de GPUs.
+	ScalingEfficiency float64
+}
+
+type metricKey struct {
+	groupKey
+	Metric string
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatal("give the simulation databases or the directories that " +
+			"contain them")
+	}
+
+	metrics := strings.Split(*metricsFlag, ",")
+
+	runs, values := readRuns(flag.Args(), metrics)
+	groups := aggregate(values, *baselineFlag)
+
+	if err := os.MkdirAll(*outputFlag, 0o755); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := simdb.WriteCSV(filepath.Join(*outputFlag, "runs.csv"), runs); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := simdb.WriteCSV(filepath.Join(*outputFlag, "groups.csv"), groups); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, m := range metrics {
+		if err := writePivot(m, groups); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	printGroups(groups)
+}
+
+func readRuns(
+	paths []string,
+	metrics []string,
+) ([]RunRow, map[metricKey][]float64) {
+	files, err := simdb.Find(paths)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var runs []RunRow
+	values := make(map[metricKey][]float64)
+
+	for _, f := range files {
+		sim, err := simdb.Read(f)
+		if err != nil {
+			log.Printf("skipping %v", err)
+			continue
+		}
+
+		key := groupKey{
+			Benchmark: sim.Benchmark(),
+			Variant:   variantOf(sim),
+			Topology:  sim.Topology(),
+			NumGPUs:   sim.NumGPUs(),
+		}
+
+		for _, m := range metrics {
+			v := metricValue(sim, m)
+			if math.IsNaN(v) {
+				continue
+			}
+
+			runs = append(runs, RunRow{
+				Benchmark: key.Benchmark,
+				Variant:   key.Variant,
+				Topology:  key.Topology,
+				NumGPUs:   key.NumGPUs,
+				Metric:    m,
+				Value:     v,
+				Path:      f,
+			})
+
+			mk := metricKey{key, m}
+			values[mk] = append(values[mk], v)
+		}
+	}
+
+	log.Printf("Read %d simulation databases", len(files))
+
+	return runs, values
+}
+
+// aggregate summarizes every group and computes its speedups against the
+// baseline topology and against the smallest GPU count.
+func aggregate(values map[metricKey][]float64, baselineTopology string) []GroupRow {
+	summaries := make(map[metricKey]summary, len(values))
+	for k, v := range values {
+		summaries[k] = summarize(v)
+	}
+
+	// Menor número de GPUs de cada benchmark, variante, topología y métrica.
+	minGPUs := make(map[metricKey]int)
+	for k := range summaries {
+		base := k
+		base.NumGPUs = 0
+		if n, ok := minGPUs[base]; !ok || k.NumGPUs < n {
+			minGPUs[base] = k.NumGPUs
+		}
+	}
+
+	groups := make([]GroupRow, 0, len(summaries))
+	for k, s := range summaries {
+		row := GroupRow{
+			Benchmark: k.Benchmark,
+			Variant:   k.Variant,
+			Topology:  k.Topology,
+			NumGPUs:   k.NumGPUs,
+			Metric:    k.Metric,
+			N:         s.N,
+			Mean:      s.Mean,
+			StdDev:    s.StdDev,
+			Min:       s.Min,
+			Max:       s.Max,
+
+			Speedup:           math.NaN(),
+			ScalingSpeedup:    math.NaN(),
+			ScalingEfficiency: math.NaN(),
+		}
+
+		baseline := k
+		baseline.Topology = baselineTopology
+		if b, ok := summaries[baseline]; ok {
+			row.Speedup = b.Mean / s.Mean
+		}
+
+		smallest := k
+		smallest.NumGPUs = 0
+		smallest.NumGPUs = minGPUs[smallest] // La clave de minGPUs no lleva GPUs.
+		if b, ok := summaries[smallest]; ok {
+			row.ScalingSpeedup = b.Mean / s.Mean
+			row.ScalingEfficiency = row.ScalingSpeedup *
+				float64(smallest.NumGPUs) / float64(k.NumGPUs)
+		}
+
+		groups = append(groups, row)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		switch {
+		case a.Metric != b.Metric:
+			return a.Metric < b.Metric
+		case a.Benchmark != b.Benchmark:
+			return a.Benchmark < b.Benchmark
+		case a.Variant != b.Variant:
+			return a.Variant < b.Variant
+		case a.NumGPUs != b.NumGPUs:
+			return a.NumGPUs < b.NumGPUs
+		default:
+			return a.Topology < b.Topology
+		}
+	})
+
+	return groups
+}
+
+// writePivot writes <metric>.csv, with one column per topology.
+func writePivot(metric string, groups []GroupRow) error {
+	var topologies []string
+	seen := map[string]bool{}
+	for _, g := range groups {
+		if g.Metric == metric && !seen[g.Topology] {
+			seen[g.Topology] = true
+			topologies = append(topologies, g.Topology)
+		}
+	}
+	sort.Strings(topologies)
+
+	header := []string{"Benchmark", "Variant", "NumGPUs"}
+	for _, t := range topologies {
+		header = append(header, t, t+"_stddev", t+"_speedup")
+	}
+
+	var rows [][]string
+	var current []string
+	var currentKey groupKey
+	for _, g := range groups { // Ya están ordenados.
+		if g.Metric != metric {
+			continue
+		}
+
+		key := groupKey{g.Benchmark, g.Variant, "", g.NumGPUs}
+		if current == nil || key != currentKey {
+			current = []string{g.Benchmark, g.Variant, fmt.Sprint(g.NumGPUs)}
+			for range topologies {
+				current = append(current, "", "", "")
+			}
+			rows = append(rows, current)
+			currentKey = key
+		}
+
+		col := 3 + 3*sort.SearchStrings(topologies, g.Topology)
+		current[col] = formatFloat(g.Mean)
+		current[col+1] = formatFloat(g.StdDev)
+		current[col+2] = formatFloat(g.Speedup)
+	}
+
+	return writeRecords(filepath.Join(*outputFlag, fileNameOf(metric)+".csv"),
+		header, rows)
+}
+
+func writeRecords(path string, header []string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return w.Error()
+}
+
+// formatFloat deja vacías las celdas sin valor para que los scripts de
+// gráficos las traten como datos faltantes.
+func formatFloat(v float64) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func fileNameOf(metric string) string {
+	metric = strings.ReplaceAll(metric, "*", "all")
+
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\[]. `, r) {
+			return '_'
+		}
+		return r
+	}, metric)
+}
+
+func printGroups(groups []GroupRow) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METRIC\tBENCHMARK\tVARIANT\tGPUS\tTOPOLOGY\tN\tMEAN\tSTDDEV\tSPEEDUP\tEFFICIENCY")
+
+	for _, g := range groups {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%d\t%.4g\t%.2g\t%.3f\t%.3f\n",
+			g.Metric, g.Benchmark, g.Variant, g.NumGPUs, g.Topology, g.N,
+			g.Mean, g.StdDev, g.Speedup, g.ScalingEfficiency)
+	}
+
+	w.Flush()
+}
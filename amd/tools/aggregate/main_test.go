@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Aggregate", func() {
+	keyOf := func(topology string, numGPUs int) metricKey {
+		return metricKey{
+			groupKey: groupKey{Benchmark: "fir", Variant: "default",
+				Topology: topology, NumGPUs: numGPUs},
+			Metric: "wall_time",
+		}
+	}
+
+	var groups map[metricKey]GroupRow
+
+	BeforeEach(func() {
+		rows := aggregate(map[metricKey][]float64{
+			keyOf("tree", 1): {10, 10},
+			keyOf("tree", 2): {6, 4},
+			keyOf("tree", 4): {4},
+			keyOf("star", 2): {4},
+			keyOf("star", 4): {2},
+			keyOf("mesh", 4): {1},
+		}, "tree")
+
+		groups = make(map[metricKey]GroupRow)
+		for _, r := range rows {
+			groups[keyOf(r.Topology, r.NumGPUs)] = r
+		}
+	})
+
+	It("should summarize every group", func() {
+		Expect(groups).To(HaveLen(6))
+		Expect(groups[keyOf("tree", 2)].N).To(Equal(2))
+		Expect(groups[keyOf("tree", 2)].Mean).To(Equal(5.0))
+		Expect(groups[keyOf("tree", 2)].Min).To(Equal(4.0))
+	})
+
+	It("should compare against the baseline topology", func() {
+		Expect(groups[keyOf("tree", 2)].Speedup).To(Equal(1.0))
+		Expect(groups[keyOf("star", 2)].Speedup).To(Equal(1.25))
+		Expect(groups[keyOf("star", 4)].Speedup).To(Equal(2.0))
+		Expect(groups[keyOf("mesh", 4)].Speedup).To(Equal(4.0))
+	})
+
+	It("should not compare if the baseline topology did not run", func() {
+		rows := aggregate(map[metricKey][]float64{keyOf("star", 2): {4}},
+			"tree")
+
+		Expect(math.IsNaN(rows[0].Speedup)).To(BeTrue())
+	})
+
+	It("should scale against the smallest GPU count of each topology",
+		func() {
+			tree4 := groups[keyOf("tree", 4)]
+			Expect(tree4.ScalingSpeedup).To(Equal(2.5))
+			Expect(tree4.ScalingEfficiency).To(Equal(0.625))
+
+			star4 := groups[keyOf("star", 4)]
+			Expect(star4.ScalingSpeedup).To(Equal(2.0))
+			Expect(star4.ScalingEfficiency).To(Equal(1.0))
+
+			mesh4 := groups[keyOf("mesh", 4)]
+			Expect(mesh4.ScalingSpeedup).To(Equal(1.0))
+			Expect(mesh4.ScalingEfficiency).To(Equal(1.0))
+		})
+
+	It("should sort the groups by benchmark, variant, GPUs and topology",
+		func() {
+			rows := aggregate(map[metricKey][]float64{
+				keyOf("tree", 2): {1},
+				keyOf("star", 2): {1},
+				keyOf("tree", 1): {1},
+			}, "tree")
+
+			Expect(rows).To(HaveLen(3))
+			Expect([]string{rows[0].Topology, rows[1].Topology,
+				rows[2].Topology}).To(Equal([]string{"tree", "star", "tree"}))
+			Expect(rows[0].NumGPUs).To(Equal(1))
+		})
+})
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/sarchlab/mgpusim/v4/amd/tools/simdb"
+)
+
+// wallTimeMetric selects the time the simulator took to run, instead of a
+// row of mgpusim_metrics.
+const wallTimeMetric = "wall_time"
+
+// Flags que no cambian el resultado de la simulación, solo lo que se reporta.
+var outputOnlyFlags = []string{
+	"report", "trace", "verify", "disable-rtm", "akitartm-port",
+	"metric-file-name", "buffer-level-trace", "analyzer", "debug-isa",
+	"optical-console-trace", "checkpoint-file",
+}
+
+// Flags que forman parte de la clave del grupo por separado.
+var keyFlags = []string{"topology", "gpus", "unified-gpus"}
+
+type groupKey struct {
+	Benchmark string
+	Variant   string
+	Topology  string
+	NumGPUs   int
+}
+
+// variantOf describes the configuration of a simulation with the flags that
+// are not part of the rest of the key and change the result. Simulations
+// with the same flags in a different order are the same variant.
+func variantOf(sim simdb.Simulation) string {
+	var flags []string
+
+	for _, arg := range sim.Args() {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if hasPrefix(name, outputOnlyFlags) || hasPrefix(name, keyFlags) ||
+			name == "timing" {
+			continue
+		}
+
+		flags = append(flags, "-"+strings.TrimLeft(arg, "-"))
+	}
+
+	if len(flags) == 0 {
+		return "default"
+	}
+
+	sort.Strings(flags)
+
+	return strings.Join(flags, " ")
+}
+
+func hasPrefix(name string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if name == p || strings.HasPrefix(name, p+"-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// metricValue returns the value of a metric given as "Location/What", or
+// "*/What" for the sum over every location. It returns NaN if the simulation
+// does not have the metric.
+func metricValue(sim simdb.Simulation, spec string) float64 {
+	if spec == wallTimeMetric {
+		if sim.WallTime == 0 {
+			return math.NaN()
+		}
+
+		return sim.WallTime
+	}
+
+	location, what, _ := strings.Cut(spec, "/")
+
+	sum, found := 0.0, false
+	for _, m := range sim.Metrics {
+		if m.What != what || (location != "*" && m.Location != location) {
+			continue
+		}
+
+		if math.IsNaN(m.Value) {
+			continue
+		}
+
+		sum += m.Value
+		found = true
+	}
+
+	if !found {
+		return math.NaN()
+	}
+
+	return sum
+}
+
+type summary struct {
+	N      int
+	Mean   float64
+	StdDev float64 // Muestral (n-1). 0 con una sola muestra.
+	Min    float64
+	Max    float64
+}
+
+func summarize(values []float64) summary {
+	s := summary{
+		N:    len(values),
+		Mean: math.NaN(), StdDev: math.NaN(),
+		Min: math.NaN(), Max: math.NaN(),
+	}
+
+	if s.N == 0 {
+		return s
+	}
+
+	sum := 0.0
+	s.Min, s.Max = values[0], values[0]
+	for _, v := range values {
+		sum += v
+		s.Min = math.Min(s.Min, v)
+		s.Max = math.Max(s.Max, v)
+	}
+	s.Mean = sum / float64(s.N)
+
+	s.StdDev = 0
+	if s.N > 1 {
+		sq := 0.0
+		for _, v := range values {
+			sq += (v - s.Mean) * (v - s.Mean)
+		}
+		s.StdDev = math.Sqrt(sq / float64(s.N-1))
+	}
+
+	return s
+}
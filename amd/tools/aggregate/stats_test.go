@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/mgpusim/v4/amd/tools/simdb"
+)
+
+var _ = Describe("Stats", func() {
+	simWith := func(command string) simdb.Simulation {
+		return simdb.Simulation{Command: command}
+	}
+
+	DescribeTable("should describe the variant with the flags that change "+
+		"the result",
+		func(command, expected string) {
+			Expect(variantOf(simWith(command))).To(Equal(expected))
+		},
+		Entry("no flags", "fir", "default"),
+		Entry("only key flags", "fir -timing -topology=star -gpus=1,2",
+			"default"),
+		Entry("unified GPUs", "fir -unified-gpus=1,2", "default"),
+		Entry("output-only flags",
+			"fir -report-all -trace-vis -verify -disable-rtm "+
+				"-metric-file-name=x -optical-console-trace "+
+				"-checkpoint-file=c.gob",
+			"default"),
+		Entry("sorted flags", "fir -length=64 -timing -magic-memory-copy",
+			"-length=64 -magic-memory-copy"),
+		Entry("double dashes", "fir --length=64", "-length=64"),
+		Entry("flag that only starts like an output flag",
+			"fir -reporting=x", "-reporting=x"),
+	)
+
+	It("should ignore the order of the flags", func() {
+		a := simWith("fir -length=64 -platform=p.yaml -report-all -gpus=1,2")
+		b := simWith("fir -verify -gpus=1,2,3 -platform=p.yaml -length=64")
+
+		Expect(variantOf(a)).To(Equal(variantOf(b)))
+	})
+
+	Context("when reading a metric", func() {
+		var sim simdb.Simulation
+
+		BeforeEach(func() {
+			sim = simdb.Simulation{
+				WallTime: 12,
+				Metrics: []simdb.Metric{
+					{Location: "Driver", What: "kernel_time", Value: 2},
+					{Location: "GPU[1]", What: "bytes", Value: 10},
+					{Location: "GPU[2]", What: "bytes", Value: 5},
+					{Location: "GPU[3]", What: "bytes", Value: math.NaN()},
+				},
+			}
+		})
+
+		It("should read one location", func() {
+			Expect(metricValue(sim, "Driver/kernel_time")).To(Equal(2.0))
+		})
+
+		It("should add every location", func() {
+			Expect(metricValue(sim, "*/bytes")).To(Equal(15.0))
+		})
+
+		It("should read the wall time", func() {
+			Expect(metricValue(sim, wallTimeMetric)).To(Equal(12.0))
+		})
+
+		It("should give NaN for missing metrics", func() {
+			Expect(math.IsNaN(metricValue(sim, "GPU[3]/bytes"))).To(BeTrue())
+			Expect(math.IsNaN(metricValue(sim, "Driver/bytes"))).To(BeTrue())
+
+			sim.WallTime = 0
+			Expect(math.IsNaN(metricValue(sim, wallTimeMetric))).To(BeTrue())
+		})
+	})
+
+	It("should give NaN statistics without samples", func() {
+		s := summarize(nil)
+
+		Expect(s.N).To(Equal(0))
+		Expect(math.IsNaN(s.Mean)).To(BeTrue())
+		Expect(math.IsNaN(s.StdDev)).To(BeTrue())
+		Expect(math.IsNaN(s.Min)).To(BeTrue())
+		Expect(math.IsNaN(s.Max)).To(BeTrue())
+	})
+
+	It("should give a zero standard deviation with one sample", func() {
+		Expect(summarize([]float64{3})).To(Equal(
+			summary{N: 1, Mean: 3, StdDev: 0, Min: 3, Max: 3}))
+	})
+
+	It("should use the sample standard deviation", func() {
+		s := summarize([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+
+		Expect(s.N).To(Equal(8))
+		Expect(s.Mean).To(Equal(5.0))
+		Expect(s.StdDev).To(BeNumerically("~", math.Sqrt(32.0/7), 1e-12))
+		Expect(s.Min).To(Equal(2.0))
+		Expect(s.Max).To(Equal(9.0))
+	})
+
+	It("should compute the standard deviation of two samples", func() {
+		Expect(summarize([]float64{1, 3}).StdDev).To(
+			BeNumerically("~", math.Sqrt2, 1e-12))
+	})
+})
@@ -0,0 +1,322 @@
+// Package simdb reads the databases that the runner writes at the end of a
+// simulation (see amd/samples/runner/report.go).
+package simdb
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	// Registers the sqlite3 driver.
+	_ "github.com/sarchlab/akita/v4/datarecording"
+)
+
+// Tablas que escribe el runner (y akita/datarecording, exec_info).
+const (
+	metricsTableName  = "mgpusim_metrics"
+	platformTableName = "mgpusim_platform"
+	execInfoTableName = "exec_info"
+
+	execTimeLayout = "2006-01-02 15:04:05.000000000"
+)
+
+// Metric is a row of the mgpusim_metrics table.
+type Metric struct {
+	Location string
+	What     string
+	Value    float64 // NaN si el runner guardó NULL.
+	Unit     string
+}
+
+// Simulation is what a simulation database tells about one run.
+type Simulation struct {
+	Path      string
+	Command   string
+	StartTime string
+	EndTime   string
+	WallTime  float64 // Segundos, 0 si faltan Start Time o End Time.
+
+	Metrics  []Metric
+	Platform map[string]string // Vacío si la simulación no era de timing.
+}
+
+// Read reads a simulation database.
+func Read(path string) (Simulation, error) {
+	s := Simulation{Path: path, Platform: map[string]string{}}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return s, err
+	}
+	defer db.Close()
+
+	// No usamos datarecording.NewReader: el runner guarda los NaN (ej. el CPI
+	// de un CU sin instrucciones) como NULL y el reader no los acepta.
+	steps := []func(*sql.DB) error{
+		s.readExecInfo,
+		s.readMetrics,
+		s.readPlatform,
+	}
+
+	for _, step := range steps {
+		if err := step(db); err != nil {
+			return s, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Simulation) readExecInfo(db *sql.DB) error {
+	rows, err := db.Query("SELECT Property, Value FROM " + execInfoTableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var property, value string
+		if err := rows.Scan(&property, &value); err != nil {
+			return err
+		}
+
+		switch property {
+		case "Start Time":
+			s.StartTime = value
+		case "End Time":
+			s.EndTime = value
+		case "Command":
+			s.Command = value
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	start, errStart := time.ParseInLocation(execTimeLayout, s.StartTime, time.Local)
+	end, errEnd := time.ParseInLocation(execTimeLayout, s.EndTime, time.Local)
+	if errStart == nil && errEnd == nil {
+		s.WallTime = end.Sub(start).Seconds()
+	}
+
+	return nil
+}
+
+func (s *Simulation) readMetrics(db *sql.DB) error {
+	if ok, err := hasTable(db, metricsTableName); !ok || err != nil {
+		return err // Sin flags -report-*, el runner no crea la tabla.
+	}
+
+	rows, err := db.Query(
+		"SELECT Location, What, Value, Unit FROM " + metricsTableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m Metric
+		var value sql.NullFloat64
+		if err := rows.Scan(&m.Location, &m.What, &value, &m.Unit); err != nil {
+			return err
+		}
+
+		m.Value = math.NaN()
+		if value.Valid {
+			m.Value = value.Float64
+		}
+
+		s.Metrics = append(s.Metrics, m)
+	}
+
+	return rows.Err()
+}
+
+func (s *Simulation) readPlatform(db *sql.DB) error {
+	if ok, err := hasTable(db, platformTableName); !ok || err != nil {
+		return err
+	}
+
+	rows, err := db.Query("SELECT Key, Value FROM " + platformTableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return err
+		}
+
+		s.Platform[key] = value
+	}
+
+	return rows.Err()
+}
+
+func hasTable(db *sql.DB, name string) (bool, error) {
+	var n int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?",
+		name).Scan(&n)
+
+	return n > 0, err
+}
+
+// Args returns the arguments of the command, without the executable.
+func (s Simulation) Args() []string {
+	fields := strings.Fields(s.Command)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return fields[1:]
+}
+
+// Benchmark returns the name of the executable.
+func (s Simulation) Benchmark() string {
+	fields := strings.Fields(s.Command)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return filepath.Base(fields[0])
+}
+
+// Flag returns the value of a flag of the command (given as -name=value), and
+// whether it was given. A boolean flag given as -name has the value "true".
+func (s Simulation) Flag(name string) (string, bool) {
+	value, found := "", false
+
+	for _, arg := range s.Args() {
+		n, v, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if n != name {
+			continue
+		}
+
+		// Como el paquete flag, gana la última aparición.
+		value, found = v, true
+		if !hasValue {
+			value = "true"
+		}
+	}
+
+	return value, found
+}
+
+// Topology returns the topology that the simulation used.
+func (s Simulation) Topology() string {
+	if t, ok := s.Platform["interconnect.topology"]; ok {
+		return t
+	}
+
+	if t, ok := s.Flag("topology"); ok {
+		return t
+	}
+
+	return "tree"
+}
+
+// NumGPUs returns the number of GPUs given with -gpus or -unified-gpus.
+func (s Simulation) NumGPUs() int {
+	list, ok := s.Flag("unified-gpus")
+	if !ok {
+		list, ok = s.Flag("gpus")
+	}
+
+	if !ok || list == "" {
+		return 1
+	}
+
+	return len(strings.Split(list, ","))
+}
+
+// Find returns the simulation databases (akita_sim_*.sqlite3) under the given
+// files and directories.
+func Find(paths []string) ([]string, error) {
+	var files []string
+
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				return nil
+			}
+
+			if path == root || isSimulationDB(d.Name()) {
+				files = append(files, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+func isSimulationDB(name string) bool {
+	return strings.HasPrefix(name, "akita_sim_") &&
+		strings.HasSuffix(name, ".sqlite3")
+}
+
+// WriteCSV writes one row per entry, with the field names as the header.
+func WriteCSV[T any](path string, entries []T) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+
+	t := reflect.TypeOf(*new(T))
+	header := make([]string, t.NumField())
+	for i := range header {
+		header[i] = t.Field(i).Name
+	}
+
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		v := reflect.ValueOf(e)
+		record := make([]string, v.NumField())
+		for i := range record {
+			record[i] = formatField(v.Field(i))
+		}
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
+
+func formatField(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sarchlab/akita/v4/datarecording"
+	"github.com/sarchlab/mgpusim/v4/amd/tools/simdb"
+)
+
+// RunResult is a row of the runs table: one per simulation.
+type RunResult struct {
+	Benchmark  string
+	Topology   string
+	Variant    string
+	NumGPUs    int
+	Repetition int
+	Status     string  // ok, failed o timeout.
+	WallTime   float64 // Segundos de ejecución del simulador.
+	StartTime  string
+	EndTime    string
+	Command    string
+	Dir        string
+}
+
+// MetricResult is a row of the metrics table: one per metric of each
+// simulation.
+type MetricResult struct {
+	Benchmark  string
+	Topology   string
+	Variant    string
+	NumGPUs    int
+	Repetition int
+	Location   string
+	What       string
+	Value      float64
+	Unit       string
+}
+
+// collect reads the database that the simulation of a job wrote in its
+// directory.
+func collect(j Job, dir string, run *RunResult) ([]MetricResult, error) {
+	dbFiles, err := simdb.Find([]string{dir})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dbFiles) == 0 {
+		return nil, fmt.Errorf("%s: no simulation database", dir)
+	}
+
+	dbFile, err := newestFile(dbFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	sim, err := simdb.Read(dbFile)
+	if err != nil {
+		return nil, err
+	}
+
+	run.StartTime = sim.StartTime
+	run.EndTime = sim.EndTime
+	run.WallTime = sim.WallTime
+	run.Command = sim.Command
+
+	metrics := make([]MetricResult, 0, len(sim.Metrics))
+	for _, m := range sim.Metrics {
+		metrics = append(metrics, MetricResult{
+			Benchmark:  j.Benchmark.Name,
+			Topology:   j.Topology,
+			Variant:    j.Variant.Name,
+			NumGPUs:    j.NumGPUs,
+			Repetition: j.Repetition,
+			Location:   m.Location,
+			What:       m.What,
+			Value:      m.Value,
+			Unit:       m.Unit,
+		})
+	}
+
+	return metrics, nil
+}
+
+// Si la simulación se volvió a lanzar a mano en el mismo directorio quedan
+// varias bases: la última es la de la corrida que terminó.
+func newestFile(paths []string) (string, error) {
+	newest := ""
+	var newestTime time.Time
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+
+		if newest == "" || info.ModTime().After(newestTime) {
+			newest = path
+			newestTime = info.ModTime()
+		}
+	}
+
+	return newest, nil
+}
+
+// writeSQLite writes the runs and metrics tables into path.sqlite3.
+func writeSQLite(path string, runs []RunResult, metrics []MetricResult) error {
+	recorder := datarecording.NewDataRecorder(path)
+
+	recorder.CreateTable("sweep_runs", RunResult{})
+	for _, r := range runs {
+		recorder.InsertData("sweep_runs", r)
+	}
+
+	recorder.CreateTable("sweep_metrics", MetricResult{})
+	for _, m := range metrics {
+		recorder.InsertData("sweep_metrics", m)
+	}
+
+	return recorder.Close()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sarchlab/akita/v4/datarecording"
+)
+
+type sampleMetric struct {
+	Location string
+	What     string
+	Value    float64
+	Unit     string
+}
+
+var _ = Describe("Collect", func() {
+	var (
+		dir string
+		job Job
+	)
+
+	writeDB := func(name string, value float64, modTime time.Time) {
+		path := filepath.Join(dir, name)
+		recorder := datarecording.NewDataRecorder(path)
+		recorder.CreateTable("mgpusim_metrics", sampleMetric{})
+		recorder.InsertData("mgpusim_metrics", sampleMetric{
+			Location: "Driver", What: "kernel_time", Value: value, Unit: "second"})
+		Expect(recorder.Close()).To(Succeed())
+		Expect(os.Chtimes(path+".sqlite3", modTime, modTime)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		job = Job{
+			Benchmark:  Benchmark{Name: "fir"},
+			Topology:   "tree",
+			Variant:    Variant{Name: "default"},
+			NumGPUs:    2,
+			Repetition: 1,
+		}
+	})
+
+	It("should read the metrics of the job", func() {
+		writeDB("akita_sim_a", 1.5, time.Now())
+		run := &RunResult{}
+
+		metrics, err := collect(job, dir, run)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics).To(Equal([]MetricResult{{
+			Benchmark: "fir", Topology: "tree", Variant: "default",
+			NumGPUs: 2, Repetition: 1, Location: "Driver",
+			What: "kernel_time", Value: 1.5, Unit: "second",
+		}}))
+		Expect(run.StartTime).NotTo(BeEmpty())
+		Expect(run.Command).NotTo(BeEmpty())
+	})
+
+	It("should read the newest database if there are several", func() {
+		now := time.Now()
+		writeDB("akita_sim_a", 1, now.Add(-2*time.Hour))
+		writeDB("akita_sim_b", 2, now)
+		writeDB("akita_sim_c", 3, now.Add(-time.Hour))
+
+		metrics, err := collect(job, dir, &RunResult{})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics).To(HaveLen(1))
+		Expect(metrics[0].Value).To(Equal(2.0))
+	})
+
+	It("should fail if there is no database", func() {
+		_, err := collect(job, dir, &RunResult{})
+
+		Expect(err).To(MatchError(dir + ": no simulation database"))
+	})
+})
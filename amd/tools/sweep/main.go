@@ -0,0 +1,288 @@
+// Sweep runs a batch of timing simulations described by a YAML file and
+// gathers their metrics into one table.
+//
+// Every combination of benchmark, topology, variant, GPU count and repetition
+// runs as a separate process in its own directory under <output>/runs, with
+// at most "workers" processes at a time. When all of them finish, the
+// mgpusim_metrics and exec_info tables of every simulation database are
+// written to <output>/runs.csv, <output>/metrics.csv and
+// <output>/results.sqlite3. Simulations that already finished in a previous
+// sweep with the same output directory are not run again.
+//
+// Example specification:
+//
+//	output: exps/sweep
+//	workers: 8
+//	repetitions: 3
+//	timeout: 2h
+//	benchmarks:
+//	  - name: fir
+//	    args: [-length=65536]
+//	  - name: matrixmultiplication
+//	topologies: [tree, star, optical]
+//	gpu_counts: [1, 2, 4, 8]
+//	unified_gpus: true
+//	args: [-timing, -report-all]
+//	variants:
+//	  - name: rdma
+//	  - name: all
+//	    args: [-optical-traffic=all]
+//
+// Run it from the root of the repository:
+//
+//	go run ./amd/tools/sweep -spec=sweep.yaml
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sarchlab/mgpusim/v4/amd/tools/simdb"
+)
+
+var specFlag = flag.String("spec", "", "The YAML file that describes the sweep.")
+var workersFlag = flag.Int("workers", 0,
+	"The number of simulations to run at a time. Overrides the spec.")
+var dryRunFlag = flag.Bool("dry-run", false,
+	"Print the simulations without running them.")
+
+// Marca que deja una simulación terminada sin errores.
+const doneFileName = "done"
+
+type sweep struct {
+	spec      Spec
+	outputDir string
+	binDir    string
+	jobs      []Job
+	runs      []RunResult
+
+	mu       sync.Mutex
+	finished int
+}
+
+func main() {
+	flag.Parse()
+
+	if *specFlag == "" {
+		log.Fatal("-spec is required")
+	}
+
+	spec, err := LoadSpec(*specFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *workersFlag > 0 {
+		spec.Workers = *workersFlag
+	}
+
+	outputDir, err := filepath.Abs(spec.Output)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := &sweep{
+		spec:      spec,
+		outputDir: outputDir,
+		binDir:    filepath.Join(outputDir, "bin"),
+		jobs:      spec.Jobs(),
+	}
+
+	if *dryRunFlag {
+		s.printJobs()
+		return
+	}
+
+	if err := s.build(); err != nil {
+		log.Fatal(err)
+	}
+
+	s.runAll()
+
+	if err := s.report(); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, r := range s.runs {
+		if r.Status != "ok" {
+			os.Exit(2)
+		}
+	}
+}
+
+func (s *sweep) printJobs() {
+	for _, j := range s.jobs {
+		fmt.Printf("%s: %s %v\n", j.Dir(), j.Benchmark.Name, j.Args(s.spec))
+	}
+}
+
+// build compiles each benchmark once. The topology and everything else are
+// runner flags.
+func (s *sweep) build() error {
+	if err := os.MkdirAll(s.binDir, 0o755); err != nil {
+		return err
+	}
+
+	for _, b := range s.spec.Benchmarks {
+		log.Printf("Building %s", b.Name)
+
+		cmd := exec.Command("go", "build",
+			"-o", filepath.Join(s.binDir, b.Name),
+			"./"+filepath.ToSlash(filepath.Join(s.spec.SamplesDir, b.Name)))
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("building %s: %v", b.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *sweep) runAll() {
+	s.runs = make([]RunResult, len(s.jobs))
+
+	jobIndexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < s.spec.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobIndexes {
+				s.runs[i] = s.run(s.jobs[i])
+			}
+		}()
+	}
+
+	for i := range s.jobs {
+		jobIndexes <- i
+	}
+	close(jobIndexes)
+
+	wg.Wait()
+}
+
+func (s *sweep) run(j Job) RunResult {
+	dir := filepath.Join(s.outputDir, j.Dir())
+	result := RunResult{
+		Benchmark:  j.Benchmark.Name,
+		Topology:   j.Topology,
+		Variant:    j.Variant.Name,
+		NumGPUs:    j.NumGPUs,
+		Repetition: j.Repetition,
+		Status:     "ok",
+		Dir:        dir,
+	}
+
+	start := time.Now()
+	status, err := s.execute(j, dir)
+	result.Status = status
+
+	s.mu.Lock()
+	s.finished++
+	if err != nil {
+		log.Printf("[%d/%d] %s: %s: %v", s.finished, len(s.jobs), j, status, err)
+	} else {
+		log.Printf("[%d/%d] %s: %s (%.1fs)", s.finished, len(s.jobs), j,
+			status, time.Since(start).Seconds())
+	}
+	s.mu.Unlock()
+
+	return result
+}
+
+// execute runs the simulation of a job, unless a previous sweep already did.
+func (s *sweep) execute(j Job, dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, doneFileName)); err == nil {
+		return "ok", nil
+	}
+
+	// Restos de un intento anterior que no terminó.
+	if err := os.RemoveAll(dir); err != nil {
+		return "failed", err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "failed", err
+	}
+
+	logFile, err := os.Create(filepath.Join(dir, "run.log"))
+	if err != nil {
+		return "failed", err
+	}
+	defer logFile.Close()
+
+	ctx := context.Background()
+	if s.spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.spec.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx,
+		filepath.Join(s.binDir, j.Benchmark.Name), j.Args(s.spec)...)
+	cmd.Dir = dir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "timeout", err
+		}
+
+		return "failed", err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, doneFileName), nil, 0o644); err != nil {
+		return "failed", err
+	}
+
+	return "ok", nil
+}
+
+// report gathers the metrics of the finished simulations.
+func (s *sweep) report() error {
+	var metrics []MetricResult
+
+	for i, j := range s.jobs {
+		run := &s.runs[i]
+		if run.Status != "ok" {
+			continue
+		}
+
+		m, err := collect(j, run.Dir, run)
+		if err != nil {
+			log.Printf("%s: %v", j, err)
+			run.Status = "failed"
+			continue
+		}
+
+		metrics = append(metrics, m...)
+	}
+
+	if err := simdb.WriteCSV(filepath.Join(s.outputDir, "runs.csv"), s.runs); err != nil {
+		return err
+	}
+
+	if err := simdb.WriteCSV(filepath.Join(s.outputDir, "metrics.csv"), metrics); err != nil {
+		return err
+	}
+
+	if err := writeSQLite(filepath.Join(s.outputDir, "results"), s.runs, metrics); err != nil {
+		return err
+	}
+
+	log.Printf("Results written to %s", s.outputDir)
+
+	return nil
+}
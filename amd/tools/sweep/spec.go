@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Spec describes a sweep: every benchmark runs with every topology, variant,
+// GPU count and repetition.
+type Spec struct {
+	Output      string        `yaml:"output"`      // Directorio de resultados.
+	SamplesDir  string        `yaml:"samples_dir"` // Donde están los main de los benchmarks.
+	Workers     int           `yaml:"workers"`     // Simulaciones a la vez.
+	Repetitions int           `yaml:"repetitions"`
+	Timeout     time.Duration `yaml:"timeout"` // Por simulación. 0 = sin límite.
+
+	Benchmarks  []Benchmark `yaml:"benchmarks"`
+	Topologies  []string    `yaml:"topologies"` // Valores de -topology.
+	GPUCounts   []int       `yaml:"gpu_counts"`
+	UnifiedGPUs bool        `yaml:"unified_gpus"` // -unified-gpus en lugar de -gpus.
+	Args        []string    `yaml:"args"`         // Flags de todas las simulaciones.
+	Variants    []Variant   `yaml:"variants"`     // Conjuntos de flags a comparar.
+}
+
+// Benchmark is one of the samples in SamplesDir and the flags that set its
+// problem size.
+type Benchmark struct {
+	Name string   `yaml:"name"`
+	Args []string `yaml:"args"`
+}
+
+// Variant is a named set of extra flags, like a platform file or an optical
+// traffic selection.
+type Variant struct {
+	Name string   `yaml:"name"`
+	Args []string `yaml:"args"`
+}
+
+// LoadSpec reads a sweep specification and fills in the defaults.
+func LoadSpec(path string) (Spec, error) {
+	s := Spec{
+		Output:      "sweep",
+		SamplesDir:  "amd/samples",
+		Workers:     runtime.NumCPU(),
+		Repetitions: 1,
+		Topologies:  []string{"tree"},
+		GPUCounts:   []int{1},
+		Args:        []string{"-timing", "-report-all"},
+		Variants:    []Variant{{Name: "default"}},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil {
+		return s, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if err := s.Validate(); err != nil {
+		return s, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return s, nil
+}
+
+// Validate checks that the sweep can run.
+func (s Spec) Validate() error {
+	checks := []struct {
+		ok  bool
+		msg string
+	}{
+		{s.Output != "", "output must not be empty"},
+		{s.Workers > 0, "workers must be positive"},
+		{s.Repetitions > 0, "repetitions must be positive"},
+		{s.Timeout >= 0, "timeout must not be negative"},
+		{len(s.Benchmarks) > 0, "benchmarks must not be empty"},
+		{len(s.Topologies) > 0, "topologies must not be empty"},
+		{len(s.GPUCounts) > 0, "gpu_counts must not be empty"},
+		{len(s.Variants) > 0, "variants must not be empty"},
+	}
+
+	for _, check := range checks {
+		if !check.ok {
+			return fmt.Errorf("%s", check.msg)
+		}
+	}
+
+	for _, n := range s.GPUCounts {
+		if n <= 0 {
+			return fmt.Errorf("gpu_counts must be positive, got %d", n)
+		}
+	}
+
+	// Los nombres forman el directorio de cada simulación.
+	names := map[string]bool{}
+	for _, b := range s.Benchmarks {
+		if !isPathComponent(b.Name) || names["b/"+b.Name] {
+			return fmt.Errorf("invalid or repeated benchmark %q", b.Name)
+		}
+		names["b/"+b.Name] = true
+	}
+
+	for _, v := range s.Variants {
+		if !isPathComponent(v.Name) || names["v/"+v.Name] {
+			return fmt.Errorf("invalid or repeated variant %q", v.Name)
+		}
+		names["v/"+v.Name] = true
+	}
+
+	for _, t := range s.Topologies {
+		if !isPathComponent(t) {
+			return fmt.Errorf("invalid topology %q", t)
+		}
+	}
+
+	return nil
+}
+
+func isPathComponent(name string) bool {
+	return name != "" && name != "." && name != ".." &&
+		!strings.ContainsAny(name, `/\`)
+}
+
+// Job is one simulation of the sweep.
+type Job struct {
+	Benchmark  Benchmark
+	Topology   string
+	Variant    Variant
+	NumGPUs    int
+	Repetition int
+}
+
+// Jobs lists the simulations of the sweep. The repetitions of a configuration
+// are not consecutive, so that a sweep cut short has some sample of every
+// configuration.
+func (s Spec) Jobs() []Job {
+	var jobs []Job
+
+	for rep := 1; rep <= s.Repetitions; rep++ {
+		for _, b := range s.Benchmarks {
+			for _, t := range s.Topologies {
+				for _, v := range s.Variants {
+					for _, n := range s.GPUCounts {
+						jobs = append(jobs, Job{
+							Benchmark:  b,
+							Topology:   t,
+							Variant:    v,
+							NumGPUs:    n,
+							Repetition: rep,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return jobs
+}
+
+// Dir is the directory where the job runs, relative to the output directory.
+func (j Job) Dir() string {
+	return filepath.Join("runs", j.Benchmark.Name, j.Topology, j.Variant.Name,
+		fmt.Sprintf("%dgpus", j.NumGPUs), strconv.Itoa(j.Repetition))
+}
+
+// Args returns the command-line arguments of the simulation.
+func (j Job) Args(s Spec) []string {
+	gpus := make([]string, j.NumGPUs)
+	for i := range gpus {
+		gpus[i] = strconv.Itoa(i + 1)
+	}
+
+	gpuFlag := "-gpus="
+	if s.UnifiedGPUs {
+		gpuFlag = "-unified-gpus="
+	}
+
+	var args []string
+	args = append(args, s.Args...)
+	args = append(args, j.Benchmark.Args...)
+	args = append(args, j.Variant.Args...)
+	args = absFileArgs(args)
+	args = append(args,
+		"-topology="+j.Topology,
+		gpuFlag+strings.Join(gpus, ","),
+		"-disable-rtm", // Un servidor web por proceso no tiene sentido aquí.
+	)
+
+	return args
+}
+
+// La simulación corre en su propio directorio: los flags que nombran un
+// archivo (ej. -platform=platforms/x.yaml) se pasan con la ruta absoluta.
+func absFileArgs(args []string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = arg
+
+		name, value, found := strings.Cut(arg, "=")
+		if !found || !strings.HasPrefix(name, "-") || filepath.IsAbs(value) {
+			continue
+		}
+
+		if info, err := os.Stat(value); err == nil && !info.IsDir() {
+			if abs, err := filepath.Abs(value); err == nil {
+				out[i] = name + "=" + abs
+			}
+		}
+	}
+
+	return out
+}
+
+func (j Job) String() string {
+	return fmt.Sprintf("%s %s %s %d GPUs #%d",
+		j.Benchmark.Name, j.Topology, j.Variant.Name, j.NumGPUs, j.Repetition)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Spec", func() {
+	writeSpec := func(content string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "sweep.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+
+		return path
+	}
+
+	It("should fill in the defaults", func() {
+		s, err := LoadSpec(writeSpec("benchmarks:\n  - name: fir\n"))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Output).To(Equal("sweep"))
+		Expect(s.SamplesDir).To(Equal("amd/samples"))
+		Expect(s.Workers).To(Equal(runtime.NumCPU()))
+		Expect(s.Repetitions).To(Equal(1))
+		Expect(s.Topologies).To(Equal([]string{"tree"}))
+		Expect(s.GPUCounts).To(Equal([]int{1}))
+		Expect(s.Args).To(Equal([]string{"-timing", "-report-all"}))
+		Expect(s.Variants).To(Equal([]Variant{{Name: "default"}}))
+	})
+
+	It("should read every field", func() {
+		s, err := LoadSpec(writeSpec(`
+output: exps/x
+workers: 2
+repetitions: 3
+timeout: 90m
+benchmarks:
+  - name: fir
+    args: [-length=1024]
+topologies: [tree, optical]
+gpu_counts: [2, 4]
+unified_gpus: true
+args: [-timing]
+variants:
+  - name: all
+    args: [-optical-traffic=all]
+`))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s).To(Equal(Spec{
+			Output:      "exps/x",
+			SamplesDir:  "amd/samples",
+			Workers:     2,
+			Repetitions: 3,
+			Timeout:     90 * time.Minute,
+			Benchmarks: []Benchmark{
+				{Name: "fir", Args: []string{"-length=1024"}}},
+			Topologies:  []string{"tree", "optical"},
+			GPUCounts:   []int{2, 4},
+			UnifiedGPUs: true,
+			Args:        []string{"-timing"},
+			Variants: []Variant{
+				{Name: "all", Args: []string{"-optical-traffic=all"}}},
+		}))
+	})
+
+	It("should reject unknown keys", func() {
+		_, err := LoadSpec(writeSpec("benchmark:\n  - name: fir\n"))
+
+		Expect(err).To(MatchError(ContainSubstring("field benchmark not found")))
+	})
+
+	DescribeTable("should reject invalid specs",
+		func(change func(s *Spec), msg string) {
+			s := Spec{
+				Output:      "sweep",
+				Workers:     1,
+				Repetitions: 1,
+				Benchmarks:  []Benchmark{{Name: "fir"}},
+				Topologies:  []string{"tree"},
+				GPUCounts:   []int{1},
+				Variants:    []Variant{{Name: "default"}},
+			}
+			Expect(s.Validate()).To(Succeed())
+
+			change(&s)
+
+			Expect(s.Validate()).To(MatchError(msg))
+		},
+		Entry("no output", func(s *Spec) { s.Output = "" },
+			"output must not be empty"),
+		Entry("no workers", func(s *Spec) { s.Workers = 0 },
+			"workers must be positive"),
+		Entry("no repetitions", func(s *Spec) { s.Repetitions = 0 },
+			"repetitions must be positive"),
+		Entry("negative timeout", func(s *Spec) { s.Timeout = -time.Second },
+			"timeout must not be negative"),
+		Entry("no benchmarks", func(s *Spec) { s.Benchmarks = nil },
+			"benchmarks must not be empty"),
+		Entry("no topologies", func(s *Spec) { s.Topologies = nil },
+			"topologies must not be empty"),
+		Entry("no GPU counts", func(s *Spec) { s.GPUCounts = nil },
+			"gpu_counts must not be empty"),
+		Entry("no variants", func(s *Spec) { s.Variants = nil },
+			"variants must not be empty"),
+		Entry("zero GPUs", func(s *Spec) { s.GPUCounts = []int{2, 0} },
+			"gpu_counts must be positive, got 0"),
+		Entry("repeated benchmark", func(s *Spec) {
+			s.Benchmarks = append(s.Benchmarks, Benchmark{Name: "fir"})
+		}, `invalid or repeated benchmark "fir"`),
+		Entry("benchmark with a path", func(s *Spec) {
+			s.Benchmarks = []Benchmark{{Name: "../fir"}}
+		}, `invalid or repeated benchmark "../fir"`),
+		Entry("repeated variant", func(s *Spec) {
+			s.Variants = append(s.Variants, Variant{Name: "default"})
+		}, `invalid or repeated variant "default"`),
+		Entry("empty variant", func(s *Spec) {
+			s.Variants = []Variant{{Name: ""}}
+		}, `invalid or repeated variant ""`),
+		Entry("topology with a path", func(s *Spec) {
+			s.Topologies = []string{".."}
+		}, `invalid topology ".."`),
+	)
+
+	It("should accept a benchmark and a variant with the same name", func() {
+		s := Spec{Output: "sweep", Workers: 1, Repetitions: 1,
+			Benchmarks: []Benchmark{{Name: "fir"}},
+			Topologies: []string{"tree"}, GPUCounts: []int{1},
+			Variants: []Variant{{Name: "fir"}}}
+
+		Expect(s.Validate()).To(Succeed())
+	})
+
+	It("should list every job with the repetitions apart", func() {
+		s := Spec{
+			Repetitions: 2,
+			Benchmarks:  []Benchmark{{Name: "fir"}, {Name: "bfs"}},
+			Topologies:  []string{"tree"},
+			GPUCounts:   []int{1, 2},
+			Variants:    []Variant{{Name: "default"}},
+		}
+
+		jobs := s.Jobs()
+
+		Expect(jobs).To(HaveLen(8))
+		for i, j := range jobs[:4] {
+			Expect(j.Repetition).To(Equal(1))
+			Expect(jobs[i+4].Repetition).To(Equal(2))
+			Expect(jobs[i+4].Benchmark).To(Equal(j.Benchmark))
+			Expect(jobs[i+4].NumGPUs).To(Equal(j.NumGPUs))
+		}
+		Expect(jobs[0].Benchmark.Name).To(Equal("fir"))
+		Expect(jobs[0].NumGPUs).To(Equal(1))
+		Expect(jobs[1].NumGPUs).To(Equal(2))
+		Expect(jobs[2].Benchmark.Name).To(Equal("bfs"))
+	})
+
+	Context("when building the command of a job", func() {
+		var (
+			s Spec
+			j Job
+		)
+
+		BeforeEach(func() {
+			s = Spec{Args: []string{"-timing"}}
+			j = Job{
+				Benchmark:  Benchmark{Name: "fir", Args: []string{"-length=64"}},
+				Topology:   "star",
+				Variant:    Variant{Name: "v", Args: []string{"-verify"}},
+				NumGPUs:    3,
+				Repetition: 2,
+			}
+		})
+
+		It("should run in its own directory", func() {
+			Expect(j.Dir()).To(Equal(
+				filepath.Join("runs", "fir", "star", "v", "3gpus", "2")))
+		})
+
+		It("should pass the flags of the spec, benchmark and variant", func() {
+			Expect(j.Args(s)).To(Equal([]string{"-timing", "-length=64",
+				"-verify", "-topology=star", "-gpus=1,2,3", "-disable-rtm"}))
+		})
+
+		It("should use unified GPUs", func() {
+			s.UnifiedGPUs = true
+
+			Expect(j.Args(s)).To(ContainElement("-unified-gpus=1,2,3"))
+		})
+	})
+
+	Context("when making file flags absolute", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dir, "platform.yaml"), nil,
+				0o644)).To(Succeed())
+			Expect(os.Mkdir(filepath.Join(dir, "platforms"), 0o755)).
+				To(Succeed())
+
+			cwd, err := os.Getwd()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Chdir(dir)).To(Succeed())
+			DeferCleanup(os.Chdir, cwd)
+		})
+
+		It("should make the files relative to the working directory "+
+			"absolute", func() {
+			abs, err := filepath.Abs("platform.yaml")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(absFileArgs([]string{"-platform=platform.yaml"})).
+				To(Equal([]string{"-platform=" + abs}))
+		})
+
+		DescribeTable("should leave the other flags alone",
+			func(arg string) {
+				Expect(absFileArgs([]string{arg})).To(Equal([]string{arg}))
+			},
+			Entry("no value", "-timing"),
+			Entry("not a flag", "platform.yaml"),
+			Entry("missing file", "-platform=missing.yaml"),
+			Entry("directory", "-output=platforms"),
+			Entry("absolute path", "-platform=/etc/hosts"),
+			Entry("plain value", "-length=1024"),
+		)
+	})
+})
@@ -2,9 +2,9 @@
 
 # --- Configuración ---
 BENCHMARKS=("fir" "floydwarshall" "kmeans" "matrixmultiplication" "memcopy2d2" "nbody" "pagerank" "simpleconvolution")
-RUNNERS=("runner" "runner_chain" "runner_clique" "runner_mesh" "runner_star")
+TOPOLOGIES=("tree" "chain" "clique" "mesh" "star")
 # BENCHMARKS=("bitonicsort")
-# RUNNERS=("runner_mesh")
+# TOPOLOGIES=("mesh")
 
 RUN_EXP_SCRIPT="run_exp.sh"
 PROCESAR_SCRIPT="procesar_benchmarks.py"
@@ -15,44 +15,26 @@ PROCESAR_SCRIPT_PATH="$SCRIPT_DIR/$PROCESAR_SCRIPT"
 
 # --- Lógica de Ejecución ---
 echo ">>> INICIANDO SCRIPT MAESTRO <<<"
-echo "Total de combinaciones a ejecutar: $((${#BENCHMARKS[@]} * ${#RUNNERS[@]}))"
+echo "Total de combinaciones a ejecutar: $((${#BENCHMARKS[@]} * ${#TOPOLOGIES[@]}))"
 echo "------------------------------------------------------------"
 
 for benchmark in "${BENCHMARKS[@]}"; do
-    for runner in "${RUNNERS[@]}"; do
+    for topology in "${TOPOLOGIES[@]}"; do
         ( # --- Inicio del subshell ---
             set -e
-            echo ">>> Iniciando: Benchmark [$benchmark], Runner [$runner]"
+            echo ">>> Iniciando: Benchmark [$benchmark], Topología [$topology]"
 
             # --- 1. Configurar y ejecutar run_exp.sh ---
             # ('run_exp.sh' está en el mismo SCRIPT_DIR)
             sed -i "s/^BENCHMARK_NAME=.*/BENCHMARK_NAME=\"$benchmark\"/" "$SCRIPT_DIR/$RUN_EXP_SCRIPT"
-            sed -i "s/^RUNNER_NAME=.*/RUNNER_NAME=\"$runner\"/" "$SCRIPT_DIR/$RUN_EXP_SCRIPT"
+            sed -i "s/^TOPOLOGY_NAME=\"[a-z]*\"/TOPOLOGY_NAME=\"$topology\"/" "$SCRIPT_DIR/$RUN_EXP_SCRIPT"
             "$SCRIPT_DIR/$RUN_EXP_SCRIPT"
             
-            echo "--- 'run_exp.sh' finalizado para [$benchmark, $runner] ---"
+            echo "--- 'run_exp.sh' finalizado para [$benchmark, $topology] ---"
 
 
             # --- 2. Configurar y ejecutar procesar_benchmarks.py ---
-            dir_path=""
-
-            case "$runner" in
-                "runner")
-                    dir_path="/home/kiara/PFC/mgpusim/amd/samples/$benchmark"
-                    ;;
-                "runner_chain")
-                    dir_path="/home/kiara/PFC/mgpusim/exps/$benchmark/chain"
-                    ;;
-                "runner_clique")
-                    dir_path="/home/kiara/PFC/mgpusim/exps/$benchmark/clique"
-                    ;;
-                "runner_mesh")
-                    dir_path="/home/kiara/PFC/mgpusim/exps/$benchmark/mesh"
-                    ;;
-                "runner_star")
-                    dir_path="/home/kiara/PFC/mgpusim/exps/$benchmark/star"
-                    ;;
-            esac
+            dir_path="$SCRIPT_DIR/exps/$benchmark/$topology"
             
             echo "  Procesando directorio: $dir_path"
             
@@ -66,7 +48,7 @@ for benchmark in "${BENCHMARKS[@]}"; do
             echo "  Limpiando archivos .log en: $dir_path"
             rm -f "$dir_path"/*.log
 
-            echo "--- Procesamiento y limpieza finalizados para [$benchmark, $runner] ---"
+            echo "--- Procesamiento y limpieza finalizados para [$benchmark, $topology] ---"
             echo "------------------------------------------------------------"
 
         ) # --- Fin del subshell ---
@@ -74,7 +56,7 @@ for benchmark in "${BENCHMARKS[@]}"; do
         # Comprobar si el subshell falló
         if [ $? -ne 0 ]; then
             echo "!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!"
-            echo "!!! ATENCIÓN: Falló la combinación [$benchmark, $runner]."
+            echo "!!! ATENCIÓN: Falló la combinación [$benchmark, $topology]."
             echo "!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!"
             echo "------------------------------------------------------------"
         fi
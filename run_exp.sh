@@ -4,7 +4,7 @@
 ACTION="all"    # "compile", "run", "all".
 
 BENCHMARK_NAME="simpleconvolution"
-RUNNER_NAME="runner_star"
+TOPOLOGY_NAME="star"    # tree, star, chain, clique, mesh, optical (ver -topology).
 
 GPU_CONFIGS=(
     "1,2"
@@ -21,24 +21,10 @@ EXTRA_SIM_ARGS="-trace-vis -report-all"
 
 BENCHMARK_SRC_DIR="amd/samples/$BENCHMARK_NAME"
 
-# --- Caso 1: Runner por Defecto ---
-if [ "$RUNNER_NAME" == "runner" ]; then
-    EXP_DIR="$BENCHMARK_SRC_DIR"
-    OUTPUT_NAME_BASE="$BENCHMARK_NAME"
-    OUTPUT_PATH="${EXP_DIR}/${OUTPUT_NAME_BASE}"
-    # GENERATED_MAIN_FILE="" <- No se usa.
-
-# --- Caso 2: Runner Experimental ---
-else
-    TOPOLOGY_NAME="${RUNNER_NAME//runner_/}"
-    EXP_DIR="exps/$BENCHMARK_NAME/$TOPOLOGY_NAME"
-    OUTPUT_NAME_BASE="${BENCHMARK_NAME}_${TOPOLOGY_NAME}"
-    OUTPUT_PATH="${EXP_DIR}/${OUTPUT_NAME_BASE}"
-    GENERATED_MAIN_FILE="${EXP_DIR}/main_${TOPOLOGY_NAME}.go"
-fi
-
-BASE_RUNNER_PATH="github.com/sarchlab/mgpusim/v4/amd/samples/runner"
-EXP_RUNNER_PATH="github.com/sarchlab/mgpusim/v4/amd/samples/$RUNNER_NAME"
+# Un solo binario por benchmark; la topología se elige con -topology.
+EXP_DIR="exps/$BENCHMARK_NAME/$TOPOLOGY_NAME"
+OUTPUT_NAME_BASE="${BENCHMARK_NAME}_${TOPOLOGY_NAME}"
+OUTPUT_PATH="${EXP_DIR}/${OUTPUT_NAME_BASE}"
 
 
 # --- Función de Compilación ---
@@ -60,15 +46,7 @@ function compile_experiment {
     fi
     
     # --- 3. Lógica de compilación ---
-    if [ "$RUNNER_NAME" == "runner" ]; then
-        # Compilamos el archivo original en su carpeta
-        go build -o "$OUTPUT_PATH" "$source_go_file"
-    else
-        # Debemos copiar, modificar y compilar el nuevo archivo
-        cp "$source_go_file" "$GENERATED_MAIN_FILE"
-        sed -i "s|${BASE_RUNNER_PATH}|${EXP_RUNNER_PATH}|" "$GENERATED_MAIN_FILE"
-        go build -o "$OUTPUT_PATH" "$GENERATED_MAIN_FILE"
-    fi
+    go build -o "$OUTPUT_PATH" "$source_go_file"
 
     if [ ! -f "$OUTPUT_PATH" ]; then
         echo "¡Error de compilación!"
@@ -97,7 +75,7 @@ function run_experiment {
         echo "Ejecutando con -gpus=$gpu_list ($NUM_RUNS veces)..."
         for (( i=1; i<=$NUM_RUNS; i++ )); do
             
-            local sim_args="-timing $EXTRA_SIM_ARGS -gpus=$gpu_list"
+            local sim_args="-timing -topology=$TOPOLOGY_NAME $EXTRA_SIM_ARGS -gpus=$gpu_list"
             ./"$OUTPUT_NAME_BASE" $sim_args > "run_${gpu_list}_${i}.log" 2>&1
             
             total_runs=$((total_runs + 1))